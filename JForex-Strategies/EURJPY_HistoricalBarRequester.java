@@ -119,6 +119,32 @@ public class EURJPY_HistoricalBarRequester implements IStrategy {
                 String[] kv = pair.split(":", 2);
                 if (kv.length == 2) commandMap.put(kv[0].trim(), kv[1].trim());
             }
+            // A fromMs/toMs range (bar end times) asks for the bars between two times, optionally for one period
+            long fromMs = Long.parseLong(commandMap.getOrDefault("fromMs", "0"));
+            long toMs = Long.parseLong(commandMap.getOrDefault("toMs", "0"));
+            if (fromMs > 0 || toMs > 0) {
+                if (fromMs <= 0 || toMs <= fromMs) {
+                    console.getErr().println("Ignoring invalid historical range: " + jsonCommand);
+                    return;
+                }
+                String periodName = commandMap.getOrDefault("period", "");
+                Period[] periods = REQUEST_PERIODS;
+                if (!periodName.isEmpty()) {
+                    Period period = findRequestPeriod(periodName);
+                    if (period == null) {
+                        console.getErr().println("Ignoring historical range for unknown period: " + periodName);
+                        return;
+                    }
+                    periods = new Period[] { period };
+                }
+                Period[] rangePeriods = periods;
+                context.executeTask((Callable<Void>) () -> {
+                    fetchAndSendHistoricalRange(INSTRUMENT, rangePeriods, fromMs, toMs);
+                    return null;
+                });
+                return;
+            }
+
             int barsCount = Integer.parseInt(commandMap.getOrDefault("barsCount", String.valueOf(defaultBarsCount)));
             context.executeTask((Callable<Void>) () -> {
                 fetchAndSendHistoricalBars(INSTRUMENT, barsCount);
@@ -140,14 +166,54 @@ public class EURJPY_HistoricalBarRequester implements IStrategy {
                 List<IBar> bidBars = history.getBars(instrument, period, OfferSide.BID, Filter.WEEKENDS, barsToRequest, toTime, 0);
                 List<IBar> askBars = history.getBars(instrument, period, OfferSide.ASK, Filter.WEEKENDS, barsToRequest, toTime, 0);
 
-                calculateAndSendBars(instrument, period, bidBars, askBars, barsCount);
+                calculateAndSendBars(instrument, period, bidBars, askBars, barsCount, true);
             } catch (Exception e) {
                 console.getErr().println("Exception while fetching for " + instrument + " " + period + ": " + e.getMessage());
             }
         }
     }
 
-    private void calculateAndSendBars(Instrument instrument, Period period, List<IBar> allBidBars, List<IBar> allAskBars, int barsToSend) throws JFException, IOException {
+    /**
+     * Sends the bars whose end time lies within [fromMs, toMs] for each period. The bars are
+     * unnumbered (sequence 0) so the backend merges them into its buffer rather than treating the
+     * response as a full backfill. Indicators are warmed up on the bars before the range.
+     */
+    private void fetchAndSendHistoricalRange(Instrument instrument, Period[] periods, long fromMs, long toMs) {
+        console.getOut().println("Fetching " + instrument + " bars ending between " + fromMs + " and " + toMs + "...");
+        for (Period period : periods) {
+            try {
+                // History is keyed by bar start; the range is in bar end times
+                long interval = period.getInterval();
+                long firstBarStart = history.getBarStart(period, fromMs - interval);
+                long lastBarStart = history.getBarStart(period, Math.min(toMs, System.currentTimeMillis()) - interval);
+                if (lastBarStart < firstBarStart) continue;
+
+                List<IBar> rangeBars = history.getBars(instrument, period, OfferSide.BID, firstBarStart, lastBarStart);
+                int barsInRange = rangeBars == null ? 0 : rangeBars.size();
+                if (barsInRange == 0) {
+                    console.getOut().println("No historical bars in range for " + instrument + " " + period);
+                    continue;
+                }
+                int barsToRequest = barsInRange + INDICATOR_HISTORY_BUFFER;
+
+                List<IBar> bidBars = history.getBars(instrument, period, OfferSide.BID, Filter.WEEKENDS, barsToRequest, lastBarStart, 0);
+                List<IBar> askBars = history.getBars(instrument, period, OfferSide.ASK, Filter.WEEKENDS, barsToRequest, lastBarStart, 0);
+
+                calculateAndSendBars(instrument, period, bidBars, askBars, barsInRange, false);
+            } catch (Exception e) {
+                console.getErr().println("Exception while fetching range for " + instrument + " " + period + ": " + e.getMessage());
+            }
+        }
+    }
+
+    private static Period findRequestPeriod(String name) {
+        for (Period period : REQUEST_PERIODS) {
+            if (period.name().equals(name)) return period;
+        }
+        return null;
+    }
+
+    private void calculateAndSendBars(Instrument instrument, Period period, List<IBar> allBidBars, List<IBar> allAskBars, int barsToSend, boolean numbered) throws JFException, IOException {
         if (allBidBars == null || allAskBars == null || allBidBars.isEmpty() || allAskBars.isEmpty()) {
             console.getOut().println("No historical bars found for " + instrument + " " + period);
             return;
@@ -166,7 +232,8 @@ public class EURJPY_HistoricalBarRequester implements IStrategy {
             IBar askBar = findMatchingBar(bidBar.getTime(), allAskBars);
             if (askBar == null) continue;
 
-            int sequence = totalBars - sentCount;
+            // A full response is numbered N..1 oldest to newest; range responses are unnumbered
+            int sequence = numbered ? totalBars - sentCount : 0;
             String jsonMessage = formatBarToJson(instrument, period, askBar, bidBar, bidTi, askTi, sequence, i);
             sendMessage(jsonMessage);
             sentCount++;
@@ -26,6 +26,7 @@ public class TradeManager implements IStrategy {
     private static final String AMQP_PASSWORD = "mark";
     private static final String ACCOUNT_INFO_QUEUE_NAME = "Account_Info";
     private static final String TRADE_COMMANDS_QUEUE_NAME = "Trade_Commands";
+    private static final String TRADE_RESULTS_QUEUE_NAME = "Trade_Results";
 
     // --- JForex and RabbitMQ state ---
     private IConsole console;
@@ -169,6 +170,9 @@ public class TradeManager implements IStrategy {
                 case "MODIFY_ORDER":
                     handleModifyOrder(cmdMap);
                     break;
+                case "CANCEL_ORDER":
+                    handleCancelOrder(cmdMap);
+                    break;
                 default:
                     console.getErr().println("Unknown command received: " + command);
             }
@@ -192,6 +196,7 @@ public class TradeManager implements IStrategy {
             console.getOut().println("Submitted order for " + instrument + " amount " + amount);
         } catch (Exception e) {
             console.getErr().println("Failed to submit order: " + e.getMessage());
+            publishTradeResult(cmdMap.getOrDefault("label", ""), "SUBMIT_ORDER", "ERROR", "", e.getMessage(), 0);
         }
     }
 
@@ -204,9 +209,29 @@ public class TradeManager implements IStrategy {
                 console.getOut().println("Closing order ID: " + orderId);
             } else {
                 console.getErr().println("Could not close order. ID not found or order not open: " + orderId);
+                publishTradeResult(orderId, "CLOSE_ORDER", "ERROR", orderId, "order not found or not open", 0);
             }
         } catch (Exception e) {
             console.getErr().println("Failed to close order: " + e.getMessage());
+            publishTradeResult(cmdMap.getOrDefault("orderId", ""), "CLOSE_ORDER", "ERROR", cmdMap.getOrDefault("orderId", ""), e.getMessage(), 0);
+        }
+    }
+
+    private void handleCancelOrder(Map<String, String> cmdMap) throws JFException {
+        try {
+            String orderId = cmdMap.get("orderId");
+            IOrder order = engine.getOrderById(orderId);
+            // Only working (unfilled) orders can be cancelled; filled positions must use CLOSE_ORDER
+            if (order != null && order.getState() == IOrder.State.OPENED) {
+                order.close();
+                console.getOut().println("Cancelling pending order ID: " + orderId);
+            } else {
+                console.getErr().println("Could not cancel order. ID not found or order not pending: " + orderId);
+                publishTradeResult(orderId, "CANCEL_ORDER", "ERROR", orderId, "order not found or not pending", 0);
+            }
+        } catch (Exception e) {
+            console.getErr().println("Failed to cancel order: " + e.getMessage());
+            publishTradeResult(cmdMap.getOrDefault("orderId", ""), "CANCEL_ORDER", "ERROR", cmdMap.getOrDefault("orderId", ""), e.getMessage(), 0);
         }
     }
 
@@ -227,10 +252,89 @@ public class TradeManager implements IStrategy {
                 }
             } else {
                 console.getErr().println("Could not modify order. ID not found or order not open: " + orderId);
+                publishTradeResult(orderId, "MODIFY_ORDER", "ERROR", orderId, "order not found or not open", 0);
             }
         } catch (Exception e) {
             console.getErr().println("Failed to modify order: " + e.getMessage());
+            publishTradeResult(cmdMap.getOrDefault("orderId", ""), "MODIFY_ORDER", "ERROR", cmdMap.getOrDefault("orderId", ""), e.getMessage(), 0);
+        }
+    }
+
+    /**
+     * Reports the broker's outcome of each order command on Trade_Results.
+     * Submit results are keyed by the order label; close, cancel and modify results by the order ID,
+     * matching how the backend logs those requests. Closes triggered by SL/TP are reported as CLOSED too.
+     */
+    @Override
+    public void onMessage(IMessage message) {
+        IOrder order = message.getOrder();
+        if (order == null) return;
+        String reason = message.getContent();
+        switch (message.getType()) {
+            case ORDER_SUBMIT_OK:
+                publishTradeResult(order.getLabel(), "SUBMIT_ORDER", "ACCEPTED", order.getId(), "", 0);
+                break;
+            case ORDER_SUBMIT_REJECTED:
+            case ORDER_FILL_REJECTED:
+                publishTradeResult(order.getLabel(), "SUBMIT_ORDER", "REJECTED", order.getId(), reason, 0);
+                break;
+            case ORDER_FILL_OK:
+                publishTradeResult(order.getLabel(), "SUBMIT_ORDER", "FILLED", order.getId(), "", order.getOpenPrice());
+                break;
+            case ORDER_CLOSE_OK:
+                // An order closed before it was filled was a cancelled working order
+                if (order.getFillTime() == 0) {
+                    publishTradeResult(order.getId(), "CANCEL_ORDER", "CANCELED", order.getId(), reason, 0);
+                } else {
+                    publishTradeResult(order.getId(), "CLOSE_ORDER", "CLOSED", order.getId(), reason, order.getClosePrice());
+                }
+                break;
+            case ORDER_CLOSE_REJECTED:
+                String closeCommand = order.getState() == IOrder.State.OPENED ? "CANCEL_ORDER" : "CLOSE_ORDER";
+                publishTradeResult(order.getId(), closeCommand, "REJECTED", order.getId(), reason, 0);
+                break;
+            case ORDER_CHANGED_OK:
+                Set<IMessage.Reason> reasons = message.getReasons();
+                if (reasons.contains(IMessage.Reason.ORDER_CHANGED_SL) || reasons.contains(IMessage.Reason.ORDER_CHANGED_TP)) {
+                    publishTradeResult(order.getId(), "MODIFY_ORDER", "MODIFIED", order.getId(), "", 0);
+                }
+                break;
+            case ORDER_CHANGED_REJECTED:
+                publishTradeResult(order.getId(), "MODIFY_ORDER", "REJECTED", order.getId(), reason, 0);
+                break;
+            default:
+                break;
+        }
+    }
+
+    private void publishTradeResult(String label, String command, String status, String orderId, String reason, double price) {
+        try {
+            String json = String.format(Locale.US,
+                "{\"produced_at\":%d,\"label\":\"%s\",\"command\":\"%s\",\"status\":\"%s\",\"orderId\":\"%s\",\"reason\":\"%s\",\"price\":%.5f}",
+                System.currentTimeMillis(), escapeJson(label), command, status, escapeJson(orderId), escapeJson(reason), price
+            );
+            sendMessage(TRADE_RESULTS_QUEUE_NAME, json);
+        } catch (Exception e) {
+            console.getErr().println("Error publishing trade result: " + e.getMessage());
+        }
+    }
+
+    private static String escapeJson(String s) {
+        if (s == null) return "";
+        StringBuilder sb = new StringBuilder(s.length());
+        for (char c : s.toCharArray()) {
+            switch (c) {
+                case '"': sb.append("\\\""); break;
+                case '\\': sb.append("\\\\"); break;
+                case '\n': sb.append("\\n"); break;
+                case '\r': sb.append("\\r"); break;
+                case '\t': sb.append("\\t"); break;
+                default:
+                    if (c < 0x20) sb.append(String.format("\\u%04x", (int) c));
+                    else sb.append(c);
+            }
         }
+        return sb.toString();
     }
 
     private boolean initializeAmqp() {
@@ -249,8 +353,9 @@ public class TradeManager implements IStrategy {
                 this.amqpChannel = amqpConnection.createChannel();
                 this.amqpChannel.queueDeclare(ACCOUNT_INFO_QUEUE_NAME, true, false, false, null);
                 this.amqpChannel.queueDeclare(TRADE_COMMANDS_QUEUE_NAME, true, false, false, null);
+                this.amqpChannel.queueDeclare(TRADE_RESULTS_QUEUE_NAME, true, false, false, null);
                 
-                console.getOut().println("AMQP connection established. Listening on '" + TRADE_COMMANDS_QUEUE_NAME + "', publishing to '" + ACCOUNT_INFO_QUEUE_NAME + "'.");
+                console.getOut().println("AMQP connection established. Listening on '" + TRADE_COMMANDS_QUEUE_NAME + "', publishing to '" + ACCOUNT_INFO_QUEUE_NAME + "' and '" + TRADE_RESULTS_QUEUE_NAME + "'.");
                 return true;
             } catch (Exception e) {
                 console.getErr().println("AMQP initialization failed: " + e.getMessage());
@@ -285,6 +390,5 @@ public class TradeManager implements IStrategy {
     // --- Unused IStrategy Methods ---
     @Override public void onTick(Instrument i, ITick t) {}
     @Override public void onBar(Instrument i, Period p, IBar a, IBar b) {}
-    @Override public void onMessage(IMessage m) {}
     @Override public void onAccount(IAccount a) {}
 }
\ No newline at end of file
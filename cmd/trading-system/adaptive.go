@@ -0,0 +1,56 @@
+package main
+
+import "time"
+
+// Adaptive broadcast rate.
+// What: Broadcasting the full state every second while the market is closed is wasted work, and a
+//      fast market deserves quicker updates.
+// How: After each broadcast the tick arrival rate since the previous one picks the next interval:
+//      below quietTicksPerSec the interval doubles up to max, above busyTicksPerSec it halves down
+//      to min, and in between it returns to the configured base. Commands and strategy status
+//      changes are handled between broadcasts regardless of the interval.
+
+const (
+	// Tick arrival rates (ticks/s across all instruments) that lengthen or shorten the interval
+	quietTicksPerSec = 0.5
+	busyTicksPerSec  = 20.0
+)
+
+// broadcastRate is the configured base interval with its floor and ceiling.
+type broadcastRate struct {
+	base, min, max time.Duration
+}
+
+// adaptiveInterval tracks the current broadcast interval. It is only used from Start.
+type adaptiveInterval struct {
+	rate      broadcastRate
+	current   time.Duration
+	lastCount int64
+	lastAt    time.Time
+}
+
+// set applies a new configuration and returns to the base interval.
+func (a *adaptiveInterval) set(r broadcastRate) time.Duration {
+	a.rate = r
+	a.current = min(max(r.base, r.min), r.max)
+	return a.current
+}
+
+// next returns the interval to use after a broadcast at now, given the total tick count so far.
+func (a *adaptiveInterval) next(tickCount int64, now time.Time) time.Duration {
+	if !a.lastAt.IsZero() {
+		if elapsed := now.Sub(a.lastAt).Seconds(); elapsed > 0 {
+			perSec := float64(tickCount-a.lastCount) / elapsed
+			switch {
+			case perSec < quietTicksPerSec:
+				a.current = min(a.current*2, a.rate.max)
+			case perSec > busyTicksPerSec:
+				a.current = max(a.current/2, a.rate.min)
+			default:
+				a.current = min(max(a.rate.base, a.rate.min), a.rate.max)
+			}
+		}
+	}
+	a.lastCount, a.lastAt = tickCount, now
+	return a.current
+}
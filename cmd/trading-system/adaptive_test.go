@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveIntervalFollowsTickRate(t *testing.T) {
+	var a adaptiveInterval
+	if got := a.set(broadcastRate{base: time.Second, min: 250 * time.Millisecond, max: 4 * time.Second}); got != time.Second {
+		t.Fatalf("initial interval %v, want the base", got)
+	}
+	now := time.Unix(0, 0)
+	var ticks int64
+	step := func(newTicks int64) time.Duration {
+		now = now.Add(a.current)
+		ticks += newTicks
+		return a.next(ticks, now)
+	}
+	step(0) // first call only records the baseline
+
+	for _, want := range []time.Duration{2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		if got := step(0); got != want {
+			t.Fatalf("quiet: got %v, want %v", got, want)
+		}
+	}
+	if got := step(8); got != time.Second { // 2 ticks/s
+		t.Fatalf("normal activity: got %v, want the base", got)
+	}
+	for _, want := range []time.Duration{500 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond} {
+		if got := step(100); got != want {
+			t.Fatalf("busy: got %v, want %v", got, want)
+		}
+	}
+}
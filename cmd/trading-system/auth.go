@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireAdminToken guards state-changing admin endpoints with a shared token.
+// What: Operator-only actions (e.g. clearing state) must not be reachable by any dashboard client.
+// How: The token is read from "Authorization: Bearer <token>" or the X-Admin-Token header and compared
+//      in constant time. When no token is configured the endpoint is disabled rather than left open.
+// Params: token configured via GOTRADER_ADMIN_TOKEN, next the handler to protect
+func requireAdminToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if token == "" {
+			writeError(w, http.StatusForbidden, errCodeForbidden, "admin endpoints disabled; set GOTRADER_ADMIN_TOKEN")
+			return
+		}
+		got := r.Header.Get("X-Admin-Token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			got = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, errCodeUnauthorized, "missing or invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdminToken(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	cases := []struct {
+		name, token, header, value string
+		want                       int
+	}{
+		{"disabled without token", "", "Authorization", "Bearer x", http.StatusForbidden},
+		{"missing", "s3cret", "", "", http.StatusUnauthorized},
+		{"wrong", "s3cret", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"bearer", "s3cret", "Authorization", "Bearer s3cret", http.StatusNoContent},
+		{"header", "s3cret", "X-Admin-Token", "s3cret", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/state/clear?instrument=EURUSD", nil)
+		if c.header != "" {
+			req.Header.Set(c.header, c.value)
+		}
+		rec := httptest.NewRecorder()
+		requireAdminToken(c.token, ok)(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s: status %d, want %d", c.name, rec.Code, c.want)
+		}
+	}
+}
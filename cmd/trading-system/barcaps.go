@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-trader/internal/state"
+)
+
+// Per-period bar caps for the WebSocket broadcast.
+// What: The UI rarely needs the whole 200-bar buffer of every period, so the snapshot and the
+//      HISTORICAL_BARS pushes can send only the newest N bars of chosen periods.
+// How: Caps trim the slices in broadcastCurrentState before marshalling; the state manager keeps
+//      every bar for strategies, and GET /api/bars returns the full historical series.
+
+// parseBarCaps parses per-period caps, e.g. "TEN_SECS:60,ONE_MIN:60" (whole bars, >= 1).
+// Periods left out are sent in full.
+func parseBarCaps(v string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		period, n, ok := strings.Cut(entry, ":")
+		period = strings.ToUpper(strings.TrimSpace(period))
+		if !ok {
+			return nil, fmt.Errorf("bar cap %q: want PERIOD:n", entry)
+		}
+		if !slices.Contains(periodList, period) {
+			return nil, fmt.Errorf("bar cap %q: unknown period %q", entry, period)
+		}
+		c, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil || c < 1 {
+			return nil, fmt.Errorf("bar cap %q: want a positive bar count", entry)
+		}
+		out[period] = c
+	}
+	return out, nil
+}
+
+// newestLiveBars returns the newest n of bars, which are oldest-first; n <= 0 means all.
+func newestLiveBars(bars []state.Bar, n int) []state.Bar {
+	if n > 0 && len(bars) > n {
+		return bars[len(bars)-n:]
+	}
+	return bars
+}
+
+// newestHistoricalBars returns the newest n of bars, which are newest-first; n <= 0 means all.
+func newestHistoricalBars(bars []state.HistoricalBar, n int) []state.HistoricalBar {
+	if n > 0 && len(bars) > n {
+		return bars[:n]
+	}
+	return bars
+}
+
+// SetBarCaps replaces the per-period caps on broadcast bars.
+func (fb *FrontendBroadcaster) SetBarCaps(caps map[string]int) {
+	fb.settingsMu.Lock()
+	defer fb.settingsMu.Unlock()
+	fb.barCaps = caps
+}
+
+// barCapsSnapshot returns the current per-period caps; the map is never modified after SetBarCaps.
+func (fb *FrontendBroadcaster) barCapsSnapshot() map[string]int {
+	fb.settingsMu.Lock()
+	defer fb.settingsMu.Unlock()
+	return fb.barCaps
+}
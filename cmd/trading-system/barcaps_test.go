@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"go-trader/internal/state"
+)
+
+func TestParseBarCaps(t *testing.T) {
+	caps, err := parseBarCaps(" one_min:60, TEN_SECS:30 ,")
+	if err != nil || len(caps) != 2 || caps["ONE_MIN"] != 60 || caps["TEN_SECS"] != 30 {
+		t.Fatalf("got %v, %v; want ONE_MIN:60 and TEN_SECS:30", caps, err)
+	}
+	for _, bad := range []string{"ONE_MIN", "ONE_MIN:0", "ONE_MIN:x", "WEEKLY:10"} {
+		if _, err := parseBarCaps(bad); err == nil {
+			t.Errorf("parseBarCaps(%q) accepted, want an error", bad)
+		}
+	}
+}
+
+func TestNewestBarsKeepsNewestEnd(t *testing.T) {
+	live := []state.Bar{{BarEndTimestamp: 1}, {BarEndTimestamp: 2}, {BarEndTimestamp: 3}}
+	if got := newestLiveBars(live, 2); len(got) != 2 || got[0].BarEndTimestamp != 2 || got[1].BarEndTimestamp != 3 {
+		t.Fatalf("live bars = %+v, want the last two", got)
+	}
+	hist := []state.HistoricalBar{{BarEndTimestamp: 3}, {BarEndTimestamp: 2}, {BarEndTimestamp: 1}}
+	if got := newestHistoricalBars(hist, 2); len(got) != 2 || got[0].BarEndTimestamp != 3 || got[1].BarEndTimestamp != 2 {
+		t.Fatalf("historical bars = %+v, want the first two", got)
+	}
+	if got := newestHistoricalBars(hist, 0); len(got) != 3 {
+		t.Fatalf("uncapped: got %d bars, want all 3", len(got))
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-trader/internal/amqp"
+)
+
+// chaosConfig is the fault injection enabled by GOTRADER_CHAOS=1 (see amqp/chaos.go and
+// db/chaos.go). It is for resilience testing against a test broker and database only.
+type chaosConfig struct {
+	amqp      amqp.Chaos
+	stallRate float64       // fraction of DB writes stalled
+	stall     time.Duration // how long a stalled DB write waits
+}
+
+// readChaosConfig reads the chaos knobs; enabled is false unless GOTRADER_CHAOS is "1".
+func readChaosConfig() (cfg chaosConfig, enabled bool, err error) {
+	if envOr("GOTRADER_CHAOS", "") != "1" {
+		return cfg, false, nil
+	}
+	rate := func(key, def string) (float64, error) {
+		v, err := strconv.ParseFloat(envOr(key, def), 64)
+		if err != nil || v < 0 || v > 1 {
+			return 0, fmt.Errorf("invalid %s: want a fraction in [0, 1]", key)
+		}
+		return v, nil
+	}
+	duration := func(key, def string) (time.Duration, error) {
+		d, err := time.ParseDuration(envOr(key, def))
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid %s: want a non-negative Go duration", key)
+		}
+		return d, nil
+	}
+	if cfg.amqp.DropRate, err = rate("GOTRADER_CHAOS_DROP_RATE", defaultChaosDropRate); err != nil {
+		return cfg, true, err
+	}
+	if cfg.amqp.MinLatency, cfg.amqp.MaxLatency, err = parseLatencyRange(envOr("GOTRADER_CHAOS_LATENCY", defaultChaosLatency)); err != nil {
+		return cfg, true, fmt.Errorf("invalid GOTRADER_CHAOS_LATENCY: %w", err)
+	}
+	if cfg.amqp.DisconnectInterval, err = duration("GOTRADER_CHAOS_DISCONNECT_INTERVAL", defaultChaosDisconnectInterval); err != nil {
+		return cfg, true, err
+	}
+	if cfg.amqp.ConnectionDropInterval, err = duration("GOTRADER_CHAOS_CONNECTION_DROP_INTERVAL", defaultChaosConnectionDropInterval); err != nil {
+		return cfg, true, err
+	}
+	if cfg.stallRate, err = rate("GOTRADER_CHAOS_DB_STALL_RATE", defaultChaosDBStallRate); err != nil {
+		return cfg, true, err
+	}
+	if cfg.stall, err = duration("GOTRADER_CHAOS_DB_STALL", defaultChaosDBStall); err != nil {
+		return cfg, true, err
+	}
+	return cfg, true, nil
+}
+
+// parseLatencyRange parses "min-max" Go durations, e.g. "10ms-200ms"; a single duration is a fixed delay.
+func parseLatencyRange(v string) (min, max time.Duration, err error) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(v), "-")
+	if min, err = time.ParseDuration(strings.TrimSpace(lo)); err != nil {
+		return 0, 0, err
+	}
+	max = min
+	if isRange {
+		if max, err = time.ParseDuration(strings.TrimSpace(hi)); err != nil {
+			return 0, 0, err
+		}
+	}
+	if min < 0 || max < min {
+		return 0, 0, fmt.Errorf("want 0 <= min <= max, got %q", v)
+	}
+	return min, max, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLatencyRange(t *testing.T) {
+	if lo, hi, err := parseLatencyRange("10ms-200ms"); err != nil || lo != 10*time.Millisecond || hi != 200*time.Millisecond {
+		t.Fatalf("range: got %s-%s, %v", lo, hi, err)
+	}
+	if lo, hi, err := parseLatencyRange("50ms"); err != nil || lo != hi || lo != 50*time.Millisecond {
+		t.Fatalf("fixed: got %s-%s, %v", lo, hi, err)
+	}
+	for _, bad := range []string{"", "200ms-10ms", "x-1s", "1s-"} {
+		if _, _, err := parseLatencyRange(bad); err == nil {
+			t.Errorf("parseLatencyRange(%q) accepted, want an error", bad)
+		}
+	}
+}
+
+func TestChaosDisabledUnlessEnabled(t *testing.T) {
+	t.Setenv("GOTRADER_CHAOS", "")
+	t.Setenv("GOTRADER_CHAOS_DROP_RATE", "2") // ignored while disabled
+	if _, enabled, err := readChaosConfig(); enabled || err != nil {
+		t.Fatalf("enabled=%v err=%v, want chaos off", enabled, err)
+	}
+	t.Setenv("GOTRADER_CHAOS", "1")
+	if _, _, err := readChaosConfig(); err == nil {
+		t.Fatal("drop rate 2 accepted")
+	}
+	t.Setenv("GOTRADER_CHAOS_DROP_RATE", "0.1")
+	cfg, enabled, err := readChaosConfig()
+	if !enabled || err != nil || cfg.amqp.DropRate != 0.1 || !cfg.amqp.Enabled() {
+		t.Fatalf("got %+v enabled=%v err=%v, want drop rate 0.1", cfg, enabled, err)
+	}
+	if cfg.amqp.ConnectionDropInterval != 30*time.Minute {
+		t.Fatalf("connection drop interval %s, want the 30m default", cfg.amqp.ConnectionDropInterval)
+	}
+	t.Setenv("GOTRADER_CHAOS_CONNECTION_DROP_INTERVAL", "-1s")
+	if _, _, err := readChaosConfig(); err == nil {
+		t.Fatal("negative connection drop interval accepted")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"go-trader/internal/state"
+)
+
+// Bounds checks for the numeric fields of WebSocket commands.
+// What: Stop a negative or absurd value (qty -5, slippage 99999) from turning into a garbage order.
+// How: Every numeric field that is set is checked; zero means "not set" because the fields are
+//      omitempty and processCommand applies defaults. Quantities, slippage and the ATR multiple have
+//      fixed ceilings. Prices and stop distances are checked against the instrument: a price must be
+//      within maxPriceDeviation of the latest mid, and a stop no further than that from it (pips are
+//      converted with the instrument's pip size). Without a tick only the fixed ceilings apply.
+//      A failing command is rejected whole with an invalid_command alert, never clamped.
+
+const (
+	maxCommandQty      = 50.0   // JForex amount (50M units)
+	maxCommandSlippage = 100.0  // pips
+	maxCommandStopPips = 5000.0 // pips, when the instrument has no tick to compare against
+	maxCommandAtrMult  = 20.0
+	// maxPriceDeviation bounds prices and stop distances as a fraction of the latest mid
+	maxPriceDeviation = 0.25
+)
+
+// validateCommandNumbers checks the numeric fields of req against the bounds above. mid is the
+// instrument's latest mid price, or 0 when unknown.
+func validateCommandNumbers(req CommandRequest, mid float64) error {
+	for _, f := range []struct {
+		name string
+		v    float64
+	}{{"qty", req.Qty}, {"price", req.Price}, {"slPips", req.SlPips}, {"tpPips", req.TpPips},
+		{"sl", req.Sl}, {"tp", req.Tp}, {"slippage", req.Slippage}, {"atrMult", req.AtrMult}} {
+		if math.IsNaN(f.v) || math.IsInf(f.v, 0) || f.v < 0 {
+			return fmt.Errorf("%s must be a non-negative number, got %v", f.name, f.v)
+		}
+	}
+	if req.Qty != 0 && (req.Qty < 1.0/lotsPerAmount || req.Qty > maxCommandQty) {
+		return fmt.Errorf("qty %v outside %.3f..%g", req.Qty, 1.0/lotsPerAmount, maxCommandQty)
+	}
+	if req.Slippage > maxCommandSlippage {
+		return fmt.Errorf("slippage %v pips exceeds %g", req.Slippage, maxCommandSlippage)
+	}
+	if req.AtrMult > maxCommandAtrMult {
+		return fmt.Errorf("atrMult %v exceeds %g", req.AtrMult, maxCommandAtrMult)
+	}
+	maxStop := maxCommandStopPips
+	if mid > 0 {
+		maxStop = mid * maxPriceDeviation / state.PipSize(req.Instrument)
+		lo, hi := mid*(1-maxPriceDeviation), mid*(1+maxPriceDeviation)
+		for _, p := range []struct {
+			name string
+			v    float64
+		}{{"price", req.Price}, {"sl", req.Sl}, {"tp", req.Tp}} {
+			if p.v != 0 && (p.v < lo || p.v > hi) {
+				return fmt.Errorf("%s %v is more than %.0f%% from the %s price %v", p.name, p.v, maxPriceDeviation*100, req.Instrument, mid)
+			}
+		}
+	}
+	if req.SlPips > maxStop {
+		return fmt.Errorf("slPips %v exceeds %.0f for %s", req.SlPips, maxStop, req.Instrument)
+	}
+	if req.TpPips > maxStop {
+		return fmt.Errorf("tpPips %v exceeds %.0f for %s", req.TpPips, maxStop, req.Instrument)
+	}
+	return nil
+}
+
+// validateCommand checks req's numbers against the latest mid of its instrument. Commands that only
+// name an order (MODIFY_ORDER) are checked against the instrument of that position or pending order.
+func (fb *FrontendBroadcaster) validateCommand(req CommandRequest) error {
+	if req.Instrument == "" && req.OrderID != "" {
+		req.Instrument = fb.orderInstrument(req.OrderID)
+	}
+	return validateCommandNumbers(req, fb.latestMid(req.Instrument))
+}
+
+// orderInstrument returns the instrument of the open position or pending order orderID, or "".
+func (fb *FrontendBroadcaster) orderInstrument(orderID string) string {
+	acct := fb.stateManager.GetAccountInfo()
+	for _, orders := range [][]state.Position{acct.Positions, acct.PendingOrders} {
+		for _, o := range orders {
+			if o.OrderID == orderID {
+				return o.Instrument
+			}
+		}
+	}
+	return ""
+}
+
+// latestMid returns the mid of instrument's newest tick, or 0 without one.
+func (fb *FrontendBroadcaster) latestMid(instrument string) float64 {
+	t, ok := fb.stateManager.LatestTicks()[instrument]
+	if !ok || t.Bid <= 0 || t.Ask <= 0 {
+		return 0
+	}
+	return (t.Bid + t.Ask) / 2
+}
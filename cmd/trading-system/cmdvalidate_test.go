@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"go-trader/internal/state"
+)
+
+func TestValidateCommandNumbersRejectsEachField(t *testing.T) {
+	valid := CommandRequest{Type: "PLACE_LIMIT", Instrument: "EURUSD", Side: "BUY", Qty: 0.1, Price: 1.1,
+		SlPips: 20, TpPips: 40, Slippage: 5, AtrMult: 1.5}
+	if err := validateCommandNumbers(valid, 1.1); err != nil {
+		t.Fatalf("valid command rejected: %v", err)
+	}
+	if err := validateCommandNumbers(CommandRequest{Type: "PLACE_ORDER", Instrument: "EURUSD"}, 0); err != nil {
+		t.Fatalf("unset fields rejected: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		mutate func(*CommandRequest)
+		mid    float64
+	}{
+		"negative qty":        {func(r *CommandRequest) { r.Qty = -5 }, 1.1},
+		"qty below min lot":   {func(r *CommandRequest) { r.Qty = 0.0001 }, 1.1},
+		"huge qty":            {func(r *CommandRequest) { r.Qty = 1000 }, 1.1},
+		"negative price":      {func(r *CommandRequest) { r.Price = -1.1 }, 1.1},
+		"price far from mid":  {func(r *CommandRequest) { r.Price = 11 }, 1.1},
+		"nan price":           {func(r *CommandRequest) { r.Price = math.NaN() }, 1.1},
+		"negative slPips":     {func(r *CommandRequest) { r.SlPips = -20 }, 1.1},
+		"slPips beyond price": {func(r *CommandRequest) { r.SlPips = 4000 }, 1.1},
+		"slPips without tick": {func(r *CommandRequest) { r.SlPips = 9000 }, 0},
+		"negative tpPips":     {func(r *CommandRequest) { r.TpPips = -1 }, 1.1},
+		"tpPips beyond price": {func(r *CommandRequest) { r.TpPips = 4000 }, 1.1},
+		"absolute sl far off": {func(r *CommandRequest) { r.Sl = 0.5 }, 1.1},
+		"absolute tp far off": {func(r *CommandRequest) { r.Tp = 2 }, 1.1},
+		"negative slippage":   {func(r *CommandRequest) { r.Slippage = -1 }, 1.1},
+		"huge slippage":       {func(r *CommandRequest) { r.Slippage = 99999 }, 1.1},
+		"negative atrMult":    {func(r *CommandRequest) { r.AtrMult = -1 }, 1.1},
+		"huge atrMult":        {func(r *CommandRequest) { r.AtrMult = 500 }, 1.1},
+		"infinite atrMult":    {func(r *CommandRequest) { r.AtrMult = math.Inf(1) }, 1.1},
+	} {
+		req := valid
+		tc.mutate(&req)
+		if err := validateCommandNumbers(req, tc.mid); err == nil {
+			t.Errorf("%s: accepted %+v", name, req)
+		}
+	}
+}
+
+func TestValidateCommandNumbersScalesStopsByPipSize(t *testing.T) {
+	// 25% of 150.00 is 3750 pips at USDJPY's 0.01 pip size
+	req := CommandRequest{Instrument: "USDJPY", SlPips: 3000}
+	if err := validateCommandNumbers(req, 150); err != nil {
+		t.Fatalf("USDJPY 3000 pip stop rejected: %v", err)
+	}
+	req.SlPips = 4000
+	if err := validateCommandNumbers(req, 150); err == nil {
+		t.Fatal("USDJPY 4000 pip stop (beyond 25% of the price) accepted")
+	}
+}
+
+func TestValidateCommandResolvesOrderInstrument(t *testing.T) {
+	sm := state.NewStateManager()
+	fb := &FrontendBroadcaster{stateManager: sm}
+	sm.UpdateTick(state.Tick{Instrument: "USDJPY", Timestamp: 1, Bid: 149.99, Ask: 150.01})
+	sm.UpdateAccountInfo(state.AccountInfo{Positions: []state.Position{{OrderID: "42", Instrument: "USDJPY", OrderCommand: "BUY"}}})
+
+	// A MODIFY_ORDER carries only the orderId; its SL is checked against USDJPY's price
+	modify := CommandRequest{Type: "MODIFY_ORDER", OrderID: "42", Sl: 1.1}
+	if err := fb.validateCommand(modify); err == nil {
+		t.Fatal("SL of 1.1 on a USDJPY position accepted")
+	}
+	modify.Sl = 149.5
+	if err := fb.validateCommand(modify); err != nil {
+		t.Fatalf("SL near the USDJPY price rejected: %v", err)
+	}
+	if got := fb.orderInstrument("unknown"); got != "" {
+		t.Fatalf("unknown order resolved to %q, want none", got)
+	}
+}
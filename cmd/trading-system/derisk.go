@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"go-trader/internal/state"
+)
+
+// Forced liquidation for margin emergencies.
+// What: When MarginAvailable/Equity falls below a fraction, close the largest losing positions until
+//      the ratio recovers, instead of waiting for the broker's margin call.
+// How: derisk closes one position at a time, largest loss first, then waits for a newer account
+//      snapshot without the closed position before re-checking, so each decision uses the margin the
+//      broker reports after the previous close. The monitor runs it on an interval; POST /api/derisk runs it on demand.
+
+// errDeriskRunning is returned when a derisk pass is already in progress.
+var errDeriskRunning = errors.New("derisk already running")
+
+// errNoFreshSnapshot is returned when no newer account snapshot reflecting a forced close arrives.
+var errNoFreshSnapshot = errors.New("no fresh account snapshot after close")
+
+// deriskPoll is how often derisk checks for a newer account snapshot.
+const deriskPoll = 100 * time.Millisecond
+
+// marginRatio returns available margin as a fraction of equity. Available margin is
+// MarginAvailable, falling back to FreeMargin when the broker leaves it at 0.
+// ok is false without equity to compare against.
+func marginRatio(acct state.Account) (ratio float64, ok bool) {
+	if acct.Equity <= 0 {
+		return 0, false
+	}
+	avail := acct.MarginAvailable
+	if avail == 0 {
+		avail = acct.FreeMargin
+	}
+	return avail / acct.Equity, true
+}
+
+// losingPositions returns the positions with negative PnL, largest loss first, skipping orderIDs in skip.
+func losingPositions(positions []state.Position, skip map[string]bool) []state.Position {
+	var out []state.Position
+	for _, pos := range positions {
+		if pos.PnL < 0 && !skip[pos.OrderID] {
+			out = append(out, pos)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].PnL < out[j].PnL })
+	return out
+}
+
+// hasPosition reports whether positions include orderID.
+func hasPosition(positions []state.Position, orderID string) bool {
+	for _, pos := range positions {
+		if pos.OrderID == orderID {
+			return true
+		}
+	}
+	return false
+}
+
+// DeriskResult reports one derisk pass.
+type DeriskResult struct {
+	Target     float64  `json:"target"`
+	StartRatio float64  `json:"startRatio"`
+	Ratio      float64  `json:"ratio"`     // after the last fresh snapshot
+	Closed     []string `json:"closed"`    // orderIds a close was requested for, in order
+	Recovered  bool     `json:"recovered"` // Ratio >= Target
+}
+
+// derisker closes losing positions until the margin ratio reaches a target.
+type derisker struct {
+	sm *state.StateManager
+	// closePosition requests a close of pos; reason is recorded with it
+	closePosition func(pos state.Position, reason string) error
+	// snapshotWait bounds the wait for a newer account snapshot after each close
+	snapshotWait time.Duration
+
+	running sync.Mutex
+}
+
+// run closes the largest losing positions one at a time until the margin ratio reaches target.
+// Returns: the pass result; errDeriskRunning if another pass is in progress, errNoFreshSnapshot
+// if the broker stops reporting, or the first close error. Closed lists what was sent either way.
+func (d *derisker) run(ctx context.Context, target float64, reason string) (DeriskResult, error) {
+	res := DeriskResult{Target: target, Closed: []string{}}
+	if !d.running.TryLock() {
+		return res, errDeriskRunning
+	}
+	defer d.running.Unlock()
+
+	info := d.sm.GetAccountInfo()
+	ratio, ok := marginRatio(info.Account)
+	if !ok {
+		return res, fmt.Errorf("no account equity reported")
+	}
+	res.StartRatio, res.Ratio = ratio, ratio
+	closed := make(map[string]bool)
+	for res.Ratio < target {
+		losers := losingPositions(info.Positions, closed)
+		if len(losers) == 0 {
+			log.Printf("⚠️ Derisk: margin ratio %.3f below target %.3f but no losing positions left to close", res.Ratio, target)
+			return res, nil
+		}
+		pos := losers[0]
+		if err := d.closePosition(pos, reason); err != nil {
+			return res, fmt.Errorf("close %s: %w", pos.OrderID, err)
+		}
+		closed[pos.OrderID] = true
+		res.Closed = append(res.Closed, pos.OrderID)
+		log.Printf("🚨 Derisk: forced close of %s (%s %s %.2f, pnl %.2f) at margin ratio %.3f < %.3f",
+			pos.OrderID, pos.Instrument, pos.OrderCommand, pos.Amount, pos.PnL, res.Ratio, target)
+
+		next, err := d.awaitSnapshot(ctx, info.ProducedAt, pos.OrderID)
+		if err != nil {
+			return res, err
+		}
+		info = next
+		if res.Ratio, ok = marginRatio(info.Account); !ok {
+			return res, fmt.Errorf("no account equity reported")
+		}
+	}
+	res.Recovered = true
+	return res, nil
+}
+
+// awaitSnapshot waits for an account snapshot produced after producedAt that no longer lists orderID.
+// Snapshots sent before the broker processed the close still show the position and its margin use.
+func (d *derisker) awaitSnapshot(ctx context.Context, producedAt int64, orderID string) (state.AccountInfo, error) {
+	timer := time.NewTimer(d.snapshotWait)
+	defer timer.Stop()
+	poll := time.NewTicker(deriskPoll)
+	defer poll.Stop()
+	for {
+		if info := d.sm.GetAccountInfo(); info.ProducedAt > producedAt && !hasPosition(info.Positions, orderID) {
+			return info, nil
+		}
+		select {
+		case <-ctx.Done():
+			return state.AccountInfo{}, ctx.Err()
+		case <-timer.C:
+			return state.AccountInfo{}, errNoFreshSnapshot
+		case <-poll.C:
+		}
+	}
+}
+
+// monitor runs a derisk pass whenever the margin ratio is below threshold, checking every interval.
+func (d *derisker) monitor(ctx context.Context, threshold float64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ratio, ok := marginRatio(d.sm.GetAccountInfo().Account)
+		if !ok || ratio >= threshold {
+			continue
+		}
+		log.Printf("🚨 Margin ratio %.3f below %.3f, starting forced liquidation", ratio, threshold)
+		res, err := d.run(ctx, threshold, "margin_monitor")
+		if err != nil && !errors.Is(err, errDeriskRunning) {
+			log.Printf("❌ Derisk stopped after %d closes: %v", len(res.Closed), err)
+			continue
+		}
+		if len(res.Closed) > 0 {
+			log.Printf("Derisk closed %d positions, margin ratio %.3f -> %.3f (recovered=%v)", len(res.Closed), res.StartRatio, res.Ratio, res.Recovered)
+		}
+	}
+}
+
+// forceClose requests a close of pos for derisking and records it as a forced_close risk event.
+func (fb *FrontendBroadcaster) forceClose(pos state.Position, reason string) error {
+	if err := fb.publisher.PublishCloseOrder(pos.OrderID); err != nil {
+		return err
+	}
+	if fb.dbLogger != nil {
+		fb.dbLogger.LogTradeCloseRequested(pos.OrderID, pos.Instrument, pos.OrderCommand)
+		fb.dbLogger.LogEvent("warn", "risk", "forced_close", map[string]any{"orderId": pos.OrderID, "instrument": pos.Instrument,
+			"side": pos.OrderCommand, "amount": pos.Amount, "pnl": pos.PnL, "reason": reason})
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-trader/internal/state"
+)
+
+func TestDeriskClosesLargestLosersUntilRecovered(t *testing.T) {
+	sm := state.NewStateManager()
+	positions := []state.Position{
+		{OrderID: "win", PnL: 50},
+		{OrderID: "small", PnL: -20},
+		{OrderID: "big", PnL: -300},
+		{OrderID: "mid", PnL: -100},
+	}
+	snapshot := func(producedAt int64, avail float64, open []state.Position) {
+		sm.UpdateAccountInfo(state.AccountInfo{ProducedAt: producedAt,
+			Account: state.Account{Equity: 1000, MarginAvailable: avail}, Positions: open})
+	}
+	snapshot(1, 100, positions)
+
+	// Each close frees 150 of margin, reported in the next snapshot without the closed position
+	var closed []string
+	d := &derisker{sm: sm, snapshotWait: time.Second, closePosition: func(pos state.Position, reason string) error {
+		closed = append(closed, pos.OrderID)
+		var open []state.Position
+		for _, p := range positions {
+			if p.OrderID != pos.OrderID {
+				open = append(open, p)
+			}
+		}
+		positions = open
+		snapshot(int64(len(closed)+1), 100+150*float64(len(closed)), open)
+		return nil
+	}}
+
+	res, err := d.run(context.Background(), 0.3, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(closed) != 2 || closed[0] != "big" || closed[1] != "mid" {
+		t.Fatalf("closed %v, want big then mid", closed)
+	}
+	if !res.Recovered || res.StartRatio != 0.1 || res.Ratio != 0.4 {
+		t.Fatalf("got %+v, want recovery from 0.1 to 0.4", res)
+	}
+
+	// Nothing left to close but winners: stop without recovering
+	res, err = d.run(context.Background(), 0.9, "test")
+	if err != nil || res.Recovered || len(res.Closed) != 1 || res.Closed[0] != "small" {
+		t.Fatalf("got %+v (%v), want only the remaining loser closed", res, err)
+	}
+}
+
+func TestDeriskStopsWithoutFreshSnapshot(t *testing.T) {
+	sm := state.NewStateManager()
+	sm.UpdateAccountInfo(state.AccountInfo{ProducedAt: 1, Account: state.Account{Equity: 1000, FreeMargin: 50},
+		Positions: []state.Position{{OrderID: "a", PnL: -10}, {OrderID: "b", PnL: -5}}})
+	d := &derisker{sm: sm, snapshotWait: 2 * deriskPoll, closePosition: func(state.Position, string) error { return nil }}
+	res, err := d.run(context.Background(), 0.5, "test")
+	if err != errNoFreshSnapshot || len(res.Closed) != 1 {
+		t.Fatalf("got %+v (%v), want one close then errNoFreshSnapshot", res, err)
+	}
+}
+
+func TestDeriskWaitsForSnapshotWithoutClosedPosition(t *testing.T) {
+	sm := state.NewStateManager()
+	open := []state.Position{{OrderID: "a", PnL: -10}, {OrderID: "b", PnL: -5}}
+	sm.UpdateAccountInfo(state.AccountInfo{ProducedAt: 1, Account: state.Account{Equity: 1000, MarginAvailable: 100}, Positions: open})
+	d := &derisker{sm: sm, snapshotWait: time.Second, closePosition: func(pos state.Position, _ string) error {
+		// A snapshot sent before the broker processed the close still lists the position
+		sm.UpdateAccountInfo(state.AccountInfo{ProducedAt: 2, Account: state.Account{Equity: 1000, MarginAvailable: 100}, Positions: open})
+		go func() {
+			time.Sleep(2 * deriskPoll)
+			sm.UpdateAccountInfo(state.AccountInfo{ProducedAt: 3, Account: state.Account{Equity: 1000, MarginAvailable: 400}, Positions: open[1:]})
+		}()
+		return nil
+	}}
+	res, err := d.run(context.Background(), 0.3, "test")
+	if err != nil || len(res.Closed) != 1 || res.Closed[0] != "a" || !res.Recovered || res.Ratio != 0.4 {
+		t.Fatalf("got %+v (%v), want only a closed, recovering on the snapshot without it", res, err)
+	}
+}
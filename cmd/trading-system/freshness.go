@@ -0,0 +1,51 @@
+package main
+
+import "go-trader/internal/state"
+
+// InstrumentFreshness gives the age of an instrument's newest data at broadcast time.
+// Ages are in ms; a missing tick age or period means no data of that kind has arrived yet.
+// TickStale is set when there is no tick or the newest is older than staleTickAge, i.e. the
+// displayed price and PnL may be frozen.
+type InstrumentFreshness struct {
+	TickAgeMs *int64           `json:"tickAgeMs,omitempty"`
+	TickStale bool             `json:"tickStale"`
+	BarAgeMs  map[string]int64 `json:"barAgeMs,omitempty"` // period -> age of the newest bar's end
+}
+
+// dataFreshness computes per-instrument data ages from one snapshot so the UI can color-code staleness.
+// The newest bar of a period is the later of the live and historical series.
+func dataFreshness(snap state.StateSnapshot, instruments, periods []string, nowMs int64) map[string]InstrumentFreshness {
+	out := make(map[string]InstrumentFreshness, len(instruments))
+	for _, inst := range instruments {
+		f := InstrumentFreshness{TickStale: true}
+		if ticks := snap.Ticks[inst]; len(ticks) > 0 {
+			if ts := tickLastTs(ticks[len(ticks)-1]); ts > 0 {
+				age := nowMs - ts
+				f.TickAgeMs = &age
+				f.TickStale = age > staleTickAge.Milliseconds()
+			}
+		}
+		for _, p := range periods {
+			var newest int64
+			if hb := snap.HistoricalBars[inst][p]; len(hb) > 0 {
+				newest = hb[0].BarEndTimestamp
+			}
+			for _, b := range snap.Bars[inst][p] {
+				newest = max(newest, b.BarEndTimestamp)
+			}
+			if newest > 0 {
+				if f.BarAgeMs == nil {
+					f.BarAgeMs = make(map[string]int64)
+				}
+				f.BarAgeMs[p] = nowMs - newest
+			}
+		}
+		out[inst] = f
+	}
+	return out
+}
+
+// tickLastTs returns when a tick was last seen: the newer of its market timestamp and produced_at.
+func tickLastTs(t state.Tick) int64 {
+	return max(t.Timestamp, t.ProducedAt)
+}
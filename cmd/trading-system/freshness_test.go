@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"go-trader/internal/state"
+)
+
+func TestDataFreshness(t *testing.T) {
+	snap := state.StateSnapshot{
+		Ticks: map[string][]state.Tick{"EURUSD": {{Timestamp: 500}, {Timestamp: 900, ProducedAt: 950}}},
+		Bars: map[string]map[string][]state.Bar{
+			"EURUSD": {"ONE_MIN": {{BarEndTimestamp: 800}}},
+		},
+		HistoricalBars: map[string]map[string][]state.HistoricalBar{
+			"EURUSD": {"ONE_MIN": {{BarEndTimestamp: 700}}, "ONE_HOUR": {{BarEndTimestamp: 100}}},
+		},
+	}
+	got := dataFreshness(snap, []string{"EURUSD", "GBPUSD"}, []string{"ONE_MIN", "ONE_HOUR", "DAILY"}, 1000)
+	eu := got["EURUSD"]
+	if eu.TickAgeMs == nil || *eu.TickAgeMs != 50 || eu.TickStale {
+		t.Fatalf("tick age: got %v (stale=%v), want 50 and fresh", eu.TickAgeMs, eu.TickStale)
+	}
+	if eu.BarAgeMs["ONE_MIN"] != 200 || eu.BarAgeMs["ONE_HOUR"] != 900 {
+		t.Fatalf("bar ages: got %v, want ONE_MIN=200 ONE_HOUR=900", eu.BarAgeMs)
+	}
+	if _, ok := eu.BarAgeMs["DAILY"]; ok {
+		t.Fatal("period without bars should be omitted")
+	}
+	if gu, ok := got["GBPUSD"]; !ok || gu.TickAgeMs != nil || gu.BarAgeMs != nil || !gu.TickStale {
+		t.Fatalf("instrument without data: got %+v", gu)
+	}
+}
+
+func TestDataFreshnessFlagsStaleTick(t *testing.T) {
+	snap := state.StateSnapshot{Ticks: map[string][]state.Tick{"EURUSD": {{Timestamp: 1000}}}}
+	nowMs := 1000 + staleTickAge.Milliseconds()
+	if f := dataFreshness(snap, []string{"EURUSD"}, nil, nowMs)["EURUSD"]; f.TickStale {
+		t.Fatal("tick at the threshold should not be stale")
+	}
+	if f := dataFreshness(snap, []string{"EURUSD"}, nil, nowMs+1)["EURUSD"]; !f.TickStale {
+		t.Fatal("tick past the threshold should be stale")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Error codes used in API error responses.
+const (
+	errCodeBadRequest        = "bad_request"
+	errCodeInvalidJSON       = "invalid_json"
+	errCodeInvalidParam      = "invalid_param"
+	errCodeInvalidInstrument = "invalid_instrument"
+	errCodeMethodNotAllowed  = "method_not_allowed"
+	errCodeNotFound          = "not_found"
+	errCodeUnauthorized      = "unauthorized"
+	errCodeForbidden         = "forbidden"
+	errCodeDBUnavailable     = "db_unavailable"
+	errCodeDBError           = "db_error"
+	errCodeUpstream          = "upstream_error"
+	errCodeConflict          = "conflict"
+)
+
+// maxQueryLimit caps the limit query parameter of list endpoints.
+const maxQueryLimit = 1000
+
+// apiError is the body of every HTTP API error: {"error":{"code":"...","message":"..."}}.
+// Code is a stable machine-readable identifier (errCode*); Message is for humans.
+type apiError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// writeError writes the JSON error envelope with status.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	var body apiError
+	body.Error.Code = code
+	body.Error.Message = message
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// requireMethod writes a 405 error and returns false unless r uses method.
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, errCodeMethodNotAllowed, fmt.Sprintf("use %s", method))
+	return false
+}
+
+// parseLimit reads the limit query parameter, returning def when absent.
+// Values that are not integers in 1..maxQueryLimit are rejected.
+func parseLimit(r *http.Request, def int) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxQueryLimit {
+		return 0, fmt.Errorf("limit must be an integer between 1 and %d, got %q", maxQueryLimit, v)
+	}
+	return n, nil
+}
+
+// parseInstrument reads an optional instrument query parameter, upper-cased.
+// A non-empty value must be one of the configured instruments.
+func parseInstrument(r *http.Request) (string, error) {
+	instr := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("instrument")))
+	if err := validateInstrument(instr); err != nil {
+		return "", err
+	}
+	return instr, nil
+}
+
+// validateInstrument accepts an empty instrument or one of the configured instruments.
+func validateInstrument(instr string) error {
+	if instr == "" || slices.Contains(instrumentList, instr) {
+		return nil
+	}
+	return fmt.Errorf("unknown instrument %q", instr)
+}
+
+// parsePeriod reads the period query parameter, upper-cased, returning def when absent.
+// The value must be one of the tracked periods.
+func parsePeriod(r *http.Request, def string) (string, error) {
+	period := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("period")))
+	if period == "" {
+		return def, nil
+	}
+	if !slices.Contains(periodList, period) {
+		return "", fmt.Errorf("unknown period %q", period)
+	}
+	return period, nil
+}
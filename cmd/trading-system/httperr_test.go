@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteErrorEnvelope(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeError(rec, http.StatusBadRequest, errCodeInvalidParam, "limit must be positive")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want 400", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content type %q", ct)
+	}
+	var body apiError
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != errCodeInvalidParam || body.Error.Message != "limit must be positive" {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestRequireMethod(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if requireMethod(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.MethodPost) {
+		t.Fatal("GET accepted for POST endpoint")
+	}
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("status %d allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	if !requireMethod(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil), http.MethodPost) {
+		t.Error("POST rejected")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	cases := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{"", 50, false},
+		{"?limit=10", 10, false},
+		{"?limit=1000", 1000, false},
+		{"?limit=0", 0, true},
+		{"?limit=-5", 0, true},
+		{"?limit=1001", 0, true},
+		{"?limit=ten", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseLimit(httptest.NewRequest(http.MethodGet, "/api/strategy/runs"+c.query, nil), 50)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("%q: got %d, %v; want %d, err=%v", c.query, got, err, c.want, c.wantErr)
+		}
+	}
+}
+
+func TestParseInstrument(t *testing.T) {
+	instr, err := parseInstrument(httptest.NewRequest(http.MethodGet, "/api/spread?instrument=eurusd", nil))
+	if err != nil || instr != "EURUSD" {
+		t.Errorf("eurusd: got %q, %v", instr, err)
+	}
+	if instr, err := parseInstrument(httptest.NewRequest(http.MethodGet, "/api/spread", nil)); err != nil || instr != "" {
+		t.Errorf("absent: got %q, %v", instr, err)
+	}
+	if _, err := parseInstrument(httptest.NewRequest(http.MethodGet, "/api/spread?instrument=XYZABC", nil)); err == nil {
+		t.Error("unknown instrument accepted")
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	if p, err := parsePeriod(httptest.NewRequest(http.MethodGet, "/api/correlation", nil), "ONE_HOUR"); err != nil || p != "ONE_HOUR" {
+		t.Errorf("default: got %q, %v", p, err)
+	}
+	if p, err := parsePeriod(httptest.NewRequest(http.MethodGet, "/api/correlation?period=four_hours", nil), "ONE_HOUR"); err != nil || p != "FOUR_HOURS" {
+		t.Errorf("four_hours: got %q, %v", p, err)
+	}
+	if _, err := parsePeriod(httptest.NewRequest(http.MethodGet, "/api/correlation?period=WEEKLY", nil), "ONE_HOUR"); err == nil {
+		t.Error("unknown period accepted")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import "go-trader/internal/state"
+
+// indicatorSnapshot flattens the indicators of one historical bar into a single JSON object for
+// GET /api/indicators. Keys are the bar's JSON field names joined with the sub-field, e.g.
+// "bid_demas_dema_25", "ask_macd_hist", "bid_atr". Indicators the broker did not send are null
+// for the pointer-valued ones (Bollinger, Donchian, VWAP) and 0 for the rest, as on the bar.
+func indicatorSnapshot(bar state.HistoricalBar) map[string]any {
+	out := map[string]any{
+		"instrument":          bar.Instrument,
+		"period":              bar.Period,
+		"sequence":            bar.Sequence,
+		"bar_start_timestamp": bar.BarStartTimestamp,
+		"bar_end_timestamp":   bar.BarEndTimestamp,
+	}
+	sides := []struct {
+		prefix     string
+		atr, obv   float64
+		demas      state.Demas
+		macd       state.Macd
+		rsi        state.Rsi
+		stoch      state.Stoch
+		cci, mfi   float64
+		bollinger  state.Bollinger
+		keltner    state.Keltner
+		donchian   state.Donchian
+		supertrend state.Supertrend
+		vwap       state.Vwap
+	}{
+		{"bid_", bar.BidAtr, bar.BidObv, bar.BidDemas, bar.BidMacd, bar.BidRsi, bar.BidStoch, bar.BidCci, bar.BidMfi,
+			bar.BidBollinger, bar.BidKeltner, bar.BidDonchian, bar.BidSupertrend, bar.BidVwap},
+		{"ask_", bar.AskAtr, bar.AskObv, bar.AskDemas, bar.AskMacd, bar.AskRsi, bar.AskStoch, bar.AskCci, bar.AskMfi,
+			bar.AskBollinger, bar.AskKeltner, bar.AskDonchian, bar.AskSupertrend, bar.AskVwap},
+	}
+	for _, s := range sides {
+		set := func(name string, v any) { out[s.prefix+name] = v }
+		set("demas_dema_25", s.demas.Dema25)
+		set("demas_dema_50", s.demas.Dema50)
+		set("demas_dema_100", s.demas.Dema100)
+		set("demas_dema_200", s.demas.Dema200)
+		set("macd_line", s.macd.Line)
+		set("macd_signal", s.macd.Signal)
+		set("macd_hist", s.macd.Hist)
+		set("rsi_fast", s.rsi.Fast)
+		set("rsi_slow", s.rsi.Slow)
+		set("stoch_k", s.stoch.K)
+		set("stoch_d", s.stoch.D)
+		set("cci", s.cci)
+		set("mfi", s.mfi)
+		set("bollinger_upper", s.bollinger.Upper)
+		set("bollinger_middle", s.bollinger.Middle)
+		set("bollinger_lower", s.bollinger.Lower)
+		set("keltner_upper", s.keltner.Upper)
+		set("keltner_middle", s.keltner.Middle)
+		set("keltner_lower", s.keltner.Lower)
+		set("donchian_upper", s.donchian.Upper)
+		set("donchian_middle", s.donchian.Middle)
+		set("donchian_lower", s.donchian.Lower)
+		set("supertrend_upper", s.supertrend.Upper)
+		set("supertrend_lower", s.supertrend.Lower)
+		set("atr", s.atr)
+		set("obv", s.obv)
+		set("vwap_tick_vwap", s.vwap.TickVwap)
+		set("vwap_bar_vwap", s.vwap.BarVwap)
+	}
+	return out
+}
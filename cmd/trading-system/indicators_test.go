@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go-trader/internal/state"
+)
+
+func TestIndicatorSnapshotIsFlat(t *testing.T) {
+	upper := 1.2
+	bar := state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", Sequence: 1,
+		BidDemas: state.Demas{Dema25: 1.1}, AskMacd: state.Macd{Hist: -0.5}, BidBollinger: state.Bollinger{Upper: &upper}, AskAtr: 0.001}
+	data, err := json.Marshal(indicatorSnapshot(bar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{"bid_demas_dema_25": 1.1, "ask_macd_hist": -0.5, "bid_bollinger_upper": 1.2,
+		"bid_bollinger_lower": nil, "ask_atr": 0.001, "ask_supertrend_lower": 0.0, "instrument": "EURUSD"} {
+		if v, ok := got[key]; !ok || v != want {
+			t.Errorf("%s = %v (present %v), want %v", key, v, ok, want)
+		}
+	}
+	for key, v := range got {
+		if _, nested := v.(map[string]any); nested {
+			t.Errorf("%s is nested", key)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// killRetryDelay is how long to wait after killing a conflicting process before rebinding.
+var killRetryDelay = 2 * time.Second
+
+// listenWithFallback binds addr, moving to the next port when it is taken.
+// What: Lets a second instance (or a stale one) coexist instead of failing at startup.
+// How: On "address in use" it first calls kill(port) when non-nil (opt-in via KILL_PORT_CONFLICTS)
+//      and retries the same port if that succeeded; otherwise it tries port+1. Any other error is fatal.
+// Params: addr host:port, attempts max number of binds, kill optional conflict resolver
+// Returns: the listener and the address actually bound, or an error after attempts are exhausted
+func listenWithFallback(addr string, attempts int, kill func(port string) bool) (net.Listener, string, error) {
+	for i := 0; i < attempts; i++ {
+		listener, err := net.Listen("tcp", addr)
+		if err == nil {
+			return listener, addr, nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, "", err
+		}
+		_, port, _ := net.SplitHostPort(addr)
+		if kill != nil {
+			log.Printf("🔄 Port %s already in use, attempting to kill conflicting process (attempt %d/%d)", port, i+1, attempts)
+			if kill(port) {
+				log.Printf("✅ Killed conflicting process, retrying in %s...", killRetryDelay)
+				time.Sleep(killRetryDelay)
+				continue
+			}
+		}
+		next, nerr := nextPortAddr(addr)
+		if nerr != nil {
+			return nil, "", fmt.Errorf("%s in use and no fallback port: %w", addr, nerr)
+		}
+		log.Printf("🔄 Port %s already in use, trying %s", port, next)
+		addr = next
+	}
+	return nil, "", fmt.Errorf("no free port after %d attempts (last tried %s)", attempts, addr)
+}
+
+// nextPortAddr returns addr with its port incremented by one, keeping the host part.
+func nextPortAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p <= 0 || p >= 65535 {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p+1)), nil
+}
+
+// killProcessUsingPort kills a stale instance of this program listening on port.
+// What: Frees the port after an unclean shutdown without touching unrelated software.
+// How: Reads /proc/net/tcp{,6} for the listening socket inode, finds the owning PID via /proc/<pid>/fd,
+//      and only kills it when its executable name matches ours. Linux only; elsewhere it does nothing.
+// Returns: true when a matching process was killed
+func killProcessUsingPort(port string) bool {
+	if runtime.GOOS != "linux" {
+		log.Printf("Port conflict kill is only supported on Linux; not killing anything on port %s", port)
+		return false
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return false
+	}
+	inodes := listeningInodes(p)
+	if len(inodes) == 0 {
+		log.Printf("No listening socket found for port %s", port)
+		return false
+	}
+	pid := findSocketOwner(inodes)
+	if pid <= 0 {
+		log.Printf("Could not determine which process holds port %s", port)
+		return false
+	}
+	if pid == os.Getpid() {
+		log.Printf("Found our own process (PID %d), not killing", pid)
+		return false
+	}
+
+	ours, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	theirs, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	if err != nil || filepath.Base(theirs) != filepath.Base(ours) {
+		log.Printf("Process %d (%s) holding port %s is not %s; refusing to kill", pid, filepath.Base(theirs), port, filepath.Base(ours))
+		return false
+	}
+
+	log.Printf("Killing stale %s process %d using port %s", filepath.Base(ours), pid, port)
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		log.Printf("Failed to find process %d: %v", pid, err)
+		return false
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		log.Printf("Failed to terminate process %d: %v", pid, err)
+		return false
+	}
+	// Wait a moment for the process to die
+	time.Sleep(500 * time.Millisecond)
+	return true
+}
+
+// listeningInodes returns the socket inodes of TCP listeners bound to port.
+func listeningInodes(port int) map[string]bool {
+	inodes := make(map[string]bool)
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		sc := bufio.NewScanner(f)
+		sc.Scan() // header
+		for sc.Scan() {
+			fields := strings.Fields(sc.Text())
+			// sl local_address rem_address st tx:rx tr:when retrnsmt uid timeout inode
+			if len(fields) < 10 || fields[3] != "0A" { // 0A = LISTEN
+				continue
+			}
+			i := strings.LastIndex(fields[1], ":")
+			if i < 0 {
+				continue
+			}
+			if lp, err := strconv.ParseInt(fields[1][i+1:], 16, 32); err == nil && int(lp) == port {
+				inodes[fields[9]] = true
+			}
+		}
+		f.Close()
+	}
+	return inodes
+}
+
+// findSocketOwner returns the PID holding one of the socket inodes, or 0 when none is visible.
+func findSocketOwner(inodes map[string]bool) int {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0
+	}
+	for _, d := range procs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%s", pid, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+				return pid
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestListenWithFallbackUsesNextPort(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	addr := busy.Addr().String()
+	want, _ := nextPortAddr(addr)
+
+	l, bound, err := listenWithFallback(addr, 3, nil)
+	if err != nil {
+		t.Skipf("fallback port unavailable in this environment: %v", err)
+	}
+	defer l.Close()
+	if bound != want {
+		t.Fatalf("bound %s, want %s", bound, want)
+	}
+}
+
+func TestListenWithFallbackRetriesAfterKill(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := busy.Addr().String()
+	defer func(d time.Duration) { killRetryDelay = d }(killRetryDelay)
+	killRetryDelay = 0
+
+	calls := 0
+	kill := func(port string) bool {
+		calls++
+		busy.Close() // simulate the stale process going away
+		return true
+	}
+	l, bound, err := listenWithFallback(addr, 3, kill)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if calls != 1 || bound != addr {
+		t.Fatalf("calls=%d bound=%s, want 1 call and %s", calls, bound, addr)
+	}
+}
+
+func TestListenWithFallbackFailedKillFallsBack(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	addr := busy.Addr().String()
+	want, _ := nextPortAddr(addr)
+
+	l, bound, err := listenWithFallback(addr, 3, func(string) bool { return false })
+	if err != nil {
+		t.Skipf("fallback port unavailable in this environment: %v", err)
+	}
+	defer l.Close()
+	if bound != want {
+		t.Fatalf("bound %s, want %s", bound, want)
+	}
+}
+
+func TestListenWithFallbackGivesUp(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	if _, _, err := listenWithFallback(busy.Addr().String(), 1, nil); err == nil {
+		t.Fatal("expected error when the only attempt hits a busy port")
+	}
+}
+
+func TestNextPortAddr(t *testing.T) {
+	cases := map[string]string{":8080": ":8081", "0.0.0.0:8080": "0.0.0.0:8081", "[::1]:9000": "[::1]:9001"}
+	for in, want := range cases {
+		got, err := nextPortAddr(in)
+		if err != nil || got != want {
+			t.Errorf("nextPortAddr(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := nextPortAddr(":65535"); err == nil {
+		t.Error("expected error past the last port")
+	}
+}
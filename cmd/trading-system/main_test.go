@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-trader/internal/ledger"
+	"go-trader/internal/msgpack"
+	"go-trader/internal/state"
+	"go-trader/internal/strategy"
+	"go-trader/internal/websocket"
+
+	gws "github.com/gorilla/websocket"
+)
+
+func TestPositionsByLabelPrefix(t *testing.T) {
+	positions := []state.Position{
+		{OrderID: "1", Label: "EURUSD_strat_buy_150405"},
+		{OrderID: "2", Label: "EURUSD_manual_1"},
+		{OrderID: "3", Label: "EURUSD_strat_sell_150500"},
+		{OrderID: "4", Label: "GBPUSD_strat_buy_150405"},
+	}
+	got := positionsByLabelPrefix(positions, "EURUSD_strat_")
+	if len(got) != 2 || got[0].OrderID != "1" || got[1].OrderID != "3" {
+		t.Fatalf("got %+v, want orders 1 and 3", got)
+	}
+	if got := positionsByLabelPrefix(positions, "USDJPY"); len(got) != 0 {
+		t.Fatalf("got %+v, want none", got)
+	}
+}
+
+func TestNewStrategyForStartRejectsUnknownKeys(t *testing.T) {
+	if _, err := newStrategyForStart("DEMA_RSII", false); err == nil {
+		t.Fatal("a misspelled key should be rejected")
+	}
+	s, err := newStrategyForStart("DEMA_RSII", true)
+	if err != nil || s.Key() != strategy.DefaultKey {
+		t.Fatalf("explicit fallback: got %v, %v; want %s", s, err, strategy.DefaultKey)
+	}
+	if s, err := newStrategyForStart("", false); err != nil || s.Key() != strategy.DefaultKey {
+		t.Fatalf("empty key: got %v, %v; want %s", s, err, strategy.DefaultKey)
+	}
+}
+
+func TestPlaceOrderWithoutTicksAlertsInsteadOfPanicking(t *testing.T) {
+	hub := websocket.NewHub()
+	go hub.Run()
+	srv := httptest.NewServer(http.HandlerFunc(hub.ServeWs))
+	defer srv.Close()
+	conn, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for deadline := time.Now().Add(2 * time.Second); hub.Stats().Connected == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("client never registered")
+		}
+	}
+
+	// No publisher: reaching the publish step would panic on the nil *amqp.Publisher
+	fb := &FrontendBroadcaster{stateManager: state.NewStateManager(), hub: hub}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("PLACE_ORDER without ticks panicked: %v", r)
+			}
+		}()
+		fb.processCommand([]byte(`{"type":"PLACE_ORDER","instrument":"EURUSD","side":"BUY","qty":0.1}`))
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("no alert received: %v", err)
+	}
+	var alert ledger.Alert
+	if err := json.Unmarshal(data, &alert); err != nil || alert.Type != "ALERT" || alert.Code != "no_price" || alert.Instrument != "EURUSD" {
+		t.Fatalf("got %s (%v), want a no_price ALERT for EURUSD", data, err)
+	}
+}
+
+// sampleFullState builds a snapshot of the size broadcast in production: every instrument with a
+// full tick buffer and ten live bars per period, all indicators set.
+func sampleFullState() FullState {
+	fs := FullState{SchemaVersion: schemaVersion, ServerTime: 1717000000123,
+		Ticks: make(map[string][]state.Tick), Bars: make(map[string]map[string][]state.Bar)}
+	f := func(v float64) *float64 { return &v }
+	for n, instrument := range instrumentList {
+		base := 1.08 + float64(n)*0.1173
+		for i := 0; i < 20; i++ {
+			px := base + float64(i)*0.00013
+			fs.Ticks[instrument] = append(fs.Ticks[instrument], state.Tick{ProducedAt: 1717000000000 + int64(i)*137, Timestamp: 1716999999900 + int64(i)*137,
+				Instrument: instrument, Bid: px, Ask: px + 0.00011, BidVol: 1.37, AskVol: 2.71})
+		}
+		fs.Bars[instrument] = make(map[string][]state.Bar)
+		for _, period := range periodList {
+			for i := 0; i < 10; i++ {
+				px := base + float64(i)*0.00021
+				ohlcv := state.OHLCV{O: px, H: px + 0.00037, L: px - 0.00029, C: px + 0.00007, V: 153.27}
+				fs.Bars[instrument][period] = append(fs.Bars[instrument][period], state.Bar{ProducedAt: 1717000000000 + int64(i)*60000,
+					BarStartTimestamp: 1716999940000 + int64(i)*60000, BarEndTimestamp: 1717000000000 + int64(i)*60000,
+					Instrument: instrument, Period: period, Bid: ohlcv, Ask: ohlcv,
+					BidVwap: state.Vwap{TickVwap: f(px + 0.00003), BarVwap: f(px + 0.00004)}, AskVwap: state.Vwap{TickVwap: f(px + 0.00013), BarVwap: f(px + 0.00014)},
+					BidEmas:      state.Emas{Ema5: f(px - 0.00011), Ema8: f(px - 0.00017), Ema30: f(px - 0.00031), Ema50: f(px - 0.00043)},
+					AskEmas:      state.Emas{Ema5: f(px + 0.00011), Ema8: f(px + 0.00017), Ema30: f(px + 0.00031), Ema50: f(px + 0.00043)},
+					BidDonchian:  state.Donchian{Upper: f(px + 0.0011), Middle: f(px), Lower: f(px - 0.0011)},
+					AskDonchian:  state.Donchian{Upper: f(px + 0.0012), Middle: f(px + 0.0001), Lower: f(px - 0.0010)},
+					BidBollinger: state.Bollinger{Upper: f(px + 0.0009), Middle: f(px), Lower: f(px - 0.0009)},
+					AskBollinger: state.Bollinger{Upper: f(px + 0.0010), Middle: f(px + 0.0001), Lower: f(px - 0.0008)}})
+			}
+		}
+	}
+	return fs
+}
+
+func TestStateViewsBuildsRequestedEncodings(t *testing.T) {
+	fs := sampleFullState()
+	views, err := stateViews(fs, map[string]bool{websocket.VerbosityFull: true, websocket.MsgpackView(websocket.VerbosityTicks): true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if views[websocket.VerbosityFull] == nil || views[websocket.VerbosityTicks] != nil {
+		t.Fatal("want JSON for the full level only")
+	}
+	packed := views.ForEncoding(websocket.VerbosityTicks, websocket.EncodingMsgpack)
+	if len(packed) == 0 || packed[0] != 0x84 { // fixmap of the four ticksView fields
+		t.Fatalf("ticks MessagePack view = % x..., want a 4-entry map", packed[:min(len(packed), 4)])
+	}
+	if _, ok := views[websocket.MsgpackView(websocket.VerbosityFull)]; ok {
+		t.Fatal("no MessagePack client asked for the full level")
+	}
+}
+
+// BenchmarkStateViewsEncoding compares marshal time and payload size (B/msg) of a full snapshot
+// in JSON and MessagePack.
+func BenchmarkStateViewsEncoding(b *testing.B) {
+	fs := sampleFullState()
+	for _, enc := range []struct {
+		name    string
+		marshal func(any) ([]byte, error)
+	}{{"json", json.Marshal}, {"msgpack", msgpack.Marshal}} {
+		b.Run(enc.name, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				data, err := enc.marshal(fs)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "B/msg")
+		})
+	}
+}
+
+func TestLedgerHealthValidAtBarCapacity(t *testing.T) {
+	sm := state.NewStateManager(state.StateManagerConfig{BarBufferSizes: map[string]int{"DAILY": 5}})
+	for i := int64(1); i <= 5; i++ {
+		sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: "DAILY", BarEndTimestamp: i * 86_400_000})
+		sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_HOUR", BarEndTimestamp: i * 3_600_000})
+	}
+	fb := &FrontendBroadcaster{stateManager: sm, instrumentList: []string{"EURUSD"}}
+	full := fb.attachLedgerHealth(FullState{}, sm.Snapshot())
+
+	periods := full.LedgerHealthSummary.Instruments[0].Periods
+	if !periods["DAILY"].Valid {
+		t.Fatalf("DAILY health %+v, want valid with its 5-bar buffer full", periods["DAILY"])
+	}
+	if periods["ONE_HOUR"].Valid {
+		t.Fatalf("ONE_HOUR health %+v, want invalid with 5 of %d bars", periods["ONE_HOUR"], historicalBarsToFetch)
+	}
+}
+
+// BenchmarkBroadcastCurrentState measures one broadcast of the 10 tracked instruments, each with
+// ticks and live and historical bars for every period. The ledger health summary and strategy
+// statuses cover all instruments and must be built once per broadcast, not once per instrument.
+func BenchmarkBroadcastCurrentState(b *testing.B) {
+	sm := state.NewStateManager()
+	fs := sampleFullState()
+	for _, instrument := range instrumentList {
+		for _, tick := range fs.Ticks[instrument] {
+			sm.UpdateTick(tick)
+		}
+		for _, period := range periodList {
+			for _, bar := range fs.Bars[instrument][period] {
+				sm.UpdateBar(bar)
+				sm.UpdateHistoricalBar(state.HistoricalBar{ProducedAt: bar.ProducedAt, BarStartTimestamp: bar.BarStartTimestamp,
+					BarEndTimestamp: bar.BarEndTimestamp, Instrument: instrument, Period: period, Bid: bar.Bid, Ask: bar.Ask})
+			}
+		}
+	}
+	hub := websocket.NewHub()
+	go hub.Run()
+	fb := &FrontendBroadcaster{stateManager: sm, hub: hub, instrumentList: instrumentList,
+		stratEngine: strategy.NewEngine(sm, nil, nil)}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fb.broadcastCurrentState()
+	}
+}
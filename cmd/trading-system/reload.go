@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go-trader/internal/state"
+)
+
+// Config file support and SIGHUP reload.
+// What: Change risk limits and similar thresholds without restarting the backend.
+// How: GOTRADER_CONFIG names a file of KEY=VALUE lines using the same keys as the environment
+//      variables (e.g. GOTRADER_MAX_NOTIONAL=5000000); '#' starts a comment line. File values take
+//      precedence over the environment so that edits apply on reload. On SIGHUP the file is re-read
+//      and the hotConfig settings are applied through setters; every other key (bind address, TLS,
+//      queue limits, buffer policies, aliases, drain mode, admin token, DB retention, tick dedup,
+//      derisk ratio, strategy jitter, chaos, account currency, tick and bar buffer sizes) is only
+//      read at startup, and a reload that changes one of them logs that a restart is required.
+//      There are no log levels in this backend; GOTRADER_WARN_THROTTLE is the reloadable log knob.
+
+var fileConfig struct {
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// restartOnlyKeys are config keys read once at startup.
+var restartOnlyKeys = []string{
+	"GOTRADER_ADDR", "GOTRADER_TLS_CERT", "GOTRADER_TLS_KEY", "GOTRADER_ADMIN_TOKEN",
+	"GOTRADER_QUEUE_LIMITS", "GOTRADER_BUFFER_POLICY", "GOTRADER_INSTRUMENT_ALIASES", "GOTRADER_DRAIN_MODE",
+	"GOTRADER_DB_RETENTION", "GOTRADER_FIELD_ALIASES", "GOTRADER_TICK_DEDUP",
+	"GOTRADER_DERISK_MARGIN_RATIO", "GOTRADER_STRATEGY_JITTER", "GOTRADER_CHAOS", "GOTRADER_ACCOUNT_CURRENCY",
+	"GOTRADER_TICK_BUFFER_SIZES", "GOTRADER_BAR_BUFFER_SIZES",
+}
+
+// loadConfigFile parses KEY=VALUE lines. Blank lines and lines starting with '#' are skipped;
+// values may be wrapped in double quotes.
+func loadConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	out := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: want KEY=VALUE", path, n)
+		}
+		val = strings.TrimSpace(val)
+		if uq, err := strconv.Unquote(val); err == nil && strings.HasPrefix(val, `"`) {
+			val = uq
+		}
+		out[key] = val
+	}
+	return out, sc.Err()
+}
+
+// setFileConfig replaces the values consulted by envOr.
+func setFileConfig(values map[string]string) {
+	fileConfig.mu.Lock()
+	defer fileConfig.mu.Unlock()
+	fileConfig.values = values
+}
+
+// fileConfigValue returns the config file value for key, if set.
+func fileConfigValue(key string) (string, bool) {
+	fileConfig.mu.RLock()
+	defer fileConfig.mu.RUnlock()
+	v, ok := fileConfig.values[key]
+	return v, ok && v != ""
+}
+
+// hotConfig holds the settings applied again on SIGHUP.
+type hotConfig struct {
+	maxNotional       float64            // GOTRADER_MAX_NOTIONAL
+	slippage          map[string]float64 // GOTRADER_SLIPPAGE
+	minStops          map[string]float64 // GOTRADER_MIN_STOP_PIPS
+	priceDigits       map[string]int     // GOTRADER_PRICE_DIGITS
+	sessionBoundary   time.Duration      // GOTRADER_SESSION_BOUNDARY
+	broadcastInterval time.Duration      // GOTRADER_BROADCAST_INTERVAL
+	broadcastMin      time.Duration      // GOTRADER_BROADCAST_MIN_INTERVAL
+	broadcastMax      time.Duration      // GOTRADER_BROADCAST_MAX_INTERVAL
+	barCaps           map[string]int     // GOTRADER_BROADCAST_BAR_CAPS
+	warnThrottle      time.Duration      // GOTRADER_WARN_THROTTLE
+}
+
+// readHotConfig reads the reloadable settings from the config file, environment, and defaults.
+func readHotConfig() (hotConfig, error) {
+	var c hotConfig
+	var err error
+	if c.maxNotional, err = strconv.ParseFloat(envOr("GOTRADER_MAX_NOTIONAL", strconv.Itoa(maxAccountNotional)), 64); err != nil || c.maxNotional < 0 {
+		return c, fmt.Errorf("invalid GOTRADER_MAX_NOTIONAL")
+	}
+	if c.slippage, err = state.ParseSlippage(envOr("GOTRADER_SLIPPAGE", defaultSlippageOverrides)); err != nil {
+		return c, fmt.Errorf("invalid GOTRADER_SLIPPAGE: %w", err)
+	}
+	if c.minStops, err = state.ParseMinStopPips(envOr("GOTRADER_MIN_STOP_PIPS", defaultMinStopPips)); err != nil {
+		return c, fmt.Errorf("invalid GOTRADER_MIN_STOP_PIPS: %w", err)
+	}
+	if c.priceDigits, err = state.ParsePriceDigits(envOr("GOTRADER_PRICE_DIGITS", defaultPriceDigits)); err != nil {
+		return c, fmt.Errorf("invalid GOTRADER_PRICE_DIGITS: %w", err)
+	}
+	if c.sessionBoundary, err = time.ParseDuration(envOr("GOTRADER_SESSION_BOUNDARY", defaultSessionBoundary.String())); err != nil {
+		return c, fmt.Errorf("invalid GOTRADER_SESSION_BOUNDARY: %w", err)
+	}
+	if c.broadcastInterval, err = time.ParseDuration(envOr("GOTRADER_BROADCAST_INTERVAL", broadcastInterval.String())); err != nil || c.broadcastInterval <= 0 {
+		return c, fmt.Errorf("invalid GOTRADER_BROADCAST_INTERVAL")
+	}
+	if c.broadcastMin, err = time.ParseDuration(envOr("GOTRADER_BROADCAST_MIN_INTERVAL", broadcastMinInterval.String())); err != nil || c.broadcastMin <= 0 {
+		return c, fmt.Errorf("invalid GOTRADER_BROADCAST_MIN_INTERVAL")
+	}
+	if c.broadcastMax, err = time.ParseDuration(envOr("GOTRADER_BROADCAST_MAX_INTERVAL", broadcastMaxInterval.String())); err != nil || c.broadcastMax < c.broadcastMin {
+		return c, fmt.Errorf("invalid GOTRADER_BROADCAST_MAX_INTERVAL: must be a duration no shorter than the minimum")
+	}
+	if c.barCaps, err = parseBarCaps(envOr("GOTRADER_BROADCAST_BAR_CAPS", defaultBroadcastBarCaps)); err != nil {
+		return c, fmt.Errorf("invalid GOTRADER_BROADCAST_BAR_CAPS: %w", err)
+	}
+	if c.warnThrottle, err = time.ParseDuration(envOr("GOTRADER_WARN_THROTTLE", enqueueWarnThrottle.String())); err != nil || c.warnThrottle < 0 {
+		return c, fmt.Errorf("invalid GOTRADER_WARN_THROTTLE")
+	}
+	return c, nil
+}
+
+// reloadConfig re-reads path and, when it and all reloadable values are valid, passes them to apply.
+// On any error the previous file values stay in effect.
+func reloadConfig(path string, apply func(hotConfig)) error {
+	values, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	before := make(map[string]string, len(restartOnlyKeys))
+	for _, k := range restartOnlyKeys {
+		before[k] = envOr(k, "")
+	}
+	fileConfig.mu.RLock()
+	prev := fileConfig.values
+	fileConfig.mu.RUnlock()
+	setFileConfig(values)
+	cfg, err := readHotConfig()
+	if err != nil {
+		setFileConfig(prev)
+		return err
+	}
+	for _, k := range restartOnlyKeys {
+		if envOr(k, "") != before[k] {
+			log.Printf("⚠️ Config %s changed; it only takes effect after a restart", k)
+		}
+	}
+	apply(cfg)
+	return nil
+}
+
+// reloadOnSIGHUP reloads the config file on each SIGHUP until the process exits.
+func reloadOnSIGHUP(path string, apply func(hotConfig)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloadConfig(path, apply); err != nil {
+				log.Printf("❌ Config reload from %s failed, keeping current settings: %v", path, err)
+				continue
+			}
+			log.Printf("🔄 Config reloaded from %s", path)
+		}
+	}()
+}
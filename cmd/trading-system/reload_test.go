@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReloadConfigAppliesValidFile(t *testing.T) {
+	defer setFileConfig(nil)
+	path := filepath.Join(t.TempDir(), "gotrader.env")
+	write := func(body string) {
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("# risk\nGOTRADER_MAX_NOTIONAL=5000000\nGOTRADER_BROADCAST_INTERVAL=\"2s\"\n\nGOTRADER_MIN_STOP_PIPS=EURUSD:5\n")
+	var got hotConfig
+	if err := reloadConfig(path, func(c hotConfig) { got = c }); err != nil {
+		t.Fatal(err)
+	}
+	if got.maxNotional != 5_000_000 || got.broadcastInterval != 2*time.Second || got.minStops["EURUSD"] != 5 {
+		t.Fatalf("got %+v", got)
+	}
+	if got.warnThrottle != enqueueWarnThrottle {
+		t.Fatalf("unset key should keep its default, got %v", got.warnThrottle)
+	}
+
+	write("GOTRADER_MAX_NOTIONAL=-1\n")
+	applied := false
+	if err := reloadConfig(path, func(hotConfig) { applied = true }); err == nil || applied {
+		t.Fatal("invalid value should be rejected without applying")
+	}
+	if v, _ := fileConfigValue("GOTRADER_MAX_NOTIONAL"); v != "5000000" {
+		t.Fatalf("previous file values should stay in effect, got %q", v)
+	}
+
+	write("not a setting\n")
+	if err := reloadConfig(path, func(hotConfig) {}); err == nil {
+		t.Fatal("malformed line should fail")
+	}
+}
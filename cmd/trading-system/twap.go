@@ -0,0 +1,316 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-trader/internal/amqp"
+	"go-trader/internal/clock"
+	"go-trader/internal/state"
+)
+
+// TWAP (time-weighted average price) execution.
+// What: Work a large market order into the market over a period instead of in one hit.
+// How: The total is split into N child market orders, the first sent at once and the rest every
+//      duration/N, each labelled <parent>_<n> so Trade_Results map back to the parent. qty and
+//      slippage have the WebSocket command bounds (see cmdvalidate.go), and the whole qty must pass
+//      the notional and margin checks before the first slice. Each child goes through the
+//      manual-order risk checks again; a failed check or publish aborts the rest.
+//      Children are not resized for fills: a rejected child is counted, not retried.
+//      POST /api/order/twap starts one, DELETE /api/order/twap/{id} (or the CANCEL_TWAP command)
+//      stops the remaining slices, GET /api/order/twap lists them.
+
+const (
+	// lotsPerAmount converts JForex amounts (millions) to minimum lots of 1,000 units; child
+	// quantities are whole lots
+	lotsPerAmount = 1000
+	// maxTWAPSlices caps the number of child orders of one TWAP order
+	maxTWAPSlices = 100
+	// twapRetention is how long a finished TWAP order stays listed and matched to late Trade_Results
+	twapRetention = time.Hour
+)
+
+// errTWAPNotFound is returned when cancelling an unknown or finished TWAP order.
+var errTWAPNotFound = errors.New("no running TWAP order with that id")
+
+// TWAPRequest is the body of POST /api/order/twap.
+type TWAPRequest struct {
+	Instrument string  `json:"instrument"`
+	Side       string  `json:"side"`     // BUY | SELL
+	Qty        float64 `json:"qty"`      // total JForex amount
+	Duration   string  `json:"duration"` // Go duration over which the slices are spread, e.g. "10m"
+	Slices     int     `json:"slices"`   // number of child orders (2-100)
+	Slippage   float64 `json:"slippage,omitempty"`
+}
+
+// TWAPStatus reports the progress of one TWAP order.
+type TWAPStatus struct {
+	ID         string  `json:"id"`
+	Instrument string  `json:"instrument"`
+	Side       string  `json:"side"`
+	Qty        float64 `json:"qty"`
+	Slices     int     `json:"slices"`
+	IntervalMs int64   `json:"intervalMs"`
+	Sent       int     `json:"sent"`      // child orders published
+	SentQty    float64 `json:"sentQty"`   // amount of the published children
+	Filled     int     `json:"filled"`    // children reported FILLED on Trade_Results
+	FilledQty  float64 `json:"filledQty"` // amount of the filled children
+	Rejected   int     `json:"rejected"`  // children reported REJECTED or ERROR
+	State      string  `json:"state"`     // running | done | cancelled | aborted
+	Reason     string  `json:"reason,omitempty"`
+	StartedAt  int64   `json:"startedAt"`
+}
+
+// twapOrder is a running or finished TWAP order.
+type twapOrder struct {
+	status   TWAPStatus
+	slippage float64
+	// childLots holds the size of each slice in lots; the remainder of the split goes to the last one
+	childLots  []int64
+	sentLots   int64
+	filledLots int64
+	stop       chan struct{}
+	finishedAt time.Time
+}
+
+// twapExecutor runs TWAP orders.
+type twapExecutor struct {
+	clock clock.Clock
+	// check risk-checks the total qty of an order before it starts; nil skips it
+	check func(instrument string, qty float64) error
+	// place risk-checks and submits one child market order; an error aborts the remaining slices
+	place func(cmd amqp.TradeCommand, parent string, slice int) error
+
+	mu     sync.Mutex
+	orders map[string]*twapOrder
+}
+
+func newTWAPExecutor(clk clock.Clock, check func(instrument string, qty float64) error,
+	place func(cmd amqp.TradeCommand, parent string, slice int) error) *twapExecutor {
+	return &twapExecutor{clock: clk, check: check, place: place, orders: make(map[string]*twapOrder)}
+}
+
+// splitLots divides qty into n whole-lot slices, the remainder going to the last.
+// Returns an error when a slice would be below one lot.
+func splitLots(qty float64, n int) ([]int64, error) {
+	lots := int64(math.Round(qty * lotsPerAmount))
+	each := lots / int64(n)
+	if each < 1 {
+		return nil, fmt.Errorf("qty %.3f is too small for %d slices (min 0.001 each)", qty, n)
+	}
+	out := make([]int64, n)
+	for i := range out {
+		out[i] = each
+	}
+	out[n-1] = lots - each*int64(n-1)
+	return out, nil
+}
+
+// lotsAmount converts lots back to a JForex amount.
+func lotsAmount(lots int64) float64 { return float64(lots) / lotsPerAmount }
+
+// start validates req and begins sending its slices. Returns the initial status.
+func (x *twapExecutor) start(req TWAPRequest) (TWAPStatus, error) {
+	req.Instrument = strings.ToUpper(strings.TrimSpace(req.Instrument))
+	req.Side = strings.ToUpper(strings.TrimSpace(req.Side))
+	if req.Instrument == "" || validateInstrument(req.Instrument) != nil {
+		return TWAPStatus{}, fmt.Errorf("instrument must be one of the configured instruments")
+	}
+	if req.Side != "BUY" && req.Side != "SELL" {
+		return TWAPStatus{}, fmt.Errorf("side must be BUY or SELL")
+	}
+	if err := validateCommandNumbers(CommandRequest{Instrument: req.Instrument, Qty: req.Qty, Slippage: req.Slippage}, 0); err != nil {
+		return TWAPStatus{}, err
+	}
+	if req.Slices < 2 || req.Slices > maxTWAPSlices {
+		return TWAPStatus{}, fmt.Errorf("slices must be between 2 and %d", maxTWAPSlices)
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil || d <= 0 {
+		return TWAPStatus{}, fmt.Errorf("duration must be a positive Go duration, e.g. \"10m\"")
+	}
+	interval := d / time.Duration(req.Slices)
+	if interval < time.Second {
+		return TWAPStatus{}, fmt.Errorf("duration %s is too short for %d slices (min 1s apart)", d, req.Slices)
+	}
+	childLots, err := splitLots(req.Qty, req.Slices)
+	if err != nil {
+		return TWAPStatus{}, err
+	}
+	if x.check != nil {
+		if err := x.check(req.Instrument, req.Qty); err != nil {
+			return TWAPStatus{}, err
+		}
+	}
+	now := x.clock.Now()
+	o := &twapOrder{
+		status: TWAPStatus{ID: req.Instrument + "_twap_" + strconv.FormatInt(now.UnixMilli(), 10), Instrument: req.Instrument,
+			Side: req.Side, Qty: req.Qty, Slices: req.Slices, IntervalMs: interval.Milliseconds(), State: "running", StartedAt: now.UnixMilli()},
+		slippage:  req.Slippage,
+		childLots: childLots,
+		stop:      make(chan struct{}),
+	}
+	x.mu.Lock()
+	x.pruneLocked(now)
+	if _, dup := x.orders[o.status.ID]; dup {
+		x.mu.Unlock()
+		return TWAPStatus{}, fmt.Errorf("a TWAP order was started on %s this millisecond; retry", req.Instrument)
+	}
+	x.orders[o.status.ID] = o
+	status := o.status
+	x.mu.Unlock()
+	log.Printf("⏱️ TWAP %s: %s %.3f %s in %d slices every %s", status.ID, status.Side, status.Qty, status.Instrument, status.Slices, interval)
+	go x.run(o, interval)
+	return status, nil
+}
+
+// run sends the slices of o, the first immediately, until all are sent or o is stopped.
+func (x *twapExecutor) run(o *twapOrder, interval time.Duration) {
+	ticker := x.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for i := range o.childLots {
+		if i > 0 {
+			select {
+			case <-o.stop:
+				return
+			case <-ticker.C():
+			}
+		}
+		if !x.sendSlice(o, i) {
+			return
+		}
+	}
+}
+
+// sendSlice places slice i of o and reports whether to continue. The lock is held while placing
+// so that no slice goes out after cancel returns.
+func (x *twapExecutor) sendSlice(o *twapOrder, i int) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if o.status.State != "running" {
+		return false
+	}
+	slippage := o.slippage
+	if slippage == 0 {
+		slippage = state.DefaultSlippage(o.status.Instrument)
+	}
+	cmd := amqp.TradeCommand{
+		Label:      fmt.Sprintf("%s_%d", o.status.ID, i+1),
+		Instrument: o.status.Instrument,
+		OrderCmd:   o.status.Side,
+		Amount:     lotsAmount(o.childLots[i]),
+		Slippage:   slippage,
+	}
+	if err := x.place(cmd, o.status.ID, i+1); err != nil {
+		log.Printf("❌ TWAP %s aborted at slice %d/%d: %v", o.status.ID, i+1, len(o.childLots), err)
+		o.finishLocked("aborted", fmt.Sprintf("slice %d: %v", i+1, err), x.clock.Now())
+		return false
+	}
+	o.status.Sent++
+	o.sentLots += o.childLots[i]
+	o.status.SentQty = lotsAmount(o.sentLots)
+	log.Printf("TWAP %s slice %d/%d: %s %.3f %s (%s)", o.status.ID, i+1, len(o.childLots), cmd.OrderCmd, cmd.Amount, cmd.Instrument, cmd.Label)
+	if o.status.Sent == len(o.childLots) {
+		o.finishLocked("done", "", x.clock.Now())
+	}
+	return true
+}
+
+// finishLocked moves o out of the running state at time at; the caller holds the executor lock.
+func (o *twapOrder) finishLocked(st, reason string, at time.Time) {
+	if o.status.State == "running" {
+		o.status.State, o.status.Reason = st, reason
+		o.finishedAt = at
+		close(o.stop)
+	}
+}
+
+// pruneLocked drops the orders that finished more than twapRetention before now, so the map does
+// not grow with every order over the process lifetime; the caller holds the executor lock.
+func (x *twapExecutor) pruneLocked(now time.Time) {
+	for id, o := range x.orders {
+		if o.status.State != "running" && now.Sub(o.finishedAt) > twapRetention {
+			delete(x.orders, id)
+		}
+	}
+}
+
+// cancel stops the remaining slices of the running TWAP order id. Children already sent are kept.
+func (x *twapExecutor) cancel(id string) (TWAPStatus, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	o, ok := x.orders[id]
+	if !ok || o.status.State != "running" {
+		return TWAPStatus{}, errTWAPNotFound
+	}
+	o.finishLocked("cancelled", "", x.clock.Now())
+	log.Printf("TWAP %s cancelled after %d/%d slices", id, o.status.Sent, o.status.Slices)
+	return o.status, nil
+}
+
+// recordResult counts a Trade_Results outcome against the TWAP order that sent the child.
+// Results for other orders are ignored.
+func (x *twapExecutor) recordResult(r amqp.TradeResult) {
+	i := strings.LastIndexByte(r.Label, '_')
+	if i < 0 {
+		return
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	o, ok := x.orders[r.Label[:i]]
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(r.Label[i+1:])
+	if err != nil || n < 1 || n > len(o.childLots) {
+		return
+	}
+	switch r.Status {
+	case amqp.ResultFilled:
+		o.status.Filled++
+		o.filledLots += o.childLots[n-1]
+		o.status.FilledQty = lotsAmount(o.filledLots)
+	case amqp.ResultRejected, amqp.ResultError:
+		o.status.Rejected++
+	}
+}
+
+// statuses returns the running and recently finished TWAP orders, newest first.
+func (x *twapExecutor) statuses() []TWAPStatus {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.pruneLocked(x.clock.Now())
+	out := make([]TWAPStatus, 0, len(x.orders))
+	for _, o := range x.orders {
+		out = append(out, o.status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt > out[j].StartedAt })
+	return out
+}
+
+// checkTWAP applies the notional and margin checks to the total qty of a TWAP order.
+func (fb *FrontendBroadcaster) checkTWAP(instrument string, qty float64) error {
+	if !fb.checkNotional(instrument, qty) || !fb.checkMargin(instrument, qty) {
+		return fmt.Errorf("total qty %.3f rejected by risk checks", qty)
+	}
+	return nil
+}
+
+// placeTWAPSlice risk-checks and submits one TWAP child market order, recording it in the trades table.
+func (fb *FrontendBroadcaster) placeTWAPSlice(cmd amqp.TradeCommand, parent string, slice int) error {
+	if !fb.checkNotional(cmd.Instrument, cmd.Amount) || !fb.checkMargin(cmd.Instrument, cmd.Amount) {
+		return fmt.Errorf("rejected by risk checks")
+	}
+	if fb.dbLogger != nil {
+		fb.dbLogger.LogTradeSubmitted(cmd.Label, cmd.Instrument, cmd.OrderCmd, cmd.OrderCmd, cmd.Amount, 0, 0, 0,
+			map[string]any{"orderType": "MARKET", "source": "twap", "parent": parent, "slice": slice})
+	}
+	return fb.publisher.PublishSubmitOrder(cmd)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go-trader/internal/amqp"
+	"go-trader/internal/clock"
+)
+
+func TestSplitLotsPutsRemainderOnLastSlice(t *testing.T) {
+	got, err := splitLots(1.001, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{250, 250, 250, 251}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("slices = %v, want %v", got, want)
+		}
+	}
+	if _, err := splitLots(0.003, 4); err == nil {
+		t.Fatal("slices below one lot should be rejected")
+	}
+}
+
+// twapHarness runs a TWAP executor on a fake clock, recording each placed child on a channel.
+func twapHarness(t *testing.T, fail func(slice int) error) (*twapExecutor, *clock.FakeClock, chan amqp.TradeCommand) {
+	t.Helper()
+	fc := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	placed := make(chan amqp.TradeCommand, maxTWAPSlices)
+	x := newTWAPExecutor(fc, nil, func(cmd amqp.TradeCommand, parent string, slice int) error {
+		if fail != nil {
+			if err := fail(slice); err != nil {
+				return err
+			}
+		}
+		placed <- cmd
+		return nil
+	})
+	return x, fc, placed
+}
+
+func waitSlice(t *testing.T, placed chan amqp.TradeCommand) amqp.TradeCommand {
+	t.Helper()
+	select {
+	case cmd := <-placed:
+		return cmd
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a slice")
+		return amqp.TradeCommand{}
+	}
+}
+
+func waitState(t *testing.T, x *twapExecutor, id, want string) TWAPStatus {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		for _, st := range x.statuses() {
+			if st.ID == id && st.State == want {
+				return st
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("TWAP %s never reached state %s: %+v", id, want, x.statuses())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTWAPSendsSlicesOnInterval(t *testing.T) {
+	x, fc, placed := twapHarness(t, nil)
+	st, err := x.start(TWAPRequest{Instrument: "eurusd", Side: "buy", Qty: 0.3, Duration: "3m", Slices: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.IntervalMs != time.Minute.Milliseconds() {
+		t.Fatalf("interval = %dms, want 1m", st.IntervalMs)
+	}
+	first := waitSlice(t, placed)
+	if first.Label != st.ID+"_1" || first.OrderCmd != "BUY" || first.Amount != 0.1 || first.Slippage == 0 {
+		t.Fatalf("first slice = %+v, want BUY 0.1 labelled %s_1 with the default slippage", first, st.ID)
+	}
+	for n := 2; n <= 3; n++ {
+		fc.Advance(time.Minute)
+		waitSlice(t, placed)
+	}
+	done := waitState(t, x, st.ID, "done")
+	if done.Sent != 3 || done.SentQty != 0.3 {
+		t.Fatalf("status = %+v, want 3 slices totalling 0.3 sent", done)
+	}
+
+	x.recordResult(amqp.TradeResult{Label: st.ID + "_1", Status: amqp.ResultFilled})
+	x.recordResult(amqp.TradeResult{Label: st.ID + "_2", Status: amqp.ResultRejected})
+	x.recordResult(amqp.TradeResult{Label: "EURUSD_buy_1", Status: amqp.ResultFilled})
+	got := x.statuses()[0]
+	if got.Filled != 1 || got.FilledQty != 0.1 || got.Rejected != 1 {
+		t.Fatalf("status = %+v, want 1 fill of 0.1 and 1 rejection", got)
+	}
+}
+
+func TestTWAPCancelStopsRemainingSlices(t *testing.T) {
+	x, fc, placed := twapHarness(t, nil)
+	st, err := x.start(TWAPRequest{Instrument: "EURUSD", Side: "SELL", Qty: 1, Duration: "10m", Slices: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitSlice(t, placed)
+	fc.Advance(time.Minute)
+	waitSlice(t, placed)
+
+	got, err := x.cancel(st.ID)
+	if err != nil || got.State != "cancelled" || got.Sent != 2 {
+		t.Fatalf("cancel = %+v, %v; want cancelled after 2 slices", got, err)
+	}
+	fc.Advance(10 * time.Minute)
+	select {
+	case cmd := <-placed:
+		t.Fatalf("slice %s sent after cancel", cmd.Label)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := x.cancel(st.ID); !errors.Is(err, errTWAPNotFound) {
+		t.Fatalf("second cancel err = %v, want errTWAPNotFound", err)
+	}
+}
+
+func TestTWAPPrunesFinishedOrders(t *testing.T) {
+	x, fc, placed := twapHarness(t, nil)
+	cancelled, err := x.start(TWAPRequest{Instrument: "EURUSD", Side: "BUY", Qty: 1, Duration: "10m", Slices: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitSlice(t, placed)
+	if _, err := x.cancel(cancelled.ID); err != nil {
+		t.Fatal(err)
+	}
+	fc.Advance(time.Minute)
+	running, err := x.start(TWAPRequest{Instrument: "GBPUSD", Side: "SELL", Qty: 1, Duration: "24h", Slices: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitSlice(t, placed)
+
+	if n := len(x.statuses()); n != 2 {
+		t.Fatalf("%d orders listed within the retention window, want 2", n)
+	}
+	fc.Set(fc.Now().Add(twapRetention))
+	got := x.statuses()
+	if len(got) != 1 || got[0].ID != running.ID {
+		t.Fatalf("statuses after the retention window = %+v, want only the running %s", got, running.ID)
+	}
+	x.cancel(running.ID)
+}
+
+func TestTWAPAbortsWhenSliceFails(t *testing.T) {
+	x, _, placed := twapHarness(t, func(slice int) error {
+		if slice == 1 {
+			return errors.New("rejected by risk checks")
+		}
+		return nil
+	})
+	st, err := x.start(TWAPRequest{Instrument: "EURUSD", Side: "BUY", Qty: 1, Duration: "10m", Slices: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := waitState(t, x, st.ID, "aborted")
+	if got.Sent != 0 || got.Reason == "" || len(placed) != 0 {
+		t.Fatalf("status = %+v, want aborted before any slice with a reason", got)
+	}
+}
+
+func TestTWAPRejectsInvalidRequests(t *testing.T) {
+	x, _, _ := twapHarness(t, nil)
+	var checked []float64
+	x.check = func(instrument string, qty float64) error {
+		checked = append(checked, qty)
+		if qty > 10 {
+			return errors.New("notional limit exceeded")
+		}
+		return nil
+	}
+	for name, req := range map[string]TWAPRequest{
+		"unknown instrument": {Instrument: "XXXYYY", Side: "BUY", Qty: 1, Duration: "10m", Slices: 10},
+		"bad side":           {Instrument: "EURUSD", Side: "HOLD", Qty: 1, Duration: "10m", Slices: 10},
+		"one slice":          {Instrument: "EURUSD", Side: "BUY", Qty: 1, Duration: "10m", Slices: 1},
+		"bad duration":       {Instrument: "EURUSD", Side: "BUY", Qty: 1, Duration: "soon", Slices: 10},
+		"slices too close":   {Instrument: "EURUSD", Side: "BUY", Qty: 1, Duration: "5s", Slices: 10},
+		"qty too small":      {Instrument: "EURUSD", Side: "BUY", Qty: 0.005, Duration: "10m", Slices: 10},
+		"negative qty":       {Instrument: "EURUSD", Side: "BUY", Qty: -1, Duration: "10m", Slices: 10},
+		"NaN qty":            {Instrument: "EURUSD", Side: "BUY", Qty: math.NaN(), Duration: "10m", Slices: 10},
+		"infinite qty":       {Instrument: "EURUSD", Side: "BUY", Qty: math.Inf(1), Duration: "10m", Slices: 10},
+		"qty above max":      {Instrument: "EURUSD", Side: "BUY", Qty: maxCommandQty + 1, Duration: "10m", Slices: 10},
+		"negative slippage":  {Instrument: "EURUSD", Side: "BUY", Qty: 1, Duration: "10m", Slices: 10, Slippage: -1},
+		"NaN slippage":       {Instrument: "EURUSD", Side: "BUY", Qty: 1, Duration: "10m", Slices: 10, Slippage: math.NaN()},
+		"slippage above max": {Instrument: "EURUSD", Side: "BUY", Qty: 1, Duration: "10m", Slices: 10, Slippage: maxCommandSlippage + 1},
+		"total fails risk":   {Instrument: "EURUSD", Side: "BUY", Qty: 20, Duration: "10m", Slices: 10},
+	} {
+		if _, err := x.start(req); err == nil {
+			t.Errorf("%s: start succeeded, want an error", name)
+		}
+	}
+	if n := len(x.statuses()); n != 0 {
+		t.Fatalf("%d orders recorded, want none", n)
+	}
+	// Only requests passing validation reach the risk check, with the total rather than a slice
+	if len(checked) != 1 || checked[0] != 20 {
+		t.Fatalf("risk-checked quantities %v, want the 20 total only", checked)
+	}
+}
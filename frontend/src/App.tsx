@@ -1,5 +1,5 @@
 import { useEffect } from 'react';
-import { useStore } from './store/store';
+import { useStore, WEBSOCKET_URL } from './store/store';
 import Dashboard from './components/Dashboard';
 
 function App() {
@@ -27,7 +27,7 @@ function App() {
             <div style={{ textAlign: 'center' }}>
               <div style={{ fontSize: '48px', marginBottom: '20px' }}>🔄</div>
               <h2>Connecting to backend...</h2>
-              <p>Establishing WebSocket connection to ws://localhost:8080/ws</p>
+              <p>Establishing WebSocket connection to {WEBSOCKET_URL}</p>
             </div>
           ) : (
             <div style={{ textAlign: 'center' }}>
@@ -35,7 +35,7 @@ function App() {
               <h2>Connection Failed</h2>
               <p>Unable to connect to backend server</p>
               <p style={{ fontSize: '14px', color: '#888', marginTop: '10px' }}>
-                Make sure the Go backend is running on port 8080
+                Make sure the Go backend is reachable at {WEBSOCKET_URL}
               </p>
             </div>
           )}
@@ -3,7 +3,7 @@ import { useStore } from '../store/store';
 import PriceChart from './PriceChart';
 
 export default function Dashboard() {
-  const { fullState, requestHistoricalData } = useStore();
+  const { fullState, requestHistoricalData, alerts, dismissAlerts } = useStore();
   const [selectedInstrument, setSelectedInstrument] = useState('Dashboard');
   const [isDarkMode, setIsDarkMode] = useState(false);
 
@@ -228,6 +228,28 @@ export default function Dashboard() {
             </div>
           </div>
         )}
+
+        {/* Backend alerts (e.g. stale tick data) */}
+        {alerts.length > 0 && (
+          <div style={{
+            marginTop: '15px',
+            padding: '8px 12px',
+            backgroundColor: isDarkMode ? '#3a2e00' : '#fff8e1',
+            border: '1px solid #FFC107',
+            borderRadius: '4px',
+            fontSize: '13px'
+          }}>
+            <div style={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', marginBottom: '4px' }}>
+              <strong>Alerts ({alerts.length})</strong>
+              <button onClick={dismissAlerts} style={{ fontSize: '12px', cursor: 'pointer' }}>Dismiss</button>
+            </div>
+            {alerts.slice(0, 5).map((a) => (
+              <div key={`${a.code}-${a.instrument ?? ''}-${a.at}`} style={{ color: a.severity === 'error' ? '#f44336' : 'inherit' }}>
+                {new Date(a.at).toLocaleTimeString()} — {a.message}
+              </div>
+            ))}
+          </div>
+        )}
       </header>
 
       {/* Main Content */}
@@ -29,6 +29,7 @@ export default function StrategyPanel({
     { key: 'DEMA_RSI', name: 'DEMA + RSI (starter)' },
     { key: 'BREAKOUT_DC', name: 'Donchian Breakout' },
     { key: 'SUPERTREND_TREND', name: 'Supertrend Trend-Follow' },
+    { key: 'BB_SQUEEZE', name: 'Bollinger Squeeze Breakout' },
   ], []);
 
   const timeframes: Array<{ key: string; label: string }> = [
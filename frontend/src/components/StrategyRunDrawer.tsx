@@ -14,11 +14,12 @@ import type { StrategyRunRow, StrategyEventRow } from '../types';
  */
 export default function StrategyRunDrawer({ open, instrument, period, onClose }: { open: boolean; instrument: string; period: string; onClose: () => void; }) {
   const fetchRuns = useStore((s) => s.fetchStrategyRuns);
-  const fetchEvents = useStore((s) => s.fetchStrategyEvents);
+  const fetchEvents = useStore((s) => s.fetchStrategyEventsPage);
 
   const [runs, setRuns] = React.useState<StrategyRunRow[]>([]);
   const [selectedRun, setSelectedRun] = React.useState<StrategyRunRow | null>(null);
   const [events, setEvents] = React.useState<StrategyEventRow[]>([]);
+  const [eventsCursor, setEventsCursor] = React.useState<string | null>(null);
   const [loading, setLoading] = React.useState(false);
 
   React.useEffect(() => {
@@ -47,13 +48,14 @@ export default function StrategyRunDrawer({ open, instrument, period, onClose }:
   React.useEffect(() => {
     let mounted = true;
     async function loadEvents() {
-      if (!selectedRun) { setEvents([]); return; }
+      if (!selectedRun) { setEvents([]); setEventsCursor(null); return; }
       setLoading(true);
       try {
-        const evts = await fetchEvents({ runId: selectedRun.runId, limit: 500 });
+        const page = await fetchEvents({ runId: selectedRun.runId, limit: 500 });
         if (!mounted) return;
         // Events come newest-first; we prefer oldest-first for timeline
-        setEvents([...evts].reverse());
+        setEvents([...page.rows].reverse());
+        setEventsCursor(page.nextCursor);
       } finally {
         if (mounted) setLoading(false);
       }
@@ -62,6 +64,19 @@ export default function StrategyRunDrawer({ open, instrument, period, onClose }:
     return () => { mounted = false; };
   }, [selectedRun, fetchEvents]);
 
+  // Prepend the next older page of events to the timeline
+  const loadOlderEvents = async () => {
+    if (!selectedRun || !eventsCursor) return;
+    setLoading(true);
+    try {
+      const page = await fetchEvents({ runId: selectedRun.runId, limit: 500, before: eventsCursor });
+      setEvents((prev) => [...[...page.rows].reverse(), ...prev]);
+      setEventsCursor(page.nextCursor);
+    } finally {
+      setLoading(false);
+    }
+  };
+
   // Compute simple metrics
   const metrics = React.useMemo(() => {
     const total = events.length;
@@ -183,6 +198,9 @@ export default function StrategyRunDrawer({ open, instrument, period, onClose }:
           <div style={{ background: '#242424', border: '1px solid #444', borderRadius: 8, padding: 10, fontSize: 12, maxHeight: '50vh', overflow: 'auto' }}>
             {loading && <div style={{ color: '#aaa' }}>Loading…</div>}
             {!loading && events.length === 0 && <div style={{ color: '#aaa' }}>No events.</div>}
+            {!loading && eventsCursor && (
+              <button onClick={loadOlderEvents} style={{ background: '#333', border: '1px solid #555', color: '#fff', padding: '4px 8px', borderRadius: 6, cursor: 'pointer', marginBottom: 6 }}>Load older events</button>
+            )}
             {!loading && events.length > 0 && (
               <ul style={{ listStyle: 'none', margin: 0, padding: 0 }}>
                 {events.map((e, i) => (
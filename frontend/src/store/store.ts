@@ -1,10 +1,13 @@
 import { create } from 'zustand';
-import type { FullState } from '../types';
+import type { AlertMessage, FullState, HistoricalBarsMessage, Page, StrategyEventRow, StrategyRunRow } from '../types';
 
 
-const API_BASE = 'http://localhost:8080';
+// Override with VITE_API_BASE / VITE_WS_URL (e.g. https://host:8443 and wss://host:8443/ws when the backend serves TLS)
+const API_BASE = import.meta.env.VITE_API_BASE ?? 'http://localhost:8080';
 
-const WEBSOCKET_URL = 'ws://localhost:8080/ws';
+export const WEBSOCKET_URL = import.meta.env.VITE_WS_URL ?? 'ws://localhost:8080/ws';
+
+const MAX_ALERTS = 50;
 
 interface ChartSettings {
   period: string;
@@ -20,6 +23,8 @@ interface ChartSettings {
 interface AppState {
   connectionStatus: 'connecting' | 'connected' | 'disconnected';
   fullState: FullState | null;
+  alerts: AlertMessage[]; // newest first
+  dismissAlerts: () => void;
   chartSettings: ChartSettings;
   setChartSettings: (settings: Partial<ChartSettings>) => void;
   connect: () => void;
@@ -30,18 +35,36 @@ interface AppState {
   placeLimitOrder: (p: { instrument: string; side: 'BUY' | 'SELL'; qty: number; price: number; slPips?: number; tpPips?: number }) => void;
   closeAll: (p: { instrument: string; side: 'BUY' | 'SELL' }) => void;
   closePosition: (p: { orderId: string }) => void;
+  // Closes every open position whose label starts with labelPrefix (e.g. 'EURUSD_strat_')
+  closeByLabel: (p: { labelPrefix: string }) => void;
 
   startStrategy: (p: { instrument: string; strategyKey: string; period: string; qty?: number; atrMult?: number; params?: Record<string, number> }) => void;
   stopStrategy: (p: { instrument: string; period: string }) => void;
   fetchStrategyRuns: (p: { instrument?: string; period?: string; limit?: number }) => Promise<any[]>;
   fetchStrategyEvents: (p: { runId: string; limit?: number }) => Promise<any[]>;
+  // Paged variants: pass the previous page's nextCursor as `before` to load older rows
+  fetchStrategyRunsPage: (p: { instrument?: string; period?: string; limit?: number; before?: string }) => Promise<Page<StrategyRunRow>>;
+  fetchStrategyEventsPage: (p: { runId: string; limit?: number; before?: string }) => Promise<Page<StrategyEventRow>>;
+}
+
+// fetchPage GETs a paged list endpoint; the next cursor arrives in the X-Next-Cursor header.
+async function fetchPage<T>(path: string, params: URLSearchParams, before?: string): Promise<Page<T>> {
+  if (before) params.set('before', before);
+  const res = await fetch(`${API_BASE}${path}?${params.toString()}`);
+  if (!res.ok) return { rows: [], nextCursor: null };
+  return { rows: await res.json(), nextCursor: res.headers.get('X-Next-Cursor') };
 }
 
 let websocket: WebSocket | null = null;
+// Historical bars arrive as separate HISTORICAL_BARS messages and are merged into every snapshot
+let historicalBars: FullState['historicalBars'] = {};
 
 export const useStore = create<AppState>((set, get) => ({
   connectionStatus: 'disconnected', // Start as disconnected for debugging
   fullState: null,
+  alerts: [],
+
+  dismissAlerts: () => set({ alerts: [] }),
 
   // Global chart settings
   chartSettings: {
@@ -77,8 +100,25 @@ export const useStore = create<AppState>((set, get) => ({
 
     websocket.onmessage = (event) => {
       try {
-        const data: FullState = JSON.parse(event.data);
-        set({ fullState: data });
+        const data = JSON.parse(event.data);
+        if (data.type === 'HISTORICAL_BARS') {
+          const msg = data as HistoricalBarsMessage;
+          historicalBars = {
+            ...historicalBars,
+            [msg.instrument]: { ...historicalBars[msg.instrument], [msg.period]: msg.bars },
+          };
+          set((state) => ({ fullState: state.fullState ? { ...state.fullState, historicalBars } : null }));
+          return;
+        }
+        if (data.type === 'ALERT') {
+          const alert = data as AlertMessage;
+          set((state) => ({ alerts: [alert, ...state.alerts].slice(0, MAX_ALERTS) }));
+          return;
+        }
+        if (data.type) {
+          return; // other out-of-band events (e.g. STRATEGY_STATUS_CHANGE) are not snapshots
+        }
+        set({ fullState: { ...(data as FullState), historicalBars } });
       } catch (error) {
         console.error('Error parsing WebSocket message:', error);
       }
@@ -125,7 +165,7 @@ export const useStore = create<AppState>((set, get) => ({
     }
   },
 
-  placeMarketOrder: ({ instrument, side, qty, slPips = 0, tpPips = 0, slippage = 5 }) => {
+  placeMarketOrder: ({ instrument, side, qty, slPips = 0, tpPips = 0, slippage = 0 }) => {
     if (!websocket || websocket.readyState !== WebSocket.OPEN) return;
     const cmd = { type: 'PLACE_ORDER', instrument, side, qty, slPips, tpPips, slippage, orderType: 'MARKET' };
     websocket.send(JSON.stringify(cmd));
@@ -149,6 +189,12 @@ export const useStore = create<AppState>((set, get) => ({
     websocket.send(JSON.stringify(cmd));
   },
 
+  closeByLabel: ({ labelPrefix }) => {
+    if (!websocket || websocket.readyState !== WebSocket.OPEN) return;
+    const cmd = { type: 'CLOSE_BY_LABEL', labelPrefix };
+    websocket.send(JSON.stringify(cmd));
+  },
+
   startStrategy: ({ instrument, strategyKey, period, qty = 0.1, atrMult = 1.0, params }) => {
     if (!websocket || websocket.readyState !== WebSocket.OPEN) return;
     const cmd = { type: 'STRATEGY_START', instrument, strategyKey, period, qty, atrMult, params };
@@ -161,18 +207,14 @@ export const useStore = create<AppState>((set, get) => ({
     websocket.send(JSON.stringify(cmd));
   },
 
-  fetchStrategyRuns: async ({ instrument = '', period = '', limit = 50 }) => {
-    const params = new URLSearchParams({ instrument, period, limit: String(limit) });
-    const res = await fetch(`${API_BASE}/api/strategy/runs?${params.toString()}`);
-    if (!res.ok) return [];
-    return await res.json();
-  },
+  fetchStrategyRuns: async (p) => (await get().fetchStrategyRunsPage(p)).rows,
 
-  fetchStrategyEvents: async ({ runId, limit = 200 }) => {
-    const params = new URLSearchParams({ runId, limit: String(limit) });
-    const res = await fetch(`${API_BASE}/api/strategy/events?${params.toString()}`);
-    if (!res.ok) return [];
-    return await res.json();
-  },
+  fetchStrategyEvents: async (p) => (await get().fetchStrategyEventsPage(p)).rows,
+
+  fetchStrategyRunsPage: ({ instrument = '', period = '', limit = 50, before }) =>
+    fetchPage<StrategyRunRow>('/api/strategy/runs', new URLSearchParams({ instrument, period, limit: String(limit) }), before),
+
+  fetchStrategyEventsPage: ({ runId, limit = 200, before }) =>
+    fetchPage<StrategyEventRow>('/api/strategy/events', new URLSearchParams({ runId, limit: String(limit) }), before),
 
 }));
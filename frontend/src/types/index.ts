@@ -160,6 +160,7 @@ export interface AccountInfo {
   timestamp: number;
   account: Account;
   positions: Position[];
+  pendingOrders?: Position[]; // working (unfilled) limit/stop orders
 }
 
 export interface StrategyStatus {
@@ -169,12 +170,14 @@ export interface StrategyStatus {
   running: boolean;
   lastSignal: 'BUY' | 'SELL' | 'NONE' | string;
   lastActionAt: number; // ms epoch
+  warmingUp?: boolean; // evaluation suppressed until enough bars are available
 }
 
 export interface PeriodHealth {
   count: number;
   valid: boolean;
   newestTs?: number;
+  completeness?: number; // percent of the latest historical response received
 }
 
 export interface TicksHealth {
@@ -194,13 +197,67 @@ export interface LedgerHealthSummary {
   instruments: InstrumentHealthSummary[];
 }
 
+// Age in ms of an instrument's newest data at broadcast time; absent when none has arrived
+export interface InstrumentFreshness {
+  tickAgeMs?: number;
+  tickStale: boolean; // no tick yet, or the newest is older than the backend's stale threshold (60s)
+  barAgeMs?: Record<string, number>; // period -> age of the newest bar's end
+}
+
+// Current session open/high/low/last mid price (sessions reset at the configured daily boundary)
+export interface SessionStats {
+  instrument: string;
+  sessionStart: number; // unix millis
+  open: number;
+  high: number;
+  low: number;
+  last: number;
+  ticks: number;
+  updatedAt: number;
+}
+
 export interface FullState {
+  accountCurrency?: string; // unit of balances, PnL and exposure (e.g. "USD")
   accountInfo: AccountInfo;
   ticks: Record<string, Tick[]>;
   bars: Record<string, Record<string, Bar[]>>;
-  historicalBars: Record<string, Record<string, HistoricalBar[]>>;
+  historicalBars: Record<string, Record<string, HistoricalBar[]>>; // merged client-side from HISTORICAL_BARS messages
   strategyStatuses?: StrategyStatus[];
   ledgerHealthSummary?: LedgerHealthSummary;
+  sessions?: Record<string, SessionStats>;
+  dataFreshness?: Record<string, InstrumentFreshness>;
+}
+
+
+export interface HistoricalBarsMessage {
+  type: 'HISTORICAL_BARS';
+  schemaVersion: number;
+  instrument: string;
+  period: string;
+  bars: HistoricalBar[];
+}
+
+// Operator warning pushed by the backend (e.g. stale tick data)
+export interface AlertMessage {
+  type: 'ALERT';
+  severity: 'info' | 'warn' | 'error';
+  code: string;
+  instrument?: string;
+  message: string;
+  at: number; // unix millis
+}
+
+// Outcome JForex reported for a trade command (Trade_Results queue)
+export interface TradeResultMessage {
+  type: 'TRADE_RESULT';
+  produced_at: number;
+  label: string; // order label, or orderId for close/modify/cancel
+  command: string;
+  status: string; // ACCEPTED | FILLED | CLOSED | MODIFIED | CANCELED | REJECTED | ERROR
+  orderId?: string;
+  reason?: string;
+  price?: number;
+  known?: boolean; // label matched a logged trade (absent without a database)
 }
 
 
@@ -227,3 +284,9 @@ export interface StrategyEventRow {
   signal?: string;
   details?: Record<string, any>;
 }
+
+// One page of a newest-first list endpoint; nextCursor is null on the last page.
+export interface Page<T> {
+  rows: T[];
+  nextCursor: string | null;
+}
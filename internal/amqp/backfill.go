@@ -0,0 +1,101 @@
+package amqp
+
+import (
+	"log"
+	"time"
+
+	"go-trader/internal/state"
+)
+
+// Atomic backfills.
+// What: A full historical response arrives one bar per message; merging each as it lands lets
+//       strategies evaluate against a half-populated, reordering buffer while it streams in.
+// How: The requester numbers a response N..1 from oldest to newest, so a bar with Sequence of at
+//      least the period's bar capacity (StateManager.BarCapacity) opens a batch; a smaller response
+//      cannot fill the buffer and would drop older bars if swapped in. Bars of the response are
+//      staged (and acked) until all N sequences are in, then swapped in with
+//      StateManager.ReplaceHistoricalBars. A batch found quiet
+//      for backfillQuiet (checked on each historical bar and every stats interval), or superseded by a
+//      new response, is merged bar by bar instead, since an incomplete response must not discard bars
+//      it did not resend.
+
+// backfillQuiet is how long a staged backfill may wait for its next bar.
+const backfillQuiet = 5 * time.Second
+
+// backfillBatch collects one full historical response for an instrument/period.
+type backfillBatch struct {
+	instrument, period string
+	expected           int // sequence of the oldest bar, i.e. the response size
+	bars               []state.HistoricalBar
+	seqs               map[int]bool
+	lastArrival        time.Time
+}
+
+// stageBackfillBar adds bar to its instrument/period's backfill batch and reports whether it was
+// staged; unstaged bars are merged by the caller. Completing a batch replaces the buffer.
+func (mh *MessageHandler) stageBackfillBar(bar state.HistoricalBar) bool {
+	now := mh.clock.Now()
+	mh.flushBackfills(now, false)
+	if bar.Sequence <= 0 {
+		return false
+	}
+	key := bar.Instrument + "|" + bar.Period
+
+	mh.backfillMu.Lock()
+	b := mh.backfills[key]
+	if b != nil && (bar.Sequence > b.expected || b.seqs[bar.Sequence]) {
+		// A new response started before the previous one completed
+		delete(mh.backfills, key)
+		mh.backfillMu.Unlock()
+		mh.mergeBackfill(b, "superseded")
+		mh.backfillMu.Lock()
+		b = nil
+	}
+	if b == nil {
+		if bar.Sequence < mh.stateManager.BarCapacity(bar.Period) {
+			mh.backfillMu.Unlock()
+			return false
+		}
+		b = &backfillBatch{instrument: bar.Instrument, period: bar.Period, expected: bar.Sequence, seqs: make(map[int]bool)}
+		mh.backfills[key] = b
+	}
+	b.bars = append(b.bars, bar)
+	b.seqs[bar.Sequence] = true
+	b.lastArrival = now
+	complete := len(b.seqs) == b.expected
+	if complete {
+		delete(mh.backfills, key)
+	}
+	mh.backfillMu.Unlock()
+
+	if complete {
+		mh.stateManager.ReplaceHistoricalBars(b.instrument, b.period, b.bars)
+		log.Printf("Historical backfill for %s %s complete: replaced buffer with %d bars", b.instrument, b.period, len(b.bars))
+	}
+	return true
+}
+
+// flushBackfills merges batches that have been quiet for backfillQuiet, or all of them when force is set.
+func (mh *MessageHandler) flushBackfills(now time.Time, force bool) {
+	var stale []*backfillBatch
+	mh.backfillMu.Lock()
+	for key, b := range mh.backfills {
+		if force || now.Sub(b.lastArrival) > backfillQuiet {
+			stale = append(stale, b)
+			delete(mh.backfills, key)
+		}
+	}
+	mh.backfillMu.Unlock()
+	for _, b := range stale {
+		mh.mergeBackfill(b, "incomplete")
+	}
+}
+
+// mergeBackfill applies the bars of an unfinished batch one by one.
+func (mh *MessageHandler) mergeBackfill(b *backfillBatch, reason string) {
+	mh.warnLog.Printf("backfill_"+reason, "WARNING: %s historical backfill for %s %s (%d of %d bars); merging bars individually",
+		reason, b.instrument, b.period, len(b.seqs), b.expected)
+	for _, bar := range b.bars {
+		mh.stateManager.UpdateHistoricalBar(bar)
+	}
+}
@@ -0,0 +1,120 @@
+package amqp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Buffer-full policies for the tick, bar, and historical processing channels.
+const (
+	BufferDropNewest = "drop-newest" // discard the incoming message (default)
+	BufferDropOldest = "drop-oldest" // discard the oldest queued message to make room
+	BufferBlock      = "block"       // wait up to Timeout for room, then discard the incoming message
+)
+
+// BufferPolicy decides what Enqueue* does when a processing channel is full.
+// Blocking holds up the AMQP delivery loop for that class, which pushes the backlog back to the broker.
+type BufferPolicy struct {
+	Mode    string
+	Timeout time.Duration // BufferBlock only
+}
+
+// ParseBufferPolicies parses per-class buffer-full policies from a config string.
+// What: Config format "class:mode[:timeout]" entries separated by commas, e.g. "bar:block:250ms,historical:drop-oldest".
+// How: mode is drop-newest, drop-oldest, or block; block requires a positive Go duration timeout.
+//      Only tick, bar, and historical have channels; account messages always keep the newest snapshot.
+// Returns: policies keyed by class (ClassTick, ClassBar, ClassHistorical).
+func ParseBufferPolicies(v string) (map[string]BufferPolicy, error) {
+	out := make(map[string]BufferPolicy)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("buffer policy %q: want class:mode[:timeout]", entry)
+		}
+		class := strings.ToLower(strings.TrimSpace(parts[0]))
+		switch class {
+		case ClassTick, ClassBar, ClassHistorical:
+		default:
+			return nil, fmt.Errorf("buffer policy %q: unsupported class %q", entry, class)
+		}
+		p := BufferPolicy{Mode: strings.ToLower(strings.TrimSpace(parts[1]))}
+		switch p.Mode {
+		case BufferDropNewest, BufferDropOldest:
+			if len(parts) == 3 {
+				return nil, fmt.Errorf("buffer policy %q: timeout only applies to %s", entry, BufferBlock)
+			}
+		case BufferBlock:
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("buffer policy %q: %s needs a timeout", entry, BufferBlock)
+			}
+			d, err := time.ParseDuration(strings.TrimSpace(parts[2]))
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("buffer policy %q: bad timeout %q", entry, parts[2])
+			}
+			p.Timeout = d
+		default:
+			return nil, fmt.Errorf("buffer policy %q: unknown mode %q", entry, p.Mode)
+		}
+		out[class] = p
+	}
+	return out, nil
+}
+
+// SetBufferPolicy sets the buffer-full policy for ClassTick, ClassBar, or ClassHistorical.
+// Call before StartConsumers.
+func (mh *MessageHandler) SetBufferPolicy(class string, p BufferPolicy) {
+	if _, ok := mh.bufferPolicies[class]; ok {
+		mh.bufferPolicies[class] = p
+		return
+	}
+	log.Printf("Unknown message class for buffer policy: %s", class)
+}
+
+// enqueue hands a delivery to a processing channel, applying the class's policy when it is full.
+// Discarded deliveries are nacked without requeue.
+func (mh *MessageHandler) enqueue(class, name string, ch chan amqp091.Delivery, delivery amqp091.Delivery) {
+	select {
+	case ch <- delivery:
+		return
+	case <-mh.stopChannel:
+		// Handler is stopping
+		return
+	default:
+	}
+
+	switch p := mh.bufferPolicies[class]; p.Mode {
+	case BufferDropOldest:
+		select {
+		case old := <-ch:
+			old.Nack(false, false)
+		default: // drained by a processor meanwhile
+		}
+		select {
+		case ch <- delivery:
+			mh.warnLog.Printf(class+"_full", "WARNING: %s channel full, discarded oldest message to enqueue %s", name, delivery.RoutingKey)
+			return
+		default:
+		}
+	case BufferBlock:
+		timer := time.NewTimer(p.Timeout)
+		defer timer.Stop()
+		select {
+		case ch <- delivery:
+			return
+		case <-mh.stopChannel:
+			return
+		case <-timer.C:
+		}
+	}
+	// Channel still full, discard message to prevent blocking
+	mh.warnLog.Printf(class+"_full", "WARNING: %s channel full, discarding message for %s", name, delivery.RoutingKey)
+	delivery.Nack(false, false) // Don't requeue
+}
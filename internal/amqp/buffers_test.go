@@ -0,0 +1,75 @@
+package amqp
+
+import (
+	"testing"
+	"time"
+
+	"go-trader/internal/state"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestParseBufferPolicies(t *testing.T) {
+	got, err := ParseBufferPolicies(" bar:block:250ms, historical:drop-oldest ,tick:drop-newest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]BufferPolicy{
+		ClassBar:        {Mode: BufferBlock, Timeout: 250 * time.Millisecond},
+		ClassHistorical: {Mode: BufferDropOldest},
+		ClassTick:       {Mode: BufferDropNewest},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %+v, want %+v", k, got[k], v)
+		}
+	}
+	for _, bad := range []string{"bar", "account:drop-oldest", "bar:nope", "bar:block", "bar:block:0", "tick:drop-oldest:1s"} {
+		if _, err := ParseBufferPolicies(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestEnqueueBufferPolicies(t *testing.T) {
+	mh := NewMessageHandler(state.NewStateManager())
+	fill := func() {
+		for len(mh.barChannel) < cap(mh.barChannel) {
+			mh.barChannel <- amqp091.Delivery{RoutingKey: "old"}
+		}
+	}
+
+	fill()
+	mh.EnqueueBar(amqp091.Delivery{RoutingKey: "new"})
+	if first := <-mh.barChannel; first.RoutingKey != "old" {
+		t.Fatal("drop-newest should keep the queued messages")
+	}
+
+	fill()
+	mh.SetBufferPolicy(ClassBar, BufferPolicy{Mode: BufferDropOldest})
+	mh.EnqueueBar(amqp091.Delivery{RoutingKey: "new"})
+	var last amqp091.Delivery
+	for len(mh.barChannel) > 0 {
+		last = <-mh.barChannel
+	}
+	if last.RoutingKey != "new" {
+		t.Fatal("drop-oldest should enqueue the new message")
+	}
+
+	fill()
+	mh.SetBufferPolicy(ClassBar, BufferPolicy{Mode: BufferBlock, Timeout: time.Second})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-mh.barChannel
+	}()
+	mh.EnqueueBar(amqp091.Delivery{RoutingKey: "new"})
+	for len(mh.barChannel) > 0 {
+		last = <-mh.barChannel
+	}
+	if last.RoutingKey != "new" {
+		t.Fatal("block should enqueue once room frees up")
+	}
+}
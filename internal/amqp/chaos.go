@@ -0,0 +1,118 @@
+package amqp
+
+import (
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Fault injection for resilience testing.
+// What: Exercise backpressure, ack batching and channel recovery under controlled faults.
+// How: Data messages (ticks, bars, historical, account) are delayed by a random latency and a
+//      fraction of them is acked without being processed, as if lost upstream. Every
+//      DisconnectInterval one consumer channel is failed with a real broker exception (a passive
+//      declare of a missing queue) so that its group supervisor has to recover it. Every
+//      ConnectionDropInterval the consumer connection itself is closed and reported to the
+//      connection supervisor as a forced close, so it re-dials and restores the consumers
+//      (see reconnect.go) as after a broker restart.
+//      Disabled unless SetChaos is called with a non-zero Chaos; never enable it in production.
+
+// chaosMissingQueue is passively declared to make the broker close a consumer channel.
+const chaosMissingQueue = "gotrader.chaos.missing"
+
+// Chaos configures fault injection on the data consumers.
+type Chaos struct {
+	DropRate           float64       // fraction of data messages acked without processing (0-1)
+	MinLatency         time.Duration // each data message is delayed by a random duration in
+	MaxLatency         time.Duration // [MinLatency, MaxLatency]; 0 adds no delay
+	DisconnectInterval time.Duration // how often a consumer channel is failed; 0 disables
+	// how often the consumer connection is dropped and re-dialed; 0 disables
+	ConnectionDropInterval time.Duration
+}
+
+// Enabled reports whether any fault is configured.
+func (c Chaos) Enabled() bool {
+	return c.DropRate > 0 || c.MaxLatency > 0 || c.DisconnectInterval > 0 || c.ConnectionDropInterval > 0
+}
+
+// SetChaos enables fault injection on the data consumers; call before StartConsumers.
+func (c *Consumer) SetChaos(chaos Chaos) {
+	c.chaos = chaos
+}
+
+// chaosHandler wraps handler with the configured latency and drop rate for class.
+func (c *Consumer) chaosHandler(class string, handler func(amqp091.Delivery)) func(amqp091.Delivery) {
+	chaos := c.chaos
+	if chaos.DropRate <= 0 && chaos.MaxLatency <= 0 {
+		return handler
+	}
+	return func(d amqp091.Delivery) {
+		if chaos.MaxLatency > 0 {
+			time.Sleep(chaos.MinLatency + rand.N(chaos.MaxLatency-chaos.MinLatency+1))
+		}
+		if chaos.DropRate > 0 && rand.Float64() < chaos.DropRate {
+			// Through the class acker so a dropped message cannot be acked twice by a batch
+			c.messageHandler.ackers[class].ack(d)
+			return
+		}
+		handler(d)
+	}
+}
+
+// chaosDisconnects fails a random consumer channel every interval until Close is called.
+// Nothing is failed while the connection is down.
+func (c *Consumer) chaosDisconnects(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		if !c.Connected() {
+			continue
+		}
+		c.mu.Lock()
+		var class string
+		var ch *amqp091.Channel
+		n := 0
+		for k, v := range c.channels {
+			// Reservoir sampling: each open channel is equally likely
+			if n++; rand.IntN(n) == 0 {
+				class, ch = k, v
+			}
+		}
+		c.mu.Unlock()
+		if ch == nil || ch.IsClosed() {
+			continue
+		}
+		log.Printf("🐒 Chaos: failing the %s consumer channel", class)
+		ch.QueueDeclarePassive(chaosMissingQueue, true, false, false, false, nil)
+	}
+}
+
+// chaosConnectionDrops closes the consumer connection every interval until Close is called, so the
+// connection supervisor has to re-dial it (see notifyClose). Nothing is closed while it is down.
+func (c *Consumer) chaosConnectionDrops(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+		if !c.Connected() {
+			continue
+		}
+		log.Printf("🐒 Chaos: dropping the consumer connection")
+		c.chaosClosing.Store(true)
+		if err := c.connection().Close(); err != nil {
+			c.chaosClosing.Store(false)
+			log.Printf("🐒 Chaos: dropping the connection failed: %s", err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package amqp
+
+import (
+	"testing"
+	"time"
+
+	"go-trader/internal/state"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestChaosHandlerDropsAndDelays(t *testing.T) {
+	c := &Consumer{messageHandler: NewMessageHandler(state.NewStateManager())}
+	handled := 0
+	handler := func(amqp091.Delivery) { handled++ }
+
+	c.SetChaos(Chaos{DisconnectInterval: time.Minute})
+	c.chaosHandler(ClassTick, handler)(amqp091.Delivery{})
+	if handled != 1 {
+		t.Fatal("without drop or latency the handler should be called directly")
+	}
+
+	c.SetChaos(Chaos{DropRate: 1, MinLatency: 5 * time.Millisecond, MaxLatency: 5 * time.Millisecond})
+	ack := &recordingAck{}
+	start := time.Now()
+	c.chaosHandler(ClassTick, handler)(amqp091.Delivery{Acknowledger: ack, DeliveryTag: 7})
+	if handled != 1 || len(ack.acked) != 1 {
+		t.Fatalf("handled %d, acked %v; want the message acked and not processed", handled, ack.acked)
+	}
+	if time.Since(start) < 5*time.Millisecond {
+		t.Fatal("message was not delayed")
+	}
+}
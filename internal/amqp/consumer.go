@@ -1,11 +1,15 @@
 package amqp
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"go-trader/internal/clock"
 	"go-trader/internal/state"
 
 	"github.com/rabbitmq/amqp091-go"
@@ -18,130 +22,290 @@ const (
 	accountInfoQueue = "Account_Info"
 )
 
+// DrainMode selects how queued messages are handled on startup.
+type DrainMode string
+
+const (
+	// DrainNone leaves the backlog in place; consumers process it normally.
+	DrainNone DrainMode = "none"
+	// DrainStale discards messages older than maxAge and processes fresh ones.
+	DrainStale DrainMode = "stale"
+	// DrainAll discards everything in the queues (legacy behaviour).
+	DrainAll DrainMode = "all"
+)
+
+// ParseDrainMode converts a config string into a DrainMode, defaulting to DrainAll.
+func ParseDrainMode(v string) DrainMode {
+	switch DrainMode(strings.ToLower(strings.TrimSpace(v))) {
+	case DrainNone:
+		return DrainNone
+	case DrainStale:
+		return DrainStale
+	default:
+		return DrainAll
+	}
+}
+
 // Note: instrumentList is declared in publisher.go to avoid duplication
 
 // Consumer handles receiving messages from RabbitMQ.
 type Consumer struct {
-	conn           *amqp091.Connection
+	uri            string
 	messageHandler *MessageHandler
+	queueLimits    map[string]QueueLimits
+	chaos          Chaos
+	stop           chan struct{} // closed by Close; stops the connection supervisor
+	// chaosClosing is set while chaosConnectionDrops closes the connection (see notifyClose)
+	chaosClosing atomic.Bool
+
+	mu sync.Mutex
+	// conn is replaced when the connection supervisor re-dials (see reconnect.go)
+	conn *amqp091.Connection
+	// channels holds the current channel of each consumer group, by class
+	channels map[string]*amqp091.Channel
+	// started and resultsStarted record what to restore on a new connection
+	started, resultsStarted bool
 }
 
 // NewConsumer creates and connects a new Consumer.
 func NewConsumer(amqpURI string, sm *state.StateManager) (*Consumer, error) {
-	var conn *amqp091.Connection
-	var err error
-
-	for i := 0; i < 10; i++ {
-		conn, err = amqp091.Dial(amqpURI)
-		if err == nil {
-			break
-		}
-		log.Printf("RabbitMQ connection attempt %d failed: %s", i+1, err)
-		time.Sleep(2 * time.Second)
-	}
+	conn, err := dialWithRetry(amqpURI, "consumer", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ after 10 attempts: %w", err)
+		return nil, err
 	}
 
 	messageHandler := NewMessageHandler(sm)
 	messageHandler.Start()
 
-	return &Consumer{conn: conn, messageHandler: messageHandler}, nil
+	c := &Consumer{uri: amqpURI, conn: conn, messageHandler: messageHandler, stop: make(chan struct{})}
+	go c.supervise(c.notifyClose(conn))
+	return c, nil
+}
+
+// notifyClose returns conn's close notifications. A close made by chaosConnectionDrops is reported
+// as a forced close, so the supervisor re-dials as it does after a broker restart; other clean
+// closes (Close) still end supervision.
+func (c *Consumer) notifyClose(conn *amqp091.Connection) <-chan *amqp091.Error {
+	closed := conn.NotifyClose(make(chan *amqp091.Error, 1))
+	out := make(chan *amqp091.Error, 1)
+	go func() {
+		defer close(out)
+		amqpErr, ok := <-closed
+		if !ok && c.chaosClosing.CompareAndSwap(true, false) {
+			amqpErr = &amqp091.Error{Code: amqp091.ConnectionForced, Reason: "chaos: connection dropped"}
+		}
+		if amqpErr != nil {
+			out <- amqpErr
+		}
+	}()
+	return out
+}
+
+// supervise re-dials after the connection closes with an error (see reconnect.go).
+func (c *Consumer) supervise(closed <-chan *amqp091.Error) {
+	superviseConnection("consumer", closed, c.stop, c.redial)
+}
+
+// redial connects again and restores the consumers that ran on the previous connection.
+func (c *Consumer) redial() (<-chan *amqp091.Error, error) {
+	conn, err := dialWithRetry(c.uri, "consumer", c.stop)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	select {
+	case <-c.stop:
+		c.mu.Unlock()
+		conn.Close()
+		return nil, errStopped
+	default:
+	}
+	c.conn = conn
+	started, results := c.started, c.resultsStarted
+	c.mu.Unlock()
+
+	closed := c.notifyClose(conn)
+	if started {
+		if err := c.StartConsumers(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if results {
+		if err := c.startTradeResults(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return closed, nil
+}
+
+// connection returns the current connection.
+func (c *Consumer) connection() *amqp091.Connection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn
+}
+
+// Connected reports whether the connection to RabbitMQ is open. It is false while the
+// connection supervisor re-dials.
+func (c *Consumer) Connected() bool {
+	conn := c.connection()
+	return conn != nil && !conn.IsClosed()
 }
 
 // StartConsumers starts a goroutine for each queue to begin consuming messages.
+// Each message class gets its own AMQP channel so batched multiple-acks only cover that class,
+// and a channel-level error on one class does not stop the others (see supervise.go).
+// The connection supervisor calls it again after a re-dial (see reconnect.go).
 func (c *Consumer) StartConsumers() error {
-	ch, err := c.conn.Channel()
-	if err != nil {
-		return fmt.Errorf("failed to open a channel: %w", err)
+	// Data queues are normally declared by the JForex feeders; only declare the
+	// classes with configured limits so that TTL/max-length apply when we create them first.
+	conn := c.connection()
+	if err := declareQueues(conn, c.limitedDataQueues()); err != nil {
+		return err
 	}
+	for _, g := range c.consumerGroups() {
+		g.handler = c.chaosHandler(g.class, g.handler)
+		if err := c.startGroup(conn, g); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	first := !c.started
+	c.started = true
+	c.mu.Unlock()
+	if first && c.chaos.Enabled() {
+		log.Printf("🐒 Chaos mode: drop rate %.3f, latency %s-%s, channel failure every %s, connection drop every %s",
+			c.chaos.DropRate, c.chaos.MinLatency, c.chaos.MaxLatency, c.chaos.DisconnectInterval, c.chaos.ConnectionDropInterval)
+		if c.chaos.DisconnectInterval > 0 {
+			go c.chaosDisconnects(c.chaos.DisconnectInterval)
+		}
+		if c.chaos.ConnectionDropInterval > 0 {
+			go c.chaosConnectionDrops(c.chaos.ConnectionDropInterval)
+		}
+	}
+	return nil
+}
 
-	// Enable channel flow control and set QoS
-	err = ch.Qos(1, 0, false)
+// openClassChannel opens a channel on conn with the prefetch suited to class.
+func (c *Consumer) openClassChannel(conn *amqp091.Connection, class string) (*amqp091.Channel, error) {
+	ch, err := conn.Channel()
 	if err != nil {
-		log.Printf("Warning: Failed to set QoS: %s", err)
-	}
-
-	// Generic handler function
-	handleFunc := func(queueName string, handler func(d amqp091.Delivery)) {
-		// Retry consumer registration a few times for robustness
-		var msgs <-chan amqp091.Delivery
-		var err error
-
-		for retry := 0; retry < 3; retry++ {
-			msgs, err = ch.Consume(
-				queueName,
-				"",    // consumer
-				false, // auto-ack (manual acks in processors)
-				false, // exclusive
-				false, // no-local
-				false, // no-wait
-				nil,   // args
-			)
-			if err == nil {
-				break
-			}
+		return nil, fmt.Errorf("failed to open a channel for %s: %w", class, err)
+	}
+	// Prefetch must exceed the batch size or the broker stalls waiting for acks
+	prefetch := 1
+	if n := c.messageHandler.AckBatchSize(class); n > 1 {
+		prefetch = n * 2
+	}
+	if err := ch.Qos(prefetch, 0, false); err != nil {
+		log.Printf("Warning: Failed to set QoS for %s: %s", class, err)
+	}
+	return ch, nil
+}
 
-			// Check for specific error types
-			if strings.Contains(err.Error(), "NOT_FOUND") {
-				log.Printf("Queue %s does not exist yet, skipping consumer registration", queueName)
-				return
-			}
+// consume registers handler for every delivery on queueName, using ch.
+// A queue that does not exist yet is skipped with a log line. A consumer cancelled by the
+// broker while ch stays open is re-registered on its own after consumerRestartDelay.
+func (c *Consumer) consume(ch *amqp091.Channel, queueName string, handler func(d amqp091.Delivery)) {
+	// Consuming a missing queue would close ch for every consumer on it, so check first
+	if exists, err := c.queueExists(queueName); err == nil && !exists {
+		log.Printf("Queue %s does not exist yet, skipping consumer registration", queueName)
+		return
+	}
 
-			if strings.Contains(err.Error(), "channel/connection is not open") {
-				log.Printf("Channel not ready for queue %s, retrying in 1 second (attempt %d/3)", queueName, retry+1)
-				time.Sleep(1 * time.Second)
-				continue
-			}
+	// Retry consumer registration a few times for robustness
+	var msgs <-chan amqp091.Delivery
+	var err error
 
-			// Other errors
-			log.Printf("Failed to register consumer for queue %s: %s", queueName, err)
-			return
+	for retry := 0; retry < 3; retry++ {
+		msgs, err = ch.Consume(
+			queueName,
+			"",    // consumer
+			false, // auto-ack (manual acks in processors)
+			false, // exclusive
+			false, // no-local
+			false, // no-wait
+			nil,   // args
+		)
+		if err == nil {
+			break
 		}
 
-		if err != nil {
-			log.Printf("Failed to register consumer for queue %s after retries: %s", queueName, err)
+		// Check for specific error types
+		if strings.Contains(err.Error(), "NOT_FOUND") {
+			log.Printf("Queue %s does not exist yet, skipping consumer registration", queueName)
 			return
 		}
 
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					log.Printf("Consumer for queue %s panicked: %v", queueName, r)
-				}
-			}()
+		if strings.Contains(err.Error(), "channel/connection is not open") {
+			log.Printf("Channel not ready for queue %s, retrying in 1 second (attempt %d/3)", queueName, retry+1)
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		// Other errors
+		log.Printf("Failed to register consumer for queue %s: %s", queueName, err)
+		return
+	}
+
+	if err != nil {
+		log.Printf("Failed to register consumer for queue %s after retries: %s", queueName, err)
+		return
+	}
 
-			for d := range msgs {
-				handler(d)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Consumer for queue %s panicked: %v", queueName, r)
 			}
-			log.Printf("Consumer for queue %s has shut down", queueName)
 		}()
-		log.Printf("Successfully started consumer for queue: %s", queueName)
-	}
 
-	// Start consumers for all queues
-	handleFunc(ticksQueue, c.tickHandler)
-	handleFunc(accountInfoQueue, c.accountInfoHandler)
+		for d := range msgs {
+			handler(d)
+		}
+		if ch.IsClosed() {
+			// The group supervisor re-registers every consumer of the channel
+			log.Printf("Consumer for queue %s has shut down", queueName)
+			return
+		}
+		log.Printf("⚠️ Consumer for queue %s was cancelled by the broker, re-registering in %s", queueName, consumerRestartDelay)
+		time.Sleep(consumerRestartDelay)
+		if !ch.IsClosed() {
+			c.consume(ch, queueName, handler)
+		}
+	}()
+	log.Printf("Successfully started consumer for queue: %s", queueName)
+}
 
-	// Start a consumer for each instrument's live bar queue
-	// Note: Some queues may not exist yet, which is fine - we'll skip them
-	for _, instrument := range instrumentList {
-		barQueueName := fmt.Sprintf("%s_Market_Data_Bars", instrument)
-		handleFunc(barQueueName, c.barHandler)
-	}
+// SetQueueLimits configures per-class TTL/max-length for the data queues; call before StartConsumers.
+// Note: the JForex feeders declare the same queues without arguments, so they must use matching
+// arguments (or a broker policy should be used instead) once a queue is created with limits.
+func (c *Consumer) SetQueueLimits(limits map[string]QueueLimits) {
+	c.queueLimits = limits
+}
 
-	// Start a consumer for each instrument's historical bar queue
+// limitedDataQueues returns the data queues whose class has limits configured.
+func (c *Consumer) limitedDataQueues() map[string]QueueLimits {
+	queues := make(map[string]QueueLimits)
+	add := func(class, name string) {
+		if l, ok := c.queueLimits[class]; ok && l.Args() != nil {
+			queues[name] = l
+		}
+	}
+	add(ClassTick, ticksQueue)
+	add(ClassAccount, accountInfoQueue)
 	for _, instrument := range instrumentList {
-		historicalBarQueueName := fmt.Sprintf("%s_H-Bars", instrument)
-		handleFunc(historicalBarQueueName, c.historicalBarHandler)
+		add(ClassBar, fmt.Sprintf("%s_Market_Data_Bars", instrument))
+		add(ClassHistorical, fmt.Sprintf("%s_H-Bars", instrument))
 	}
-
-	return nil
+	return queues
 }
 
 // isStale checks if a message is older than the defined threshold.
-func isStale(producedAt int64) bool {
-	return time.Now().UnixMilli()-producedAt > staleMessageThreshold.Milliseconds()
+func (mh *MessageHandler) isStale(producedAt int64) bool {
+	return mh.clock.Now().UnixMilli()-producedAt > staleMessageThreshold.Milliseconds()
 }
 
 func (c *Consumer) tickHandler(d amqp091.Delivery) {
@@ -164,10 +328,82 @@ func (c *Consumer) accountInfoHandler(d amqp091.Delivery) {
 	c.messageHandler.EnqueueAccount(d)
 }
 
+// Drain applies the configured startup drain mode.
+// What: Clear the startup backlog according to mode.
+// How: DrainNone does nothing; DrainAll discards via DrainQueues; DrainStale inspects each
+//      message's produced_at and only discards messages older than maxAge, processing the rest.
+// Params: mode, duration upper bound for draining, maxAge staleness cut-off (DrainStale only)
+// Returns: error if a channel cannot be opened.
+func (c *Consumer) Drain(mode DrainMode, duration, maxAge time.Duration) error {
+	switch mode {
+	case DrainNone:
+		log.Printf("Drain mode '%s': keeping queued messages", mode)
+		return nil
+	case DrainStale:
+		return c.drainStaleQueues(duration, maxAge)
+	default:
+		return c.DrainQueues(duration)
+	}
+}
+
+// drainStaleQueues discards messages older than maxAge and processes fresh ones in place.
+func (c *Consumer) drainStaleQueues(duration, maxAge time.Duration) error {
+	ch, err := c.connection().Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %w", err)
+	}
+	defer ch.Close()
+
+	type queueSpec struct {
+		name    string
+		process func(amqp091.Delivery)
+	}
+	mh := c.messageHandler
+	queues := []queueSpec{{ticksQueue, mh.processTick}, {accountInfoQueue, mh.processAccountInfo}}
+	for _, instrument := range instrumentList {
+		queues = append(queues, queueSpec{fmt.Sprintf("%s_Market_Data_Bars", instrument), mh.processBar})
+		queues = append(queues, queueSpec{fmt.Sprintf("%s_H-Bars", instrument), mh.processHistoricalBar})
+	}
+
+	log.Printf("Draining stale messages (older than %s) from %d queues for up to %s...", maxAge, len(queues), duration)
+
+	// Deadline uses wall time; staleness uses the injected clock
+	deadline := time.Now().Add(duration)
+	discarded, kept := 0, 0
+	for _, q := range queues {
+		for time.Now().Before(deadline) {
+			// Manual ack: processors ack fresh messages on this channel, stale ones are acked here
+			d, ok, err := ch.Get(q.name, false)
+			if err != nil {
+				log.Printf("Error getting message from queue %s: %s. Moving to next queue.", q.name, err)
+				break
+			}
+			if !ok {
+				break
+			}
+			var hdr struct {
+				ProducedAt int64 `json:"produced_at"`
+			}
+			if err := json.Unmarshal(d.Body, &hdr); err != nil || mh.clock.Now().UnixMilli()-hdr.ProducedAt > maxAge.Milliseconds() {
+				d.Ack(false)
+				discarded++
+				continue
+			}
+			q.process(d)
+			kept++
+		}
+	}
+
+	// Batched acks reference this channel; settle them before it closes
+	mh.FlushAcks()
+	log.Printf("Finished stale-aware drain. Discarded %d stale messages, processed %d fresh messages.", discarded, kept)
+	return nil
+}
+
 // DrainQueues consumes and discards all messages currently in the queues.
 // This is useful on startup to clear any backlog of stale data.
 func (c *Consumer) DrainQueues(duration time.Duration) error {
-	ch, err := c.conn.Channel()
+	ch, err := c.connection().Channel()
 	if err != nil {
 		return fmt.Errorf("failed to open a channel: %w", err)
 	}
@@ -216,6 +452,11 @@ func (c *Consumer) DrainQueues(duration time.Duration) error {
 	return nil
 }
 
+// SetClock injects the clock used for staleness checks (defaults to the real clock).
+func (c *Consumer) SetClock(clk clock.Clock) {
+	c.messageHandler.SetClock(clk)
+}
+
 // GetMessageHandler returns the message handler for external access
 func (c *Consumer) GetMessageHandler() *MessageHandler {
 	return c.messageHandler
@@ -223,10 +464,20 @@ func (c *Consumer) GetMessageHandler() *MessageHandler {
 
 // Close closes the consumer's connection and message handler.
 func (c *Consumer) Close() {
+	c.mu.Lock()
+	if c.stop != nil {
+		select {
+		case <-c.stop:
+		default:
+			close(c.stop)
+		}
+	}
+	conn := c.conn
+	c.mu.Unlock()
 	if c.messageHandler != nil {
 		c.messageHandler.Stop()
 	}
-	if c.conn != nil {
-		c.conn.Close()
+	if conn != nil {
+		conn.Close()
 	}
 }
@@ -0,0 +1,61 @@
+package amqp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// QueueDepth is the broker-side backlog of one queue.
+type QueueDepth struct {
+	Queue     string `json:"queue"`
+	Class     string `json:"class"` // ClassTick, ClassBar, ClassHistorical, ClassAccount, ClassCommand, "result"
+	Exists    bool   `json:"exists"`
+	Messages  int    `json:"messages"`  // ready (unacked deliveries are not counted)
+	Consumers int    `json:"consumers"`
+}
+
+// depthQueues lists the queues reported by QueueDepths, in display order.
+func depthQueues() []QueueDepth {
+	qs := []QueueDepth{{Queue: ticksQueue, Class: ClassTick}, {Queue: accountInfoQueue, Class: ClassAccount}}
+	for _, instrument := range instrumentList {
+		qs = append(qs, QueueDepth{Queue: fmt.Sprintf("%s_Market_Data_Bars", instrument), Class: ClassBar})
+	}
+	for _, instrument := range instrumentList {
+		qs = append(qs, QueueDepth{Queue: fmt.Sprintf("%s_H-Bars", instrument), Class: ClassHistorical})
+	}
+	return append(qs, QueueDepth{Queue: tradeCommandsQueue, Class: ClassCommand}, QueueDepth{Queue: tradeResultsQueue, Class: classResult})
+}
+
+// QueueDepths reads the message and consumer counts of the data, trade command and trade result queues.
+// What: Shows whether the consumer keeps up without opening the RabbitMQ management UI.
+// How: Passively declares each queue on a short-lived channel. A missing queue makes the broker
+//      close the channel with NOT_FOUND; it is reported with Exists=false and a new channel is opened.
+// Returns: one entry per queue, or an error if the broker cannot be queried.
+func (c *Consumer) QueueDepths() ([]QueueDepth, error) {
+	conn := c.connection()
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a channel for queue inspection: %w", err)
+	}
+	defer func() { ch.Close() }()
+
+	qs := depthQueues()
+	for i := range qs {
+		q, err := ch.QueueDeclarePassive(qs[i].Queue, true, false, false, false, nil)
+		if err == nil {
+			qs[i].Exists, qs[i].Messages, qs[i].Consumers = true, q.Messages, q.Consumers
+			continue
+		}
+		var amqpErr *amqp091.Error
+		if !errors.As(err, &amqpErr) || amqpErr.Code != amqp091.NotFound {
+			return nil, fmt.Errorf("failed to inspect queue '%s': %w", qs[i].Queue, err)
+		}
+		// The failed declare closed the channel
+		if ch, err = conn.Channel(); err != nil {
+			return nil, fmt.Errorf("failed to reopen channel after inspecting '%s': %w", qs[i].Queue, err)
+		}
+	}
+	return qs, nil
+}
@@ -0,0 +1,22 @@
+package amqp
+
+import "testing"
+
+func TestDepthQueuesCoverDataAndCommandQueues(t *testing.T) {
+	qs := depthQueues()
+	if want := 2 + 2*len(instrumentList) + 2; len(qs) != want {
+		t.Fatalf("got %d queues, want %d", len(qs), want)
+	}
+	seen := make(map[string]bool)
+	for _, q := range qs {
+		if seen[q.Queue] {
+			t.Errorf("duplicate queue %s", q.Queue)
+		}
+		seen[q.Queue] = true
+	}
+	for _, name := range []string{ticksQueue, accountInfoQueue, "EURUSD_Market_Data_Bars", "EURGBP_H-Bars", tradeCommandsQueue, tradeResultsQueue} {
+		if !seen[name] {
+			t.Errorf("missing queue %s", name)
+		}
+	}
+}
@@ -2,61 +2,213 @@ package amqp
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"sync"
 	"time"
 
+	"go-trader/internal/clock"
+	"go-trader/internal/logutil"
 	"go-trader/internal/state"
 
 	"github.com/rabbitmq/amqp091-go"
 )
 
+// defaultWarnThrottle is the minimum interval between repeated enqueue warnings of the same kind.
+const defaultWarnThrottle = 1 * time.Second
+
 // MessageHandler manages different types of message processing with dedicated goroutines
 type MessageHandler struct {
 	stateManager      *state.StateManager
 	tickChannel       chan amqp091.Delivery
 	barChannel        chan amqp091.Delivery
 	historicalChannel chan amqp091.Delivery
-	accountChannel    chan amqp091.Delivery
+	accountSlot       *latestSlot // only the newest account snapshot is kept
 	stopChannel       chan struct{}
 	wg                sync.WaitGroup
+	warnLog           *logutil.ThrottledLogger
+	clock             clock.Clock
+	ackers            map[string]*ackBatcher  // per message class
+	symbols           *SymbolNormalizer       // nil leaves instrument symbols unchanged
+	requeueAccount    bool                    // requeue an account message once on processing failure
+	bufferPolicies    map[string]BufferPolicy // what to do when a class's channel is full
+
+	// invalidBars counts malformed bars rejected per message class; invalidSamples keeps the newest
+	// rejected payloads per class, oldest first
+	invalidMu      sync.Mutex
+	invalidBars    map[string]int64
+	invalidSamples map[string][]InvalidBar
+	// unknownInstruments counts messages ignored per class for instruments outside the configured list
+	unknownInstruments map[string]int64
+	dynamicInstruments bool // store data for any instrument instead of ignoring unknown ones
+
+	// backfills stages full historical responses per "instrument|period" until they are complete
+	backfillMu sync.Mutex
+	backfills  map[string]*backfillBatch
+
+	// processors tracks processor goroutine liveness by name for the watchdog
+	procMu         sync.Mutex
+	processors     map[string]*processor
+	stallAfter     time.Duration // 0 disables the watchdog
+	restartStalled bool
+	onStall        func(ProcessorStatus)
+	onTradeResult  func(TradeResult)
 }
 
 // NewMessageHandler creates a new message handler with dedicated channels
 func NewMessageHandler(sm *state.StateManager) *MessageHandler {
 	return &MessageHandler{
-		stateManager:      sm,
-		tickChannel:       make(chan amqp091.Delivery, 1000), // Buffer for high-frequency ticks
-		barChannel:        make(chan amqp091.Delivery, 100),
-		historicalChannel: make(chan amqp091.Delivery, 500), // Buffer for bulk historical data
-		accountChannel:    make(chan amqp091.Delivery, 10),
-		stopChannel:       make(chan struct{}),
+		stateManager:       sm,
+		tickChannel:        make(chan amqp091.Delivery, 1000), // Buffer for high-frequency ticks
+		barChannel:         make(chan amqp091.Delivery, 100),
+		historicalChannel:  make(chan amqp091.Delivery, 500), // Buffer for bulk historical data
+		accountSlot:        newLatestSlot(),
+		stopChannel:        make(chan struct{}),
+		warnLog:            logutil.NewThrottledLogger(defaultWarnThrottle),
+		clock:              clock.Real(),
+		requeueAccount:     true,
+		invalidBars:        make(map[string]int64),
+		invalidSamples:     make(map[string][]InvalidBar),
+		unknownInstruments: make(map[string]int64),
+		backfills:          make(map[string]*backfillBatch),
+		bufferPolicies: map[string]BufferPolicy{
+			ClassTick:       {Mode: BufferDropNewest},
+			ClassBar:        {Mode: BufferDropNewest},
+			ClassHistorical: {Mode: BufferDropNewest},
+		},
+		processors: make(map[string]*processor),
+		ackers: map[string]*ackBatcher{
+			ClassTick:       newAckBatcher(),
+			ClassBar:        newAckBatcher(),
+			ClassHistorical: newAckBatcher(),
+			ClassAccount:    newAckBatcher(),
+		},
+	}
+}
+
+// ackFlushInterval bounds how long a partially filled ack batch may wait.
+const ackFlushInterval = 500 * time.Millisecond
+
+// SetAckBatchSize enables multiple-ack batching for a message class (ClassTick, ClassBar,
+// ClassHistorical, ClassAccount). size <= 1 restores individual acks. A batching class runs a single
+// processor (see processorCount). Call before Start and StartConsumers.
+func (mh *MessageHandler) SetAckBatchSize(class string, size int) {
+	if b, ok := mh.ackers[class]; ok {
+		b.setBatchSize(size)
+		return
+	}
+	log.Printf("Unknown message class for ack batching: %s", class)
+}
+
+// AckBatchSize returns the configured batch size for a message class (0 or 1 means individual acks).
+func (mh *MessageHandler) AckBatchSize(class string) int {
+	if b, ok := mh.ackers[class]; ok {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.batchSize
+	}
+	return 0
+}
+
+// processorCount returns n, or 1 when class batches acks: a multiple-ack settles every lower delivery
+// tag on the class's channel, which is only safe while a single processor handles them in order.
+func (mh *MessageHandler) processorCount(class string, n int) int {
+	if mh.AckBatchSize(class) > 1 {
+		return 1
+	}
+	return n
+}
+
+// FlushAcks acknowledges any pending batched deliveries.
+func (mh *MessageHandler) FlushAcks() {
+	for _, b := range mh.ackers {
+		b.flush()
+	}
+}
+
+// ackFlusher periodically flushes partially filled ack batches.
+func (mh *MessageHandler) ackFlusher() {
+	defer mh.wg.Done()
+	ticker := time.NewTicker(ackFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-mh.stopChannel:
+			mh.FlushAcks()
+			return
+		case <-ticker.C:
+			mh.FlushAcks()
+		}
 	}
 }
 
+// SetClock injects the clock used for staleness checks. Call before Start.
+func (mh *MessageHandler) SetClock(clk clock.Clock) {
+	if clk != nil {
+		mh.clock = clk
+	}
+}
+
+// SetSymbolNormalizer maps incoming instrument symbols to canonical keys for ticks, bars, and
+// positions. Call before StartConsumers.
+func (mh *MessageHandler) SetSymbolNormalizer(n *SymbolNormalizer) {
+	mh.symbols = n
+}
+
+// SetAccountRequeue controls whether an account message that fails processing is requeued once
+// (default) or dead-lettered immediately. Call before StartConsumers.
+func (mh *MessageHandler) SetAccountRequeue(enabled bool) {
+	mh.requeueAccount = enabled
+}
+
+// SetDynamicInstruments controls whether ticks and bars for instruments outside the symbol
+// normalizer's configured list are stored (true) or ignored and counted (default). Without a
+// normalizer every instrument is accepted. Call before StartConsumers.
+func (mh *MessageHandler) SetDynamicInstruments(enabled bool) {
+	mh.dynamicInstruments = enabled
+}
+
+// SetWarnThrottle sets the minimum interval between repeated "channel full" warnings (0 disables throttling).
+func (mh *MessageHandler) SetWarnThrottle(interval time.Duration) {
+	mh.warnLog.SetInterval(interval)
+}
+
 // Start launches all message processing goroutines
 func (mh *MessageHandler) Start() {
 	log.Println("Starting message handler with dedicated goroutines...")
 
 	// Start tick processing goroutine
-	mh.wg.Add(1)
-	go mh.tickProcessor()
+	mh.startProcessor("tick", ClassTick, chanBacklog(mh.tickChannel), 0, mh.tickProcessor)
 
 	// Start bar processing goroutines (multiple for load balancing)
-	for i := 0; i < 3; i++ {
-		mh.wg.Add(1)
-		go mh.barProcessor(i)
+	for i := 0; i < mh.processorCount(ClassBar, 3); i++ {
+		id := i
+		mh.startProcessor(fmt.Sprintf("bar-%d", id), ClassBar, chanBacklog(mh.barChannel), 0,
+			func(p *processor) { mh.barProcessor(p, id) })
 	}
 
 	// Start historical bar processing goroutines
-	for i := 0; i < 2; i++ {
-		mh.wg.Add(1)
-		go mh.historicalProcessor(i)
+	for i := 0; i < mh.processorCount(ClassHistorical, 2); i++ {
+		id := i
+		mh.startProcessor(fmt.Sprintf("historical-%d", id), ClassHistorical, chanBacklog(mh.historicalChannel), 0,
+			func(p *processor) { mh.historicalProcessor(p, id) })
 	}
 
 	// Start account info processing goroutine
+	mh.startProcessor("account", ClassAccount, mh.accountSlot.len, 0, mh.accountProcessor)
+
+	// Start periodic flushing of batched acks
 	mh.wg.Add(1)
-	go mh.accountProcessor()
+	go mh.ackFlusher()
+
+	// Start the stalled-processor watchdog when enabled
+	mh.procMu.Lock()
+	stallAfter := mh.stallAfter
+	mh.procMu.Unlock()
+	if stallAfter > 0 {
+		mh.wg.Add(1)
+		go mh.watchdog(stallAfter)
+	}
 
 	log.Println("All message processing goroutines started")
 }
@@ -69,69 +221,60 @@ func (mh *MessageHandler) Stop() {
 	log.Println("All message processing goroutines stopped")
 }
 
-// EnqueueTick sends a tick message to the tick processing channel
+// EnqueueTick sends a tick message to the tick processing channel (see SetBufferPolicy when full)
 func (mh *MessageHandler) EnqueueTick(delivery amqp091.Delivery) {
-	select {
-	case mh.tickChannel <- delivery:
-		// Successfully enqueued
-	case <-mh.stopChannel:
-		// Handler is stopping
-		return
-	default:
-		// Channel full, discard message to prevent blocking
-		log.Printf("WARNING: Tick channel full, discarding message for %s", delivery.RoutingKey)
-		delivery.Nack(false, false) // Don't requeue
-	}
+	mh.enqueue(ClassTick, "Tick", mh.tickChannel, delivery)
 }
 
-// EnqueueBar sends a bar message to the bar processing channel
+// EnqueueBar sends a bar message to the bar processing channel (see SetBufferPolicy when full)
 func (mh *MessageHandler) EnqueueBar(delivery amqp091.Delivery) {
-	select {
-	case mh.barChannel <- delivery:
-		// Successfully enqueued
-	case <-mh.stopChannel:
-		// Handler is stopping
-		return
-	default:
-		// Channel full, discard message to prevent blocking
-		log.Printf("WARNING: Bar channel full, discarding message for %s", delivery.RoutingKey)
-		delivery.Nack(false, false) // Don't requeue
-	}
+	mh.enqueue(ClassBar, "Bar", mh.barChannel, delivery)
 }
 
-// EnqueueHistorical sends a historical bar message to the historical processing channel
+// EnqueueHistorical sends a historical bar message to the historical processing channel (see SetBufferPolicy when full)
 func (mh *MessageHandler) EnqueueHistorical(delivery amqp091.Delivery) {
+	mh.enqueue(ClassHistorical, "Historical", mh.historicalChannel, delivery)
+}
+
+// EnqueueAccount hands an account info message to the account processor.
+// Account messages are full snapshots, so a newer message replaces one still waiting to be
+// processed (the replaced one is acked as superseded) instead of either being dropped.
+func (mh *MessageHandler) EnqueueAccount(delivery amqp091.Delivery) {
 	select {
-	case mh.historicalChannel <- delivery:
-		// Successfully enqueued
 	case <-mh.stopChannel:
-		// Handler is stopping
+		// Handler is stopping: return it to the queue for the next consumer
+		delivery.Nack(false, true)
 		return
 	default:
-		// Channel full, discard message to prevent blocking
-		log.Printf("WARNING: Historical channel full, discarding message for %s", delivery.RoutingKey)
-		delivery.Nack(false, false) // Don't requeue
+	}
+	if old, replaced, total := mh.accountSlot.put(delivery); replaced {
+		mh.ackers[ClassAccount].ack(old)
+		mh.warnLog.Printf("account_replaced", "Account message superseded before processing (%d total)", total)
 	}
 }
 
-// EnqueueAccount sends an account info message to the account processing channel
-func (mh *MessageHandler) EnqueueAccount(delivery amqp091.Delivery) {
-	select {
-	case mh.accountChannel <- delivery:
-		// Successfully enqueued
-	case <-mh.stopChannel:
-		// Handler is stopping
-		return
+// Process handles a delivery of the given class (ClassTick, ClassBar, ClassHistorical or
+// ClassAccount) synchronously on the caller's goroutine, bypassing the processor channels.
+// It is meant for replaying recorded messages in order; Start is not required.
+func (mh *MessageHandler) Process(class string, delivery amqp091.Delivery) error {
+	switch class {
+	case ClassTick:
+		mh.processTick(delivery)
+	case ClassBar:
+		mh.processBar(delivery)
+	case ClassHistorical:
+		mh.processHistoricalBar(delivery)
+	case ClassAccount:
+		mh.processAccountInfo(delivery)
 	default:
-		// Channel full, discard message to prevent blocking
-		log.Printf("WARNING: Account channel full, discarding message for %s", delivery.RoutingKey)
-		delivery.Nack(false, false) // Don't requeue
+		return fmt.Errorf("unknown message class %q", class)
 	}
+	return nil
 }
 
 // tickProcessor handles high-frequency tick messages
-func (mh *MessageHandler) tickProcessor() {
-	defer mh.wg.Done()
+func (mh *MessageHandler) tickProcessor(p *processor) {
+	defer mh.processorDone(p)
 	log.Println("Tick processor started")
 
 	ticker := time.NewTicker(time.Second * 5) // Log stats every 5 seconds
@@ -146,8 +289,12 @@ func (mh *MessageHandler) tickProcessor() {
 			return
 
 		case delivery := <-mh.tickChannel:
+			p.begin(mh.clock.Now())
 			mh.processTick(delivery)
 			processedTicks++
+			if !p.finish(mh.clock.Now()) {
+				return
+			}
 
 		case <-ticker.C:
 			log.Printf("Tick processor stats: %d ticks processed in last 5 seconds", processedTicks)
@@ -157,8 +304,8 @@ func (mh *MessageHandler) tickProcessor() {
 }
 
 // barProcessor handles live bar messages
-func (mh *MessageHandler) barProcessor(id int) {
-	defer mh.wg.Done()
+func (mh *MessageHandler) barProcessor(p *processor, id int) {
+	defer mh.processorDone(p)
 	log.Printf("Bar processor %d started", id)
 
 	ticker := time.NewTicker(time.Second * 10) // Log stats every 10 seconds
@@ -173,8 +320,12 @@ func (mh *MessageHandler) barProcessor(id int) {
 			return
 
 		case delivery := <-mh.barChannel:
+			p.begin(mh.clock.Now())
 			mh.processBar(delivery)
 			processedBars++
+			if !p.finish(mh.clock.Now()) {
+				return
+			}
 
 		case <-ticker.C:
 			log.Printf("Bar processor %d stats: %d bars processed in last 10 seconds", id, processedBars)
@@ -184,8 +335,8 @@ func (mh *MessageHandler) barProcessor(id int) {
 }
 
 // historicalProcessor handles bulk historical bar messages
-func (mh *MessageHandler) historicalProcessor(id int) {
-	defer mh.wg.Done()
+func (mh *MessageHandler) historicalProcessor(p *processor, id int) {
+	defer mh.processorDone(p)
 	log.Printf("Historical processor %d started", id)
 
 	ticker := time.NewTicker(time.Second * 30) // Log stats every 30 seconds
@@ -197,13 +348,19 @@ func (mh *MessageHandler) historicalProcessor(id int) {
 		select {
 		case <-mh.stopChannel:
 			log.Printf("Historical processor %d stopping. Total historical bars processed: %d", id, processedBars)
+			mh.flushBackfills(mh.clock.Now(), true)
 			return
 
 		case delivery := <-mh.historicalChannel:
+			p.begin(mh.clock.Now())
 			mh.processHistoricalBar(delivery)
 			processedBars++
+			if !p.finish(mh.clock.Now()) {
+				return
+			}
 
 		case <-ticker.C:
+			mh.flushBackfills(mh.clock.Now(), false)
 			log.Printf("Historical processor %d stats: %d bars processed in last 30 seconds", id, processedBars)
 			processedBars = 0
 		}
@@ -211,18 +368,29 @@ func (mh *MessageHandler) historicalProcessor(id int) {
 }
 
 // accountProcessor handles account and position messages
-func (mh *MessageHandler) accountProcessor() {
-	defer mh.wg.Done()
+func (mh *MessageHandler) accountProcessor(p *processor) {
+	defer mh.processorDone(p)
 	log.Println("Account processor started")
 
 	for {
 		select {
 		case <-mh.stopChannel:
+			if delivery, ok := mh.accountSlot.take(); ok {
+				delivery.Nack(false, true) // requeue the unprocessed snapshot
+			}
 			log.Println("Account processor stopping")
 			return
 
-		case delivery := <-mh.accountChannel:
+		case <-mh.accountSlot.ready:
+			delivery, ok := mh.accountSlot.take()
+			if !ok {
+				continue
+			}
+			p.begin(mh.clock.Now())
 			mh.processAccountInfo(delivery)
+			if !p.finish(mh.clock.Now()) {
+				return
+			}
 		}
 	}
 }
@@ -235,14 +403,19 @@ func (mh *MessageHandler) processTick(delivery amqp091.Delivery) {
 		delivery.Nack(false, false)
 		return
 	}
+	tick.Instrument = mh.symbols.Normalize(tick.Instrument)
+	if !mh.acceptInstrument(ClassTick, delivery, tick.Instrument) {
+		return
+	}
+	mh.warnZeroFields(ClassTick, tick.Instrument, tick.ZeroRequiredFields())
 
-	if isStale(tick.ProducedAt) {
-		delivery.Ack(false)
+	if mh.isStale(tick.ProducedAt) {
+		mh.ackers[ClassTick].ack(delivery)
 		return
 	}
 
 	mh.stateManager.UpdateTick(tick)
-	delivery.Ack(false)
+	mh.ackers[ClassTick].ack(delivery)
 }
 
 // processBar handles individual bar messages
@@ -253,15 +426,25 @@ func (mh *MessageHandler) processBar(delivery amqp091.Delivery) {
 		delivery.Nack(false, false)
 		return
 	}
+	bar.Instrument = mh.symbols.Normalize(bar.Instrument)
+	if !mh.acceptInstrument(ClassBar, delivery, bar.Instrument) {
+		return
+	}
+	mh.warnZeroFields(ClassBar, bar.Instrument, bar.ZeroRequiredFields())
+
+	if mh.isStale(bar.ProducedAt) {
+		mh.ackers[ClassBar].ack(delivery)
+		return
+	}
 
-	if isStale(bar.ProducedAt) {
-		delivery.Ack(false)
+	if err := state.ValidateBarSides(bar.Bid, bar.Ask); err != nil {
+		mh.rejectInvalidBar(ClassBar, delivery, bar.Instrument, bar.Period, err)
 		return
 	}
 
 	log.Printf("Processing live bar for %s, period: %s", bar.Instrument, bar.Period)
 	mh.stateManager.UpdateLiveBar(bar)
-	delivery.Ack(false)
+	mh.ackers[ClassBar].ack(delivery)
 }
 
 // processHistoricalBar handles historical bar messages
@@ -272,29 +455,159 @@ func (mh *MessageHandler) processHistoricalBar(delivery amqp091.Delivery) {
 		delivery.Nack(false, false)
 		return
 	}
+	bar.Instrument = mh.symbols.Normalize(bar.Instrument)
+	if !mh.acceptInstrument(ClassHistorical, delivery, bar.Instrument) {
+		return
+	}
+	mh.warnZeroFields(ClassHistorical, bar.Instrument, bar.ZeroRequiredFields())
 
+	if err := state.ValidateBarSides(bar.Bid, bar.Ask); err != nil {
+		mh.rejectInvalidBar(ClassHistorical, delivery, bar.Instrument, bar.Period, err)
+		return
+	}
 
 	log.Printf("Processing historical bar for %s, period: %s, sequence: %d", bar.Instrument, bar.Period, bar.Sequence)
-	mh.stateManager.UpdateHistoricalBar(bar)
-	delivery.Ack(false)
+	if !mh.stageBackfillBar(bar) {
+		mh.stateManager.UpdateHistoricalBar(bar)
+	}
+	mh.ackers[ClassHistorical].ack(delivery)
+}
+
+// warnZeroFields logs required fields that decoded as zero, which usually means the broker's JSON
+// keys drifted from the expected names (see state.SetFieldAliases).
+func (mh *MessageHandler) warnZeroFields(class, instrument string, zero []string) {
+	if len(zero) == 0 {
+		return
+	}
+	mh.warnLog.Printf("zero_fields_"+class, "WARNING: %s for %q decoded with zero required fields %v; check the broker's JSON keys (GOTRADER_FIELD_ALIASES)", class, instrument, zero)
+}
+
+// maxInvalidBarSamples bounds the rejected payloads kept per message class.
+const maxInvalidBarSamples = 10
+
+// InvalidBar is a rejected bar kept for inspection (see InvalidBarSamples).
+type InvalidBar struct {
+	RejectedAt int64  `json:"rejectedAt"` // unix ms
+	Instrument string `json:"instrument"`
+	Period     string `json:"period"`
+	Reason     string `json:"reason"`
+	Payload    string `json:"payload"`
+}
+
+// rejectInvalidBar counts a malformed bar, keeps and logs its payload, and discards it.
+// The data queues have no dead-letter exchange, so the Nack drops the message for good; the kept
+// payload is the only copy left for diagnosing the broker's output.
+func (mh *MessageHandler) rejectInvalidBar(class string, delivery amqp091.Delivery, instrument, period string, err error) {
+	mh.invalidMu.Lock()
+	mh.invalidBars[class]++
+	total := mh.invalidBars[class]
+	samples := append(mh.invalidSamples[class], InvalidBar{RejectedAt: mh.clock.Now().UnixMilli(),
+		Instrument: instrument, Period: period, Reason: err.Error(), Payload: string(delivery.Body)})
+	if len(samples) > maxInvalidBarSamples {
+		samples = samples[len(samples)-maxInvalidBarSamples:]
+	}
+	mh.invalidSamples[class] = samples
+	mh.invalidMu.Unlock()
+	mh.warnLog.Printf("invalid_"+class, "WARNING: Rejecting malformed %s bar for %s %s: %v (total rejected: %d): %s", class, instrument, period, err, total, delivery.Body)
+	delivery.Nack(false, false)
+}
+
+// InvalidBarCounts returns the number of malformed bars rejected per message class.
+func (mh *MessageHandler) InvalidBarCounts() map[string]int64 {
+	mh.invalidMu.Lock()
+	defer mh.invalidMu.Unlock()
+	out := make(map[string]int64, len(mh.invalidBars))
+	for k, v := range mh.invalidBars {
+		out[k] = v
+	}
+	return out
+}
+
+// InvalidBarSamples returns the newest rejected bars per message class, oldest first.
+func (mh *MessageHandler) InvalidBarSamples() map[string][]InvalidBar {
+	mh.invalidMu.Lock()
+	defer mh.invalidMu.Unlock()
+	out := make(map[string][]InvalidBar, len(mh.invalidSamples))
+	for k, v := range mh.invalidSamples {
+		out[k] = append([]InvalidBar(nil), v...)
+	}
+	return out
+}
+
+// acceptInstrument reports whether data for instrument should be stored. Messages for instruments
+// outside the configured list are acked, counted, and dropped unless dynamic instruments are
+// enabled, so stray symbols cannot grow the state maps without bound.
+func (mh *MessageHandler) acceptInstrument(class string, delivery amqp091.Delivery, instrument string) bool {
+	if mh.dynamicInstruments || mh.symbols.Known(instrument) {
+		return true
+	}
+	mh.invalidMu.Lock()
+	mh.unknownInstruments[class]++
+	total := mh.unknownInstruments[class]
+	mh.invalidMu.Unlock()
+	mh.warnLog.Printf("unknown_"+class, "WARNING: Ignoring %s for unconfigured instrument %q (total ignored: %d)", class, instrument, total)
+	mh.ackers[class].ack(delivery)
+	return false
+}
+
+// UnknownInstrumentCounts returns the number of messages ignored per class for unconfigured instruments.
+func (mh *MessageHandler) UnknownInstrumentCounts() map[string]int64 {
+	mh.invalidMu.Lock()
+	defer mh.invalidMu.Unlock()
+	out := make(map[string]int64, len(mh.unknownInstruments))
+	for k, v := range mh.unknownInstruments {
+		out[k] = v
+	}
+	return out
 }
 
 // processAccountInfo handles account and position messages
 func (mh *MessageHandler) processAccountInfo(delivery amqp091.Delivery) {
 	var info state.AccountInfo
 	if err := json.Unmarshal(delivery.Body, &info); err != nil {
-		log.Printf("Error unmarshalling account info: %s", err)
-		delivery.Nack(false, false)
+		// Requeue once in case the failure is transient; a redelivered message that still
+		// fails is dead-lettered so a poison message cannot loop.
+		requeue := mh.requeueAccount && !delivery.Redelivered
+		log.Printf("Error unmarshalling account info (requeue=%t): %s", requeue, err)
+		delivery.Nack(false, requeue)
 		return
 	}
+	for i := range info.Positions {
+		info.Positions[i].Instrument = mh.symbols.Normalize(info.Positions[i].Instrument)
+	}
+	var dups int
+	if info.Positions, dups = dedupPositions(info.Positions); dups > 0 {
+		mh.warnLog.Printf("account_duplicates", "Account snapshot had %d duplicate position IDs; kept the latest of each", dups)
+	}
 
-	if isStale(info.ProducedAt) {
-		delivery.Ack(false)
+	if mh.isStale(info.ProducedAt) {
+		mh.ackers[ClassAccount].ack(delivery)
 		return
 	}
 
 	log.Printf("Processing account info - Balance: %.2f, Equity: %.2f, Positions: %d",
 		info.Account.Balance, info.Account.Equity, len(info.Positions))
-	mh.stateManager.UpdateAccountInfo(info)
-	delivery.Ack(false)
+	if !mh.stateManager.UpdateAccountInfo(info) {
+		mh.warnLog.Printf("account_out_of_order", "Ignoring account snapshot produced at %d: older than the stored one", info.ProducedAt)
+	}
+	mh.ackers[ClassAccount].ack(delivery)
+}
+
+// dedupPositions drops repeated OrderIDs from an account snapshot, keeping the last entry of each
+// (a snapshot taken mid-update can list a position twice). Positions without an OrderID are kept.
+// Returns the positions in their original order and the number dropped.
+func dedupPositions(positions []state.Position) ([]state.Position, int) {
+	last := make(map[string]int, len(positions))
+	for i, p := range positions {
+		if p.OrderID != "" {
+			last[p.OrderID] = i
+		}
+	}
+	out := positions[:0:0]
+	for i, p := range positions {
+		if p.OrderID == "" || last[p.OrderID] == i {
+			out = append(out, p)
+		}
+	}
+	return out, len(positions) - len(out)
 }
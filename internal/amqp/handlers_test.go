@@ -0,0 +1,201 @@
+package amqp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go-trader/internal/clock"
+	"go-trader/internal/state"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestIsStaleUsesInjectedClock(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	fc := clock.NewFake(start)
+	mh := NewMessageHandler(state.NewStateManager())
+	mh.SetClock(fc)
+
+	producedAt := start.UnixMilli()
+	if mh.isStale(producedAt) {
+		t.Fatal("message produced now should not be stale")
+	}
+	fc.Advance(staleMessageThreshold)
+	if mh.isStale(producedAt) {
+		t.Fatal("message exactly at the threshold should not be stale")
+	}
+	fc.Advance(time.Millisecond)
+	if !mh.isStale(producedAt) {
+		t.Fatal("message older than the threshold should be stale")
+	}
+}
+
+func TestDedupPositionsKeepsLatest(t *testing.T) {
+	in := []state.Position{{OrderID: "a", PnL: 1}, {OrderID: "b"}, {OrderID: ""}, {OrderID: "a", PnL: 2}, {OrderID: ""}}
+	out, dropped := dedupPositions(in)
+	if dropped != 1 || len(out) != 4 {
+		t.Fatalf("got %d positions (%d dropped), want 4 (1 dropped)", len(out), dropped)
+	}
+	if out[0].OrderID != "b" || out[2].OrderID != "a" || out[2].PnL != 2 {
+		t.Fatalf("got %+v, want the later entry of a kept in order", out)
+	}
+}
+
+func TestOutOfOrderAccountSnapshotIgnored(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	sm := state.NewStateManager()
+	mh := NewMessageHandler(sm)
+	mh.SetClock(clock.NewFake(start))
+	ack := &recordingAck{}
+	send := func(tag uint64, producedAt int64, balance float64) {
+		body := fmt.Sprintf(`{"produced_at":%d,"account":{"balance":%g},"positions":[{"orderId":"1","state":"FILLED"},{"orderId":"1","state":"FILLED"}]}`, producedAt, balance)
+		mh.processAccountInfo(amqp091.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: []byte(body)})
+	}
+
+	send(1, start.UnixMilli(), 100)
+	send(2, start.UnixMilli()-500, 50) // delivered late
+	got := sm.GetAccountInfo()
+	if got.Account.Balance != 100 {
+		t.Fatalf("balance = %v, want the newer snapshot's 100", got.Account.Balance)
+	}
+	if len(got.Positions) != 1 {
+		t.Fatalf("positions = %+v, want duplicates dropped", got.Positions)
+	}
+	send(3, start.UnixMilli()+500, 200)
+	if got := sm.GetAccountInfo(); got.Account.Balance != 200 {
+		t.Fatalf("balance = %v, want 200", got.Account.Balance)
+	}
+	if len(ack.acked) != 3 {
+		t.Fatalf("acked %v, want all three messages acked", ack.acked)
+	}
+}
+
+func TestUnknownInstrumentsIgnoredUnlessDynamic(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	sm := state.NewStateManager()
+	mh := NewMessageHandler(sm)
+	mh.SetClock(clock.NewFake(start))
+	mh.SetSymbolNormalizer(NewSymbolNormalizer([]string{"EURUSD"}, nil))
+	ack := &recordingAck{}
+	tick := func(tag uint64, instrument string) {
+		body := fmt.Sprintf(`{"produced_at":%d,"instrument":%q,"bid":1.1,"ask":1.2}`, start.UnixMilli(), instrument)
+		mh.processTick(amqp091.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: []byte(body)})
+	}
+
+	tick(1, "EUR/USD")
+	tick(2, "XAUUSD")
+	if len(sm.GetTicks("EURUSD")) != 1 || len(sm.GetTicks("XAUUSD")) != 0 {
+		t.Fatal("only the configured instrument should be stored")
+	}
+	if got := mh.UnknownInstrumentCounts()[ClassTick]; got != 1 {
+		t.Fatalf("ignored ticks = %d, want 1", got)
+	}
+	if len(ack.acked) != 2 || len(ack.nacked) != 0 {
+		t.Fatalf("acked %v nacked %v, want both acked", ack.acked, ack.nacked)
+	}
+
+	mh.SetDynamicInstruments(true)
+	tick(3, "XAUUSD")
+	if len(sm.GetTicks("XAUUSD")) != 1 {
+		t.Fatal("dynamic instruments should store unknown symbols")
+	}
+}
+
+func TestFullBackfillReplacesBufferAtOnce(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	fc := clock.NewFake(start)
+	sm := state.NewStateManager(state.StateManagerConfig{BarBufferSizes: map[string]int{"FIFTEEN_MINS": 1000}})
+	mh := NewMessageHandler(sm)
+	mh.SetClock(fc)
+	fullBackfillBars := sm.BarCapacity("ONE_MIN")
+	bar := func(seq int) state.HistoricalBar {
+		return state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: int64(fullBackfillBars-seq+1) * 60_000, Sequence: seq}
+	}
+	sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 30_000})
+
+	for seq := fullBackfillBars; seq > 1; seq-- {
+		if !mh.stageBackfillBar(bar(seq)) {
+			t.Fatalf("bar %d of a full backfill should be staged", seq)
+		}
+	}
+	if n := len(sm.GetHistoricalBars("EURUSD", "ONE_MIN")); n != 1 {
+		t.Fatalf("buffer has %d bars mid-backfill, want the old 1", n)
+	}
+	mh.stageBackfillBar(bar(1))
+	bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN")
+	if len(bars) != fullBackfillBars || bars[0].Sequence != 1 {
+		t.Fatalf("buffer has %d bars (newest seq %d), want the full backfill", len(bars), bars[0].Sequence)
+	}
+
+	// Gap fills merge directly; an incomplete backfill is merged once it goes quiet
+	if mh.stageBackfillBar(state.HistoricalBar{Instrument: "EURUSD", Period: "FIVE_MINS", BarEndTimestamp: 300_000, Sequence: 3}) {
+		t.Fatal("a small response should not be staged")
+	}
+	mh.stageBackfillBar(state.HistoricalBar{Instrument: "EURUSD", Period: "TEN_MINS", BarEndTimestamp: 600_000, Sequence: fullBackfillBars})
+	fc.Advance(backfillQuiet + time.Second)
+	mh.flushBackfills(fc.Now(), false)
+	if n := len(sm.GetHistoricalBars("EURUSD", "TEN_MINS")); n != 1 {
+		t.Fatalf("incomplete backfill merged %d bars, want 1", n)
+	}
+
+	// A response smaller than a larger buffer would drop its older bars, so it is merged directly
+	if mh.stageBackfillBar(state.HistoricalBar{Instrument: "EURUSD", Period: "FIFTEEN_MINS", BarEndTimestamp: 900_000, Sequence: fullBackfillBars}) {
+		t.Fatal("a response smaller than the FIFTEEN_MINS capacity should not be staged")
+	}
+}
+
+func TestTradeResultsReachHandler(t *testing.T) {
+	mh := NewMessageHandler(state.NewStateManager())
+	var got []TradeResult
+	mh.SetTradeResultHandler(func(r TradeResult) { got = append(got, r) })
+	ack := &recordingAck{}
+	send := func(tag uint64, body string) {
+		mh.processTradeResult(amqp091.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: []byte(body)})
+	}
+
+	send(1, `{"produced_at":1,"label":"EURUSD_buy_1","command":"SUBMIT_ORDER","status":"rejected","reason":"Not enough margin"}`)
+	send(2, `{"label":"EURUSD_buy_2"}`) // no status
+	send(3, `not json`)
+	if len(got) != 1 || got[0].Status != ResultRejected || got[0].Reason != "Not enough margin" {
+		t.Fatalf("got %+v, want the rejected result with status upper-cased", got)
+	}
+	if len(ack.acked) != 1 || len(ack.nacked) != 2 || ack.requeue[0] || ack.requeue[1] {
+		t.Fatalf("acked %v nacked %v (requeue %v), want invalid results discarded", ack.acked, ack.nacked, ack.requeue)
+	}
+}
+
+func TestInvalidBarsKeptForInspection(t *testing.T) {
+	mh := NewMessageHandler(state.NewStateManager())
+	mh.SetClock(clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)))
+	ack := &recordingAck{}
+	// ask high below its low
+	body := `{"instrument":"EURUSD","period":"ONE_MIN","bid":{"o":1.1,"h":1.2,"l":1.0,"c":1.1},"ask":{"o":1.1,"h":1.0,"l":1.2,"c":1.1}}`
+	for tag := uint64(1); tag <= maxInvalidBarSamples+2; tag++ {
+		mh.processHistoricalBar(amqp091.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: []byte(body)})
+	}
+	if got := mh.InvalidBarCounts()[ClassHistorical]; got != maxInvalidBarSamples+2 {
+		t.Fatalf("rejected %d bars, want %d", got, maxInvalidBarSamples+2)
+	}
+	samples := mh.InvalidBarSamples()[ClassHistorical]
+	if len(samples) != maxInvalidBarSamples || samples[0].Payload != body || samples[0].Instrument != "EURUSD" || samples[0].Reason == "" {
+		t.Fatalf("kept %d samples (first %+v), want the newest %d with their payloads", len(samples), samples[0], maxInvalidBarSamples)
+	}
+	if len(ack.nacked) != maxInvalidBarSamples+2 || ack.requeue[0] {
+		t.Fatalf("nacked %v (requeue %v), want every invalid bar discarded", ack.nacked, ack.requeue)
+	}
+}
+
+func TestAckBatchingRunsSingleProcessor(t *testing.T) {
+	mh := NewMessageHandler(state.NewStateManager())
+	mh.SetAckBatchSize(ClassHistorical, 100)
+	mh.Start()
+	defer mh.Stop()
+	counts := make(map[string]int)
+	for _, p := range mh.ProcessorStatuses() {
+		counts[p.Class]++
+	}
+	if counts[ClassHistorical] != 1 || counts[ClassBar] != 3 {
+		t.Fatalf("processors per class %v, want one for batched historical bars and three for bars", counts)
+	}
+}
@@ -0,0 +1,113 @@
+package amqp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HistoricalRequest is a request to the JForex HistoricalBarRequester strategies.
+// What: Single definition of the <INSTRUMENT>_H-Requests wire format.
+// How: Encode produces the plain-text "key:value,key:value" form the Java parser understands
+//      (no braces or quotes; it strips those naively and splits on ',' and ':'). EncodeJSON gives
+//      the same fields as JSON for consumers that can parse it. DecodeHistoricalRequest reads either.
+// Params: Instrument e.g. "EURUSD" (required), BarsCount number of most recent bars, and optionally
+//         Period (e.g. "ONE_MIN"; empty means all periods) and a FromMs/ToMs range in unix millis.
+//         A request needs BarsCount > 0 or a range; requesters that predate ranges ignore the range keys.
+type HistoricalRequest struct {
+	Instrument string `json:"instrument"`
+	BarsCount  int    `json:"barsCount,omitempty"`
+	Period     string `json:"period,omitempty"`
+	FromMs     int64  `json:"fromMs,omitempty"`
+	ToMs       int64  `json:"toMs,omitempty"`
+}
+
+// IsRange reports whether the request asks for a time range rather than the last N bars.
+func (r HistoricalRequest) IsRange() bool { return r.FromMs > 0 || r.ToMs > 0 }
+
+// Validate checks the request has the fields the requester needs.
+func (r HistoricalRequest) Validate() error {
+	if r.Instrument == "" {
+		return fmt.Errorf("historical request: instrument is required")
+	}
+	if r.BarsCount < 0 {
+		return fmt.Errorf("historical request: barsCount must not be negative, got %d", r.BarsCount)
+	}
+	if r.IsRange() {
+		if r.FromMs <= 0 || r.ToMs <= 0 || r.FromMs >= r.ToMs {
+			return fmt.Errorf("historical request: invalid range fromMs=%d toMs=%d", r.FromMs, r.ToMs)
+		}
+		return nil
+	}
+	if r.BarsCount == 0 {
+		return fmt.Errorf("historical request: barsCount or a fromMs/toMs range is required")
+	}
+	return nil
+}
+
+// Encode returns the plain-text payload, e.g. "instrument:EURUSD,barsCount:200".
+// Optional keys (period, fromMs, toMs) are appended only when set.
+func (r HistoricalRequest) Encode() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "instrument:%s", r.Instrument)
+	if r.BarsCount > 0 {
+		fmt.Fprintf(&b, ",barsCount:%d", r.BarsCount)
+	}
+	if r.Period != "" {
+		fmt.Fprintf(&b, ",period:%s", r.Period)
+	}
+	if r.IsRange() {
+		fmt.Fprintf(&b, ",fromMs:%d,toMs:%d", r.FromMs, r.ToMs)
+	}
+	return b.String()
+}
+
+// EncodeJSON returns the JSON form of the request.
+func (r HistoricalRequest) EncodeJSON() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+// DecodeHistoricalRequest parses a request body in either the plain-text or the JSON form.
+// Unknown keys are ignored so older decoders keep working as fields are added.
+func DecodeHistoricalRequest(body []byte) (HistoricalRequest, error) {
+	var r HistoricalRequest
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &r); err != nil {
+			return r, fmt.Errorf("historical request: %w", err)
+		}
+		return r, r.Validate()
+	}
+	for _, pair := range strings.Split(string(trimmed), ",") {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 {
+			return r, fmt.Errorf("historical request: malformed pair %q", pair)
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch key {
+		case "instrument":
+			r.Instrument = val
+		case "barsCount":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return r, fmt.Errorf("historical request: barsCount %q: %w", val, err)
+			}
+			r.BarsCount = n
+		case "period":
+			r.Period = val
+		case "fromMs", "toMs":
+			n, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return r, fmt.Errorf("historical request: %s %q: %w", key, val, err)
+			}
+			if key == "fromMs" {
+				r.FromMs = n
+			} else {
+				r.ToMs = n
+			}
+		}
+	}
+	return r, r.Validate()
+}
@@ -0,0 +1,58 @@
+package amqp
+
+import "testing"
+
+func TestHistoricalRequestEncodeMatchesWireFormat(t *testing.T) {
+	r := HistoricalRequest{Instrument: "EURUSD", BarsCount: 200}
+	if got, want := r.Encode(), "instrument:EURUSD,barsCount:200"; got != want {
+		t.Fatalf("Encode() = %q, want %q", got, want)
+	}
+}
+
+func TestHistoricalRequestRoundTrip(t *testing.T) {
+	r := HistoricalRequest{Instrument: "USDJPY", BarsCount: 50}
+	got, err := DecodeHistoricalRequest([]byte(r.Encode()))
+	if err != nil || got != r {
+		t.Fatalf("plain round trip = %+v, %v; want %+v", got, err, r)
+	}
+	js, err := r.EncodeJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = DecodeHistoricalRequest(js)
+	if err != nil || got != r {
+		t.Fatalf("json round trip = %+v, %v; want %+v", got, err, r)
+	}
+}
+
+func TestDecodeHistoricalRequestErrors(t *testing.T) {
+	for _, body := range []string{"", "instrument:EURUSD", "instrument:EURUSD,barsCount:x", "barsCount:10", "garbage", `{"instrument":`} {
+		if _, err := DecodeHistoricalRequest([]byte(body)); err == nil {
+			t.Errorf("expected error for %q", body)
+		}
+	}
+	// Unknown keys are ignored
+	if _, err := DecodeHistoricalRequest([]byte("instrument:EURUSD,barsCount:10,extra:1")); err != nil {
+		t.Errorf("unexpected error for unknown key: %v", err)
+	}
+}
+
+func TestHistoricalRequestRange(t *testing.T) {
+	r := HistoricalRequest{Instrument: "EURUSD", Period: "ONE_MIN", FromMs: 1000, ToMs: 2000}
+	if got, want := r.Encode(), "instrument:EURUSD,period:ONE_MIN,fromMs:1000,toMs:2000"; got != want {
+		t.Fatalf("Encode() = %q, want %q", got, want)
+	}
+	got, err := DecodeHistoricalRequest([]byte(r.Encode()))
+	if err != nil || got != r {
+		t.Fatalf("round trip = %+v, %v; want %+v", got, err, r)
+	}
+	for _, bad := range []HistoricalRequest{
+		{Instrument: "EURUSD", FromMs: 2000, ToMs: 1000},
+		{Instrument: "EURUSD", FromMs: 1000},
+		{Instrument: "EURUSD", ToMs: 1000},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("expected invalid range for %+v", bad)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package amqp
+
+import (
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// latestSlot is a single-slot "latest wins" buffer of deliveries.
+// What: Account info is a full snapshot, so only the newest unprocessed message matters; queueing
+//       older ones delays the dashboard and dropping the newest when a queue is full leaves it stale.
+// How: put stores the delivery and hands back the one it replaced (for the caller to ack as
+//      superseded); ready is signalled whenever the slot fills so a processor can take it.
+type latestSlot struct {
+	mu       sync.Mutex
+	pending  *amqp091.Delivery
+	replaced int64
+	ready    chan struct{} // capacity 1
+}
+
+func newLatestSlot() *latestSlot {
+	return &latestSlot{ready: make(chan struct{}, 1)}
+}
+
+// put stores d as the newest delivery. It returns the replaced delivery, if any, and the total
+// number of replacements so far.
+func (s *latestSlot) put(d amqp091.Delivery) (old amqp091.Delivery, replaced bool, total int64) {
+	s.mu.Lock()
+	if s.pending != nil {
+		old, replaced = *s.pending, true
+		s.replaced++
+	}
+	s.pending = &d
+	total = s.replaced
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default: // already signalled
+	}
+	return old, replaced, total
+}
+
+// take removes and returns the pending delivery.
+func (s *latestSlot) take() (amqp091.Delivery, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		return amqp091.Delivery{}, false
+	}
+	d := *s.pending
+	s.pending = nil
+	return d, true
+}
+
+// len returns 1 when a delivery is waiting, else 0.
+func (s *latestSlot) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending != nil {
+		return 1
+	}
+	return 0
+}
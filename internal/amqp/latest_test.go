@@ -0,0 +1,86 @@
+package amqp
+
+import (
+	"sync"
+	"testing"
+
+	"go-trader/internal/state"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// recordingAck records acks and nacks by delivery tag.
+type recordingAck struct {
+	mu      sync.Mutex
+	acked   []uint64
+	nacked  []uint64
+	requeue []bool
+}
+
+func (r *recordingAck) Ack(tag uint64, multiple bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.acked = append(r.acked, tag)
+	return nil
+}
+
+func (r *recordingAck) Nack(tag uint64, multiple, requeue bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nacked = append(r.nacked, tag)
+	r.requeue = append(r.requeue, requeue)
+	return nil
+}
+
+func (r *recordingAck) Reject(tag uint64, requeue bool) error { return r.Nack(tag, false, requeue) }
+
+func TestLatestSlotKeepsNewest(t *testing.T) {
+	s := newLatestSlot()
+	if _, replaced, _ := s.put(amqp091.Delivery{DeliveryTag: 1}); replaced {
+		t.Fatal("empty slot reported a replacement")
+	}
+	old, replaced, total := s.put(amqp091.Delivery{DeliveryTag: 2})
+	if !replaced || old.DeliveryTag != 1 || total != 1 {
+		t.Fatalf("replace: old=%d replaced=%t total=%d", old.DeliveryTag, replaced, total)
+	}
+	if s.len() != 1 {
+		t.Fatalf("len = %d", s.len())
+	}
+	d, ok := s.take()
+	if !ok || d.DeliveryTag != 2 {
+		t.Fatalf("take = %d, %t", d.DeliveryTag, ok)
+	}
+	if _, ok := s.take(); ok || s.len() != 0 {
+		t.Fatal("slot not empty after take")
+	}
+}
+
+func TestEnqueueAccountAcksSupersededMessage(t *testing.T) {
+	mh := NewMessageHandler(state.NewStateManager())
+	ack := &recordingAck{}
+	for tag := uint64(1); tag <= 3; tag++ {
+		mh.EnqueueAccount(amqp091.Delivery{Acknowledger: ack, DeliveryTag: tag})
+	}
+	if len(ack.acked) != 2 || ack.acked[0] != 1 || ack.acked[1] != 2 || len(ack.nacked) != 0 {
+		t.Fatalf("acked %v nacked %v, want the two superseded messages acked", ack.acked, ack.nacked)
+	}
+	if d, ok := mh.accountSlot.take(); !ok || d.DeliveryTag != 3 {
+		t.Fatalf("pending = %d, %t; want the newest", d.DeliveryTag, ok)
+	}
+}
+
+func TestAccountParseFailureRequeuesOnce(t *testing.T) {
+	mh := NewMessageHandler(state.NewStateManager())
+	ack := &recordingAck{}
+	mh.processAccountInfo(amqp091.Delivery{Acknowledger: ack, DeliveryTag: 1, Body: []byte("{")})
+	mh.processAccountInfo(amqp091.Delivery{Acknowledger: ack, DeliveryTag: 2, Body: []byte("{"), Redelivered: true})
+	if len(ack.requeue) != 2 || !ack.requeue[0] || ack.requeue[1] {
+		t.Fatalf("requeue flags %v, want [true false]", ack.requeue)
+	}
+
+	mh.SetAccountRequeue(false)
+	mh.processAccountInfo(amqp091.Delivery{Acknowledger: ack, DeliveryTag: 3, Body: []byte("{")})
+	if ack.requeue[2] {
+		t.Fatal("requeued with requeue disabled")
+	}
+}
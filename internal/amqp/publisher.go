@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/rabbitmq/amqp091-go"
@@ -23,6 +24,12 @@ var instrumentList = []string{
 
 // Publisher handles sending messages to RabbitMQ.
 type Publisher struct {
+	uri    string
+	limits map[string]QueueLimits
+	stop   chan struct{} // closed by Close; stops the connection supervisor
+
+	mu sync.Mutex
+	// conn and channel are replaced when the connection supervisor re-dials (see reconnect.go)
 	conn    *amqp091.Connection
 	channel *amqp091.Channel
 }
@@ -30,62 +37,92 @@ type Publisher struct {
 // NewPublisher creates and connects a new Publisher.
 // It will attempt to connect to RabbitMQ with retries.
 func NewPublisher(amqpURI string) (*Publisher, error) {
-	var conn *amqp091.Connection
-	var err error
-
-	// Retry connection for a few seconds
-	for i := 0; i < 10; i++ {
-		conn, err = amqp091.Dial(amqpURI)
-		if err == nil {
-			break
-		}
-		fmt.Printf("RabbitMQ publisher connection attempt %d failed: %s\n", i+1, err)
-		time.Sleep(2 * time.Second)
-	}
+	return NewPublisherWithLimits(amqpURI, nil)
+}
+
+// NewPublisherWithLimits is NewPublisher with queue limits keyed by class
+// (ClassRequest for <INSTRUMENT>_H-Requests, ClassCommand for Trade_Commands); see ParseQueueLimits.
+func NewPublisherWithLimits(amqpURI string, limits map[string]QueueLimits) (*Publisher, error) {
+	conn, err := dialWithRetry(amqpURI, "publisher", nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ after 10 attempts: %w", err)
+		return nil, err
+	}
+	p := &Publisher{uri: amqpURI, limits: limits, stop: make(chan struct{})}
+	closed := conn.NotifyClose(make(chan *amqp091.Error, 1))
+	if err := p.open(conn); err != nil {
+		conn.Close()
+		return nil, err
 	}
+	go p.supervise(closed)
+	return p, nil
+}
 
+// open opens the publishing channel on conn, declares the queues and makes conn current.
+func (p *Publisher) open(conn *amqp091.Connection) error {
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open a channel: %w", err)
+		return fmt.Errorf("failed to open a channel: %w", err)
 	}
 
 	// Enable publisher confirms for reliability
 	err = ch.Confirm(false)
 	if err != nil {
-		fmt.Printf("Warning: Failed to enable publisher confirms: %s\n", err)
+		log.Printf("Warning: Failed to enable publisher confirms: %s", err)
 	}
 
-	// Declare queues to ensure they exist
+	// Declare queues to ensure they exist, with TTL/max-length limits when configured
+	queues := map[string]QueueLimits{tradeCommandsQueue: p.limits[ClassCommand]}
 	for _, instrument := range instrumentList {
-		queueName := fmt.Sprintf("%s_H-Requests", instrument)
-		_, err = ch.QueueDeclare(
-			queueName,
-			true,  // durable
-			false, // delete when unused
-			false, // exclusive
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to declare queue '%s': %w", queueName, err)
-		}
+		queues[fmt.Sprintf("%s_H-Requests", instrument)] = p.limits[ClassRequest]
+	}
+	if err := declareQueues(conn, queues); err != nil {
+		return err
 	}
 
-	_, err = ch.QueueDeclare(
-		tradeCommandsQueue,
-		true,  // durable
-		false, // delete when unused
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.stop:
+		return errStopped
+	default:
+	}
+	p.conn, p.channel = conn, ch
+	return nil
+}
+
+// supervise re-dials after the connection closes with an error (see reconnect.go).
+func (p *Publisher) supervise(closed <-chan *amqp091.Error) {
+	superviseConnection("publisher", closed, p.stop, p.redial)
+}
+
+// redial connects again and reopens the publishing channel.
+func (p *Publisher) redial() (<-chan *amqp091.Error, error) {
+	conn, err := dialWithRetry(p.uri, "publisher", p.stop)
 	if err != nil {
-		return nil, fmt.Errorf("failed to declare queue '%s': %w", tradeCommandsQueue, err)
+		return nil, err
+	}
+	closed := conn.NotifyClose(make(chan *amqp091.Error, 1))
+	if err := p.open(conn); err != nil {
+		conn.Close()
+		return nil, err
 	}
+	return closed, nil
+}
+
+// publishChannel returns the current publishing channel.
+func (p *Publisher) publishChannel() *amqp091.Channel {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.channel
+}
 
-	return &Publisher{conn: conn, channel: ch}, nil
+// Connected reports whether the connection to RabbitMQ is open. It is false while the
+// connection supervisor re-dials; publishing fails until it succeeds.
+func (p *Publisher) Connected() bool {
+	p.mu.Lock()
+	conn := p.conn
+	p.mu.Unlock()
+	return conn != nil && !conn.IsClosed()
 }
 
 // RequestHistoricalBars sends a request to fetch historical data for a given instrument.
@@ -94,22 +131,39 @@ func NewPublisher(amqpURI string) (*Publisher, error) {
 // How:
 //   Their current Java parser naively strips only '{' and '"' and then splits on commas/colons.
 //   JSON bodies like {"barsCount":200} leave a trailing '}', causing NumberFormatException.
-//   To be robust, we publish a simple plain-text key/value payload without braces or quotes:
+//   To be robust, we publish a simple plain-text key/value payload without braces or quotes
+//   (see HistoricalRequest.Encode):
 //     instrument:EURUSD,barsCount:200
 // Params:
 //   instrument string, barsCount int
 // Returns:
 //   error if publish fails.
 func (p *Publisher) RequestHistoricalBars(instrument string, barsCount int) error {
+	return p.publishHistoricalRequest(HistoricalRequest{Instrument: instrument, BarsCount: barsCount})
+}
+
+// RequestHistoricalRange asks JForex for the bars of instrument between fromMs and toMs (unix millis).
+// period limits the request to one period; empty requests all periods. The bars arrive on the
+// normal <INSTRUMENT>_H-Bars queue unnumbered (sequence 0), so they are merged into the buffer
+// through the same dedup as other historical bars and never replace it as a full backfill.
+func (p *Publisher) RequestHistoricalRange(instrument, period string, fromMs, toMs int64) error {
+	return p.publishHistoricalRequest(HistoricalRequest{Instrument: instrument, Period: period, FromMs: fromMs, ToMs: toMs})
+}
+
+// publishHistoricalRequest validates and publishes req to the instrument's request queue.
+func (p *Publisher) publishHistoricalRequest(req HistoricalRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	queueName := fmt.Sprintf("%s_H-Requests", instrument)
+	queueName := fmt.Sprintf("%s_H-Requests", req.Instrument)
 
 	// Plain-text payload compatible with the requester's naive parser
-	payload := fmt.Sprintf("instrument:%s,barsCount:%d", instrument, barsCount)
+	payload := req.Encode()
 
-	err := p.channel.PublishWithContext(ctx,
+	err := p.publishChannel().PublishWithContext(ctx,
 		"", // exchange
 		queueName,
 		false, // mandatory
@@ -121,7 +175,7 @@ func (p *Publisher) RequestHistoricalBars(instrument string, barsCount int) erro
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to publish historical request for %s to queue %s: %w", instrument, queueName, err)
+		return fmt.Errorf("failed to publish historical request for %s to queue %s: %w", req.Instrument, queueName, err)
 	}
 	return nil
 }
@@ -141,17 +195,27 @@ func (p *Publisher) RequestAllHistoricalBars(barsCount int) error {
 
 // Close closes the publisher's channel and connection.
 func (p *Publisher) Close() {
-	if p.channel != nil {
-		p.channel.Close()
+	p.mu.Lock()
+	if p.stop != nil {
+		select {
+		case <-p.stop:
+		default:
+			close(p.stop)
+		}
+	}
+	conn, ch := p.conn, p.channel
+	p.mu.Unlock()
+	if ch != nil {
+		ch.Close()
 	}
-	if p.conn != nil {
-		p.conn.Close()
+	if conn != nil {
+		conn.Close()
 	}
 }
 
 // TradeCommand is the generic payload consumed by JForex TradeManager
 // Fields align with TradeManager.java parseSimpleJson expectations
-// command: SUBMIT_ORDER | CLOSE_ORDER | MODIFY_ORDER
+// command: SUBMIT_ORDER | CLOSE_ORDER | MODIFY_ORDER | CANCEL_ORDER
 // orderCmd: BUY | SELL | BUY_LIMIT | SELL_LIMIT | BUY_STOP | SELL_STOP
 // amount: JForex order amount (e.g., 0.10 = 10k units)
 // stopLossPrice / takeProfitPrice: absolute prices (optional)
@@ -181,6 +245,12 @@ func (p *Publisher) PublishCloseOrder(orderID string) error {
 	return p.publishTradeCommand(cmd)
 }
 
+// PublishCancelOrder publishes a CANCEL_ORDER command for a working (unfilled) order
+func (p *Publisher) PublishCancelOrder(orderID string) error {
+	cmd := TradeCommand{Command: "CANCEL_ORDER", OrderID: orderID}
+	return p.publishTradeCommand(cmd)
+}
+
 // PublishModifyOrder publishes a MODIFY_ORDER command (e.g., to set SL/TP)
 func (p *Publisher) PublishModifyOrder(orderID string, sl, tp float64) error {
 	cmd := TradeCommand{Command: "MODIFY_ORDER", OrderID: orderID}
@@ -200,7 +270,7 @@ func (p *Publisher) publishTradeCommand(cmd TradeCommand) error {
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	return p.channel.PublishWithContext(ctx, "", tradeCommandsQueue, false, false, amqp091.Publishing{
+	return p.publishChannel().PublishWithContext(ctx, "", tradeCommandsQueue, false, false, amqp091.Publishing{
 		ContentType: "application/json",
 		Body:        body,
 	})
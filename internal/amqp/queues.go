@@ -0,0 +1,123 @@
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Queue classes that only the publisher declares (data classes are in acks.go).
+const (
+	ClassRequest = "request" // <INSTRUMENT>_H-Requests
+	ClassCommand = "command" // Trade_Commands
+)
+
+// QueueLimits bounds how long messages live in a queue and how many it holds.
+// Zero values mean unlimited, in which case the queue is declared without arguments.
+type QueueLimits struct {
+	TTL       time.Duration // x-message-ttl
+	MaxLength int           // x-max-length; the oldest messages are dropped first
+}
+
+// Args returns the x-arguments for QueueDeclare, or nil when no limit is set.
+func (l QueueLimits) Args() amqp091.Table {
+	if l.TTL <= 0 && l.MaxLength <= 0 {
+		return nil
+	}
+	args := amqp091.Table{}
+	if l.TTL > 0 {
+		args["x-message-ttl"] = l.TTL.Milliseconds()
+	}
+	if l.MaxLength > 0 {
+		args["x-max-length"] = int64(l.MaxLength)
+		args["x-overflow"] = "drop-head"
+	}
+	return args
+}
+
+// ParseQueueLimits parses per-class limits from a config string.
+// What: Config format "class:ttl:maxLen" entries separated by commas, e.g. "tick:30s:10000,request:5m:0".
+// How: ttl is a Go duration (0 for none) and maxLen an integer (0 for none). Unknown classes are rejected.
+// Returns: limits keyed by class (ClassTick, ClassBar, ClassHistorical, ClassAccount, ClassRequest, ClassCommand).
+func ParseQueueLimits(v string) (map[string]QueueLimits, error) {
+	out := make(map[string]QueueLimits)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("queue limits %q: want class:ttl:maxLen", entry)
+		}
+		class := strings.ToLower(strings.TrimSpace(parts[0]))
+		switch class {
+		case ClassTick, ClassBar, ClassHistorical, ClassAccount, ClassRequest, ClassCommand:
+		default:
+			return nil, fmt.Errorf("queue limits %q: unknown class %q", entry, class)
+		}
+		var l QueueLimits
+		if ttl := strings.TrimSpace(parts[1]); ttl != "" && ttl != "0" {
+			d, err := time.ParseDuration(ttl)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("queue limits %q: bad ttl %q", entry, ttl)
+			}
+			l.TTL = d
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("queue limits %q: bad max length %q", entry, parts[2])
+		}
+		l.MaxLength = n
+		out[class] = l
+	}
+	return out, nil
+}
+
+// declareQueues declares durable queues with their limits on a short-lived channel.
+// What: Applies TTL/max-length to new queues without breaking on ones that already exist.
+// How: Queue arguments cannot change on an existing queue; RabbitMQ answers PRECONDITION_FAILED and
+//      closes the channel. In that case a fresh channel is opened, the queue is checked passively, and
+//      a warning explains that the queue must be deleted (or a policy used) for the new limits to apply.
+// Params: conn open connection, queues name -> limits
+// Returns: error for failures other than an argument mismatch
+func declareQueues(conn *amqp091.Connection, queues map[string]QueueLimits) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel for queue declaration: %w", err)
+	}
+	defer func() { ch.Close() }()
+
+	for name, limits := range queues {
+		_, err := ch.QueueDeclare(
+			name,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			limits.Args(),
+		)
+		if err == nil {
+			continue
+		}
+		var amqpErr *amqp091.Error
+		if !errors.As(err, &amqpErr) || amqpErr.Code != amqp091.PreconditionFailed {
+			return fmt.Errorf("failed to declare queue '%s': %w", name, err)
+		}
+		log.Printf("Warning: queue %s exists with different arguments; keeping it as is. Delete it or use a broker policy to apply ttl=%s maxLength=%d",
+			name, limits.TTL, limits.MaxLength)
+		// The failed declare closed the channel
+		if ch, err = conn.Channel(); err != nil {
+			return fmt.Errorf("failed to reopen channel after declaring '%s': %w", name, err)
+		}
+		if _, err := ch.QueueDeclarePassive(name, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("queue '%s' not usable: %w", name, err)
+		}
+	}
+	return nil
+}
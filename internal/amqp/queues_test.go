@@ -0,0 +1,44 @@
+package amqp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQueueLimits(t *testing.T) {
+	got, err := ParseQueueLimits(" tick:30s:10000, request:5m:0 ,historical:0:500")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]QueueLimits{
+		ClassTick:       {TTL: 30 * time.Second, MaxLength: 10000},
+		ClassRequest:    {TTL: 5 * time.Minute},
+		ClassHistorical: {MaxLength: 500},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %+v, want %+v", k, got[k], v)
+		}
+	}
+	for _, bad := range []string{"tick:30s", "nope:1s:1", "tick:xx:1", "tick:1s:-1"} {
+		if _, err := ParseQueueLimits(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+	if l, _ := ParseQueueLimits(""); len(l) != 0 {
+		t.Errorf("empty config should yield no limits, got %v", l)
+	}
+}
+
+func TestQueueLimitsArgs(t *testing.T) {
+	if (QueueLimits{}).Args() != nil {
+		t.Fatal("unlimited queue must be declared without arguments")
+	}
+	args := QueueLimits{TTL: 1500 * time.Millisecond, MaxLength: 10}.Args()
+	if args["x-message-ttl"] != int64(1500) || args["x-max-length"] != int64(10) || args["x-overflow"] != "drop-head" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
@@ -0,0 +1,87 @@
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Connection recovery.
+// What: A RabbitMQ restart closes the long-lived connection and with it every channel, consumer
+//      and publisher channel. Recover without restarting the backend.
+// How: NewConsumer and NewPublisher register NotifyClose on their connection. When it closes with
+//      an error, a supervisor re-dials with the startup retry loop (repeated until it succeeds or
+//      Close is called) and restores what ran on the old connection: the publisher reopens its
+//      channel and re-declares its queues, the consumer re-registers its consumers through
+//      StartConsumers and, if it was started, the Trade_Results consumer. Messages that were
+//      unacked on the old connection are redelivered by the broker.
+
+// connectAttempts is the number of dials in one retry loop.
+const connectAttempts = 10
+
+var (
+	// dialAMQP opens a connection; replaced in tests.
+	dialAMQP = amqp091.Dial
+	// connectRetryDelay is the wait between two dials of a retry loop.
+	connectRetryDelay = 2 * time.Second
+)
+
+// errStopped is returned by a re-dial interrupted by Close.
+var errStopped = errors.New("connection closed")
+
+// dialWithRetry dials uri up to connectAttempts times. It gives up early once stop is closed;
+// a nil stop never does.
+func dialWithRetry(uri, name string, stop <-chan struct{}) (*amqp091.Connection, error) {
+	var err error
+	for i := 0; i < connectAttempts; i++ {
+		var conn *amqp091.Connection
+		if conn, err = dialAMQP(uri); err == nil {
+			return conn, nil
+		}
+		log.Printf("RabbitMQ %s connection attempt %d failed: %s", name, i+1, err)
+		select {
+		case <-stop:
+			return nil, errStopped
+		case <-time.After(connectRetryDelay):
+		}
+	}
+	return nil, fmt.Errorf("failed to connect to RabbitMQ after %d attempts: %w", connectAttempts, err)
+}
+
+// superviseConnection waits for closed to report an error, then calls redial until it succeeds and
+// watches the close notifications it returns. It returns when a connection is closed without an
+// error (Close was called) or stop is closed.
+func superviseConnection(name string, closed <-chan *amqp091.Error, stop <-chan struct{}, redial func() (<-chan *amqp091.Error, error)) {
+	for {
+		var amqpErr *amqp091.Error
+		select {
+		case <-stop:
+			return
+		case amqpErr = <-closed:
+		}
+		if amqpErr == nil {
+			return
+		}
+		log.Printf("⚠️ RabbitMQ %s connection lost: %v; re-dialing", name, amqpErr)
+		for {
+			next, err := redial()
+			if err == nil {
+				log.Printf("✅ RabbitMQ %s connection restored", name)
+				closed = next
+				break
+			}
+			if errors.Is(err, errStopped) {
+				return
+			}
+			log.Printf("❌ Re-dialing the RabbitMQ %s connection failed, retrying: %s", name, err)
+			select {
+			case <-stop:
+				return
+			case <-time.After(connectRetryDelay):
+			}
+		}
+	}
+}
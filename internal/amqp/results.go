@@ -0,0 +1,107 @@
+package amqp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// tradeResultsQueue is where JForex TradeManager reports the outcome of each trade command.
+	tradeResultsQueue = "Trade_Results"
+	// classResult is the QueueDepth class of Trade_Results.
+	classResult = "result"
+)
+
+// Trade result statuses reported on Trade_Results.
+const (
+	ResultAccepted = "ACCEPTED" // order submitted to the broker, not filled yet
+	ResultFilled   = "FILLED"
+	ResultClosed   = "CLOSED"
+	ResultModified = "MODIFIED"
+	ResultCanceled = "CANCELED"
+	ResultRejected = "REJECTED" // the broker refused the command; Reason says why
+	ResultError    = "ERROR"    // TradeManager failed to execute the command
+)
+
+// TradeResult is the outcome of one trade command, published by JForex on Trade_Results.
+// What: Closes the order lifecycle loop: we learn whether each command was accepted, rejected, or failed.
+// How: Expected JSON, e.g.
+//      {"produced_at":1717000000000,"label":"EURUSD_buy_1717000000000","command":"SUBMIT_ORDER",
+//       "status":"REJECTED","orderId":"","reason":"Not enough margin","price":0}
+//      label is the TradeCommand label for SUBMIT_ORDER, and the orderId for CLOSE_ORDER,
+//      MODIFY_ORDER and CANCEL_ORDER (the trades table records those requests under the orderId).
+type TradeResult struct {
+	ProducedAt int64   `json:"produced_at"`
+	Label      string  `json:"label"`
+	Command    string  `json:"command"`           // SUBMIT_ORDER | CLOSE_ORDER | MODIFY_ORDER | CANCEL_ORDER
+	Status     string  `json:"status"`            // Result*
+	OrderID    string  `json:"orderId,omitempty"` // broker order ID once assigned
+	Reason     string  `json:"reason,omitempty"`
+	Price      float64 `json:"price,omitempty"` // fill or close price
+}
+
+// parseTradeResult decodes and validates a Trade_Results message; status is upper-cased.
+func parseTradeResult(body []byte) (TradeResult, error) {
+	var r TradeResult
+	if err := json.Unmarshal(body, &r); err != nil {
+		return r, err
+	}
+	r.Label = strings.TrimSpace(r.Label)
+	r.Status = strings.ToUpper(strings.TrimSpace(r.Status))
+	if r.Label == "" || r.Status == "" {
+		return r, fmt.Errorf("label and status are required")
+	}
+	return r, nil
+}
+
+// StartTradeResults declares Trade_Results (we are its only consumer) and passes each result to fn.
+// Call once fn's dependencies are ready: results are acked after fn returns.
+func (c *Consumer) StartTradeResults(fn func(TradeResult)) error {
+	c.messageHandler.SetTradeResultHandler(fn)
+	if err := c.startTradeResults(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.resultsStarted = true
+	c.mu.Unlock()
+	return nil
+}
+
+// startTradeResults declares Trade_Results and starts its consumer group on the current connection.
+func (c *Consumer) startTradeResults() error {
+	conn := c.connection()
+	if err := declareQueues(conn, map[string]QueueLimits{tradeResultsQueue: {}}); err != nil {
+		return err
+	}
+	return c.startGroup(conn, consumerGroup{class: classResult, queues: []string{tradeResultsQueue}, handler: c.messageHandler.processTradeResult})
+}
+
+// SetTradeResultHandler registers the callback for trade results, e.g. to update the trades table
+// and notify WebSocket clients. It runs on the Trade_Results consumer goroutine, in arrival order.
+func (mh *MessageHandler) SetTradeResultHandler(fn func(TradeResult)) {
+	mh.procMu.Lock()
+	defer mh.procMu.Unlock()
+	mh.onTradeResult = fn
+}
+
+// processTradeResult hands a trade result to the registered handler. Results are never dropped as
+// stale; malformed messages are logged and discarded.
+func (mh *MessageHandler) processTradeResult(delivery amqp091.Delivery) {
+	r, err := parseTradeResult(delivery.Body)
+	if err != nil {
+		log.Printf("Discarding invalid trade result %q: %s", delivery.Body, err)
+		delivery.Nack(false, false)
+		return
+	}
+	mh.procMu.Lock()
+	fn := mh.onTradeResult
+	mh.procMu.Unlock()
+	if fn != nil {
+		fn(r)
+	}
+	delivery.Ack(false)
+}
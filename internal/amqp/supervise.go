@@ -0,0 +1,138 @@
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// Consumer channel supervision.
+// What: A channel-level exception (e.g. one bad queue) closes the AMQP channel and every consumer
+//      on it. Keep such a failure confined to one consumer group and recover from it.
+// How: Each group (ticks, bars, historical, account, trade results) has its own channel with its
+//      own prefetch. A supervisor per group waits for the channel to close with an error, then
+//      opens a new channel and re-registers the group's consumers. A consumer cancelled by the
+//      broker on an open channel is re-registered on its own (see consume). A supervisor stops
+//      with its connection; after a re-dial, new groups are started on the new connection
+//      (see reconnect.go).
+
+// consumerRestartDelay is the wait before re-registering consumers after a failure; shortened in tests.
+var consumerRestartDelay = 2 * time.Second
+
+// consumerGroup is a set of queues of one message class consumed on a shared channel.
+type consumerGroup struct {
+	class   string
+	queues  []string
+	handler func(amqp091.Delivery)
+}
+
+// consumerGroups lists the data consumer groups started by StartConsumers.
+func (c *Consumer) consumerGroups() []consumerGroup {
+	bars := consumerGroup{class: ClassBar, handler: c.barHandler}
+	historical := consumerGroup{class: ClassHistorical, handler: c.historicalBarHandler}
+	for _, instrument := range instrumentList {
+		bars.queues = append(bars.queues, fmt.Sprintf("%s_Market_Data_Bars", instrument))
+		historical.queues = append(historical.queues, fmt.Sprintf("%s_H-Bars", instrument))
+	}
+	return []consumerGroup{
+		{class: ClassTick, queues: []string{ticksQueue}, handler: c.tickHandler},
+		{class: ClassAccount, queues: []string{accountInfoQueue}, handler: c.accountInfoHandler},
+		bars,
+		historical,
+	}
+}
+
+// startGroup opens g's channel on conn, registers its consumers and supervises the channel in the background.
+func (c *Consumer) startGroup(conn *amqp091.Connection, g consumerGroup) error {
+	ch, err := c.openClassChannel(conn, g.class)
+	if err != nil {
+		return err
+	}
+	go superviseGroup(connGroups{c: c, conn: conn}, g, c.registerGroup(ch, g))
+	return nil
+}
+
+// groupOpener reopens consumer group channels on one connection; faked in tests.
+type groupOpener interface {
+	// connClosed reports whether the connection is gone
+	connClosed() bool
+	// reopen opens a new channel for g, registers g's consumers on it and returns its close notifications
+	reopen(g consumerGroup) (<-chan *amqp091.Error, error)
+}
+
+// connGroups opens c's consumer group channels on conn.
+type connGroups struct {
+	c    *Consumer
+	conn *amqp091.Connection
+}
+
+func (o connGroups) connClosed() bool { return o.conn.IsClosed() }
+
+func (o connGroups) reopen(g consumerGroup) (<-chan *amqp091.Error, error) {
+	ch, err := o.c.openClassChannel(o.conn, g.class)
+	if err != nil {
+		return nil, err
+	}
+	return o.c.registerGroup(ch, g), nil
+}
+
+// registerGroup registers g's consumers on ch and returns ch's close notifications.
+func (c *Consumer) registerGroup(ch *amqp091.Channel, g consumerGroup) <-chan *amqp091.Error {
+	closed := ch.NotifyClose(make(chan *amqp091.Error, 1))
+	c.mu.Lock()
+	if c.channels == nil {
+		c.channels = make(map[string]*amqp091.Channel)
+	}
+	c.channels[g.class] = ch
+	c.mu.Unlock()
+	for _, q := range g.queues {
+		c.consume(ch, q, g.handler)
+	}
+	return closed
+}
+
+// superviseGroup re-opens g's channel with o and re-registers its consumers each time the channel
+// closes with an error. It returns when the channel is closed cleanly or the connection is gone.
+func superviseGroup(o groupOpener, g consumerGroup, closed <-chan *amqp091.Error) {
+	for {
+		amqpErr, ok := <-closed
+		if !ok || o.connClosed() {
+			return
+		}
+		log.Printf("⚠️ AMQP channel for %s consumers closed: %v; re-registering %d consumers in %s",
+			g.class, amqpErr, len(g.queues), consumerRestartDelay)
+		for {
+			time.Sleep(consumerRestartDelay)
+			if o.connClosed() {
+				return
+			}
+			var err error
+			if closed, err = o.reopen(g); err != nil {
+				log.Printf("❌ Reopening the %s channel failed, retrying: %s", g.class, err)
+				continue
+			}
+			break
+		}
+	}
+}
+
+// queueExists passively declares name on a short-lived channel, so that a missing queue (which
+// makes the broker close the channel with NOT_FOUND) does not close a consumer channel.
+func (c *Consumer) queueExists(name string) (bool, error) {
+	ch, err := c.connection().Channel()
+	if err != nil {
+		return false, err
+	}
+	defer func() { ch.Close() }()
+	if _, err := ch.QueueDeclarePassive(name, true, false, false, false, nil); err != nil {
+		var amqpErr *amqp091.Error
+		if errors.As(err, &amqpErr) && amqpErr.Code == amqp091.NotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
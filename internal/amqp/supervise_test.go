@@ -0,0 +1,114 @@
+package amqp
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestConsumerGroupsSplitDataQueuesByClass(t *testing.T) {
+	c := &Consumer{}
+	classes := make(map[string]bool)
+	queues := make(map[string]string)
+	for _, g := range c.consumerGroups() {
+		if classes[g.class] {
+			t.Errorf("class %s has more than one group", g.class)
+		}
+		classes[g.class] = true
+		if g.handler == nil {
+			t.Errorf("group %s has no handler", g.class)
+		}
+		for _, q := range g.queues {
+			if prev, dup := queues[q]; dup {
+				t.Errorf("queue %s in groups %s and %s", q, prev, g.class)
+			}
+			queues[q] = g.class
+		}
+	}
+	// Every data queue reported by QueueDepths is consumed by the group of its class
+	for _, q := range depthQueues() {
+		if q.Class == ClassCommand || q.Class == classResult {
+			continue
+		}
+		if got := queues[q.Queue]; got != q.Class {
+			t.Errorf("queue %s consumed by group %q, want %q", q.Queue, got, q.Class)
+		}
+	}
+}
+
+// fakeOpener reopens group channels in memory: each reopen registers the group's queues and hands
+// out a new close channel, after failing the first fails times.
+type fakeOpener struct {
+	mu         sync.Mutex
+	fails      int
+	attempts   int
+	registered [][]string
+	channels   chan chan *amqp091.Error // close channel of each reopened channel
+}
+
+func (o *fakeOpener) connClosed() bool { return false }
+
+func (o *fakeOpener) reopen(g consumerGroup) (<-chan *amqp091.Error, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.attempts++
+	if o.attempts <= o.fails {
+		return nil, errors.New("channel open failed")
+	}
+	o.registered = append(o.registered, append([]string(nil), g.queues...))
+	closed := make(chan *amqp091.Error, 1)
+	o.channels <- closed
+	return closed, nil
+}
+
+func TestGroupChannelErrorReopensAndReregisters(t *testing.T) {
+	prevDelay := consumerRestartDelay
+	consumerRestartDelay = time.Millisecond
+	t.Cleanup(func() { consumerRestartDelay = prevDelay })
+	o := &fakeOpener{fails: 1, channels: make(chan chan *amqp091.Error, 4)}
+	g := consumerGroup{class: ClassBar, queues: []string{"EURUSD_Market_Data_Bars", "GBPUSD_Market_Data_Bars"}}
+	closed := make(chan *amqp091.Error, 1)
+	done := make(chan struct{})
+	go func() {
+		superviseGroup(o, g, closed)
+		close(done)
+	}()
+
+	reopened := func() chan *amqp091.Error {
+		t.Helper()
+		select {
+		case ch := <-o.channels:
+			return ch
+		case <-time.After(2 * time.Second):
+			t.Fatal("channel not reopened")
+			return nil
+		}
+	}
+	closed <- &amqp091.Error{Code: amqp091.PreconditionFailed, Reason: "PRECONDITION_FAILED - unknown delivery tag"}
+	next := reopened()
+	next <- &amqp091.Error{Code: amqp091.NotFound, Reason: "NOT_FOUND - no queue"}
+	last := reopened()
+
+	o.mu.Lock()
+	attempts, registered := o.attempts, o.registered
+	o.mu.Unlock()
+	if attempts != 3 || len(registered) != 2 {
+		t.Fatalf("%d reopen attempts, %d registrations; want the failed open retried and each close recovered", attempts, len(registered))
+	}
+	for _, qs := range registered {
+		if len(qs) != len(g.queues) || qs[0] != g.queues[0] || qs[1] != g.queues[1] {
+			t.Fatalf("re-registered %v, want %v", qs, g.queues)
+		}
+	}
+
+	// A channel closed cleanly ends supervision
+	close(last)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("supervisor still running after a clean close")
+	}
+}
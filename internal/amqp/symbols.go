@@ -0,0 +1,85 @@
+package amqp
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// SymbolNormalizer maps broker instrument symbols to the canonical keys used throughout the system.
+// What: JForex may send "EUR/USD" or "EURUSD" depending on its configuration; state, strategies and
+//       the frontend all key on "EURUSD", so an unmatched symbol would silently never display.
+// How: An explicit alias wins; otherwise the symbol is upper-cased with separators ("/", "-", "_",
+//      ".", " ") removed and used when it is a known instrument. Anything else passes through
+//      unchanged and is logged once per symbol so misconfiguration is visible.
+type SymbolNormalizer struct {
+	aliases map[string]string // upper-cased broker symbol -> canonical key
+	known   map[string]bool
+
+	mu       sync.Mutex
+	unmapped map[string]bool
+}
+
+// NewSymbolNormalizer creates a normalizer for the canonical instruments with optional aliases.
+func NewSymbolNormalizer(instruments []string, aliases map[string]string) *SymbolNormalizer {
+	n := &SymbolNormalizer{
+		aliases:  make(map[string]string, len(aliases)),
+		known:    make(map[string]bool, len(instruments)),
+		unmapped: make(map[string]bool),
+	}
+	for _, instr := range instruments {
+		n.known[instr] = true
+	}
+	for from, to := range aliases {
+		n.aliases[strings.ToUpper(strings.TrimSpace(from))] = to
+	}
+	return n
+}
+
+var symbolSeparators = strings.NewReplacer("/", "", "-", "", "_", "", ".", "", " ", "")
+
+// Normalize returns the canonical key for a broker symbol.
+func (n *SymbolNormalizer) Normalize(symbol string) string {
+	if n == nil || n.known[symbol] {
+		return symbol
+	}
+	upper := strings.ToUpper(strings.TrimSpace(symbol))
+	if to, ok := n.aliases[upper]; ok {
+		return to
+	}
+	if key := symbolSeparators.Replace(upper); n.known[key] {
+		return key
+	}
+	n.mu.Lock()
+	first := !n.unmapped[symbol]
+	n.unmapped[symbol] = true
+	n.mu.Unlock()
+	if first {
+		log.Printf("WARNING: Unmapped instrument symbol %q; add an alias (GOTRADER_INSTRUMENT_ALIASES) if it should match a configured instrument", symbol)
+	}
+	return symbol
+}
+
+// Known reports whether a normalized symbol is a configured instrument. A nil normalizer knows everything.
+func (n *SymbolNormalizer) Known(instrument string) bool {
+	return n == nil || n.known[instrument]
+}
+
+// ParseInstrumentAliases parses "broker:canonical" pairs separated by commas, e.g. "EUR/USD:EURUSD".
+func ParseInstrumentAliases(v string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(entry, ":")
+		from, to = strings.TrimSpace(from), strings.ToUpper(strings.TrimSpace(to))
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("instrument alias %q: want broker:canonical", entry)
+		}
+		out[from] = to
+	}
+	return out, nil
+}
@@ -0,0 +1,34 @@
+package amqp
+
+import "testing"
+
+func TestSymbolNormalizer(t *testing.T) {
+	aliases, err := ParseInstrumentAliases("XAU/USD:xauusd, EURUSD.FX:EURUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewSymbolNormalizer([]string{"EURUSD", "GBPJPY", "XAUUSD"}, aliases)
+	cases := map[string]string{
+		"EURUSD":    "EURUSD",
+		"EUR/USD":   "EURUSD",
+		"gbp-jpy":   "GBPJPY",
+		"xau/usd":   "XAUUSD",
+		"EURUSD.FX": "EURUSD",
+		"USD/TRY":   "USD/TRY", // unmapped passes through
+	}
+	for in, want := range cases {
+		if got := n.Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if !n.unmapped["USD/TRY"] || len(n.unmapped) != 1 {
+		t.Fatalf("unmapped = %v, want only USD/TRY", n.unmapped)
+	}
+	var none *SymbolNormalizer
+	if got := none.Normalize("EUR/USD"); got != "EUR/USD" {
+		t.Fatalf("nil normalizer changed symbol to %q", got)
+	}
+	if _, err := ParseInstrumentAliases("EUR/USD"); err == nil {
+		t.Fatal("alias without target should fail")
+	}
+}
@@ -0,0 +1,188 @@
+package amqp
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// minWatchdogInterval bounds how often the watchdog scans processors.
+const minWatchdogInterval = 1 * time.Second
+
+// processor is the liveness record of one message processing goroutine.
+// The goroutine stamps busySince before handling a delivery and lastDone after it, so the
+// watchdog can tell a processor waiting for work from one stuck inside a handler.
+type processor struct {
+	name     string
+	class    string
+	backlog  func() int // deliveries waiting for this processor's class
+	run      func(*processor)
+	restarts int
+
+	busySince atomic.Int64 // unix millis the current delivery started; 0 while waiting
+	lastDone  atomic.Int64 // unix millis the last delivery finished
+	processed atomic.Int64
+	abandoned atomic.Bool // set when the watchdog replaced this goroutine
+	exit      sync.Once   // releases the handler WaitGroup exactly once
+}
+
+// begin marks the start of a delivery.
+func (p *processor) begin(now time.Time) { p.busySince.Store(now.UnixMilli()) }
+
+// finish marks a delivery done. It returns false when the goroutine was replaced while
+// handling it and must exit instead of taking more work.
+func (p *processor) finish(now time.Time) bool {
+	p.lastDone.Store(now.UnixMilli())
+	p.busySince.Store(0)
+	p.processed.Add(1)
+	return !p.abandoned.Load()
+}
+
+// ProcessorStatus reports the liveness of one message processor for /healthz.
+type ProcessorStatus struct {
+	Name            string `json:"name"`
+	Class           string `json:"class"`
+	Backlog         int    `json:"backlog"` // deliveries waiting for the processor's class
+	Busy            bool   `json:"busy"`
+	BusyForMs       int64  `json:"busyForMs,omitempty"`
+	LastProcessedAt int64  `json:"lastProcessedAt,omitempty"` // unix millis
+	Processed       int64  `json:"processed"`
+	Restarts        int    `json:"restarts"`
+	Stalled         bool   `json:"stalled"`
+}
+
+// status snapshots the processor. It is stalled when stuck on one delivery for longer than
+// stallAfter while its channel has backlog; a processor waiting for work is never stalled.
+func (p *processor) status(now time.Time, stallAfter time.Duration) ProcessorStatus {
+	s := ProcessorStatus{
+		Name:            p.name,
+		Class:           p.class,
+		Backlog:         p.backlog(),
+		LastProcessedAt: p.lastDone.Load(),
+		Processed:       p.processed.Load(),
+		Restarts:        p.restarts,
+	}
+	if since := p.busySince.Load(); since > 0 {
+		s.Busy = true
+		s.BusyForMs = now.UnixMilli() - since
+	}
+	s.Stalled = stallAfter > 0 && s.Busy && s.Backlog > 0 && s.BusyForMs > stallAfter.Milliseconds()
+	return s
+}
+
+// chanBacklog reports the number of deliveries queued on a processor channel.
+func chanBacklog(ch chan amqp091.Delivery) func() int {
+	return func() int { return len(ch) }
+}
+
+// startProcessor registers and launches a processor goroutine.
+func (mh *MessageHandler) startProcessor(name, class string, backlog func() int, restarts int, run func(*processor)) {
+	p := &processor{name: name, class: class, backlog: backlog, run: run, restarts: restarts}
+	mh.procMu.Lock()
+	mh.processors[name] = p
+	mh.procMu.Unlock()
+	mh.wg.Add(1)
+	go run(p)
+}
+
+// processorDone releases a processor goroutine from the handler WaitGroup.
+func (mh *MessageHandler) processorDone(p *processor) { p.exit.Do(mh.wg.Done) }
+
+// SetWatchdog enables stalled-processor detection. A processor stuck on one delivery for longer
+// than stallAfter while its channel has backlog is logged and reported to the stall handler; with
+// restart, a replacement goroutine takes over its channel and the stuck one exits when (if ever) its
+// delivery returns. stallAfter <= 0 disables the watchdog. Call before Start.
+func (mh *MessageHandler) SetWatchdog(stallAfter time.Duration, restart bool) {
+	mh.procMu.Lock()
+	defer mh.procMu.Unlock()
+	mh.stallAfter = stallAfter
+	mh.restartStalled = restart
+}
+
+// SetStallHandler registers a callback for stalled processors, e.g. to alert WebSocket clients.
+// It shares the stall warning throttle, so each call matches a logged warning.
+func (mh *MessageHandler) SetStallHandler(fn func(ProcessorStatus)) {
+	mh.procMu.Lock()
+	defer mh.procMu.Unlock()
+	mh.onStall = fn
+}
+
+// ProcessorStatuses returns the liveness of every processor, ordered by name.
+func (mh *MessageHandler) ProcessorStatuses() []ProcessorStatus {
+	now := mh.clock.Now()
+	mh.procMu.Lock()
+	stallAfter := mh.stallAfter
+	procs := make([]*processor, 0, len(mh.processors))
+	for _, p := range mh.processors {
+		procs = append(procs, p)
+	}
+	mh.procMu.Unlock()
+
+	out := make([]ProcessorStatus, 0, len(procs))
+	for _, p := range procs {
+		out = append(out, p.status(now, stallAfter))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// watchdog periodically checks processors for stalls.
+func (mh *MessageHandler) watchdog(stallAfter time.Duration) {
+	defer mh.wg.Done()
+	interval := stallAfter / 4
+	if interval < minWatchdogInterval {
+		interval = minWatchdogInterval
+	}
+	ticker := mh.clock.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-mh.stopChannel:
+			return
+		case <-ticker.C():
+			mh.checkProcessors()
+		}
+	}
+}
+
+// checkProcessors logs and reports stalled processors, restarting them when enabled.
+// Returns the statuses of the processors found stalled.
+func (mh *MessageHandler) checkProcessors() []ProcessorStatus {
+	now := mh.clock.Now()
+	mh.procMu.Lock()
+	stallAfter, restart, onStall := mh.stallAfter, mh.restartStalled, mh.onStall
+	var stalled []*processor
+	for _, p := range mh.processors {
+		if p.status(now, stallAfter).Stalled {
+			stalled = append(stalled, p)
+		}
+	}
+	mh.procMu.Unlock()
+
+	out := make([]ProcessorStatus, 0, len(stalled))
+	for _, p := range stalled {
+		s := p.status(now, stallAfter)
+		out = append(out, s)
+		msg := fmt.Sprintf("%s processor stalled for %v with %d queued", s.Name,
+			time.Duration(s.BusyForMs)*time.Millisecond, s.Backlog)
+		if restart {
+			p.abandoned.Store(true)
+			mh.processorDone(p)
+			mh.startProcessor(p.name, p.class, p.backlog, p.restarts+1, p.run)
+			log.Printf("WARNING: %s; started replacement (restart %d)", msg, p.restarts+1)
+			if onStall != nil {
+				onStall(s)
+			}
+			continue
+		}
+		if mh.warnLog.Printf("stall_"+s.Name, "WARNING: %s", msg) && onStall != nil {
+			onStall(s)
+		}
+	}
+	return out
+}
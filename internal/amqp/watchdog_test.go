@@ -0,0 +1,85 @@
+package amqp
+
+import (
+	"testing"
+	"time"
+
+	"go-trader/internal/clock"
+	"go-trader/internal/state"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// hungProcessor starts a bar processor that blocks inside its delivery until release is closed.
+// It returns once the processor is busy.
+func hungProcessor(mh *MessageHandler, fc *clock.FakeClock, release chan struct{}) {
+	started := make(chan struct{}, 2)
+	mh.startProcessor("bar-0", ClassBar, chanBacklog(mh.barChannel), 0, func(p *processor) {
+		defer mh.processorDone(p)
+		p.begin(fc.Now())
+		started <- struct{}{}
+		<-release
+		p.finish(fc.Now())
+	})
+	<-started
+}
+
+func TestWatchdogDetectsStallOnlyWithBacklog(t *testing.T) {
+	fc := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	mh := NewMessageHandler(state.NewStateManager())
+	mh.SetClock(fc)
+	mh.SetWatchdog(10*time.Second, false)
+	var alerts []ProcessorStatus
+	mh.SetStallHandler(func(s ProcessorStatus) { alerts = append(alerts, s) })
+
+	release := make(chan struct{})
+	defer close(release)
+	hungProcessor(mh, fc, release)
+
+	fc.Advance(11 * time.Second)
+	if got := mh.checkProcessors(); len(got) != 0 {
+		t.Fatalf("busy processor without backlog reported stalled: %+v", got)
+	}
+
+	mh.barChannel <- amqp091.Delivery{}
+	got := mh.checkProcessors()
+	if len(got) != 1 || got[0].Name != "bar-0" || got[0].Backlog != 1 || !got[0].Stalled {
+		t.Fatalf("stalled = %+v", got)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("alerts = %d, want 1", len(alerts))
+	}
+	if st := mh.ProcessorStatuses(); len(st) != 1 || !st[0].Stalled || st[0].Restarts != 0 {
+		t.Fatalf("statuses = %+v", st)
+	}
+}
+
+func TestWatchdogRestartsStalledProcessor(t *testing.T) {
+	fc := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	mh := NewMessageHandler(state.NewStateManager())
+	mh.SetClock(fc)
+	mh.SetWatchdog(10*time.Second, true)
+
+	release := make(chan struct{})
+	hungProcessor(mh, fc, release)
+	mh.barChannel <- amqp091.Delivery{}
+	fc.Advance(11 * time.Second)
+
+	if got := mh.checkProcessors(); len(got) != 1 {
+		t.Fatalf("stalled = %+v", got)
+	}
+	st := mh.ProcessorStatuses()
+	if len(st) != 1 || st[0].Restarts != 1 {
+		t.Fatalf("statuses after restart = %+v", st)
+	}
+
+	// Both the abandoned and the replacement goroutine must release the WaitGroup exactly once
+	close(release)
+	done := make(chan struct{})
+	go func() { mh.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("processor goroutines did not exit")
+	}
+}
@@ -0,0 +1,15 @@
+// Package broadcast defines the dependency components use to push messages to connected clients.
+// It has no imports of its own so the ledger, strategies and the WebSocket hub can share it without cycles.
+package broadcast
+
+// Broadcaster delivers a message to all connected clients (implemented by websocket.Hub).
+type Broadcaster interface {
+	Broadcast(message []byte)
+}
+
+// Func adapts a plain function to a Broadcaster, e.g. broadcast.Func(hub.Notify) for
+// out-of-band events that must not replace the hub's retained snapshot.
+type Func func(message []byte)
+
+// Broadcast calls f(message).
+func (f Func) Broadcast(message []byte) { f(message) }
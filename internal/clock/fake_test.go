@@ -0,0 +1,72 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClockAdvance(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	fc := NewFake(start)
+	if !fc.Now().Equal(start) {
+		t.Fatalf("Now() = %v, want %v", fc.Now(), start)
+	}
+	fc.Advance(90 * time.Second)
+	if got, want := fc.Now(), start.Add(90*time.Second); !got.Equal(want) {
+		t.Fatalf("Now() after Advance = %v, want %v", got, want)
+	}
+}
+
+func TestFakeTickerFiresOnAdvance(t *testing.T) {
+	fc := NewFake(time.Unix(0, 0))
+	tk := fc.NewTicker(time.Second)
+	defer tk.Stop()
+
+	select {
+	case <-tk.C():
+		t.Fatal("ticker fired before time advanced")
+	default:
+	}
+
+	fc.Advance(500 * time.Millisecond)
+	select {
+	case <-tk.C():
+		t.Fatal("ticker fired before its period elapsed")
+	default:
+	}
+
+	fc.Advance(500 * time.Millisecond)
+	select {
+	case got := <-tk.C():
+		if want := time.Unix(1, 0); !got.Equal(want) {
+			t.Fatalf("tick time = %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("ticker did not fire after one period")
+	}
+}
+
+func TestFakeTickerDropsUnreadTicks(t *testing.T) {
+	fc := NewFake(time.Unix(0, 0))
+	tk := fc.NewTicker(time.Second)
+	fc.Advance(5 * time.Second)
+
+	<-tk.C()
+	select {
+	case <-tk.C():
+		t.Fatal("expected missed ticks to be dropped like time.Ticker")
+	default:
+	}
+}
+
+func TestFakeTickerStop(t *testing.T) {
+	fc := NewFake(time.Unix(0, 0))
+	tk := fc.NewTicker(time.Second)
+	tk.Stop()
+	fc.Advance(3 * time.Second)
+	select {
+	case <-tk.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+    "context"
+    "errors"
+    "log"
+    "sync"
+    "time"
+)
+
+const (
+    // defaultBreakerThreshold is the number of consecutive insert failures that trips the breaker.
+    defaultBreakerThreshold = 5
+    // defaultBreakerProbeInterval is how often the database is probed while the breaker is open.
+    defaultBreakerProbeInterval = 30 * time.Second
+)
+
+// BreakerStatus reports the database circuit breaker for health checks.
+type BreakerStatus struct {
+    State               string     `json:"state"` // closed | open
+    ConsecutiveFailures int        `json:"consecutiveFailures"`
+    Dropped             int64      `json:"dropped"` // writes skipped while open
+    LastError           string     `json:"lastError,omitempty"`
+    OpenedAt            *time.Time `json:"openedAt,omitempty"`
+}
+
+// ErrBreakerOpen is returned by synchronous writes while the circuit breaker is open.
+var ErrBreakerOpen = errors.New("db circuit breaker open")
+
+// breaker stops fire-and-forget writes after repeated failures.
+// What: When Postgres is down, avoid spawning goroutines that are bound to fail and make the outage visible.
+// How: threshold consecutive failures open the breaker (one clear log line); while open, writes are
+//      dropped and counted, and a single probe loop pings the pool every probeInterval. A successful
+//      ping closes it again.
+type breaker struct {
+    mu            sync.Mutex
+    threshold     int
+    probeInterval time.Duration
+    failures      int
+    open          bool
+    openedAt      time.Time
+    dropped       int64
+    lastErr       string
+}
+
+func newBreaker() *breaker {
+    return &breaker{threshold: defaultBreakerThreshold, probeInterval: defaultBreakerProbeInterval}
+}
+
+// allow reports whether a write should be attempted, counting it as dropped otherwise.
+func (b *breaker) allow() bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.open {
+        b.dropped++
+        return false
+    }
+    return true
+}
+
+// record notes a write result and returns true when this failure tripped the breaker.
+func (b *breaker) record(err error) bool {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if err == nil {
+        b.failures = 0
+        return false
+    }
+    b.failures++
+    b.lastErr = err.Error()
+    if b.open || b.failures < b.threshold {
+        return false
+    }
+    b.open = true
+    b.openedAt = time.Now()
+    log.Printf("❌ DB circuit breaker open after %d consecutive write failures (last: %v); dropping writes and probing every %s",
+        b.failures, err, b.probeInterval)
+    return true
+}
+
+// reset closes the breaker after a successful probe, returning how many writes were dropped.
+func (b *breaker) reset() int64 {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    dropped := b.dropped
+    b.open = false
+    b.failures = 0
+    b.dropped = 0
+    return dropped
+}
+
+func (b *breaker) status() BreakerStatus {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    s := BreakerStatus{State: "closed", ConsecutiveFailures: b.failures, Dropped: b.dropped, LastError: b.lastErr}
+    if b.open {
+        s.State = "open"
+        openedAt := b.openedAt
+        s.OpenedAt = &openedAt
+    }
+    return s
+}
+
+// SetCircuitBreaker configures the failure threshold and probe interval (values <= 0 keep the current setting).
+func (l *Logger) SetCircuitBreaker(threshold int, probeInterval time.Duration) {
+    l.breaker.mu.Lock()
+    defer l.breaker.mu.Unlock()
+    if threshold > 0 { l.breaker.threshold = threshold }
+    if probeInterval > 0 { l.breaker.probeInterval = probeInterval }
+}
+
+// BreakerStatus returns the current circuit breaker state.
+func (l *Logger) BreakerStatus() BreakerStatus { return l.breaker.status() }
+
+// execAsync runs a write in the background unless the breaker is open.
+func (l *Logger) execAsync(query string, args ...any) {
+    if !l.breaker.allow() {
+        return
+    }
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+        defer cancel()
+        l.chaosStall(ctx)
+        _, err := l.pool.Exec(ctx, query, args...)
+        if l.breaker.record(err) {
+            go l.probeUntilHealthy()
+        }
+    }()
+}
+
+// probeUntilHealthy pings the database until it answers, then closes the breaker.
+func (l *Logger) probeUntilHealthy() {
+    for {
+        l.breaker.mu.Lock()
+        interval := l.breaker.probeInterval
+        l.breaker.mu.Unlock()
+        time.Sleep(interval)
+
+        ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+        err := l.pool.Ping(ctx)
+        cancel()
+        if err == nil {
+            dropped := l.breaker.reset()
+            log.Printf("✅ DB reachable again; circuit breaker closed (%d writes dropped while open)", dropped)
+            return
+        }
+        l.breaker.mu.Lock()
+        l.breaker.lastErr = err.Error()
+        l.breaker.mu.Unlock()
+    }
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestBreakerTripsAfterThresholdAndResets(t *testing.T) {
+    b := newBreaker()
+    b.threshold = 3
+    fail := errors.New("connection refused")
+
+    if b.record(fail) || b.record(fail) {
+        t.Fatal("tripped before threshold")
+    }
+    b.record(nil) // success resets the streak
+    if b.record(fail) || b.record(fail) {
+        t.Fatal("streak not reset by success")
+    }
+    if !b.record(fail) {
+        t.Fatal("expected trip on third consecutive failure")
+    }
+    if b.record(fail) {
+        t.Fatal("already open breaker must not report a new trip")
+    }
+    if b.allow() || b.allow() {
+        t.Fatal("open breaker must drop writes")
+    }
+    if s := b.status(); s.State != "open" || s.Dropped != 2 || s.OpenedAt == nil || s.LastError == "" {
+        t.Fatalf("unexpected status: %+v", s)
+    }
+    if dropped := b.reset(); dropped != 2 {
+        t.Fatalf("reset dropped = %d, want 2", dropped)
+    }
+    if !b.allow() || b.status().State != "closed" {
+        t.Fatal("breaker should be closed after reset")
+    }
+}
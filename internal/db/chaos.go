@@ -0,0 +1,25 @@
+package db
+
+import (
+    "context"
+    "math/rand/v2"
+    "time"
+)
+
+// SetChaosStall makes a fraction rate of async writes stall for d before running, for resilience
+// testing only. A stall longer than the 3s write timeout fails the write, so stalls count toward
+// the circuit breaker. Call before the logger is used; rate <= 0 disables it.
+func (l *Logger) SetChaosStall(rate float64, d time.Duration) {
+    l.stallRate, l.stall = rate, d
+}
+
+// chaosStall waits out an injected stall, or until ctx is done.
+func (l *Logger) chaosStall(ctx context.Context) {
+    if l.stallRate <= 0 || l.stall <= 0 || rand.Float64() >= l.stallRate {
+        return
+    }
+    select {
+    case <-time.After(l.stall):
+    case <-ctx.Done():
+    }
+}
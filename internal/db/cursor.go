@@ -0,0 +1,50 @@
+package db
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// PageCursor marks the last row of a newest-first page; the next page starts strictly after it.
+// What: Lets API clients page back through long strategy run/event histories.
+// How: Keyset pagination on (timestamp, id) rather than OFFSET, so rows inserted while a client
+//      is paging do not shift later pages and deep pages cost the same as the first. The id breaks
+//      ties between rows written in the same microsecond.
+// The zero value means "start from the newest row".
+type PageCursor struct {
+    TS time.Time
+    ID int64
+}
+
+// IsZero reports whether the cursor is unset.
+func (c PageCursor) IsZero() bool { return c.ID == 0 && c.TS.IsZero() }
+
+// String encodes the cursor as "<unixMicros>_<id>"; the zero cursor encodes as "".
+func (c PageCursor) String() string {
+    if c.IsZero() {
+        return ""
+    }
+    return fmt.Sprintf("%d_%d", c.TS.UnixMicro(), c.ID)
+}
+
+// ParsePageCursor decodes a cursor produced by PageCursor.String. An empty string is the zero cursor.
+func ParsePageCursor(s string) (PageCursor, error) {
+    if s == "" {
+        return PageCursor{}, nil
+    }
+    ts, id, ok := strings.Cut(s, "_")
+    if !ok {
+        return PageCursor{}, fmt.Errorf("invalid cursor %q", s)
+    }
+    us, err := strconv.ParseInt(ts, 10, 64)
+    if err != nil {
+        return PageCursor{}, fmt.Errorf("invalid cursor %q", s)
+    }
+    n, err := strconv.ParseInt(id, 10, 64)
+    if err != nil || n <= 0 {
+        return PageCursor{}, fmt.Errorf("invalid cursor %q", s)
+    }
+    return PageCursor{TS: time.UnixMicro(us).UTC(), ID: n}, nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+    "testing"
+    "time"
+)
+
+func TestPageCursorRoundTrip(t *testing.T) {
+    c := PageCursor{TS: time.Date(2026, 3, 2, 14, 5, 6, 123456000, time.UTC), ID: 42}
+    got, err := ParsePageCursor(c.String())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !got.TS.Equal(c.TS) || got.ID != c.ID {
+        t.Fatalf("round trip: got %+v, want %+v", got, c)
+    }
+}
+
+func TestPageCursorZero(t *testing.T) {
+    if s := (PageCursor{}).String(); s != "" {
+        t.Fatalf("zero cursor encodes as %q", s)
+    }
+    c, err := ParsePageCursor("")
+    if err != nil || !c.IsZero() {
+        t.Fatalf("empty cursor: %+v, %v", c, err)
+    }
+}
+
+func TestParsePageCursorRejectsGarbage(t *testing.T) {
+    for _, s := range []string{"abc", "123", "x_1", "123_y", "123_0", "123_-4"} {
+        if _, err := ParsePageCursor(s); err == nil {
+            t.Errorf("%q accepted", s)
+        }
+    }
+}
@@ -2,8 +2,11 @@ package db
 
 import (
     "context"
+    "encoding/csv"
     "encoding/json"
     "fmt"
+    "io"
+    "strconv"
     "time"
 
     "github.com/jackc/pgx/v5/pgxpool"
@@ -15,7 +18,11 @@ import (
 // Params: NewLogger(dsn string) to construct. Individual Log*/Query* methods accept relevant fields.
 // Returns: *Logger with Close() to release resources.
 type Logger struct {
-    pool *pgxpool.Pool
+    pool    *pgxpool.Pool
+    breaker *breaker
+    // injected write stalls for resilience testing (see chaos.go)
+    stallRate float64
+    stall     time.Duration
 }
 
 // StrategyRunRow represents a row in strategy_runs for API responses.
@@ -44,6 +51,16 @@ type StrategyEventRow struct {
     Details    json.RawMessage `json:"details,omitempty"`
 }
 
+// StrategyPerf is one leaderboard row aggregated from trade_closed events.
+type StrategyPerf struct {
+    Strategy    string  `json:"strategyKey"`
+    Trades      int     `json:"trades"`
+    TotalPips   float64 `json:"totalPips"`
+    TotalPnL    float64 `json:"totalPnl"`
+    WinRate     float64 `json:"winRate"`     // 0..1, winners are trades with pnl > 0
+    AvgHoldMins float64 `json:"avgHoldMins"` // over trades that recorded a hold time
+}
+
 // NewLogger creates a connection pool and ensures tables exist.
 func NewLogger(dsn string) (*Logger, error) {
     ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -52,7 +69,7 @@ func NewLogger(dsn string) (*Logger, error) {
     if err != nil {
         return nil, fmt.Errorf("pgxpool.New: %w", err)
     }
-    l := &Logger{pool: pool}
+    l := &Logger{pool: pool, breaker: newBreaker()}
     if err := l.ensureSchema(ctx); err != nil {
         pool.Close()
         return nil, err
@@ -114,6 +131,13 @@ func (l *Logger) ensureSchema(ctx context.Context) error {
             details jsonb
         )`,
         `create index if not exists idx_strategy_events_run on strategy_events(run_id, ts desc)`,
+        `create index if not exists idx_strategy_events_type_ts on strategy_events(event_type, ts)`,
+        // Retention purges select by ts
+        `create index if not exists idx_trades_ts on trades(ts)`,
+        // UpdateTradeStatus looks up the newest row by label
+        `create index if not exists idx_trades_label on trades(label, id desc)`,
+        `create index if not exists idx_logs_ts on logs(ts)`,
+        `create index if not exists idx_strategy_events_ts on strategy_events(ts)`,
     }
     for _, s := range stmts {
         if _, err := l.pool.Exec(ctx, s); err != nil {
@@ -135,49 +159,66 @@ func (l *Logger) LogTradeCloseRequested(orderID, instrument, side string) {
     l.insertTrade("close_requested", orderID, instrument, side, "CLOSE_ORDER", 0, 0, 0, 0, details)
 }
 
+// LogTradeCancelRequested records a request to cancel a working (unfilled) order.
+func (l *Logger) LogTradeCancelRequested(orderID, instrument, orderCmd string) {
+    details := map[string]any{"orderId": orderID}
+    l.insertTrade("cancel_requested", orderID, instrument, "", orderCmd, 0, 0, 0, 0, details)
+}
+
+// LogTradeModifyRequested records a request to change SL/TP of an open order.
+func (l *Logger) LogTradeModifyRequested(orderID, instrument, side string, sl, tp float64) {
+    details := map[string]any{"orderId": orderID}
+    l.insertTrade("modify_requested", orderID, instrument, side, "MODIFY_ORDER", 0, 0, sl, tp, details)
+}
+
+// UpdateTradeStatus applies a broker-reported outcome to the newest trades row with label.
+// What: Moves an order on from submitted/close_requested/... to the status the broker reported.
+// How: Sets status and merges result into details. Unlike the Log* writers it runs synchronously
+//      (still honouring the circuit breaker) so the caller learns whether the label was known.
+// Returns: false when no trade has that label.
+func (l *Logger) UpdateTradeStatus(ctx context.Context, label, status string, result any) (bool, error) {
+    if !l.breaker.allow() {
+        return false, ErrBreakerOpen
+    }
+    rj, _ := json.Marshal(result)
+    tag, err := l.pool.Exec(ctx, `update trades set status = $2, details = coalesce(details, '{}'::jsonb) || jsonb_build_object('result', $3::jsonb)
+        where id = (select id from trades where label = $1 order by id desc limit 1)`, label, status, rj)
+    if l.breaker.record(err) {
+        go l.probeUntilHealthy()
+    }
+    if err != nil {
+        return false, err
+    }
+    return tag.RowsAffected() > 0, nil
+}
+
 // LogEvent writes an arbitrary log row.
 func (l *Logger) LogEvent(level, category, message string, details any) {
-    go func() {
-        ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-        defer cancel()
-        var dj []byte
-        if details != nil {
-            dj, _ = json.Marshal(details)
-        }
-        _, _ = l.pool.Exec(ctx, `insert into logs(level, category, message, details) values($1,$2,$3,$4)`, level, category, message, dj)
-    }()
+    var dj []byte
+    if details != nil {
+        dj, _ = json.Marshal(details)
+    }
+    l.execAsync(`insert into logs(level, category, message, details) values($1,$2,$3,$4)`, level, category, message, dj)
 }
 
 // Strategy run/event logging
 func (l *Logger) LogStrategyRunStart(runID, instrument, period, strategyKey string, qty, atrMult float64, params map[string]float64) {
-    go func() {
-        ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-        defer cancel()
-        var pj []byte
-        if params != nil { pj, _ = json.Marshal(params) }
-        _, _ = l.pool.Exec(ctx, `insert into strategy_runs(run_id, instrument, period, strategy_key, qty, atr_mult, params, status)
-            values($1,$2,$3,$4,$5,$6,$7,'running')`, runID, instrument, period, strategyKey, qty, atrMult, pj)
-    }()
+    var pj []byte
+    if params != nil { pj, _ = json.Marshal(params) }
+    l.execAsync(`insert into strategy_runs(run_id, instrument, period, strategy_key, qty, atr_mult, params, status)
+        values($1,$2,$3,$4,$5,$6,$7,'running')`, runID, instrument, period, strategyKey, qty, atrMult, pj)
 }
 
 func (l *Logger) LogStrategyRunStop(runID, status string) {
-    go func() {
-        ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-        defer cancel()
-        if status == "" { status = "stopped" }
-        _, _ = l.pool.Exec(ctx, `update strategy_runs set stopped_at = now(), status=$2 where run_id=$1`, runID, status)
-    }()
+    if status == "" { status = "stopped" }
+    l.execAsync(`update strategy_runs set stopped_at = now(), status=$2 where run_id=$1`, runID, status)
 }
 
 func (l *Logger) LogStrategyEvent(runID, instrument, period, strategyKey, eventType, signal string, details any) {
-    go func() {
-        ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-        defer cancel()
-        var dj []byte
-        if details != nil { dj, _ = json.Marshal(details) }
-        _, _ = l.pool.Exec(ctx, `insert into strategy_events(run_id, instrument, period, strategy_key, event_type, signal, details)
-            values($1,$2,$3,$4,$5,$6,$7)`, runID, instrument, period, strategyKey, eventType, signal, dj)
-    }()
+    var dj []byte
+    if details != nil { dj, _ = json.Marshal(details) }
+    l.execAsync(`insert into strategy_events(run_id, instrument, period, strategy_key, event_type, signal, details)
+        values($1,$2,$3,$4,$5,$6,$7)`, runID, instrument, period, strategyKey, eventType, signal, dj)
 }
 
 // LogStrategyOrderFilled writes a standardized fill event.
@@ -194,51 +235,158 @@ func (l *Logger) LogStrategyTradeClosed(runID, instrument, period, strategyKey s
 
 
 // Queries for API
-func (l *Logger) QueryStrategyRuns(ctx context.Context, instrument, period string, limit int) ([]StrategyRunRow, error) {
+
+// QueryStrategyRuns returns one newest-first page of strategy runs, optionally filtered by instrument/period.
+// Params: before is the cursor returned with the previous page (zero for the newest runs).
+// Returns: the runs and the cursor for the next page, which is zero when there are no older runs.
+func (l *Logger) QueryStrategyRuns(ctx context.Context, instrument, period string, limit int, before PageCursor) ([]StrategyRunRow, PageCursor, error) {
     if limit <= 0 || limit > 200 { limit = 50 }
-    rows, err := l.pool.Query(ctx, `select run_id, started_at, stopped_at, instrument, period, strategy_key, coalesce(qty,0), coalesce(atr_mult,0), coalesce(params,'{}'::jsonb), status
+    // Fetch one extra row to know whether another page exists
+    rows, err := l.pool.Query(ctx, `select id, run_id, started_at, stopped_at, instrument, period, strategy_key, coalesce(qty,0), coalesce(atr_mult,0), coalesce(params,'{}'::jsonb), status
         from strategy_runs where ($1='' or instrument=$1) and ($2='' or period=$2)
-        order by started_at desc limit $3`, instrument, period, limit)
-    if err != nil { return nil, err }
+        and ($4::bigint = 0 or (started_at, id) < ($5::timestamptz, $4))
+        order by started_at desc, id desc limit $3`, instrument, period, limit+1, before.ID, before.TS)
+    if err != nil { return nil, PageCursor{}, err }
     defer rows.Close()
     res := []StrategyRunRow{}
+    var lastID int64
+    more := false
     for rows.Next() {
+        if len(res) == limit {
+            more = true
+            break
+        }
         var r StrategyRunRow
-        if err := rows.Scan(&r.RunID, &r.StartedAt, &r.StoppedAt, &r.Instrument, &r.Period, &r.Strategy, &r.Qty, &r.AtrMult, &r.Params, &r.Status); err != nil {
-            return nil, err
+        if err := rows.Scan(&lastID, &r.RunID, &r.StartedAt, &r.StoppedAt, &r.Instrument, &r.Period, &r.Strategy, &r.Qty, &r.AtrMult, &r.Params, &r.Status); err != nil {
+            return nil, PageCursor{}, err
         }
         res = append(res, r)
     }
-    return res, nil
+    if err := rows.Err(); err != nil { return nil, PageCursor{}, err }
+    if !more { return res, PageCursor{}, nil }
+    return res, PageCursor{TS: res[len(res)-1].StartedAt, ID: lastID}, nil
 }
 
-func (l *Logger) QueryStrategyEvents(ctx context.Context, runID string, limit int) ([]StrategyEventRow, error) {
+// QueryStrategyEvents returns one newest-first page of a run's events.
+// Params: before is the cursor returned with the previous page (zero for the newest events).
+// Returns: the events and the cursor for the next page, which is zero when there are no older events.
+func (l *Logger) QueryStrategyEvents(ctx context.Context, runID string, limit int, before PageCursor) ([]StrategyEventRow, PageCursor, error) {
     if limit <= 0 || limit > 1000 { limit = 200 }
-    rows, err := l.pool.Query(ctx, `select run_id, ts, instrument, period, strategy_key, event_type, coalesce(signal,''), coalesce(details,'{}'::jsonb)
-        from strategy_events where run_id=$1 order by ts desc limit $2`, runID, limit)
-    if err != nil { return nil, err }
+    rows, err := l.pool.Query(ctx, `select id, run_id, ts, instrument, period, strategy_key, event_type, coalesce(signal,''), coalesce(details,'{}'::jsonb)
+        from strategy_events where run_id=$1
+        and ($3::bigint = 0 or (ts, id) < ($4::timestamptz, $3))
+        order by ts desc, id desc limit $2`, runID, limit+1, before.ID, before.TS)
+    if err != nil { return nil, PageCursor{}, err }
     defer rows.Close()
     res := []StrategyEventRow{}
+    var lastID int64
+    more := false
     for rows.Next() {
+        if len(res) == limit {
+            more = true
+            break
+        }
         var r StrategyEventRow
-        if err := rows.Scan(&r.RunID, &r.TS, &r.Instrument, &r.Period, &r.Strategy, &r.EventType, &r.Signal, &r.Details); err != nil {
+        if err := rows.Scan(&lastID, &r.RunID, &r.TS, &r.Instrument, &r.Period, &r.Strategy, &r.EventType, &r.Signal, &r.Details); err != nil {
+            return nil, PageCursor{}, err
+        }
+        res = append(res, r)
+    }
+    if err := rows.Err(); err != nil { return nil, PageCursor{}, err }
+    if !more { return res, PageCursor{}, nil }
+    return res, PageCursor{TS: res[len(res)-1].TS, ID: lastID}, nil
+}
+
+// QueryStrategyLeaderboard aggregates closed trades per strategy for comparison.
+// What: Trade count, total pips/PnL, win rate, and average hold time per strategy_key.
+// How: Single grouped query over trade_closed events (served by idx_strategy_events_type_ts);
+//      events logged before pnlPips/holdMins were recorded count as 0 pips and are left out of the hold average.
+// Params: ctx, sinceMs only include trades closed at or after this unix-millis time (0 = all time)
+// Returns: rows ordered by total pips, best first.
+func (l *Logger) QueryStrategyLeaderboard(ctx context.Context, sinceMs int64) ([]StrategyPerf, error) {
+    since := time.UnixMilli(sinceMs)
+    rows, err := l.pool.Query(ctx, `select strategy_key, count(*),
+            coalesce(sum((details->>'pnlPips')::numeric), 0)::float8,
+            coalesce(sum((details->>'pnl')::numeric), 0)::float8,
+            coalesce(avg(case when (details->>'pnl')::numeric > 0 then 1 else 0 end), 0)::float8,
+            coalesce(avg((details->>'holdMins')::numeric), 0)::float8
+        from strategy_events where event_type = 'trade_closed' and ts >= $1
+        group by strategy_key order by 3 desc`, since)
+    if err != nil { return nil, err }
+    defer rows.Close()
+    res := []StrategyPerf{}
+    for rows.Next() {
+        var r StrategyPerf
+        if err := rows.Scan(&r.Strategy, &r.Trades, &r.TotalPips, &r.TotalPnL, &r.WinRate, &r.AvgHoldMins); err != nil {
             return nil, err
         }
         res = append(res, r)
     }
-    return res, nil
+    return res, rows.Err()
 }
 
-func (l *Logger) insertTrade(status, label, instrument, side, orderCmd string, amount, price, sl, tp float64, details any) {
-    go func() {
-        ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-        defer cancel()
-        var dj []byte
-        if details != nil { dj, _ = json.Marshal(details) }
-        _, _ = l.pool.Exec(ctx,
-            `insert into trades(label, instrument, side, order_cmd, amount, price, sl, tp, status, details)
-             values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
-            label, instrument, side, orderCmd, amount, price, sl, tp, status, dj,
+// ExportTradesCSV streams the trades table as CSV for record-keeping.
+// What: Trade journal export between from and to (zero values mean unbounded).
+// How: Iterates the query row-by-row and writes each record immediately, flushing periodically,
+//      so large histories are never buffered in memory. Close PnL is left-joined from the
+//      strategy trade_closed events by label when available.
+// Params: ctx, w destination writer, from/to time bounds on trades.ts
+// Returns: error on query, scan, or write failure.
+func (l *Logger) ExportTradesCSV(ctx context.Context, w io.Writer, from, to time.Time) error {
+    var fromArg, toArg *time.Time
+    if !from.IsZero() { fromArg = &from }
+    if !to.IsZero() { toArg = &to }
+    rows, err := l.pool.Query(ctx, `select t.id, t.ts, coalesce(t.label,''), coalesce(t.instrument,''), coalesce(t.side,''), coalesce(t.order_cmd,''),
+            coalesce(t.amount,0), coalesce(t.price,0), coalesce(t.sl,0), coalesce(t.tp,0), coalesce(t.status,''),
+            (select (e.details->>'pnl')::numeric from strategy_events e
+              where e.event_type='trade_closed' and e.details->>'label' = t.label
+              order by e.ts desc limit 1)
+        from trades t
+        where ($1::timestamptz is null or t.ts >= $1) and ($2::timestamptz is null or t.ts < $2)
+        order by t.ts asc, t.id asc`, fromArg, toArg)
+    if err != nil { return fmt.Errorf("ExportTradesCSV query: %w", err) }
+    defer rows.Close()
+
+    cw := csv.NewWriter(w)
+    header := []string{"id", "ts", "label", "instrument", "side", "order_cmd", "amount", "price", "sl", "tp", "status", "close_pnl"}
+    if err := cw.Write(header); err != nil { return err }
+    n := 0
+    for rows.Next() {
+        var (
+            id                                       int64
+            ts                                       time.Time
+            label, instrument, side, orderCmd, status string
+            amount, price, sl, tp                    float64
+            pnl                                      *float64
         )
-    }()
+        if err := rows.Scan(&id, &ts, &label, &instrument, &side, &orderCmd, &amount, &price, &sl, &tp, &status, &pnl); err != nil {
+            return fmt.Errorf("ExportTradesCSV scan: %w", err)
+        }
+        pnlStr := ""
+        if pnl != nil { pnlStr = strconv.FormatFloat(*pnl, 'f', -1, 64) }
+        rec := []string{
+            strconv.FormatInt(id, 10), ts.UTC().Format(time.RFC3339Nano), label, instrument, side, orderCmd,
+            strconv.FormatFloat(amount, 'f', -1, 64), strconv.FormatFloat(price, 'f', -1, 64),
+            strconv.FormatFloat(sl, 'f', -1, 64), strconv.FormatFloat(tp, 'f', -1, 64), status, pnlStr,
+        }
+        if err := cw.Write(rec); err != nil { return err }
+        n++
+        if n%500 == 0 {
+            cw.Flush()
+            if err := cw.Error(); err != nil { return err }
+        }
+    }
+    if err := rows.Err(); err != nil { return fmt.Errorf("ExportTradesCSV rows: %w", err) }
+    cw.Flush()
+    return cw.Error()
+}
+
+func (l *Logger) insertTrade(status, label, instrument, side, orderCmd string, amount, price, sl, tp float64, details any) {
+    var dj []byte
+    if details != nil { dj, _ = json.Marshal(details) }
+    l.execAsync(
+        `insert into trades(label, instrument, side, order_cmd, amount, price, sl, tp, status, details)
+         values($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`,
+        label, instrument, side, orderCmd, amount, price, sl, tp, status, dj,
+    )
 }
@@ -0,0 +1,130 @@
+package db
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "slices"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// RetentionTables are the append-only tables that can be purged by age (all have id and ts columns).
+var RetentionTables = []string{"trades", "logs", "strategy_events"}
+
+// purgeBatchSize is the number of rows deleted per statement, so each delete holds its locks briefly.
+const purgeBatchSize = 5000
+
+// ParseRetention parses per-table retention from a config string.
+// What: Config format "table:age" entries separated by commas, e.g. "logs:30d,strategy_events:90d".
+// How: age is a Go duration or a whole number of days with a "d" suffix. Tables left out are kept forever.
+// Returns: retention keyed by table name.
+func ParseRetention(v string) (map[string]time.Duration, error) {
+    out := make(map[string]time.Duration)
+    for _, entry := range strings.Split(v, ",") {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        table, age, ok := strings.Cut(entry, ":")
+        table = strings.ToLower(strings.TrimSpace(table))
+        if !ok {
+            return nil, fmt.Errorf("retention %q: want table:age", entry)
+        }
+        if !slices.Contains(RetentionTables, table) {
+            return nil, fmt.Errorf("retention %q: unsupported table %q", entry, table)
+        }
+        d, err := parseAge(strings.TrimSpace(age))
+        if err != nil || d <= 0 {
+            return nil, fmt.Errorf("retention %q: bad age %q", entry, age)
+        }
+        out[table] = d
+    }
+    return out, nil
+}
+
+// parseAge accepts a Go duration or "<n>d" days.
+func parseAge(s string) (time.Duration, error) {
+    if days, ok := strings.CutSuffix(s, "d"); ok {
+        n, err := strconv.Atoi(days)
+        if err != nil {
+            return 0, err
+        }
+        return time.Duration(n) * 24 * time.Hour, nil
+    }
+    return time.ParseDuration(s)
+}
+
+// PurgeOlderThan deletes rows of table whose ts is older than d.
+// What: Keeps the trades, logs, and strategy_events tables from growing forever.
+// How: Deletes in batches of purgeBatchSize until a batch comes back short, so a large
+//      backlog never holds one long lock; ctx cancellation stops between batches.
+// Params: ctx, table one of RetentionTables, d age cutoff (> 0)
+// Returns: the number of rows deleted, and an error for an unknown table or a failed delete.
+func (l *Logger) PurgeOlderThan(ctx context.Context, table string, d time.Duration) (int64, error) {
+    if !slices.Contains(RetentionTables, table) {
+        return 0, fmt.Errorf("PurgeOlderThan: unsupported table %q", table)
+    }
+    if d <= 0 {
+        return 0, fmt.Errorf("PurgeOlderThan: age must be positive, got %s", d)
+    }
+    cutoff := time.Now().Add(-d)
+    // table is from the allow-list above, so it is safe to interpolate
+    query := fmt.Sprintf(`delete from %[1]s where id in (select id from %[1]s where ts < $1 limit $2)`, table)
+    var total int64
+    for {
+        if err := ctx.Err(); err != nil {
+            return total, err
+        }
+        tag, err := l.pool.Exec(ctx, query, cutoff, purgeBatchSize)
+        if err != nil {
+            return total, fmt.Errorf("PurgeOlderThan %s: %w", table, err)
+        }
+        total += tag.RowsAffected()
+        if tag.RowsAffected() < purgeBatchSize {
+            return total, nil
+        }
+    }
+}
+
+// Purge applies retention to each listed table and returns the rows deleted per table.
+// A failing table is logged and skipped; the first error is returned after the others ran.
+func (l *Logger) Purge(ctx context.Context, retention map[string]time.Duration) (map[string]int64, error) {
+    out := make(map[string]int64, len(retention))
+    var firstErr error
+    for _, table := range RetentionTables {
+        d, ok := retention[table]
+        if !ok {
+            continue
+        }
+        n, err := l.PurgeOlderThan(ctx, table, d)
+        out[table] = n
+        if err != nil {
+            log.Printf("⚠️ Retention purge of %s failed after %d rows: %v", table, n, err)
+            if firstErr == nil { firstErr = err }
+            continue
+        }
+        if n > 0 {
+            log.Printf("🧹 Purged %d rows older than %s from %s", n, d, table)
+        }
+    }
+    return out, firstErr
+}
+
+// RunRetention purges by retention every interval until ctx is done. An empty retention returns at once.
+func (l *Logger) RunRetention(ctx context.Context, retention map[string]time.Duration, interval time.Duration) {
+    if len(retention) == 0 || interval <= 0 {
+        return
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for {
+        l.Purge(ctx, retention)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
@@ -0,0 +1,21 @@
+package db
+
+import (
+    "testing"
+    "time"
+)
+
+func TestParseRetention(t *testing.T) {
+    got, err := ParseRetention(" logs:30d, strategy_events:2160h ")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got["logs"] != 30*24*time.Hour || got["strategy_events"] != 90*24*time.Hour || len(got) != 2 {
+        t.Fatalf("got %v", got)
+    }
+    for _, bad := range []string{"logs", "strategy_runs:30d", "trades:0d", "trades:soon"} {
+        if _, err := ParseRetention(bad); err == nil {
+            t.Fatalf("%q: expected error", bad)
+        }
+    }
+}
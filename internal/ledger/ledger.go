@@ -1,21 +1,33 @@
 package ledger
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	"go-trader/internal/amqp"
+	"go-trader/internal/broadcast"
+	"go-trader/internal/clock"
+	"go-trader/internal/logutil"
 	"go-trader/internal/state"
 )
 
+// histRequestCooldown is the minimum interval between last-N-bars historical requests for one
+// instrument, shared by startup, the health checker, and frontend requests.
+const histRequestCooldown = 30 * time.Second
+
+// staleWarnThrottle limits how often stale/missing tick warnings repeat per instrument.
+const staleWarnThrottle = 5 * time.Minute
+
 // CentralLedger coordinates all data sources and maintains the trading system's state
 type CentralLedger struct {
 	// Core components
 	stateManager   *state.StateManager
 	messageHandler *amqp.MessageHandler
-	publisher      *amqp.Publisher
-	hub            interface{} // Will be set to websocket.Hub
+	publisher      HistoricalRequester
+	hub            broadcast.Broadcaster // WebSocket hub, set via SetHub; nil until then
 
 	// Configuration
 	instrumentList        []string
@@ -30,7 +42,32 @@ type CentralLedger struct {
 	startTime         time.Time
 	messagesProcessed map[string]int64
 	lastHistRequest   map[string]time.Time
+	// gapRequests records, per "instrument|period", the response a gap re-request was sent for
+	gapRequests map[string]time.Time
 	mu                sync.RWMutex
+
+	// warnLog throttles repetitive data-consistency warnings
+	warnLog *logutil.ThrottledLogger
+
+	// clock drives request cooldowns (real clock unless injected)
+	clock clock.Clock
+}
+
+// Alert is a structured operator warning pushed to WebSocket clients.
+type Alert struct {
+	Type       string `json:"type"`     // always "ALERT"
+	Severity   string `json:"severity"` // info | warn | error
+	Code       string `json:"code"`     // e.g. stale_ticks, no_ticks
+	Instrument string `json:"instrument,omitempty"`
+	Message    string `json:"message"`
+	At         int64  `json:"at"` // unix millis
+}
+
+// HistoricalRequester publishes historical bar requests to the JForex requester; *amqp.Publisher
+// implements it.
+type HistoricalRequester interface {
+	RequestHistoricalBars(instrument string, barsCount int) error
+	RequestHistoricalRange(instrument, period string, fromMs, toMs int64) error
 }
 
 // LedgerCommand represents commands that can be sent to the ledger
@@ -54,8 +91,8 @@ type LedgerStats struct {
 func NewCentralLedger(
 	stateManager *state.StateManager,
 	messageHandler *amqp.MessageHandler,
-	publisher *amqp.Publisher,
-	hub interface{},
+	publisher HistoricalRequester,
+	hub broadcast.Broadcaster,
 	instrumentList []string,
 	historicalBarsToFetch int,
 ) *CentralLedger {
@@ -72,6 +109,9 @@ func NewCentralLedger(
 		startTime:             time.Now(),
 		messagesProcessed:     make(map[string]int64),
 		lastHistRequest:       make(map[string]time.Time),
+		gapRequests:           make(map[string]time.Time),
+		warnLog:               logutil.NewThrottledLogger(staleWarnThrottle),
+		clock:                 clock.Real(),
 	}
 }
 
@@ -140,17 +180,18 @@ func (cl *CentralLedger) GetStats() LedgerStats {
 	// Copy instrument list
 	copy(stats.ActiveInstruments, cl.instrumentList)
 
-	// Get current data counts from state manager
+	// Get current data counts from one coherent state snapshot
+	snap := cl.stateManager.Snapshot()
 	for _, instrument := range cl.instrumentList {
-		stats.TickCounts[instrument] = len(cl.stateManager.GetTicks(instrument))
+		stats.TickCounts[instrument] = len(snap.Ticks[instrument])
 
 		stats.BarCounts[instrument] = make(map[string]int)
 		stats.HistoricalBarCounts[instrument] = make(map[string]int)
 
 		periods := []string{"TEN_SECS", "ONE_MIN", "FIVE_MINS", "FIFTEEN_MINS", "ONE_HOUR", "FOUR_HOURS", "DAILY"}
 		for _, period := range periods {
-			stats.BarCounts[instrument][period] = len(cl.stateManager.GetBars(instrument, period))
-			stats.HistoricalBarCounts[instrument][period] = len(cl.stateManager.GetHistoricalBars(instrument, period))
+			stats.BarCounts[instrument][period] = len(snap.Bars[instrument][period])
+			stats.HistoricalBarCounts[instrument][period] = len(snap.HistoricalBars[instrument][period])
 		}
 	}
 
@@ -189,9 +230,9 @@ func (cl *CentralLedger) processCommand(cmd LedgerCommand) {
 	switch cmd.Type {
 	case "REQUEST_HISTORICAL_DATA":
 		if instrument, ok := cmd.Data.(string); ok {
-			log.Printf("Processing historical data request for %s", instrument)
-			if err := cl.publisher.RequestHistoricalBars(instrument, cl.historicalBarsToFetch); err != nil {
-				log.Printf("Failed to request historical data for %s: %v", instrument, err)
+			if sent, last := cl.requestHistorical(instrument); !sent && !last.IsZero() {
+				log.Printf("Historical data request for %s coalesced; last request sent %v ago",
+					instrument, cl.clock.Now().Sub(last).Truncate(time.Second))
 			}
 		}
 
@@ -250,18 +291,41 @@ func (cl *CentralLedger) checkDataConsistency() {
 		// Check if we have recent tick data (within last 5 minutes)
 		if len(ticks) > 0 {
 			lastTick := ticks[len(ticks)-1]
-			timeSinceLastTick := time.Since(time.UnixMilli(lastTick.Timestamp))
+			timeSinceLastTick := cl.clock.Now().Sub(time.UnixMilli(lastTick.Timestamp))
 
 			if timeSinceLastTick > 5*time.Minute {
-				log.Printf("WARNING: Stale tick data for %s - last tick %v ago",
-					instrument, timeSinceLastTick.Truncate(time.Second))
+				if cl.warnLog.Printf("stale_ticks:"+instrument, "WARNING: Stale tick data for %s - last tick %v ago",
+					instrument, timeSinceLastTick.Truncate(time.Second)) {
+					cl.sendAlert("warn", "stale_ticks", instrument,
+						fmt.Sprintf("Stale tick data for %s - last tick %v ago", instrument, timeSinceLastTick.Truncate(time.Second)))
+				}
 			}
 		} else {
-			log.Printf("WARNING: No tick data available for %s", instrument)
+			if cl.warnLog.Printf("no_ticks:"+instrument, "WARNING: No tick data available for %s", instrument) {
+				cl.sendAlert("warn", "no_ticks", instrument, fmt.Sprintf("No tick data available for %s", instrument))
+			}
 		}
 	}
 }
 
+// sendAlert pushes an ALERT message to WebSocket clients when a hub is attached.
+// Alerts share the warning log throttle, so each one matches a logged warning.
+func (cl *CentralLedger) sendAlert(severity, code, instrument, message string) {
+	cl.mu.RLock()
+	hub := cl.hub
+	cl.mu.RUnlock()
+	if hub == nil {
+		return
+	}
+	data, err := json.Marshal(Alert{Type: "ALERT", Severity: severity, Code: code, Instrument: instrument,
+		Message: message, At: cl.clock.Now().UnixMilli()})
+	if err != nil {
+		log.Printf("Error marshalling alert: %s", err)
+		return
+	}
+	hub.Broadcast(data)
+}
+
 // logCurrentState logs a summary of the current ledger state
 func (cl *CentralLedger) logCurrentState() {
 	totalTicks := 0
@@ -288,16 +352,73 @@ func (cl *CentralLedger) initializeHistoricalData() error {
 		len(cl.instrumentList), cl.historicalBarsToFetch)
 
 	for _, instrument := range cl.instrumentList {
-		if err := cl.publisher.RequestHistoricalBars(instrument, cl.historicalBarsToFetch); err != nil {
-			log.Printf("Failed to request historical data for %s: %v", instrument, err)
-			continue
-		}
-		log.Printf("Requested %d historical bars for %s", cl.historicalBarsToFetch, instrument)
+		cl.requestHistorical(instrument)
 	}
 
 	return nil
 }
 
+// requestHistorical publishes a last-N-bars request for instrument unless one was sent within
+// histRequestCooldown, so startup, health-check, and frontend requests for the same instrument
+// coalesce instead of loading the requester several times over. A failed publish does not start
+// the cooldown.
+// Returns: whether a request was sent and, when it was coalesced, the time of the earlier request.
+func (cl *CentralLedger) requestHistorical(instrument string) (bool, time.Time) {
+	sent, last, err := cl.throttleHistorical(instrument, func() error {
+		return cl.publisher.RequestHistoricalBars(instrument, cl.historicalBarsToFetch)
+	})
+	if err != nil {
+		log.Printf("Failed to request historical data for %s: %v", instrument, err)
+		return false, time.Time{}
+	}
+	if sent {
+		log.Printf("Requested %d historical bars for %s", cl.historicalBarsToFetch, instrument)
+	}
+	return sent, last
+}
+
+// throttleHistorical calls send unless a historical request for instrument was sent within
+// histRequestCooldown, in which case it returns the earlier request's time. A failed send does not
+// start the cooldown.
+func (cl *CentralLedger) throttleHistorical(instrument string, send func() error) (bool, time.Time, error) {
+	now := cl.clock.Now()
+	cl.mu.Lock()
+	last := cl.lastHistRequest[instrument]
+	if !last.IsZero() && now.Sub(last) < histRequestCooldown {
+		cl.mu.Unlock()
+		return false, last, nil
+	}
+	cl.lastHistRequest[instrument] = now
+	cl.mu.Unlock()
+
+	if err := send(); err != nil {
+		// Nothing was sent, so the next request must not be coalesced with this one
+		cl.mu.Lock()
+		if cl.lastHistRequest[instrument].Equal(now) {
+			if last.IsZero() {
+				delete(cl.lastHistRequest, instrument)
+			} else {
+				cl.lastHistRequest[instrument] = last
+			}
+		}
+		cl.mu.Unlock()
+		return false, time.Time{}, err
+	}
+	return true, time.Time{}, nil
+}
+
+// HistoricalRequestTimes returns when a historical request (last-N bars or a gap range) was last
+// sent per instrument.
+func (cl *CentralLedger) HistoricalRequestTimes() map[string]time.Time {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	out := make(map[string]time.Time, len(cl.lastHistRequest))
+	for instrument, t := range cl.lastHistRequest {
+		out[instrument] = t
+	}
+	return out
+}
+
 // getTotalMessageCount returns the total number of messages processed
 func (cl *CentralLedger) getTotalMessageCount() int64 {
 	cl.mu.RLock()
@@ -319,19 +440,74 @@ func (cl *CentralLedger) RequestHistoricalDataForInstrument(instrument string) {
 	})
 }
 
-// SetHub sets the WebSocket hub reference
-func (cl *CentralLedger) SetHub(hub interface{}) {
+// SetHub sets the WebSocket hub used for alerts
+func (cl *CentralLedger) SetHub(hub broadcast.Broadcaster) {
 	cl.mu.Lock()
 	defer cl.mu.Unlock()
 	cl.hub = hub
 }
 
+// SetClock injects the clock used for request cooldowns. Call before Start.
+func (cl *CentralLedger) SetClock(clk clock.Clock) {
+	if clk != nil {
+		cl.clock = clk
+	}
+}
+
+// SetWarnThrottle sets the minimum interval between repeated stale-data warnings (0 disables throttling).
+func (cl *CentralLedger) SetWarnThrottle(interval time.Duration) {
+	cl.warnLog.SetInterval(interval)
+}
+
 // GetInstruments returns the list of active instruments
 func (cl *CentralLedger) GetInstruments() []string {
 	return cl.instrumentList
 }
 
 
+// requestSequenceGaps re-requests the missing bars of settled, incomplete historical responses.
+// What: Catches partial sends from the JForex requester (e.g. sequences 1-150 of 200) that still
+//       leave enough bars to pass the count check.
+// How: Once per response, asks for the time range spanning the gaps for that period only; a missing
+//      newest tail is requested up to now. Range requests share the instrument's historical-request
+//      cooldown, so gaps in several periods are asked for one per cooldown, on later passes.
+func (cl *CentralLedger) requestSequenceGaps(instrument string, periods []string) {
+	now := cl.clock.Now()
+	for _, p := range periods {
+		st, ok := cl.stateManager.HistoricalSequenceStatus(instrument, p, now)
+		if !ok || !st.Settled || len(st.Missing) == 0 || st.GapFromMs == 0 {
+			continue
+		}
+		key := instrument + "|" + p
+		cl.mu.RLock()
+		requested := cl.gapRequests[key].Equal(st.LastArrival)
+		cl.mu.RUnlock()
+		if requested {
+			continue
+		}
+
+		toMs := st.GapToMs
+		if toMs == 0 {
+			toMs = now.UnixMilli()
+		}
+		sent, _, err := cl.throttleHistorical(instrument, func() error {
+			return cl.publisher.RequestHistoricalRange(instrument, p, st.GapFromMs, toMs)
+		})
+		if err != nil {
+			log.Printf("HealthCheck: failed to re-request %s %s gap: %v", instrument, p, err)
+			continue
+		}
+		if !sent {
+			continue
+		}
+		cl.mu.Lock()
+		cl.gapRequests[key] = st.LastArrival
+		cl.mu.Unlock()
+		log.Printf("HealthCheck: %s %s historical response %.1f%% complete (%d of %d bars); re-requested %d..%d",
+			instrument, p, st.Completeness, st.Received, st.Expected, st.GapFromMs, toMs)
+	}
+}
+
 // startLedgerHealthChecker periodically ensures we have the desired number of
 // historical bars for each instrument/period and re-requests if missing.
 func (cl *CentralLedger) startLedgerHealthChecker() {
@@ -341,7 +517,6 @@ func (cl *CentralLedger) startLedgerHealthChecker() {
 		log.Println("Ledger health checker started")
 		ticker := time.NewTicker(15 * time.Second)
 		defer ticker.Stop()
-		cooldown := 30 * time.Second
 		periods := []string{"TEN_SECS", "ONE_MIN", "FIVE_MINS", "FIFTEEN_MINS", "ONE_HOUR", "FOUR_HOURS", "DAILY"}
 		for {
 			select {
@@ -350,31 +525,26 @@ func (cl *CentralLedger) startLedgerHealthChecker() {
 				return
 			case <-ticker.C:
 				for _, instrument := range cl.instrumentList {
-					// If any period is short, request for this instrument (requester sends all periods)
-					needs := false
-					for _, p := range periods {
-						if len(cl.stateManager.GetHistoricalBars(instrument, p)) < cl.historicalBarsToFetch {
-							needs = true
-							break
-						}
-					}
-					if !needs {
-						continue
-					}
-					cl.mu.Lock()
-					last := cl.lastHistRequest[instrument]
-					if time.Since(last) < cooldown {
-						cl.mu.Unlock()
-						continue
-					}
-					cl.lastHistRequest[instrument] = time.Now()
-					cl.mu.Unlock()
-					log.Printf("HealthCheck: %s missing historical bars; requesting %d bars", instrument, cl.historicalBarsToFetch)
-					if err := cl.publisher.RequestHistoricalBars(instrument, cl.historicalBarsToFetch); err != nil {
-						log.Printf("HealthCheck: failed to request historical bars for %s: %v", instrument, err)
-					}
+					cl.checkHistoricalHealth(instrument, periods)
 				}
 			}
 		}
 	}()
 }
+
+// checkHistoricalHealth requests historical bars for instrument when any period holds fewer than
+// wanted, otherwise re-requests sequence gaps. A period whose bar buffer is smaller than
+// historicalBarsToFetch is full at its buffer size.
+func (cl *CentralLedger) checkHistoricalHealth(instrument string, periods []string) {
+	// If any period is short, request for this instrument (requester sends all periods)
+	for _, p := range periods {
+		want := min(cl.historicalBarsToFetch, cl.stateManager.BarCapacity(p))
+		if len(cl.stateManager.GetHistoricalBars(instrument, p)) < want {
+			if sent, _ := cl.requestHistorical(instrument); sent {
+				log.Printf("HealthCheck: %s missing historical bars; requested %d bars", instrument, cl.historicalBarsToFetch)
+			}
+			return
+		}
+	}
+	cl.requestSequenceGaps(instrument, periods)
+}
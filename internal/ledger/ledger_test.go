@@ -0,0 +1,147 @@
+package ledger
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go-trader/internal/clock"
+	"go-trader/internal/state"
+)
+
+// fakeRequester records historical requests; each of the first fail requests returns an error.
+type fakeRequester struct {
+	mu     sync.Mutex
+	fail   int
+	bars   []string
+	ranges []string
+}
+
+func (f *fakeRequester) RequestHistoricalBars(instrument string, barsCount int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("channel/connection is not open")
+	}
+	f.bars = append(f.bars, instrument)
+	return nil
+}
+
+func (f *fakeRequester) RequestHistoricalRange(instrument, period string, fromMs, toMs int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ranges = append(f.ranges, instrument+"|"+period)
+	return nil
+}
+
+func (f *fakeRequester) barRequests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.bars)
+}
+
+func TestFailedHistoricalRequestDoesNotStartCooldown(t *testing.T) {
+	pub := &fakeRequester{fail: 1}
+	cl := NewCentralLedger(state.NewStateManager(), nil, pub, nil, []string{"EURUSD"}, 200)
+	clk := clock.NewFake(time.UnixMilli(1717000000000))
+	cl.SetClock(clk)
+
+	if sent, last := cl.requestHistorical("EURUSD"); sent || !last.IsZero() {
+		t.Fatalf("failed publish: sent=%v last=%v, want not sent and not coalesced", sent, last)
+	}
+	if times := cl.HistoricalRequestTimes(); len(times) != 0 {
+		t.Fatalf("request times %v after a failed publish, want none", times)
+	}
+	clk.Advance(time.Second)
+	if sent, _ := cl.requestHistorical("EURUSD"); !sent {
+		t.Fatal("retry after a failed publish was coalesced")
+	}
+	if sent, last := cl.requestHistorical("EURUSD"); sent || !last.Equal(clk.Now()) {
+		t.Fatalf("request within the cooldown: sent=%v last=%v, want coalesced with %v", sent, last, clk.Now())
+	}
+	if n := pub.barRequests(); n != 1 {
+		t.Fatalf("%d requests published, want 1", n)
+	}
+}
+
+// addResponseWithGap stores a historical response of n EURUSD bars for period, sent oldest first,
+// in which sequence missing never arrived.
+func addResponseWithGap(sm *state.StateManager, period string, n, missing int, stepMs int64) {
+	for i := 1; i <= n; i++ {
+		if seq := n + 1 - i; seq != missing {
+			sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: period, BarEndTimestamp: int64(i) * stepMs, Sequence: seq})
+		}
+	}
+}
+
+func TestHealthCheckAcceptsPeriodsFullAtBufferCapacity(t *testing.T) {
+	const fetch = 20
+	periods := []string{"ONE_MIN", "DAILY"}
+	sm := state.NewStateManager(state.StateManagerConfig{BarBufferSizes: map[string]int{"DAILY": 5}})
+	for i := 1; i <= fetch; i++ {
+		sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: int64(i) * 60_000})
+	}
+	addResponseWithGap(sm, "DAILY", fetch, 3, 86_400_000)
+	if n := len(sm.GetHistoricalBars("EURUSD", "DAILY")); n != 5 {
+		t.Fatalf("%d DAILY bars kept, want the buffer's 5", n)
+	}
+
+	pub := &fakeRequester{}
+	cl := NewCentralLedger(sm, nil, pub, nil, []string{"EURUSD"}, fetch)
+	clk := clock.NewFake(time.Now().Add(time.Minute)) // past the sequence settle window
+	cl.SetClock(clk)
+	for i := 0; i < 3; i++ {
+		cl.checkHistoricalHealth("EURUSD", periods)
+		clk.Advance(time.Minute) // past the historical request cooldown
+	}
+
+	if n := pub.barRequests(); n != 0 {
+		t.Fatalf("%d historical requests for periods full at their buffer size, want none", n)
+	}
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	if len(pub.ranges) != 1 || pub.ranges[0] != "EURUSD|DAILY" {
+		t.Fatalf("gap requests %v, want one for EURUSD|DAILY", pub.ranges)
+	}
+}
+
+func TestGapRequestsShareHistoricalCooldown(t *testing.T) {
+	const fetch = 20
+	periods := []string{"ONE_MIN", "DAILY"}
+	sm := state.NewStateManager(state.StateManagerConfig{BarBufferSizes: map[string]int{"ONE_MIN": 5, "DAILY": 5}})
+	addResponseWithGap(sm, "ONE_MIN", fetch, 3, 60_000)
+	addResponseWithGap(sm, "DAILY", fetch, 3, 86_400_000)
+
+	pub := &fakeRequester{}
+	cl := NewCentralLedger(sm, nil, pub, nil, []string{"EURUSD"}, fetch)
+	clk := clock.NewFake(time.Now().Add(time.Minute)) // past the sequence settle window
+	cl.SetClock(clk)
+	ranges := func() []string {
+		pub.mu.Lock()
+		defer pub.mu.Unlock()
+		return append([]string(nil), pub.ranges...)
+	}
+
+	cl.checkHistoricalHealth("EURUSD", periods)
+	if got := ranges(); len(got) != 1 || got[0] != "EURUSD|ONE_MIN" {
+		t.Fatalf("first pass requested %v, want only the ONE_MIN gap", got)
+	}
+	clk.Advance(histRequestCooldown / 2)
+	cl.checkHistoricalHealth("EURUSD", periods)
+	if got := ranges(); len(got) != 1 {
+		t.Fatalf("pass within the cooldown requested %v, want nothing new", got)
+	}
+	if sent, _ := cl.requestHistorical("EURUSD"); sent {
+		t.Fatal("last-N request within the cooldown of a gap request was sent")
+	}
+
+	clk.Advance(histRequestCooldown)
+	cl.checkHistoricalHealth("EURUSD", periods)
+	clk.Advance(histRequestCooldown)
+	cl.checkHistoricalHealth("EURUSD", periods)
+	if got := ranges(); len(got) != 2 || got[1] != "EURUSD|DAILY" {
+		t.Fatalf("requested %v, want each gap once: ONE_MIN then DAILY", got)
+	}
+}
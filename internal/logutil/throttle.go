@@ -0,0 +1,72 @@
+package logutil
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ThrottledLogger rate-limits repetitive log lines per key.
+// What: Keeps high-frequency warnings (e.g. "channel full") from flooding the logs.
+// How: The first message for a key is logged; further messages within the interval are counted
+//      and reported as a suppressed-count suffix on the next message that gets through.
+// Params: NewThrottledLogger(interval) to construct; interval <= 0 disables throttling.
+// Returns: *ThrottledLogger safe for concurrent use.
+type ThrottledLogger struct {
+	interval time.Duration
+	mu       sync.Mutex
+	entries  map[string]*throttleEntry
+}
+
+type throttleEntry struct {
+	lastLogged time.Time
+	suppressed int
+}
+
+// NewThrottledLogger creates a logger that emits at most one line per key per interval.
+func NewThrottledLogger(interval time.Duration) *ThrottledLogger {
+	return &ThrottledLogger{interval: interval, entries: make(map[string]*throttleEntry)}
+}
+
+// SetInterval changes the throttle window at runtime.
+func (t *ThrottledLogger) SetInterval(interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interval = interval
+}
+
+// Printf logs the formatted message unless another message with the same key was logged
+// within the interval, in which case it is counted and suppressed. It reports whether the
+// message was logged, so callers can tie side effects (e.g. alerts) to the same throttle.
+func (t *ThrottledLogger) Printf(key, format string, args ...any) bool {
+	t.mu.Lock()
+	if t.interval <= 0 {
+		t.mu.Unlock()
+		log.Printf(format, args...)
+		return true
+	}
+	now := time.Now()
+	e, ok := t.entries[key]
+	if !ok {
+		e = &throttleEntry{}
+		t.entries[key] = e
+	}
+	if !e.lastLogged.IsZero() && now.Sub(e.lastLogged) < t.interval {
+		e.suppressed++
+		t.mu.Unlock()
+		return false
+	}
+	suppressed := e.suppressed
+	e.suppressed = 0
+	e.lastLogged = now
+	interval := t.interval
+	t.mu.Unlock()
+
+	msg := fmt.Sprintf(format, args...)
+	if suppressed > 0 {
+		msg = fmt.Sprintf("%s (suppressed %d similar messages in last %s)", msg, suppressed, interval)
+	}
+	log.Print(msg)
+	return true
+}
@@ -0,0 +1,186 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decode is a test-only MessagePack decoder producing the same shapes as json.Unmarshal into
+// any: numbers become float64, maps map[string]any, arrays []any.
+func decode(b []byte) (any, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("unexpected end")
+	}
+	tag, b := b[0], b[1:]
+	n := func(size int) (int, []byte) {
+		switch size {
+		case 1:
+			return int(b[0]), b[1:]
+		case 2:
+			return int(binary.BigEndian.Uint16(b)), b[2:]
+		}
+		return int(binary.BigEndian.Uint32(b)), b[4:]
+	}
+	switch {
+	case tag <= 0x7f:
+		return float64(tag), b, nil
+	case tag >= 0xe0:
+		return float64(int8(tag)), b, nil
+	case tag&0xf0 == 0x80:
+		return decodeMap(int(tag&0x0f), b)
+	case tag&0xf0 == 0x90:
+		return decodeArray(int(tag&0x0f), b)
+	case tag&0xe0 == 0xa0:
+		l := int(tag & 0x1f)
+		return string(b[:l]), b[l:], nil
+	}
+	switch tag {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2, 0xc3:
+		return tag == 0xc3, b, nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xcc, 0xcd, 0xce:
+		v, rest := n(1 << (tag - 0xcc))
+		return float64(v), rest, nil
+	case 0xcf:
+		return float64(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xd0:
+		return float64(int8(b[0])), b[1:], nil
+	case 0xd1:
+		return float64(int16(binary.BigEndian.Uint16(b))), b[2:], nil
+	case 0xd2:
+		return float64(int32(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xd3:
+		return float64(int64(binary.BigEndian.Uint64(b))), b[8:], nil
+	case 0xd9, 0xda, 0xdb:
+		l, rest := n(1 << (tag - 0xd9))
+		return string(rest[:l]), rest[l:], nil
+	case 0xdc, 0xdd:
+		l, rest := n(2 << (tag - 0xdc))
+		return decodeArray(l, rest)
+	case 0xde, 0xdf:
+		l, rest := n(2 << (tag - 0xde))
+		return decodeMap(l, rest)
+	}
+	return nil, nil, fmt.Errorf("unexpected tag %#x", tag)
+}
+
+func decodeArray(l int, b []byte) (any, []byte, error) {
+	out := make([]any, l)
+	var err error
+	for i := range out {
+		if out[i], b, err = decode(b); err != nil {
+			return nil, nil, err
+		}
+	}
+	return out, b, nil
+}
+
+func decodeMap(l int, b []byte) (any, []byte, error) {
+	out := make(map[string]any, l)
+	for i := 0; i < l; i++ {
+		k, rest, err := decode(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		if out[k.(string)], b, err = decode(rest); err != nil {
+			return nil, nil, err
+		}
+	}
+	return out, b, nil
+}
+
+type inner struct {
+	Price  float64 `json:"price"`
+	Volume float64 `json:"volume,omitempty"`
+}
+
+type embedded struct {
+	Source string `json:"source"`
+	Label  string `json:"label"`
+}
+
+type sample struct {
+	embedded
+	Label     string                        `json:"label"` // shadows embedded.Label
+	Time      int64                         `json:"time"`
+	Negative  int                           `json:"negative"`
+	Small     int8                          `json:"small"`
+	Count     uint32                        `json:"count"`
+	Ok        bool                          `json:"ok"`
+	Skipped   string                        `json:"-"`
+	Omitted   []int                         `json:"omitted,omitempty"`
+	Nil       *inner                        `json:"nil"`
+	Ptr       *inner                        `json:"ptr"`
+	Ticks     map[string][]inner            `json:"ticks"`
+	ByID      map[int]string                `json:"byId"`
+	Nested    map[string]map[string][]inner `json:"nested"`
+	Any       any                           `json:"any"`
+	Untagged  string
+	Long      string `json:"long"`
+	unexposed int
+}
+
+func TestMarshalMatchesJSON(t *testing.T) {
+	ticks := make([]inner, 40)
+	for i := range ticks {
+		ticks[i] = inner{Price: 1.08 + float64(i)*0.00013, Volume: float64(i) / 4}
+	}
+	v := sample{
+		embedded: embedded{Source: "jforex", Label: "hidden"},
+		Label:    "EURUSD_buy_1", Time: 1717000000000, Negative: -40000, Small: -7, Count: 70000, Ok: true,
+		Skipped: "x", Ptr: &inner{Price: -2.5}, Ticks: map[string][]inner{"EURUSD": ticks, "USDJPY": {}},
+		ByID: map[int]string{7: "seven"}, Nested: map[string]map[string][]inner{"EURUSD": {"ONE_MIN": ticks[:3]}},
+		Any: []any{"a", 1.5, map[string]any{"k": nil}}, Untagged: "u", Long: strings.Repeat("x", 300), unexposed: 1,
+	}
+	packed, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, rest, err := decode(packed)
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("decode: %v (%d trailing bytes)", err, len(rest))
+	}
+	text, _ := json.Marshal(v)
+	var want any
+	json.Unmarshal(text, &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("msgpack decodes to\n%v\nwant the JSON form\n%v", got, want)
+	}
+	if len(packed) >= len(text) {
+		t.Fatalf("msgpack is %d bytes, JSON %d; want smaller", len(packed), len(text))
+	}
+}
+
+func TestMarshalIntegerForms(t *testing.T) {
+	for _, tc := range []struct {
+		v    any
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0xcc, 0x80}},
+		{-1, []byte{0xff}},
+		{-33, []byte{0xd0, 0xdf}},
+		{1717000000000, []byte{0xcf, 0x00, 0x00, 0x01, 0x8f, 0xc5, 0x2c, 0xd2, 0x00}},
+		{2.0, []byte{0x02}}, // integral float
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}}, // float64
+		{"EURUSD", []byte{0xa6, 'E', 'U', 'R', 'U', 'S', 'D'}},
+		{[]string(nil), []byte{0xc0}},
+	} {
+		got, err := Marshal(tc.v)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Marshal(%v) = % x, %v; want % x", tc.v, got, err, tc.want)
+		}
+	}
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Error("channels should be rejected")
+	}
+}
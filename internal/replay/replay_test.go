@@ -0,0 +1,147 @@
+package replay
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-trader/internal/amqp"
+	"go-trader/internal/clock"
+	"go-trader/internal/state"
+	"go-trader/internal/strategy"
+)
+
+// evalProbe is the RSI cross strategy reporting each signal it evaluates, so the test can wait
+// for the engine to see every replayed bar before delivering the next one.
+type evalProbe struct {
+	*strategy.RsiCrossStrategy
+	signals chan strategy.Signal
+}
+
+func (p *evalProbe) EvaluateWithConfidence(bars []state.HistoricalBar) (strategy.Signal, float64) {
+	sig, conf := p.RsiCrossStrategy.EvaluateWithConfidence(bars)
+	p.signals <- sig
+	return sig, conf
+}
+
+func mustLoad(t *testing.T, name string) []Message {
+	t.Helper()
+	msgs, err := Load(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+func awaitSignal(t *testing.T, signals chan strategy.Signal) strategy.Signal {
+	t.Helper()
+	select {
+	case sig := <-signals:
+		return sig
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the engine to evaluate")
+		return strategy.SignalNone
+	}
+}
+
+// TestReplayRsiCross replays a recorded EURUSD session: an account snapshot, a 26-bar history
+// backfill with the fast RSI below the slow one, then two live bars with ticks; the second live
+// bar turns the locally computed fast RSI above the slow RSI.
+func TestReplayRsiCross(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		account string
+		order   bool
+	}{
+		{"funded account buys", "account_funded.jsonl", true},
+		{"insufficient margin blocks the order", "account_no_margin.jsonl", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sm := state.NewStateManager()
+			history := mustLoad(t, "eurusd_history.jsonl")
+			p := NewPlayer(sm, time.UnixMilli(history[0].At))
+			if err := p.Play(mustLoad(t, tc.account)); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Play(history); err != nil {
+				t.Fatal(err)
+			}
+			if bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN"); len(bars) != 26 || bars[0].Sequence != 1 || bars[0].Bid.C != 1.0783 {
+				t.Fatalf("history: %d bars, newest %+v; want 26 with sequence 1 newest", len(bars), bars[0])
+			}
+
+			sink := &Sink{Submitted: make(chan amqp.TradeCommand, 4)}
+			ec := clock.NewFake(p.Clock.Now())
+			e := strategy.NewEngine(sm, sink, nil)
+			e.SetClock(ec)
+			e.SetEvalJitter(0)
+			probe := &evalProbe{RsiCrossStrategy: &strategy.RsiCrossStrategy{}, signals: make(chan strategy.Signal, 8)}
+			e.StartStrategy("EURUSD", "ONE_MIN", probe, 0.1, 1.5)
+			defer e.StopStrategy("EURUSD", "ONE_MIN")
+
+			// The run creates its ticker on its own goroutine, so step until the first evaluation
+			signals := []strategy.Signal{}
+			deadline := time.After(2 * time.Second)
+		start:
+			for {
+				ec.Advance(time.Second)
+				select {
+				case sig := <-probe.signals:
+					signals = append(signals, sig)
+					break start
+				case <-deadline:
+					t.Fatal("run never evaluated the history")
+				case <-time.After(5 * time.Millisecond):
+				}
+			}
+			// Each live bar is evaluated at its delivery time, which fixes the order label
+			for _, m := range mustLoad(t, "eurusd_live.jsonl") {
+				if err := p.Deliver(m); err != nil {
+					t.Fatal(err)
+				}
+				if m.Class == amqp.ClassBar {
+					ec.Set(time.UnixMilli(m.At))
+					signals = append(signals, awaitSignal(t, probe.signals))
+				}
+			}
+			if want := []strategy.Signal{strategy.SignalNone, strategy.SignalNone, strategy.SignalBuy}; len(signals) != 3 ||
+				signals[0] != want[0] || signals[1] != want[1] || signals[2] != want[2] {
+				t.Fatalf("signals = %v, want %v", signals, want)
+			}
+
+			bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN")
+			if len(bars) != 28 || bars[0].Sequence != 28 || bars[0].Bid.C != 1.0811 {
+				t.Fatalf("%d bars, newest %+v; want the 2 live bars merged on top with sequence 28 newest", len(bars), bars[0])
+			}
+			if tick := sm.LatestTicks()["EURUSD"]; tick.Bid != 1.0811 || tick.Ask != 1.08122 {
+				t.Fatalf("latest tick = %+v, want 1.0811/1.08122", tick)
+			}
+			if acked, nacked := p.Acks.Counts(); acked != 31 || nacked != 0 {
+				t.Fatalf("acked %d, nacked %d; want all 31 messages acked", acked, nacked)
+			}
+
+			if !tc.order {
+				select {
+				case cmd := <-sink.Submitted:
+					t.Fatalf("order %+v published, want it rejected for margin", cmd)
+				case <-time.After(50 * time.Millisecond):
+				}
+				return
+			}
+			var cmd amqp.TradeCommand
+			select {
+			case cmd = <-sink.Submitted:
+			case <-time.After(2 * time.Second):
+				t.Fatal("no order published for the BUY signal")
+			}
+			want := amqp.TradeCommand{Label: "EURUSD_strat_buy_092800", Instrument: "EURUSD", OrderCmd: "BUY", Amount: 0.1,
+				Slippage: cmd.Slippage, StopLossPrice: 1.08016, TakeProfitPrice: 1.08216}
+			if cmd != want || cmd.Slippage <= 0 {
+				t.Fatalf("order = %+v, want %+v", cmd, want)
+			}
+			if n := len(sink.Orders()); n != 1 {
+				t.Fatalf("%d orders published, want 1", n)
+			}
+		})
+	}
+}
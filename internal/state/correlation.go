@@ -0,0 +1,120 @@
+package state
+
+import (
+	"math"
+	"sort"
+)
+
+// CorrelationMatrix is the pairwise return correlation of instruments over one period.
+type CorrelationMatrix struct {
+	Period      string   `json:"period"`
+	Len         int      `json:"len"` // returns per instrument
+	Instruments []string `json:"instruments"`
+	// Matrix[i][j] is the correlation of Instruments[i] and Instruments[j]; null when undefined
+	// (e.g. a flat price series or too few common bars).
+	Matrix  [][]*float64 `json:"matrix"`
+	Omitted []string     `json:"omitted,omitempty"` // instruments with fewer than Len+1 bars
+}
+
+// minCommonReturns is the fewest timestamp-aligned returns a pair needs for a correlation.
+const minCommonReturns = 3
+
+// Correlation returns the Pearson correlation of two equal-length series.
+// ok is false when the series are too short or either has zero variance.
+func Correlation(a, b []float64) (float64, bool) {
+	n := len(a)
+	if n != len(b) || n < 2 {
+		return 0, false
+	}
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(n)
+	meanB /= float64(n)
+	var cov, varA, varB float64
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varA*varB), true
+}
+
+// barReturns returns the log returns of the newest n+1 bars' bid closes keyed by bar end timestamp.
+// bars are newest-first. ok is false when fewer than n+1 usable bars exist.
+func barReturns(bars []HistoricalBar, n int) (map[int64]float64, bool) {
+	if len(bars) < n+1 {
+		return nil, false
+	}
+	out := make(map[int64]float64, n)
+	for i := 0; i < n; i++ {
+		cur, prev := bars[i].Bid.C, bars[i+1].Bid.C
+		if cur <= 0 || prev <= 0 {
+			return nil, false
+		}
+		out[bars[i].BarEndTimestamp] = math.Log(cur / prev)
+	}
+	return out, true
+}
+
+// Correlations computes pairwise return correlations between instruments from their historical bars.
+// What: Shows how much open instruments move together, for portfolio risk.
+// How: Each instrument's last n bid-close log returns are keyed by bar end timestamp, and each pair is
+//      correlated over the timestamps both have, so a missing bar on one side does not misalign the
+//      series. Instruments with fewer than n+1 bars are omitted from the matrix.
+// Params: instruments to compare, period (e.g. ONE_HOUR), n returns per instrument (n >= 2).
+// Returns: the matrix; its diagonal is 1 for every included instrument.
+func (sm *StateManager) Correlations(instruments []string, period string, n int) CorrelationMatrix {
+	res := CorrelationMatrix{Period: period, Len: n, Instruments: []string{}, Matrix: [][]*float64{}}
+	returns := make([]map[int64]float64, 0, len(instruments))
+	for _, instr := range instruments {
+		r, ok := barReturns(sm.GetHistoricalBars(instr, period), n)
+		if !ok {
+			res.Omitted = append(res.Omitted, instr)
+			continue
+		}
+		res.Instruments = append(res.Instruments, instr)
+		returns = append(returns, r)
+	}
+
+	res.Matrix = make([][]*float64, len(returns))
+	for i := range returns {
+		res.Matrix[i] = make([]*float64, len(returns))
+	}
+	one := 1.0
+	for i := range returns {
+		res.Matrix[i][i] = &one
+		for j := i + 1; j < len(returns); j++ {
+			a, b := alignReturns(returns[i], returns[j])
+			if len(a) < minCommonReturns {
+				continue
+			}
+			if c, ok := Correlation(a, b); ok {
+				res.Matrix[i][j], res.Matrix[j][i] = &c, &c
+			}
+		}
+	}
+	return res
+}
+
+// alignReturns returns the values of a and b at their common timestamps, oldest first.
+func alignReturns(a, b map[int64]float64) ([]float64, []float64) {
+	ts := make([]int64, 0, len(a))
+	for t := range a {
+		if _, ok := b[t]; ok {
+			ts = append(ts, t)
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	xa, xb := make([]float64, len(ts)), make([]float64, len(ts))
+	for i, t := range ts {
+		xa[i], xb[i] = a[t], b[t]
+	}
+	return xa, xb
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCorrelation(t *testing.T) {
+	a := []float64{1, 2, 3, 4}
+	if c, ok := Correlation(a, []float64{2, 4, 6, 8}); !ok || math.Abs(c-1) > 1e-12 {
+		t.Errorf("perfect positive: %v %v", c, ok)
+	}
+	if c, ok := Correlation(a, []float64{8, 6, 4, 2}); !ok || math.Abs(c+1) > 1e-12 {
+		t.Errorf("perfect negative: %v %v", c, ok)
+	}
+	if _, ok := Correlation(a, []float64{5, 5, 5, 5}); ok {
+		t.Error("flat series must be undefined")
+	}
+	if _, ok := Correlation(a, a[:3]); ok {
+		t.Error("length mismatch must be undefined")
+	}
+}
+
+// seedCloses stores bars with the given bid closes (oldest first) one minute apart.
+func seedCloses(sm *StateManager, instrument string, closes []float64) {
+	for i, c := range closes {
+		sm.UpdateHistoricalBar(HistoricalBar{Instrument: instrument, Period: "ONE_HOUR",
+			BarEndTimestamp: int64(i+1) * 60_000, Bid: OHLCV{O: c, H: c, L: c, C: c}})
+	}
+}
+
+func TestCorrelationsMatrixOmitsShortInstruments(t *testing.T) {
+	sm := NewStateManager()
+	seedCloses(sm, "EURUSD", []float64{1.10, 1.11, 1.12, 1.11, 1.13, 1.14})
+	seedCloses(sm, "GBPUSD", []float64{1.30, 1.31, 1.32, 1.31, 1.33, 1.34}) // moves with EURUSD
+	seedCloses(sm, "USDCHF", []float64{0.90, 0.89, 0.88, 0.89, 0.87, 0.86}) // moves against it
+	seedCloses(sm, "USDJPY", []float64{150, 151})                           // too short
+
+	m := sm.Correlations([]string{"EURUSD", "GBPUSD", "USDCHF", "USDJPY"}, "ONE_HOUR", 5)
+	if len(m.Instruments) != 3 || len(m.Omitted) != 1 || m.Omitted[0] != "USDJPY" {
+		t.Fatalf("instruments %v omitted %v", m.Instruments, m.Omitted)
+	}
+	if len(m.Matrix) != 3 || len(m.Matrix[0]) != 3 {
+		t.Fatalf("matrix shape %d", len(m.Matrix))
+	}
+	if v := m.Matrix[0][0]; v == nil || *v != 1 {
+		t.Errorf("diagonal %v", v)
+	}
+	if v := m.Matrix[0][1]; v == nil || *v < 0.9 {
+		t.Errorf("EURUSD/GBPUSD %v, want strongly positive", v)
+	}
+	if v := m.Matrix[0][2]; v == nil || *v > -0.9 {
+		t.Errorf("EURUSD/USDCHF %v, want strongly negative", v)
+	}
+	if m.Matrix[1][2] != m.Matrix[2][1] {
+		t.Error("matrix not symmetric")
+	}
+}
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Account currency conversion.
+// What: Balance and equity arrive in the broker account's currency, so locally computed PnL, pip
+//      values and notionals must be in that currency too (e.g. a EUR account trading USD pairs).
+// How: SetAccountCurrency names the currency (GOTRADER_ACCOUNT_CURRENCY, default USD). Amounts are
+//      converted with ConversionRate from the latest tick mids: a direct pair (EURUSD for EUR->USD),
+//      the inverted pair (USDJPY for JPY->USD), or crossed through USD when neither is streamed
+//      (GBP->CHF via GBPUSD and USDCHF). PipValue, PositionPnL and Notional all go through
+//      ToAccountCurrency, so risk checks, strategy sizing and broadcast exposure agree.
+
+// crossCurrency is the currency rates are crossed through when no direct pair is streamed.
+const crossCurrency = "USD"
+
+var (
+	currencyMu      sync.RWMutex
+	accountCurrency = "USD"
+)
+
+// AccountCurrency returns the currency PnL, pip values and notionals are converted into.
+func AccountCurrency() string {
+	currencyMu.RLock()
+	defer currencyMu.RUnlock()
+	return accountCurrency
+}
+
+// SetAccountCurrency sets the account currency, a three-letter ISO code such as "EUR".
+func SetAccountCurrency(ccy string) error {
+	ccy = strings.ToUpper(strings.TrimSpace(ccy))
+	if len(ccy) != 3 || strings.IndexFunc(ccy, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return fmt.Errorf("account currency %q is not a three-letter code", ccy)
+	}
+	currencyMu.Lock()
+	defer currencyMu.Unlock()
+	accountCurrency = ccy
+	return nil
+}
+
+// ToAccountCurrency converts amount in ccy into the account currency. Returns false when no
+// rate is available.
+func ToAccountCurrency(amount float64, ccy string, rates map[string]Tick) (float64, bool) {
+	conv, ok := ConversionRate(ccy, AccountCurrency(), rates)
+	if !ok {
+		return 0, false
+	}
+	return amount * conv, true
+}
+
+// ConversionRate returns the multiplier converting an amount in currency from into currency to.
+func ConversionRate(from, to string, rates map[string]Tick) (float64, bool) {
+	if r, ok := directRate(from, to, rates); ok {
+		return r, true
+	}
+	if from == crossCurrency || to == crossCurrency {
+		return 0, false
+	}
+	a, ok := directRate(from, crossCurrency, rates)
+	if !ok {
+		return 0, false
+	}
+	b, ok := directRate(crossCurrency, to, rates)
+	if !ok {
+		return 0, false
+	}
+	return a * b, true
+}
+
+// directRate converts with the from/to pair or its inverse, without crossing.
+func directRate(from, to string, rates map[string]Tick) (float64, bool) {
+	if from == to {
+		return 1, true
+	}
+	if t, ok := rates[from+to]; ok {
+		if m := mid(t); m > 0 {
+			return m, true
+		}
+	}
+	if t, ok := rates[to+from]; ok {
+		if m := mid(t); m > 0 {
+			return 1 / m, true
+		}
+	}
+	return 0, false
+}
+
+func mid(t Tick) float64 {
+	if t.Bid <= 0 || t.Ask <= 0 {
+		return 0
+	}
+	return (t.Bid + t.Ask) / 2
+}
@@ -0,0 +1,52 @@
+package state
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAccountCurrencyConversion(t *testing.T) {
+	t.Cleanup(func() { SetAccountCurrency("USD") })
+	rates := map[string]Tick{
+		"EURUSD": {Bid: 1.0999, Ask: 1.1001},
+		"USDJPY": {Bid: 149.99, Ask: 150.01},
+		"GBPUSD": {Bid: 1.2499, Ask: 1.2501},
+		"USDCHF": {Bid: 0.8999, Ask: 0.9001},
+		"EURGBP": {Bid: 0.8799, Ask: 0.8801},
+	}
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+
+	// USD account: one lot of EURUSD is worth 10 USD a pip
+	if got := PipValue("EURUSD", 1, rates); !near(got, 10) {
+		t.Fatalf("USD account EURUSD pip value = %v, want 10", got)
+	}
+
+	if err := SetAccountCurrency("eur"); err != nil || AccountCurrency() != "EUR" {
+		t.Fatalf("SetAccountCurrency: %v, currency %s", err, AccountCurrency())
+	}
+	if got := PipValue("EURUSD", 1, rates); !near(got, 10/1.1) {
+		t.Fatalf("EUR account EURUSD pip value = %v, want %v (inverted EURUSD)", got, 10/1.1)
+	}
+	if got := Notional("EURUSD", 1, rates); !near(got, 100000) {
+		t.Fatalf("EUR account EURUSD notional = %v, want 100000 EUR", got)
+	}
+	// No EURJPY tick: JPY crosses through USD (1/150 USD, then 1/1.1 EUR)
+	if got := PipValue("USDJPY", 1, rates); !near(got, 1000.0/150/1.1) {
+		t.Fatalf("EUR account USDJPY pip value = %v, want %v", got, 1000.0/150/1.1)
+	}
+	pnl, ok := PositionPnL(Position{Instrument: "EURUSD", OrderCommand: "BUY", Amount: 1, OpenPrice: 1.0989}, rates)
+	if !ok || !near(pnl, 10*10/1.1) {
+		t.Fatalf("EUR account PnL = %v, %v; want 10 pips worth %v EUR", pnl, ok, 10*10/1.1)
+	}
+
+	SetAccountCurrency("CHF")
+	if got, ok := ToAccountCurrency(100, "GBP", rates); !ok || !near(got, 100*1.25*0.9) {
+		t.Fatalf("GBP->CHF = %v, %v; want %v via USD", got, ok, 100*1.25*0.9)
+	}
+	if _, ok := ToAccountCurrency(100, "NZD", rates); ok {
+		t.Fatal("NZD has no rate and should not convert")
+	}
+	if err := SetAccountCurrency("EURO"); err == nil {
+		t.Fatal("a four-letter currency should be rejected")
+	}
+}
@@ -0,0 +1,218 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Tolerant decoding of broker messages.
+// What: JForex builds differ in JSON key style (bar_end_timestamp vs barEndTimestamp); with plain
+//       struct tags an unmatched key silently leaves the field zero and corrupts indicators.
+// How: Tick, Bar, and HistoricalBar rewrite incoming keys to their canonical tags before decoding,
+//      recursing into nested objects (bid, bid_demas, ...). A key matches a tag when both are equal
+//      ignoring case, '_' and '-'; keys the rule cannot map can be set with SetFieldAliases. A key
+//      already present in canonical form wins over its variants. Messages whose keys all match are
+//      decoded without rewriting. Encoding is unchanged.
+
+var fieldAliases struct {
+	mu     sync.RWMutex
+	values map[string]string // broker key -> canonical json tag
+}
+
+// SetFieldAliases replaces the explicit broker-key aliases, e.g. {"time": "timestamp"}.
+// Aliases apply at any nesting level. Call before consuming.
+func SetFieldAliases(aliases map[string]string) {
+	fieldAliases.mu.Lock()
+	defer fieldAliases.mu.Unlock()
+	fieldAliases.values = aliases
+}
+
+// ParseFieldAliases parses "brokerKey:canonicalKey" pairs separated by commas, e.g. "time:timestamp".
+func ParseFieldAliases(v string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(entry, ":")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("field alias %q: want brokerKey:canonicalKey", entry)
+		}
+		out[from] = to
+	}
+	return out, nil
+}
+
+// fieldSet describes the json keys of one struct type.
+type fieldSet struct {
+	tags   map[string]bool      // canonical tags
+	byNorm map[string]string    // normalized key -> canonical tag
+	nested map[string]*fieldSet // canonical tag -> struct-typed field
+}
+
+var fieldSets sync.Map // reflect.Type -> *fieldSet
+
+// fieldsOf returns the cached fieldSet for a struct type.
+func fieldsOf(t reflect.Type) *fieldSet {
+	if fs, ok := fieldSets.Load(t); ok {
+		return fs.(*fieldSet)
+	}
+	fs := &fieldSet{tags: make(map[string]bool), byNorm: make(map[string]string), nested: make(map[string]*fieldSet)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fs.tags[tag] = true
+		fs.byNorm[normalizeKey(tag)] = tag
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			fs.nested[tag] = fieldsOf(ft)
+		}
+	}
+	fieldSets.Store(t, fs)
+	return fs
+}
+
+// normalizeKey lowercases k and drops '_' and '-'.
+func normalizeKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(k))
+}
+
+// canonicalKeys rewrites the object keys in data to fs's tags. ok is false when nothing changed,
+// in which case data can be decoded as is.
+func canonicalKeys(data []byte, fs *fieldSet) (out []byte, ok bool, err error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return data, false, err // not an object (or null): let the regular decoder report it
+	}
+	fieldAliases.mu.RLock()
+	aliases := fieldAliases.values
+	fieldAliases.mu.RUnlock()
+
+	// Canonical keys first so they win over variants of the same field
+	fixed := make(map[string]json.RawMessage, len(obj))
+	changed := false
+	for _, canonicalPass := range []bool{true, false} {
+		for k, v := range obj {
+			if fs.tags[k] != canonicalPass {
+				continue
+			}
+			tag := k
+			if !canonicalPass {
+				if a, found := aliases[k]; found && fs.tags[a] {
+					tag = a
+				} else if t, found := fs.byNorm[normalizeKey(k)]; found {
+					tag = t
+				}
+				if _, exists := fixed[tag]; exists {
+					changed = true // drop the duplicate variant
+					continue
+				}
+				changed = changed || tag != k
+			}
+			if sub, isStruct := fs.nested[tag]; isStruct {
+				if nv, subChanged, err := canonicalKeys(v, sub); err == nil && subChanged {
+					v, changed = nv, true
+				}
+			}
+			fixed[tag] = v
+		}
+	}
+	if !changed {
+		return data, false, nil
+	}
+	out, err = json.Marshal(fixed)
+	return out, err == nil, err
+}
+
+// decodeTolerant unmarshals data into v, a pointer to struct type t without an UnmarshalJSON
+// method, after canonicalizing its keys for t.
+func decodeTolerant(data []byte, v any, t reflect.Type) error {
+	fixed, _, err := canonicalKeys(data, fieldsOf(t))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(fixed, v)
+}
+
+// UnmarshalJSON decodes a tick, accepting key variants (see canonicalKeys).
+func (t *Tick) UnmarshalJSON(data []byte) error {
+	type plain Tick
+	return decodeTolerant(data, (*plain)(t), reflect.TypeOf(plain{}))
+}
+
+// UnmarshalJSON decodes a live bar, accepting key variants (see canonicalKeys).
+func (b *Bar) UnmarshalJSON(data []byte) error {
+	type plain Bar
+	return decodeTolerant(data, (*plain)(b), reflect.TypeOf(plain{}))
+}
+
+// UnmarshalJSON decodes a historical bar, accepting key variants (see canonicalKeys).
+func (b *HistoricalBar) UnmarshalJSON(data []byte) error {
+	type plain HistoricalBar
+	return decodeTolerant(data, (*plain)(b), reflect.TypeOf(plain{}))
+}
+
+// ZeroRequiredFields lists required tick fields that are zero after decoding, a sign that the
+// broker's keys no longer match.
+func (t Tick) ZeroRequiredFields() []string {
+	var out []string
+	if t.Instrument == "" {
+		out = append(out, "instrument")
+	}
+	if t.Timestamp == 0 && t.ProducedAt == 0 {
+		out = append(out, "timestamp")
+	}
+	if t.Bid == 0 {
+		out = append(out, "bid")
+	}
+	if t.Ask == 0 {
+		out = append(out, "ask")
+	}
+	return out
+}
+
+// ZeroRequiredFields lists required live bar fields that are zero after decoding.
+func (b Bar) ZeroRequiredFields() []string {
+	return zeroBarFields(b.Instrument, b.Period, b.BarEndTimestamp, b.Bid, b.Ask)
+}
+
+// ZeroRequiredFields lists required historical bar fields that are zero after decoding.
+func (b HistoricalBar) ZeroRequiredFields() []string {
+	return zeroBarFields(b.Instrument, b.Period, b.BarEndTimestamp, b.Bid, b.Ask)
+}
+
+func zeroBarFields(instrument, period string, barEnd int64, bid, ask OHLCV) []string {
+	var out []string
+	if instrument == "" {
+		out = append(out, "instrument")
+	}
+	if period == "" {
+		out = append(out, "period")
+	}
+	if barEnd == 0 {
+		out = append(out, "bar_end_timestamp")
+	}
+	if bid.C == 0 {
+		out = append(out, "bid.c")
+	}
+	if ask.C == 0 {
+		out = append(out, "ask.c")
+	}
+	return out
+}
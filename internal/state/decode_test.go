@@ -0,0 +1,40 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHistoricalBarAcceptsKeyVariants(t *testing.T) {
+	body := `{"barEndTimestamp":120000,"BAR_START_TIMESTAMP":60000,"instrument":"EURUSD","period":"ONE_MIN",
+		"bid":{"c":1.1},"ask":{"C":1.1002},"bidDemas":{"dema25":1.05},"bid-rsi":{"fast":55},"seq":7}`
+	SetFieldAliases(map[string]string{"seq": "sequence"})
+	defer SetFieldAliases(nil)
+
+	var b HistoricalBar
+	if err := json.Unmarshal([]byte(body), &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.BarEndTimestamp != 120000 || b.BarStartTimestamp != 60000 || b.Sequence != 7 {
+		t.Fatalf("timestamps/sequence = %d/%d/%d", b.BarEndTimestamp, b.BarStartTimestamp, b.Sequence)
+	}
+	if b.Ask.C != 1.1002 || b.BidDemas.Dema25 != 1.05 || b.BidRsi.Fast != 55 {
+		t.Fatalf("nested fields not mapped: %+v %+v %+v", b.Ask, b.BidDemas, b.BidRsi)
+	}
+	if z := b.ZeroRequiredFields(); len(z) != 0 {
+		t.Fatalf("zero fields = %v", z)
+	}
+}
+
+func TestCanonicalKeyWinsOverVariant(t *testing.T) {
+	var tick Tick
+	if err := json.Unmarshal([]byte(`{"produced_at":5,"producedAt":9,"bid":1.1}`), &tick); err != nil {
+		t.Fatal(err)
+	}
+	if tick.ProducedAt != 5 {
+		t.Fatalf("produced_at = %d, want the canonical key's 5", tick.ProducedAt)
+	}
+	if z := tick.ZeroRequiredFields(); len(z) != 2 || z[0] != "instrument" || z[1] != "ask" {
+		t.Fatalf("zero fields = %v, want [instrument ask]", z)
+	}
+}
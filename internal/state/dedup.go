@@ -0,0 +1,47 @@
+package state
+
+import "fmt"
+
+// Tick deduplication modes for SetTickDedup.
+const (
+	TickDedupOff       = "off"       // store every tick (default)
+	TickDedupTimestamp = "timestamp" // drop a tick with the same Timestamp as the instrument's newest tick
+	TickDedupExact     = "exact"     // drop it only when bid and ask are also identical
+)
+
+// ParseTickDedup validates a tick dedup mode; empty means TickDedupOff.
+func ParseTickDedup(v string) (string, error) {
+	switch v {
+	case "", TickDedupOff:
+		return TickDedupOff, nil
+	case TickDedupTimestamp, TickDedupExact:
+		return v, nil
+	}
+	return "", fmt.Errorf("tick dedup mode %q: want %s, %s, or %s", v, TickDedupOff, TickDedupTimestamp, TickDedupExact)
+}
+
+// SetTickDedup sets how UpdateTick treats ticks the feed resends with the newest tick's timestamp.
+// What: Resent ticks inflate tick counts, volume averages, and VWAP.
+// How: Opt-in, since fast markets legitimately produce several ticks in the same millisecond;
+//      TickDedupExact only drops true repeats. Dropped ticks are counted (DedupedTickCount).
+func (sm *StateManager) SetTickDedup(mode string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.tickDedup = mode
+}
+
+// DedupedTickCount returns the number of ticks dropped as duplicates since startup.
+func (sm *StateManager) DedupedTickCount() int64 { return sm.dedupedTicks.Load() }
+
+// isDuplicateTick reports whether tick repeats the newest tick in ring under the dedup mode.
+// Ticks without a Timestamp are never considered duplicates.
+func (sm *StateManager) isDuplicateTick(ring *tickRing, tick Tick) bool {
+	if sm.tickDedup == "" || sm.tickDedup == TickDedupOff || tick.Timestamp == 0 {
+		return false
+	}
+	last, ok := ring.last()
+	if !ok || last.Timestamp != tick.Timestamp {
+		return false
+	}
+	return sm.tickDedup == TickDedupTimestamp || (last.Bid == tick.Bid && last.Ask == tick.Ask)
+}
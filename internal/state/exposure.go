@@ -0,0 +1,96 @@
+package state
+
+import (
+	"sort"
+	"strings"
+)
+
+// InstrumentExposure summarises all open positions for a single instrument.
+type InstrumentExposure struct {
+	Instrument    string  `json:"instrument"`
+	Positions     int     `json:"positions"`
+	LongAmount    float64 `json:"longAmount"`
+	ShortAmount   float64 `json:"shortAmount"`
+	NetAmount     float64 `json:"netAmount"`   // long - short
+	GrossAmount   float64 `json:"grossAmount"` // long + short
+	AvgLongEntry  float64 `json:"avgLongEntry,omitempty"`
+	AvgShortEntry float64 `json:"avgShortEntry,omitempty"`
+	AvgNetEntry   float64 `json:"avgNetEntry,omitempty"`
+	Hedged        bool    `json:"hedged"`
+	PnL           float64 `json:"pnl"`
+	// UnrealizedPnL is marked to market locally in the account currency (see AggregatePositionsMarked)
+	UnrealizedPnL float64 `json:"unrealizedPnL,omitempty"`
+}
+
+// AggregatePositions groups open positions by instrument.
+// What: Net and gross exposure per instrument for a portfolio view.
+// How: BUY* orders count as long and SELL* orders as short; entries are amount-weighted averages.
+// Hedged instruments (long and short at once) report both legs plus the net.
+// Params: info AccountInfo snapshot
+// Returns: exposures sorted by instrument
+func AggregatePositions(info AccountInfo) []InstrumentExposure {
+	type acc struct {
+		exp           InstrumentExposure
+		longNotional  float64
+		shortNotional float64
+	}
+	byInstr := make(map[string]*acc)
+	for _, p := range info.Positions {
+		if p.Instrument == "" || p.Amount <= 0 {
+			continue
+		}
+		a, ok := byInstr[p.Instrument]
+		if !ok {
+			a = &acc{exp: InstrumentExposure{Instrument: p.Instrument}}
+			byInstr[p.Instrument] = a
+		}
+		a.exp.Positions++
+		a.exp.PnL += p.PnL
+		cmd := strings.ToUpper(p.OrderCommand)
+		switch {
+		case strings.HasPrefix(cmd, "BUY"):
+			a.exp.LongAmount += p.Amount
+			a.longNotional += p.Amount * p.OpenPrice
+		case strings.HasPrefix(cmd, "SELL"):
+			a.exp.ShortAmount += p.Amount
+			a.shortNotional += p.Amount * p.OpenPrice
+		}
+	}
+
+	out := make([]InstrumentExposure, 0, len(byInstr))
+	for _, a := range byInstr {
+		e := a.exp
+		e.NetAmount = e.LongAmount - e.ShortAmount
+		e.GrossAmount = e.LongAmount + e.ShortAmount
+		e.Hedged = e.LongAmount > 0 && e.ShortAmount > 0
+		if e.LongAmount > 0 {
+			e.AvgLongEntry = a.longNotional / e.LongAmount
+		}
+		if e.ShortAmount > 0 {
+			e.AvgShortEntry = a.shortNotional / e.ShortAmount
+		}
+		if e.NetAmount != 0 {
+			// Break-even price of the net position
+			e.AvgNetEntry = (a.longNotional - a.shortNotional) / e.NetAmount
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Instrument < out[j].Instrument })
+	return out
+}
+
+// AggregatePositionsMarked is AggregatePositions plus a locally computed, pip-value-aware
+// UnrealizedPnL per instrument using the latest ticks as rates.
+func AggregatePositionsMarked(info AccountInfo, rates map[string]Tick) []InstrumentExposure {
+	exps := AggregatePositions(info)
+	pnl := make(map[string]float64)
+	for _, p := range info.Positions {
+		if v, ok := PositionPnL(p, rates); ok {
+			pnl[p.Instrument] += v
+		}
+	}
+	for i := range exps {
+		exps[i].UnrealizedPnL = pnl[exps[i].Instrument]
+	}
+	return exps
+}
@@ -1,24 +1,64 @@
 package state
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
-	// tickRingBufferSize is the number of recent ticks to store for each instrument.
+	// tickRingBufferSize is the default number of recent ticks to store for each instrument.
 	tickRingBufferSize = 20
-	// barRingBufferSize is the number of recent bars to store for each instrument and period.
+	// barRingBufferSize is the default number of recent bars to store for each instrument and period.
 	barRingBufferSize = 200
 )
 
+// StateManagerConfig sizes the per-instrument buffers; zero values fall back to the defaults.
+type StateManagerConfig struct {
+	// TickBufferSize is the tick ring capacity per instrument (default 20).
+	TickBufferSize int
+	// TickBufferSizes overrides TickBufferSize for individual instruments, e.g. a deep buffer
+	// for an instrument scalped on TEN_SECS.
+	TickBufferSizes map[string]int
+	// BarBufferSize is the number of bars kept per instrument and period (default 200).
+	BarBufferSize int
+	// BarBufferSizes overrides BarBufferSize for individual periods, e.g. fewer DAILY bars.
+	BarBufferSizes map[string]int
+}
+
 // StateManager is the in-memory, thread-safe state cache for the entire trading system.
 // It acts as the single source of truth for all market and account data.
 type StateManager struct {
 	// mu protects all fields within the StateManager.
 	mu sync.RWMutex
 
-	// ticks stores the last N ticks for each instrument.
-	ticks map[string][]Tick
+	// ticks stores the last N ticks for each instrument in fixed-size circular buffers.
+	ticks map[string]*tickRing
+
+	// tickBufferSize is the ring capacity per instrument; tickBufferSizes overrides it per instrument.
+	tickBufferSize  int
+	tickBufferSizes map[string]int
+
+	// barBufferSize is the bar capacity per instrument and period; barBufferSizes overrides it per period.
+	barBufferSize  int
+	barBufferSizes map[string]int
+
+	// tickVwapMinCoverage is the fraction of a live bar's window the retained ticks must span
+	// for its tick VWAP to be computed.
+	tickVwapMinCoverage float64
+
+	// spreads keeps rolling spread statistics per instrument, fed by UpdateTick.
+	spreads map[string]*spreadAccumulator
+
+	// sessions keeps the current session OHLC per instrument, fed by UpdateTick.
+	sessions map[string]*sessionAccumulator
+
+	// sessionOffset is the daily session boundary as an offset from 00:00 UTC.
+	sessionOffset time.Duration
 
 	// bars stores the last N bars for each instrument and period combination.
 	bars map[string]map[string][]Bar
@@ -26,32 +66,219 @@ type StateManager struct {
 	// historicalBars stores the last N historical bars, separate from live bars.
 	historicalBars map[string]map[string][]HistoricalBar
 
+	// histSeq tracks the sequences of the latest historical response per "instrument|period".
+	histSeq map[string]*sequenceTracker
+
 	// accountInfo holds the latest snapshot of the user's trading account.
 	accountInfo AccountInfo
+
+	// tickCount is the number of ticks stored since startup, for rate measurements.
+	tickCount atomic.Int64
+
+	// tickDedup is the TickDedup* mode; dedupedTicks counts ticks it dropped.
+	tickDedup    string
+	dedupedTicks atomic.Int64
 }
 
-// NewStateManager creates and initializes a new StateManager.
-func NewStateManager() *StateManager {
+// NewStateManager creates and initializes a new StateManager. An optional config sizes the
+// tick and bar buffers; without one the defaults apply.
+func NewStateManager(cfg ...StateManagerConfig) *StateManager {
+	var c StateManagerConfig
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
 	return &StateManager{
-		ticks:          make(map[string][]Tick),
-		bars:           make(map[string]map[string][]Bar),
-		historicalBars: make(map[string]map[string][]HistoricalBar),
+		ticks:               make(map[string]*tickRing),
+		tickBufferSize:      positiveOr(c.TickBufferSize, tickRingBufferSize),
+		tickBufferSizes:     positiveSizes(c.TickBufferSizes),
+		barBufferSize:       positiveOr(c.BarBufferSize, barRingBufferSize),
+		barBufferSizes:      positiveSizes(c.BarBufferSizes),
+		tickVwapMinCoverage: defaultTickVwapMinCoverage,
+		spreads:             make(map[string]*spreadAccumulator),
+		sessions:            make(map[string]*sessionAccumulator),
+		bars:                make(map[string]map[string][]Bar),
+		historicalBars:      make(map[string]map[string][]HistoricalBar),
+		histSeq:             make(map[string]*sequenceTracker),
 	}
 }
 
+// ParseBufferSizes parses per-key buffer sizes for StateManagerConfig, e.g. "EURUSD:2000" for
+// ticks or "TEN_SECS:1000,DAILY:50" for bars. Keys are upper-cased; sizes must be positive.
+func ParseBufferSizes(v string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, size, ok := strings.Cut(entry, ":")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if !ok || key == "" {
+			return nil, fmt.Errorf("buffer size %q: want KEY:n", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("buffer size %q: want a positive size", entry)
+		}
+		out[key] = n
+	}
+	return out, nil
+}
+
+// positiveOr returns n, or def when n is not positive.
+func positiveOr(n, def int) int {
+	if n > 0 {
+		return n
+	}
+	return def
+}
+
+// positiveSizes copies the positive entries of sizes.
+func positiveSizes(sizes map[string]int) map[string]int {
+	out := make(map[string]int, len(sizes))
+	for k, n := range sizes {
+		if n > 0 {
+			out[k] = n
+		}
+	}
+	return out
+}
+
+// tickCapacity returns the tick ring capacity for instrument. Callers hold sm.mu.
+func (sm *StateManager) tickCapacity(instrument string) int {
+	if n, ok := sm.tickBufferSizes[instrument]; ok {
+		return n
+	}
+	return sm.tickBufferSize
+}
+
+// barCapacity returns the number of bars kept for period. Callers hold sm.mu.
+func (sm *StateManager) barCapacity(period string) int {
+	if n, ok := sm.barBufferSizes[period]; ok {
+		return n
+	}
+	return sm.barBufferSize
+}
+
+// BarCapacity returns the number of bars kept for period; historical bars beyond it are trimmed.
+func (sm *StateManager) BarCapacity(period string) int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.barCapacity(period)
+}
+
+// TickCount returns the number of ticks stored since startup.
+func (sm *StateManager) TickCount() int64 { return sm.tickCount.Load() }
+
 // UpdateTick adds a new tick to the state, ensuring the history size is maintained.
 func (sm *StateManager) UpdateTick(tick Tick) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	instrumentTicks := sm.ticks[tick.Instrument]
-	instrumentTicks = append(instrumentTicks, tick)
+	ring, ok := sm.ticks[tick.Instrument]
+	if !ok {
+		ring = newTickRing(sm.tickCapacity(tick.Instrument))
+		sm.ticks[tick.Instrument] = ring
+	}
+	if sm.isDuplicateTick(ring, tick) {
+		sm.dedupedTicks.Add(1)
+		return
+	}
+	// Overwrites the oldest tick in place once full; no per-tick allocation.
+	ring.push(tick)
+	sm.tickCount.Add(1)
+
+	if pips, ok := tickSpreadPips(tick); ok {
+		acc, ok := sm.spreads[tick.Instrument]
+		if !ok {
+			acc = newSpreadAccumulator(spreadWindowSize)
+			sm.spreads[tick.Instrument] = acc
+		}
+		acc.add(pips, tick.Timestamp)
+	}
 
-	// Trim the slice to maintain the ring buffer size.
-	if len(instrumentTicks) > tickRingBufferSize {
-		instrumentTicks = instrumentTicks[len(instrumentTicks)-tickRingBufferSize:]
+	if price, ts, ok := tickSessionPrice(tick); ok {
+		acc, ok := sm.sessions[tick.Instrument]
+		if !ok {
+			acc = &sessionAccumulator{}
+			sm.sessions[tick.Instrument] = acc
+		}
+		acc.add(tick.Instrument, price, ts, sm.sessionOffset)
+	}
+}
+
+// GetSpreadStats returns rolling spread statistics (in pips) for an instrument.
+// ok is false when no valid tick has been seen for it yet.
+func (sm *StateManager) GetSpreadStats(instrument string) (SpreadStats, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	acc, ok := sm.spreads[instrument]
+	if !ok {
+		return SpreadStats{Instrument: instrument}, false
+	}
+	return acc.stats(instrument), true
+}
+
+// ClearResult reports how much data StateManager.Clear removed.
+type ClearResult struct {
+	Instrument     string `json:"instrument"`
+	Period         string `json:"period,omitempty"`
+	Ticks          int    `json:"ticks"`
+	Bars           int    `json:"bars"`
+	HistoricalBars int    `json:"historicalBars"`
+}
+
+// Clear drops buffered data for an instrument so a bad feed can be flushed without a restart.
+// What: With a period, only that period's live and historical bars are removed; with an empty period,
+//       all bars plus the instrument's ticks, spread and session statistics are removed too.
+// How: Deletes the map entries under the write lock; the buffers refill from the next messages.
+// Returns: counts of removed ticks, bars, and historical bars.
+func (sm *StateManager) Clear(instrument, period string) ClearResult {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	res := ClearResult{Instrument: instrument, Period: period}
+	if period != "" {
+		res.Bars = len(sm.bars[instrument][period])
+		res.HistoricalBars = len(sm.historicalBars[instrument][period])
+		delete(sm.bars[instrument], period)
+		delete(sm.historicalBars[instrument], period)
+		delete(sm.histSeq, instrument+"|"+period)
+		return res
+	}
+
+	if ring, ok := sm.ticks[instrument]; ok {
+		res.Ticks = ring.len()
+	}
+	for _, bars := range sm.bars[instrument] {
+		res.Bars += len(bars)
+	}
+	for p, bars := range sm.historicalBars[instrument] {
+		res.HistoricalBars += len(bars)
+		delete(sm.histSeq, instrument+"|"+p)
+	}
+	delete(sm.ticks, instrument)
+	delete(sm.spreads, instrument)
+	delete(sm.sessions, instrument)
+	delete(sm.bars, instrument)
+	delete(sm.historicalBars, instrument)
+	return res
+}
+
+// SetTickBufferSize changes the default per-instrument tick capacity, keeping the newest ticks.
+// Instruments with their own size in StateManagerConfig.TickBufferSizes keep it.
+func (sm *StateManager) SetTickBufferSize(n int) {
+	if n < 1 {
+		return
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.tickBufferSize = n
+	for instrument, ring := range sm.ticks {
+		if _, ok := sm.tickBufferSizes[instrument]; !ok {
+			sm.ticks[instrument] = ring.resize(n)
+		}
 	}
-	sm.ticks[tick.Instrument] = instrumentTicks
 }
 
 // UpdateBar adds a new live bar to the state, ensuring the history size is maintained.
@@ -67,14 +294,14 @@ func (sm *StateManager) UpdateBar(bar Bar) {
 	periodBars = append(periodBars, bar)
 
 	// Trim the slice to maintain the ring buffer size.
-	if len(periodBars) > barRingBufferSize {
-		periodBars = periodBars[len(periodBars)-barRingBufferSize:]
+	if n := sm.barCapacity(bar.Period); len(periodBars) > n {
+		periodBars = periodBars[len(periodBars)-n:]
 	}
 	sm.bars[bar.Instrument][bar.Period] = periodBars
 }
 
 // UpdateHistoricalBar adds/updates a historical bar with timestamp-keyed deduplication.
-// What: Insert or update a HistoricalBar for instrument/period while keeping at most 200, newest-first.
+// What: Insert or update a HistoricalBar for instrument/period while keeping the period's bar capacity (default 200), newest-first.
 // How: Prefer BarEndTimestamp as the primary identity (dedup) and fall back to Sequence for legacy updates.
 // Params: bar HistoricalBar (complete OHLCV+indicators, UTC timestamps)
 // Returns: none (mutates in-memory state)
@@ -86,6 +313,16 @@ func (sm *StateManager) UpdateHistoricalBar(bar HistoricalBar) {
 		sm.historicalBars[bar.Instrument] = make(map[string][]HistoricalBar)
 	}
 
+	if bar.Sequence > 0 {
+		key := bar.Instrument + "|" + bar.Period
+		t, ok := sm.histSeq[key]
+		if !ok {
+			t = &sequenceTracker{}
+			sm.histSeq[key] = t
+		}
+		t.add(bar.Sequence, bar.BarEndTimestamp, time.Now())
+	}
+
 	periodBars := sm.historicalBars[bar.Instrument][bar.Period]
 
 	// 1) Dedup by bar_end_timestamp (UTC): replace existing entry if same ts
@@ -97,45 +334,120 @@ func (sm *StateManager) UpdateHistoricalBar(bar HistoricalBar) {
 		}
 	}
 
-	// 2) Legacy: if a bar arrives with same Sequence, treat it as an update
+	// 2) Legacy: a bar without a timestamp and with the same Sequence is an update. Timestamped bars
+	//    never match by Sequence, since live-merged bars number independently of historical responses.
 	for i := range periodBars {
-		if periodBars[i].Sequence == bar.Sequence && bar.Sequence != 0 {
+		if bar.BarEndTimestamp == 0 && periodBars[i].Sequence == bar.Sequence && bar.Sequence != 0 {
 			periodBars[i] = bar
 			sm.historicalBars[bar.Instrument][bar.Period] = periodBars
 			return
 		}
 	}
 
-	// 3) Add new bar and keep newest-first ordering
-	periodBars = append(periodBars, bar)
+	// 3) Add new bar and keep newest-first ordering. A bar newer than the buffer (live updates,
+	//    backfills streamed oldest-first) is prepended; anything else is sorted into place.
+	if isNewestBar(periodBars, bar) {
+		periodBars = prependBar(periodBars, bar)
+	} else {
+		periodBars = append(periodBars, bar)
+		sortNewestFirst(periodBars)
 
-	// Sort newest-first by BarEndTimestamp (robust across sources); ties keep earlier element
-	for i := 0; i < len(periodBars)-1; i++ {
-		for j := i + 1; j < len(periodBars); j++ {
-			if periodBars[i].BarEndTimestamp < periodBars[j].BarEndTimestamp {
-				periodBars[i], periodBars[j] = periodBars[j], periodBars[i]
-			}
-		}
+		// 4) Remove any duplicates by timestamp that may still exist (first occurrence wins)
+		periodBars = dedupBars(periodBars)
 	}
 
-	// 4) Remove any duplicates by timestamp that may still exist (first occurrence wins)
-	seen := make(map[int64]struct{})
-	dedup := make([]HistoricalBar, 0, len(periodBars))
-	for _, b := range periodBars {
+	// 5) Trim to maintain buffer size (keep newest bars)
+	if n := sm.barCapacity(bar.Period); len(periodBars) > n {
+		periodBars = periodBars[:n]
+	}
+
+	sm.historicalBars[bar.Instrument][bar.Period] = periodBars
+}
+
+// isNewestBar reports whether bar ends after every bar of the newest-first buffer bars.
+func isNewestBar(bars []HistoricalBar, bar HistoricalBar) bool {
+	return len(bars) == 0 || bar.BarEndTimestamp > bars[0].BarEndTimestamp
+}
+
+// prependBar inserts bar at the front of bars, reusing spare capacity instead of reallocating.
+func prependBar(bars []HistoricalBar, bar HistoricalBar) []HistoricalBar {
+	bars = append(bars, HistoricalBar{})
+	copy(bars[1:], bars)
+	bars[0] = bar
+	return bars
+}
+
+// sortNewestFirst orders bars by BarEndTimestamp descending; ties keep their order. Buffers are
+// kept sorted, so an already ordered slice is only checked.
+func sortNewestFirst(bars []HistoricalBar) {
+	newer := func(i, j int) bool { return bars[i].BarEndTimestamp > bars[j].BarEndTimestamp }
+	if !sort.SliceIsSorted(bars, newer) {
+		sort.SliceStable(bars, newer)
+	}
+}
+
+// dedupBars drops bars repeating an earlier bar's BarEndTimestamp (first occurrence wins).
+func dedupBars(bars []HistoricalBar) []HistoricalBar {
+	seen := make(map[int64]struct{}, len(bars))
+	out := make([]HistoricalBar, 0, len(bars))
+	for _, b := range bars {
 		if _, ok := seen[b.BarEndTimestamp]; ok {
 			continue
 		}
 		seen[b.BarEndTimestamp] = struct{}{}
-		dedup = append(dedup, b)
+		out = append(out, b)
 	}
-	periodBars = dedup
+	return out
+}
 
-	// 5) Trim to maintain buffer size (keep newest bars)
-	if len(periodBars) > barRingBufferSize {
-		periodBars = periodBars[:barRingBufferSize]
+// ReplaceHistoricalBars swaps in a complete backfill for instrument/period in one step.
+// What: Strategies reading during a 200-bar backfill see either the old buffer or the new one,
+//       never a half-populated or reordering mix.
+// How: Sorts bars newest-first, drops duplicate timestamps (first wins) and trims to the bar capacity under the
+//      write lock. Bars already in the buffer that are newer than the backfill's newest bar (live bars
+//      merged while it was in flight) are kept. The sequence tracker is reset to the backfill's sequences.
+// Params: instrument, period, bars (any order)
+// Returns: none (mutates in-memory state)
+func (sm *StateManager) ReplaceHistoricalBars(instrument, period string, bars []HistoricalBar) {
+	sorted := make([]HistoricalBar, len(bars))
+	copy(sorted, bars)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].BarEndTimestamp > sorted[j].BarEndTimestamp })
+	var newestEnd int64
+	if len(sorted) > 0 {
+		newestEnd = sorted[0].BarEndTimestamp
 	}
 
-	sm.historicalBars[bar.Instrument][bar.Period] = periodBars
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if _, ok := sm.historicalBars[instrument]; !ok {
+		sm.historicalBars[instrument] = make(map[string][]HistoricalBar)
+	}
+	capacity := sm.barCapacity(period)
+	out := make([]HistoricalBar, 0, capacity)
+	for _, b := range sm.historicalBars[instrument][period] {
+		if b.BarEndTimestamp > newestEnd {
+			out = append(out, b)
+		}
+	}
+	seen := make(map[int64]struct{}, len(sorted))
+	t := &sequenceTracker{}
+	now := time.Now()
+	for _, b := range sorted {
+		if _, ok := seen[b.BarEndTimestamp]; ok {
+			continue
+		}
+		seen[b.BarEndTimestamp] = struct{}{}
+		if b.Sequence > 0 {
+			t.add(b.Sequence, b.BarEndTimestamp, now)
+		}
+		out = append(out, b)
+	}
+	if len(out) > capacity {
+		out = out[:capacity]
+	}
+	sm.historicalBars[instrument][period] = out
+	sm.histSeq[instrument+"|"+period] = t
 }
 
 // UpdateLiveBar integrates a newly closed bar (from the real-time stream) into the canonical bars.
@@ -152,7 +464,12 @@ func (sm *StateManager) UpdateLiveBar(bar Bar) {
 
 // updateHistoricalSequenceOnLiveBar integrates a newly completed live bar into historicals.
 // What: Insert/update the newest completed bar into the historical buffer for instrument/period.
-// How: Convert live->HistoricalBar, dedup by BarEndTimestamp; if new, prepend; keep <=200, newest-first.
+// How: Convert live->HistoricalBar, dedup by BarEndTimestamp; if new, prepend; keep the bar capacity, newest-first.
+//      A new bar gets Sequence max+1 over the buffer so the engine's sequence check sees every new
+//      bar; a replayed bar (same end timestamp) keeps the sequence it already has.
+//      A missing tick VWAP is computed from the retained ticks within the bar window (see tickVwap).
+//      Indicators the live bar carries (VWAP, ATR, Bollinger, Keltner, Donchian, Supertrend) are
+//      copied; the others are left zero.
 // Params: instrument, period, liveBar (completed bar)
 // Returns: none (mutates in-memory state)
 func (sm *StateManager) updateHistoricalSequenceOnLiveBar(instrument, period string, liveBar Bar) {
@@ -162,6 +479,19 @@ func (sm *StateManager) updateHistoricalSequenceOnLiveBar(instrument, period str
 
 	historicalBars := sm.historicalBars[instrument][period]
 
+	bidVwap, askVwap := liveBar.BidVwap, liveBar.AskVwap
+	if bidVwap.TickVwap == nil || askVwap.TickVwap == nil {
+		if ring, ok := sm.ticks[instrument]; ok {
+			bid, ask := tickVwap(ring.snapshot(), liveBar.BarStartTimestamp, liveBar.BarEndTimestamp, sm.tickVwapMinCoverage)
+			if bidVwap.TickVwap == nil {
+				bidVwap.TickVwap = bid
+			}
+			if askVwap.TickVwap == nil {
+				askVwap.TickVwap = ask
+			}
+		}
+	}
+
 	// Convert live bar to historical bar format
 	historicalBar := HistoricalBar{
 		ProducedAt:        liveBar.ProducedAt,
@@ -172,10 +502,10 @@ func (sm *StateManager) updateHistoricalSequenceOnLiveBar(instrument, period str
 		Period:            liveBar.Period,
 		Bid:               liveBar.Bid,
 		Ask:               liveBar.Ask,
-		BidVwap:           Vwap{TickVwap: nil}, // Live bars don't have tick VWAP
-		AskVwap:           Vwap{TickVwap: nil},
-		BidAtr:            0.0,
-		AskAtr:            0.0,
+		BidVwap:           bidVwap,
+		AskVwap:           askVwap,
+		BidAtr:            liveBar.BidAtr,
+		AskAtr:            liveBar.AskAtr,
 		BidObv:            0.0,
 		AskObv:            0.0,
 		BidDemas:          Demas{Dema25: 0, Dema50: 0, Dema100: 0, Dema200: 0},
@@ -192,64 +522,80 @@ func (sm *StateManager) updateHistoricalSequenceOnLiveBar(instrument, period str
 		AskMfi:            0.0,
 		BidBollinger:      liveBar.BidBollinger,
 		AskBollinger:      liveBar.AskBollinger,
-		BidKeltner:        Keltner{Upper: 0, Middle: 0, Lower: 0},
-		AskKeltner:        Keltner{Upper: 0, Middle: 0, Lower: 0},
+		BidKeltner:        liveBar.BidKeltner,
+		AskKeltner:        liveBar.AskKeltner,
 		BidDonchian:       liveBar.BidDonchian,
 		AskDonchian:       liveBar.AskDonchian,
-		BidSupertrend:     Supertrend{Upper: 0, Lower: 0},
-		AskSupertrend:     Supertrend{Upper: 0, Lower: 0},
-		Sequence:          1,
+		BidSupertrend:     liveBar.BidSupertrend,
+		AskSupertrend:     liveBar.AskSupertrend,
 	}
 
 	// 1) If a bar with the same end timestamp exists, replace it in-place
+	maxSeq := 0
+	for i := range historicalBars {
+		if historicalBars[i].Sequence > maxSeq {
+			maxSeq = historicalBars[i].Sequence
+		}
+	}
+	historicalBar.Sequence = maxSeq + 1
 	for i := range historicalBars {
 		if historicalBars[i].BarEndTimestamp == historicalBar.BarEndTimestamp {
+			historicalBar.Sequence = historicalBars[i].Sequence
 			historicalBars[i] = historicalBar
 			// Reorder newest-first by timestamp to be safe
-			for a := 0; a < len(historicalBars)-1; a++ {
-				for b := a + 1; b < len(historicalBars); b++ {
-					if historicalBars[a].BarEndTimestamp < historicalBars[b].BarEndTimestamp {
-						historicalBars[a], historicalBars[b] = historicalBars[b], historicalBars[a]
-					}
-				}
-			}
+			sortNewestFirst(historicalBars)
 			// Trim
-			if len(historicalBars) > barRingBufferSize {
-				historicalBars = historicalBars[:barRingBufferSize]
+			if n := sm.barCapacity(period); len(historicalBars) > n {
+				historicalBars = historicalBars[:n]
 			}
 			sm.historicalBars[instrument][period] = historicalBars
 			return
 		}
 	}
 
-	// 2) Otherwise prepend as the newest
-	historicalBars = append([]HistoricalBar{historicalBar}, historicalBars...)
+	// 2) Otherwise prepend as the newest; a late bar older than the buffer is sorted into place
+	if isNewestBar(historicalBars, historicalBar) {
+		historicalBars = prependBar(historicalBars, historicalBar)
+	} else {
+		historicalBars = append(historicalBars, historicalBar)
+		sortNewestFirst(historicalBars)
 
-	// 3) Remove any duplicates by timestamp (first occurrence wins)
-	seen := make(map[int64]struct{})
-	unique := make([]HistoricalBar, 0, len(historicalBars))
-	for _, b := range historicalBars {
-		if _, ok := seen[b.BarEndTimestamp]; ok {
-			continue
-		}
-		seen[b.BarEndTimestamp] = struct{}{}
-		unique = append(unique, b)
+		// 3) Remove any duplicates by timestamp (first occurrence wins)
+		historicalBars = dedupBars(historicalBars)
 	}
-	historicalBars = unique
 
 	// 4) Trim to maintain buffer size
-	if len(historicalBars) > barRingBufferSize {
-		historicalBars = historicalBars[:barRingBufferSize]
+	if n := sm.barCapacity(period); len(historicalBars) > n {
+		historicalBars = historicalBars[:n]
 	}
 
 	sm.historicalBars[instrument][period] = historicalBars
 }
 
 // UpdateAccountInfo updates the current account and position status.
-func (sm *StateManager) UpdateAccountInfo(info AccountInfo) {
+// Working (unfilled) orders are split out of Positions into PendingOrders.
+// A snapshot produced before the stored one is ignored (returns false) so a late message cannot
+// roll the account back.
+func (sm *StateManager) UpdateAccountInfo(info AccountInfo) bool {
+	filled := make([]Position, 0, len(info.Positions))
+	pending := make([]Position, 0)
+	for _, p := range info.Positions {
+		if p.State == PositionStatePending {
+			pending = append(pending, p)
+			continue
+		}
+		filled = append(filled, p)
+	}
+	info.Positions = filled
+	info.PendingOrders = append(pending, info.PendingOrders...)
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	if info.ProducedAt > 0 && info.ProducedAt < sm.accountInfo.ProducedAt {
+		return false
+	}
 	sm.accountInfo = info
+	return true
 }
 
 // GetTicks returns a copy of the recent ticks for a given instrument.
@@ -257,10 +603,26 @@ func (sm *StateManager) GetTicks(instrument string) []Tick {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
 
-	// Return a copy to prevent race conditions on the slice itself.
-	ticksCopy := make([]Tick, len(sm.ticks[instrument]))
-	copy(ticksCopy, sm.ticks[instrument])
-	return ticksCopy
+	// Return a copy (oldest to newest) to prevent race conditions on the buffer itself.
+	ring, ok := sm.ticks[instrument]
+	if !ok {
+		return []Tick{}
+	}
+	return ring.snapshot()
+}
+
+// LatestTicks returns the most recent tick per instrument, usable as a rate table for conversions.
+func (sm *StateManager) LatestTicks() map[string]Tick {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	out := make(map[string]Tick, len(sm.ticks))
+	for instrument, ring := range sm.ticks {
+		if t, ok := ring.last(); ok {
+			out[instrument] = t
+		}
+	}
+	return out
 }
 
 // GetBars returns a copy of the recent bars for a given instrument and period.
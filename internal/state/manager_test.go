@@ -0,0 +1,257 @@
+package state
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestClearPeriodAndInstrument(t *testing.T) {
+	sm := NewStateManager()
+	for i := int64(1); i <= 3; i++ {
+		sm.UpdateTick(Tick{Instrument: "EURUSD", Timestamp: i, Bid: 1.1, Ask: 1.1002})
+		for _, p := range []string{"ONE_MIN", "FIVE_MINS"} {
+			sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: p, BarEndTimestamp: i, Sequence: int(i)})
+		}
+	}
+	sm.UpdateTick(Tick{Instrument: "GBPUSD", Timestamp: 1, Bid: 1.3, Ask: 1.3002})
+
+	res := sm.Clear("EURUSD", "ONE_MIN")
+	if res.HistoricalBars != 3 || res.Ticks != 0 {
+		t.Fatalf("period clear = %+v", res)
+	}
+	if n := len(sm.GetHistoricalBars("EURUSD", "ONE_MIN")); n != 0 {
+		t.Fatalf("ONE_MIN still has %d bars", n)
+	}
+	if _, ok := sm.HistoricalSequenceStatus("EURUSD", "ONE_MIN", time.Now()); ok {
+		t.Fatal("sequence tracking should be cleared with the period")
+	}
+	if n := len(sm.GetHistoricalBars("EURUSD", "FIVE_MINS")); n != 3 {
+		t.Fatalf("FIVE_MINS has %d bars, want 3", n)
+	}
+
+	res = sm.Clear("EURUSD", "")
+	if res.Ticks != 3 || res.HistoricalBars != 3 {
+		t.Fatalf("instrument clear = %+v", res)
+	}
+	snap := sm.Snapshot()
+	if len(snap.Ticks["EURUSD"]) != 0 || len(snap.HistoricalBars["EURUSD"]) != 0 {
+		t.Fatal("EURUSD data should be gone")
+	}
+	if _, ok := sm.GetSpreadStats("EURUSD"); ok {
+		t.Fatal("spread stats should be cleared")
+	}
+	if len(snap.Ticks["GBPUSD"]) != 1 {
+		t.Fatal("other instruments must be untouched")
+	}
+}
+
+func TestLiveBarsGetIncreasingSequences(t *testing.T) {
+	sm := NewStateManager()
+	// Newest historical bar of a response is sequence 1, oldest is N
+	for i, seq := range []int{3, 2, 1} {
+		sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: int64(i+1) * 60_000, Sequence: seq})
+	}
+	live := func(end int64) { sm.UpdateLiveBar(Bar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: end}) }
+
+	live(240_000)
+	live(300_000)
+	bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN")
+	if bars[0].Sequence != 5 || bars[1].Sequence != 4 {
+		t.Fatalf("live sequences = %d, %d; want 5, 4", bars[0].Sequence, bars[1].Sequence)
+	}
+
+	// A replayed live bar keeps its sequence so it is not evaluated twice
+	live(300_000)
+	if bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN"); len(bars) != 5 || bars[0].Sequence != 5 {
+		t.Fatalf("replay: %d bars, newest sequence %d", len(bars), bars[0].Sequence)
+	}
+
+	// A later historical bar must not overwrite a live bar that happens to share its sequence
+	sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 360_000, Sequence: 4})
+	if bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN"); len(bars) != 6 || bars[2].BarEndTimestamp != 240_000 {
+		t.Fatalf("historical bar replaced a live bar by sequence: %d bars", len(bars))
+	}
+}
+
+func TestReplaceHistoricalBarsKeepsNewerLiveBars(t *testing.T) {
+	sm := NewStateManager()
+	sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 60_000, Sequence: 9})
+	sm.UpdateLiveBar(Bar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 300_000})
+
+	sm.ReplaceHistoricalBars("EURUSD", "ONE_MIN", []HistoricalBar{
+		{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 120_000, Sequence: 3},
+		{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 240_000, Sequence: 1},
+		{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 180_000, Sequence: 2},
+	})
+	bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN")
+	var ends []int64
+	for _, b := range bars {
+		ends = append(ends, b.BarEndTimestamp)
+	}
+	if len(ends) != 4 || ends[0] != 300_000 || ends[1] != 240_000 || ends[3] != 120_000 {
+		t.Fatalf("bar ends = %v, want the live bar then the backfill newest-first", ends)
+	}
+	if st, _ := sm.HistoricalSequenceStatus("EURUSD", "ONE_MIN", time.Now()); st.Expected != 3 || len(st.Missing) != 0 {
+		t.Fatalf("sequence status = %+v, want the backfill's 3 sequences", st)
+	}
+}
+
+func TestConfiguredBufferSizesKeepNewest(t *testing.T) {
+	sm := NewStateManager(StateManagerConfig{
+		TickBufferSize:  5,
+		TickBufferSizes: map[string]int{"EURUSD": 50},
+		BarBufferSize:   10,
+		BarBufferSizes:  map[string]int{"TEN_SECS": 30, "DAILY": 3},
+	})
+	for i := int64(1); i <= 100; i++ {
+		for _, inst := range []string{"EURUSD", "GBPUSD"} {
+			sm.UpdateTick(Tick{Instrument: inst, Timestamp: i})
+		}
+		for _, p := range []string{"TEN_SECS", "ONE_MIN", "DAILY"} {
+			sm.UpdateBar(Bar{Instrument: "EURUSD", Period: p, BarEndTimestamp: i})
+			sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: p, BarEndTimestamp: i})
+			sm.UpdateLiveBar(Bar{Instrument: "GBPUSD", Period: p, BarEndTimestamp: i})
+		}
+	}
+
+	for inst, want := range map[string]int{"EURUSD": 50, "GBPUSD": 5} {
+		ticks := sm.GetTicks(inst)
+		if len(ticks) != want || ticks[0].Timestamp != int64(101-want) || ticks[want-1].Timestamp != 100 {
+			t.Fatalf("%s: %d ticks from %d, want the newest %d", inst, len(ticks), ticks[0].Timestamp, want)
+		}
+	}
+	for p, want := range map[string]int{"TEN_SECS": 30, "ONE_MIN": 10, "DAILY": 3} {
+		if n := sm.BarCapacity(p); n != want {
+			t.Fatalf("BarCapacity(%s) = %d, want %d", p, n, want)
+		}
+		live := sm.GetBars("EURUSD", p) // oldest-first
+		if len(live) != want || live[0].BarEndTimestamp != int64(101-want) || live[want-1].BarEndTimestamp != 100 {
+			t.Fatalf("%s live bars: %d from %d, want the newest %d", p, len(live), live[0].BarEndTimestamp, want)
+		}
+		for _, inst := range []string{"EURUSD", "GBPUSD"} {
+			hist := sm.GetHistoricalBars(inst, p) // newest-first
+			if len(hist) != want || hist[0].BarEndTimestamp != 100 || hist[want-1].BarEndTimestamp != int64(101-want) {
+				t.Fatalf("%s %s historical bars: %d down to %d, want the newest %d", inst, p, len(hist), hist[len(hist)-1].BarEndTimestamp, want)
+			}
+		}
+	}
+
+	backfill := make([]HistoricalBar, 20)
+	for i := range backfill {
+		backfill[i] = HistoricalBar{Instrument: "USDJPY", Period: "DAILY", BarEndTimestamp: int64(i + 1), Sequence: 20 - i}
+	}
+	sm.ReplaceHistoricalBars("USDJPY", "DAILY", backfill)
+	if hist := sm.GetHistoricalBars("USDJPY", "DAILY"); len(hist) != 3 || hist[0].BarEndTimestamp != 20 {
+		t.Fatalf("backfill kept %d bars, want the newest 3", len(hist))
+	}
+
+	// Changing the default size leaves instruments with their own size alone
+	sm.SetTickBufferSize(2)
+	if n, m := len(sm.GetTicks("EURUSD")), len(sm.GetTicks("GBPUSD")); n != 50 || m != 2 {
+		t.Fatalf("after SetTickBufferSize(2): EURUSD %d, GBPUSD %d ticks; want 50 and 2", n, m)
+	}
+}
+
+func TestZeroConfigUsesDefaultBufferSizes(t *testing.T) {
+	sm := NewStateManager(StateManagerConfig{TickBufferSizes: map[string]int{"EURUSD": 0}})
+	for i := int64(1); i <= barRingBufferSize+10; i++ {
+		sm.UpdateTick(Tick{Instrument: "EURUSD", Timestamp: i})
+		sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: i})
+	}
+	if n := len(sm.GetTicks("EURUSD")); n != tickRingBufferSize {
+		t.Fatalf("%d ticks, want the default %d", n, tickRingBufferSize)
+	}
+	if n := len(sm.GetHistoricalBars("EURUSD", "ONE_MIN")); n != barRingBufferSize {
+		t.Fatalf("%d bars, want the default %d", n, barRingBufferSize)
+	}
+}
+
+func TestParseBufferSizes(t *testing.T) {
+	got, err := ParseBufferSizes(" ten_secs:1000, DAILY:50 ")
+	if err != nil || len(got) != 2 || got["TEN_SECS"] != 1000 || got["DAILY"] != 50 {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	for _, bad := range []string{"DAILY", "DAILY:0", "DAILY:-5", ":10", "DAILY:many"} {
+		if _, err := ParseBufferSizes(bad); err == nil {
+			t.Errorf("ParseBufferSizes(%q) should fail", bad)
+		}
+	}
+}
+
+func TestHistoricalBarsStayNewestFirst(t *testing.T) {
+	sm := NewStateManager()
+	for _, end := range rand.New(rand.NewSource(1)).Perm(50) {
+		sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: int64(end+1) * 60_000})
+	}
+	// A live bar older than the newest (delivered late) is sorted into place, not prepended
+	sm.UpdateLiveBar(Bar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 3030_000})
+	sm.UpdateLiveBar(Bar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 3060_000})
+	bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN")
+	if len(bars) != 52 || bars[0].BarEndTimestamp != 3060_000 {
+		t.Fatalf("%d bars, newest %d; want 52 with the newest live bar first", len(bars), bars[0].BarEndTimestamp)
+	}
+	for i := 1; i < len(bars); i++ {
+		if bars[i-1].BarEndTimestamp <= bars[i].BarEndTimestamp {
+			t.Fatalf("bars[%d] ends at %d after bars[%d] at %d; want strictly newest-first", i, bars[i].BarEndTimestamp, i-1, bars[i-1].BarEndTimestamp)
+		}
+	}
+}
+
+// nestedLoopInsert reproduces the previous insert: append, then a nested-loop sort newest-first.
+func nestedLoopInsert(bars []HistoricalBar, bar HistoricalBar) []HistoricalBar {
+	bars = append(bars, bar)
+	for i := 0; i < len(bars)-1; i++ {
+		for j := i + 1; j < len(bars); j++ {
+			if bars[i].BarEndTimestamp < bars[j].BarEndTimestamp {
+				bars[i], bars[j] = bars[j], bars[i]
+			}
+		}
+	}
+	return bars[:barRingBufferSize]
+}
+
+// sortedInsert is the current insert without the StateManager bookkeeping.
+func sortedInsert(bars []HistoricalBar, bar HistoricalBar) []HistoricalBar {
+	if isNewestBar(bars, bar) {
+		bars = prependBar(bars, bar)
+	} else {
+		bars = append(bars, bar)
+		sortNewestFirst(bars)
+	}
+	return bars[:barRingBufferSize]
+}
+
+// benchmarkInsert inserts bars into a full 200-bar buffer; newest adds each bar as the newest
+// (the live and backfill case), otherwise each bar lands mid-buffer.
+func benchmarkInsert(b *testing.B, insert func([]HistoricalBar, HistoricalBar) []HistoricalBar, newest bool) {
+	bars := make([]HistoricalBar, barRingBufferSize)
+	for i := range bars {
+		bars[i].BarEndTimestamp = int64(barRingBufferSize-i) * 2
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		end := bars[barRingBufferSize/2].BarEndTimestamp + 1
+		if newest {
+			end = bars[0].BarEndTimestamp + 2
+		}
+		bars = insert(bars, HistoricalBar{BarEndTimestamp: end})
+	}
+}
+
+func BenchmarkHistoricalInsertNestedLoopNewest(b *testing.B) {
+	benchmarkInsert(b, nestedLoopInsert, true)
+}
+
+func BenchmarkHistoricalInsertSortedNewest(b *testing.B) {
+	benchmarkInsert(b, sortedInsert, true)
+}
+
+func BenchmarkHistoricalInsertNestedLoopMiddle(b *testing.B) {
+	benchmarkInsert(b, nestedLoopInsert, false)
+}
+
+func BenchmarkHistoricalInsertSortedMiddle(b *testing.B) {
+	benchmarkInsert(b, sortedInsert, false)
+}
@@ -67,6 +67,13 @@ type Bar struct {
 	AskDonchian       Donchian  `json:"ask_donchian"`
 	BidBollinger      Bollinger `json:"bid_bollinger"`
 	AskBollinger      Bollinger `json:"ask_bollinger"`
+	// ATR, Keltner and Supertrend are carried into the merged historical bar
+	BidAtr        float64    `json:"bid_atr"`
+	AskAtr        float64    `json:"ask_atr"`
+	BidKeltner    Keltner    `json:"bid_keltner"`
+	AskKeltner    Keltner    `json:"ask_keltner"`
+	BidSupertrend Supertrend `json:"bid_supertrend"`
+	AskSupertrend Supertrend `json:"ask_supertrend"`
 }
 
 // Demas contains the double exponential moving averages.
@@ -119,8 +126,8 @@ type HistoricalBar struct {
 	Period            string     `json:"period"`
 	Bid               OHLCV      `json:"bid"`
 	Ask               OHLCV      `json:"ask"`
-	BidVwap           Vwap       `json:"bid_vwap"` // TickVwap will be null
-	AskVwap           Vwap       `json:"ask_vwap"` // TickVwap will be null
+	BidVwap           Vwap       `json:"bid_vwap"` // TickVwap is null unless computed for a live bar
+	AskVwap           Vwap       `json:"ask_vwap"` // TickVwap is null unless computed for a live bar
 	BidAtr            float64    `json:"bid_atr"`
 	AskAtr            float64    `json:"ask_atr"`
 	BidObv            float64    `json:"bid_obv"`
@@ -175,10 +182,16 @@ type Position struct {
 	State        string  `json:"state"`
 }
 
+// PositionStatePending is the broker state of a working (unfilled) limit/stop order.
+const PositionStatePending = "OPENED"
+
 // AccountInfo represents the complete account status message.
 type AccountInfo struct {
 	ProducedAt int64      `json:"produced_at"`
 	Timestamp  int64      `json:"timestamp"`
 	Account    Account    `json:"account"`
 	Positions  []Position `json:"positions"`
+	// PendingOrders holds working orders that have not filled yet. The broker reports them
+	// inside positions; UpdateAccountInfo moves them here so Positions only holds filled trades.
+	PendingOrders []Position `json:"pendingOrders"`
 }
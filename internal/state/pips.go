@@ -0,0 +1,242 @@
+package state
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// lotUnits is the number of base-currency units in 1.0 of order amount (0.10 = 10k).
+const lotUnits = 100000.0
+
+// PipSize returns pip size based on instrument (0.01 for JPY quotes, otherwise 0.0001).
+func PipSize(instrument string) float64 {
+	if strings.Contains(instrument, "JPY") {
+		return 0.01
+	}
+	return 0.0001
+}
+
+// DefaultSlippagePips is the market-order slippage for instruments without their own entry.
+const DefaultSlippagePips = 5.0
+
+// defaultSlippagePips holds the built-in market-order slippage in pips for fast-moving crosses.
+var defaultSlippagePips = map[string]float64{
+	"GBPJPY": 10,
+	"EURJPY": 8,
+	"GBPUSD": 6,
+}
+
+var (
+	slippageMu sync.RWMutex
+	// slippagePips holds per-instrument slippage: the defaults plus the configured overrides.
+	slippagePips = withPips(defaultSlippagePips, nil)
+)
+
+// DefaultSlippage returns the market-order slippage in pips for instrument, used by manual and
+// strategy orders when the request does not set one.
+func DefaultSlippage(instrument string) float64 {
+	slippageMu.RLock()
+	defer slippageMu.RUnlock()
+	if v, ok := slippagePips[strings.ToUpper(instrument)]; ok {
+		return v
+	}
+	return DefaultSlippagePips
+}
+
+// SetDefaultSlippage replaces the per-instrument slippage overrides; instruments not given revert to
+// the built-in defaults, so an override removed from the config on reload no longer applies.
+func SetDefaultSlippage(overrides map[string]float64) {
+	table := withPips(defaultSlippagePips, overrides)
+	slippageMu.Lock()
+	slippagePips = table
+	slippageMu.Unlock()
+}
+
+// withPips returns a new table holding base plus entries (instruments upper-cased), entries winning.
+func withPips(base, entries map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(base)+len(entries))
+	for instr, v := range base {
+		out[instr] = v
+	}
+	for instr, v := range entries {
+		out[strings.ToUpper(instr)] = v
+	}
+	return out
+}
+
+// ParseSlippage parses per-instrument slippage overrides, e.g. "GBPJPY:12,EURJPY:9" (pips, >= 0).
+func ParseSlippage(v string) (map[string]float64, error) {
+	return parseInstrumentPips("slippage", v)
+}
+
+// parseInstrumentPips parses "INSTRUMENT:pips" entries separated by commas (pips >= 0).
+// kind names the setting in errors.
+func parseInstrumentPips(kind, v string) (map[string]float64, error) {
+	out := make(map[string]float64)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		instr, pips, ok := strings.Cut(entry, ":")
+		instr = strings.ToUpper(strings.TrimSpace(instr))
+		if !ok || len(instr) != 6 {
+			return nil, fmt.Errorf("%s %q: want INSTRUMENT:pips", kind, entry)
+		}
+		n, err := strconv.ParseFloat(strings.TrimSpace(pips), 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s %q: bad pips %q", kind, entry, pips)
+		}
+		out[instr] = n
+	}
+	return out, nil
+}
+
+var (
+	minStopMu sync.RWMutex
+	// minStopPips holds the broker's minimum SL/TP distance from entry per instrument (none by default).
+	minStopPips = map[string]float64{}
+)
+
+// MinStopPips returns the minimum SL/TP distance in pips for instrument, or 0 when unconstrained.
+func MinStopPips(instrument string) float64 {
+	minStopMu.RLock()
+	defer minStopMu.RUnlock()
+	return minStopPips[strings.ToUpper(instrument)]
+}
+
+// SetMinStopPips replaces the per-instrument minimum stop distances; instruments not given are unconstrained.
+func SetMinStopPips(mins map[string]float64) {
+	table := withPips(nil, mins)
+	minStopMu.Lock()
+	minStopPips = table
+	minStopMu.Unlock()
+}
+
+// ParseMinStopPips parses per-instrument minimum stop distances, e.g. "EURUSD:5,GBPJPY:8" (pips, >= 0).
+func ParseMinStopPips(v string) (map[string]float64, error) {
+	return parseInstrumentPips("min stop", v)
+}
+
+// ClampStopPips widens an SL/TP distance to the instrument's minimum stop distance.
+// A distance of 0 (no stop) is left alone. widened reports whether the distance was raised.
+func ClampStopPips(instrument string, pips float64) (clamped float64, widened bool) {
+	if min := MinStopPips(instrument); pips > 0 && pips < min {
+		return min, true
+	}
+	return pips, false
+}
+
+var (
+	digitsMu sync.RWMutex
+	// priceDigits holds per-instrument price precision overrides (e.g. metals or CFDs).
+	priceDigits = map[string]int{}
+)
+
+// PriceDigits returns the number of decimals instrument is quoted in: an override, otherwise
+// 3 for JPY quotes and 5 for other FX.
+func PriceDigits(instrument string) int {
+	digitsMu.RLock()
+	d, ok := priceDigits[strings.ToUpper(instrument)]
+	digitsMu.RUnlock()
+	if ok {
+		return d
+	}
+	if strings.Contains(strings.ToUpper(instrument), "JPY") {
+		return 3
+	}
+	return 5
+}
+
+// SetPriceDigits replaces the per-instrument price precision overrides; instruments not given use the
+// JPY/FX defaults again.
+func SetPriceDigits(digits map[string]int) {
+	table := make(map[string]int, len(digits))
+	for instr, d := range digits {
+		table[strings.ToUpper(instr)] = d
+	}
+	digitsMu.Lock()
+	priceDigits = table
+	digitsMu.Unlock()
+}
+
+// ParsePriceDigits parses per-instrument price precision, e.g. "XAUUSD:2,USDHUF:3" (0-8 decimals).
+func ParsePriceDigits(v string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		instr, digits, ok := strings.Cut(entry, ":")
+		instr = strings.ToUpper(strings.TrimSpace(instr))
+		if !ok || len(instr) != 6 {
+			return nil, fmt.Errorf("price digits %q: want INSTRUMENT:digits", entry)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(digits))
+		if err != nil || n < 0 || n > 8 {
+			return nil, fmt.Errorf("price digits %q: bad digits %q", entry, digits)
+		}
+		out[instr] = n
+	}
+	return out, nil
+}
+
+// RoundPrice rounds a computed price to instrument's precision (PriceDigits), half away from zero.
+// What: Pip arithmetic leaves float noise (1.10000000000002) that brokers reject or round oddly.
+// How: The scaled price is first snapped to 6 decimals so that noise on an exact half
+//      (1.0999949999999 for 1.099995) still rounds up. 0 (no SL/TP) stays 0.
+func RoundPrice(instrument string, price float64) float64 {
+	p := math.Pow10(PriceDigits(instrument))
+	scaled := math.Round(price*p*1e6) / 1e6
+	return math.Round(scaled) / p
+}
+
+// PipValue returns the value of one pip for the given amount, in the account currency.
+// What: Pip value that is correct for USD-quoted, USD-based, and cross pairs (EURGBP, GBPJPY, ...).
+// How: One pip is worth units*pipSize in the quote currency, converted to the account currency
+//      with ConversionRate (direct, inverted, or crossed through USD from the latest ticks).
+// Params: instrument e.g. "EURGBP", lots order amount, rates latest tick per instrument
+// Returns: pip value in account currency, or 0 when no conversion rate is available.
+func PipValue(instrument string, lots float64, rates map[string]Tick) float64 {
+	if len(instrument) != 6 || lots <= 0 {
+		return 0
+	}
+	quote := instrument[3:]
+	quoteValue := lots * lotUnits * PipSize(instrument)
+	v, ok := ToAccountCurrency(quoteValue, quote, rates)
+	if !ok {
+		return 0
+	}
+	return v
+}
+
+// PositionPnL marks an open position to market in the account currency using the latest ticks.
+// Longs exit at bid, shorts at ask. Returns false when prices or conversion are unavailable.
+func PositionPnL(p Position, rates map[string]Tick) (float64, bool) {
+	t, ok := rates[p.Instrument]
+	if !ok || p.OpenPrice <= 0 {
+		return 0, false
+	}
+	pv := PipValue(p.Instrument, p.Amount, rates)
+	if pv == 0 {
+		return 0, false
+	}
+	pip := PipSize(p.Instrument)
+	var pips float64
+	if strings.HasPrefix(strings.ToUpper(p.OrderCommand), "SELL") {
+		if t.Ask <= 0 {
+			return 0, false
+		}
+		pips = (p.OpenPrice - t.Ask) / pip
+	} else {
+		if t.Bid <= 0 {
+			return 0, false
+		}
+		pips = (t.Bid - p.OpenPrice) / pip
+	}
+	return pips * pv, true
+}
@@ -0,0 +1,108 @@
+package state
+
+import "testing"
+
+func TestDefaultSlippage(t *testing.T) {
+	if got := DefaultSlippage("EURUSD"); got != DefaultSlippagePips {
+		t.Fatalf("EURUSD: got %v, want %v", got, DefaultSlippagePips)
+	}
+	overrides, err := ParseSlippage("gbpjpy:12, AUDUSD:4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetDefaultSlippage(nil)
+	SetDefaultSlippage(overrides)
+	if got := DefaultSlippage("GBPJPY"); got != 12 {
+		t.Fatalf("GBPJPY override: got %v, want 12", got)
+	}
+	if got := DefaultSlippage("audusd"); got != 4 {
+		t.Fatalf("AUDUSD override: got %v, want 4", got)
+	}
+	if got := DefaultSlippage("EURJPY"); got != 8 {
+		t.Fatalf("EURJPY untouched: got %v, want 8", got)
+	}
+
+	// A reload without the overrides reverts them to the defaults
+	SetDefaultSlippage(map[string]float64{"AUDUSD": 4})
+	if got := DefaultSlippage("GBPJPY"); got != 10 {
+		t.Fatalf("GBPJPY after reload: got %v, want the default 10", got)
+	}
+	SetDefaultSlippage(nil)
+	if got := DefaultSlippage("AUDUSD"); got != DefaultSlippagePips {
+		t.Fatalf("AUDUSD after reload: got %v, want %v", got, DefaultSlippagePips)
+	}
+	for _, bad := range []string{"GBPJPY", "GBPJPY:-1", "GBP:3", "GBPJPY:x"} {
+		if _, err := ParseSlippage(bad); err == nil {
+			t.Errorf("ParseSlippage(%q) should fail", bad)
+		}
+	}
+}
+
+func TestClampStopPips(t *testing.T) {
+	mins, err := ParseMinStopPips("eurusd:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetMinStopPips(nil)
+	SetMinStopPips(mins)
+	if got, widened := ClampStopPips("EURUSD", 3); got != 5 || !widened {
+		t.Fatalf("tight stop: got %v (widened=%v), want 5", got, widened)
+	}
+	if got, widened := ClampStopPips("EURUSD", 8); got != 8 || widened {
+		t.Fatalf("wide stop: got %v (widened=%v), want 8", got, widened)
+	}
+	if got, _ := ClampStopPips("EURUSD", 0); got != 0 {
+		t.Fatalf("no stop: got %v, want 0", got)
+	}
+	if got, widened := ClampStopPips("GBPUSD", 1); got != 1 || widened {
+		t.Fatalf("unconstrained instrument: got %v (widened=%v), want 1", got, widened)
+	}
+	if _, err := ParseMinStopPips("EURUSD:-2"); err == nil {
+		t.Error("negative minimum should fail")
+	}
+
+	// A reload without EURUSD removes its minimum
+	SetMinStopPips(map[string]float64{"GBPUSD": 2})
+	if got, widened := ClampStopPips("EURUSD", 3); got != 3 || widened {
+		t.Fatalf("EURUSD after reload: got %v (widened=%v), want unconstrained", got, widened)
+	}
+}
+
+func TestRoundPrice(t *testing.T) {
+	digits, err := ParsePriceDigits("xauusd:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetPriceDigits(nil)
+	SetPriceDigits(digits)
+	cases := []struct {
+		instrument string
+		in, want   float64
+	}{
+		{"EURUSD", 1.10000000000002, 1.1},
+		{"EURUSD", 1.1001 - 15*0.0001, 1.0986}, // 1.0985999999999998 from pip arithmetic
+		{"EURUSD", 1.099995, 1.1},              // exact half rounds up despite float noise
+		{"EURUSD", 1.123454, 1.12345},
+		{"USDJPY", 150.005 + 15*0.01, 150.155},
+		{"USDJPY", 149.9995, 150},
+		{"XAUUSD", 2345.678, 2345.68},
+		{"EURUSD", 0, 0},
+	}
+	for _, c := range cases {
+		if got := RoundPrice(c.instrument, c.in); got != c.want {
+			t.Errorf("RoundPrice(%s, %v) = %v, want %v", c.instrument, c.in, got, c.want)
+		}
+	}
+	if PriceDigits("eurjpy") != 3 || PriceDigits("GBPUSD") != 5 {
+		t.Fatalf("defaults: got %d/%d, want 3/5", PriceDigits("eurjpy"), PriceDigits("GBPUSD"))
+	}
+	SetPriceDigits(map[string]int{"USDHUF": 3})
+	if got := PriceDigits("XAUUSD"); got != 5 {
+		t.Fatalf("XAUUSD after a reload without it: got %d, want the default 5", got)
+	}
+	for _, bad := range []string{"XAUUSD", "XAUUSD:9", "XAUUSD:-1", "XAU:2", "XAUUSD:two"} {
+		if _, err := ParsePriceDigits(bad); err == nil {
+			t.Errorf("ParsePriceDigits(%q) should fail", bad)
+		}
+	}
+}
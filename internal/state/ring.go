@@ -0,0 +1,61 @@
+package state
+
+// tickRing is a fixed-capacity circular buffer of ticks that overwrites the oldest entry in place.
+// It is not safe for concurrent use; StateManager guards it with its mutex.
+type tickRing struct {
+	buf   []Tick
+	start int // index of the oldest tick
+	count int
+}
+
+func newTickRing(capacity int) *tickRing {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &tickRing{buf: make([]Tick, capacity)}
+}
+
+// push appends a tick, evicting the oldest once full. Never allocates.
+func (r *tickRing) push(t Tick) {
+	n := len(r.buf)
+	if r.count < n {
+		r.buf[(r.start+r.count)%n] = t
+		r.count++
+		return
+	}
+	r.buf[r.start] = t
+	r.start = (r.start + 1) % n
+}
+
+// len returns the number of stored ticks.
+func (r *tickRing) len() int { return r.count }
+
+// last returns the newest tick.
+func (r *tickRing) last() (Tick, bool) {
+	if r.count == 0 {
+		return Tick{}, false
+	}
+	return r.buf[(r.start+r.count-1)%len(r.buf)], true
+}
+
+// snapshot returns a copy of the ticks ordered oldest to newest.
+func (r *tickRing) snapshot() []Tick {
+	out := make([]Tick, r.count)
+	n := len(r.buf)
+	first := n - r.start
+	if first > r.count {
+		first = r.count
+	}
+	copy(out, r.buf[r.start:r.start+first])
+	copy(out[first:], r.buf[:r.count-first])
+	return out
+}
+
+// resize returns a ring with the new capacity holding the newest ticks.
+func (r *tickRing) resize(capacity int) *tickRing {
+	nr := newTickRing(capacity)
+	for _, t := range r.snapshot() {
+		nr.push(t)
+	}
+	return nr
+}
@@ -0,0 +1,111 @@
+package state
+
+import (
+	"testing"
+)
+
+func TestTickRingOrderAndEviction(t *testing.T) {
+	r := newTickRing(3)
+	if got := r.snapshot(); len(got) != 0 {
+		t.Fatalf("empty ring snapshot len = %d", len(got))
+	}
+	for i := 1; i <= 5; i++ {
+		r.push(Tick{Timestamp: int64(i)})
+	}
+	got := r.snapshot()
+	want := []int64{3, 4, 5}
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Timestamp != want[i] {
+			t.Fatalf("snapshot[%d] = %d, want %d", i, got[i].Timestamp, want[i])
+		}
+	}
+	if last, _ := r.last(); last.Timestamp != 5 {
+		t.Fatalf("last = %d, want 5", last.Timestamp)
+	}
+}
+
+func TestGetTicksCopyOnRead(t *testing.T) {
+	sm := NewStateManager()
+	sm.UpdateTick(Tick{Instrument: "EURUSD", Bid: 1})
+	ticks := sm.GetTicks("EURUSD")
+	ticks[0].Bid = 99
+	if sm.GetTicks("EURUSD")[0].Bid != 1 {
+		t.Fatal("GetTicks must return a copy")
+	}
+}
+
+func TestSetTickBufferSizeKeepsNewest(t *testing.T) {
+	sm := NewStateManager()
+	for i := 1; i <= 10; i++ {
+		sm.UpdateTick(Tick{Instrument: "EURUSD", Timestamp: int64(i)})
+	}
+	sm.SetTickBufferSize(4)
+	got := sm.GetTicks("EURUSD")
+	if len(got) != 4 || got[0].Timestamp != 7 || got[3].Timestamp != 10 {
+		t.Fatalf("after resize got %v", got)
+	}
+}
+
+// BenchmarkUpdateTick measures steady-state cost of storing ticks; the ring buffer
+// should report 0 allocs/op once full (the previous append+reslice allocated repeatedly).
+func BenchmarkUpdateTick(b *testing.B) {
+	sm := NewStateManager()
+	instruments := []string{"EURUSD", "GBPUSD", "USDJPY", "USDCHF", "AUDUSD", "USDCAD", "NZDUSD", "EURJPY", "GBPJPY", "EURGBP"}
+	for _, inst := range instruments {
+		for i := 0; i < tickRingBufferSize; i++ {
+			sm.UpdateTick(Tick{Instrument: inst})
+		}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sm.UpdateTick(Tick{Instrument: instruments[i%len(instruments)], Timestamp: int64(i)})
+	}
+}
+
+// BenchmarkAppendReslice reproduces the previous append-and-trim storage for comparison.
+func BenchmarkAppendReslice(b *testing.B) {
+	store := make(map[string][]Tick)
+	instruments := []string{"EURUSD", "GBPUSD", "USDJPY", "USDCHF", "AUDUSD", "USDCAD", "NZDUSD", "EURJPY", "GBPJPY", "EURGBP"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		inst := instruments[i%len(instruments)]
+		ts := append(store[inst], Tick{Instrument: inst, Timestamp: int64(i)})
+		if len(ts) > tickRingBufferSize {
+			ts = ts[len(ts)-tickRingBufferSize:]
+		}
+		store[inst] = ts
+	}
+}
+
+func TestTickDedupModes(t *testing.T) {
+	sm := NewStateManager()
+	send := func(ts int64, bid float64) { sm.UpdateTick(Tick{Instrument: "EURUSD", Timestamp: ts, Bid: bid, Ask: bid + 0.0002}) }
+
+	send(1, 1.1)
+	send(1, 1.1)
+	if n := len(sm.GetTicks("EURUSD")); n != 2 || sm.DedupedTickCount() != 0 {
+		t.Fatalf("off: %d ticks stored, %d deduped; want 2 and 0", n, sm.DedupedTickCount())
+	}
+
+	sm.SetTickDedup(TickDedupExact)
+	send(1, 1.1)    // repeat
+	send(1, 1.1001) // same ms, new price
+	if n := len(sm.GetTicks("EURUSD")); n != 3 || sm.DedupedTickCount() != 1 {
+		t.Fatalf("exact: %d ticks stored, %d deduped; want 3 and 1", n, sm.DedupedTickCount())
+	}
+
+	sm.SetTickDedup(TickDedupTimestamp)
+	send(1, 1.1002)
+	send(2, 1.1002)
+	if n := len(sm.GetTicks("EURUSD")); n != 4 || sm.DedupedTickCount() != 2 {
+		t.Fatalf("timestamp: %d ticks stored, %d deduped; want 4 and 2", n, sm.DedupedTickCount())
+	}
+	if _, err := ParseTickDedup("sometimes"); err == nil {
+		t.Fatal("unknown mode should be rejected")
+	}
+}
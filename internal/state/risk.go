@@ -0,0 +1,107 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	contractMu sync.RWMutex
+	// contractSizes overrides the number of base units per 1.0 order amount for specific instruments.
+	// Instruments not listed use lotUnits (standard FX lot).
+	contractSizes = map[string]float64{}
+)
+
+// ContractSize returns base-currency units per 1.0 of order amount for instrument.
+func ContractSize(instrument string) float64 {
+	contractMu.RLock()
+	v, ok := contractSizes[instrument]
+	contractMu.RUnlock()
+	if ok && v > 0 {
+		return v
+	}
+	return lotUnits
+}
+
+// SetContractSize overrides the contract size for an instrument (e.g. non-FX CFDs).
+func SetContractSize(instrument string, size float64) {
+	if size > 0 {
+		contractMu.Lock()
+		contractSizes[instrument] = size
+		contractMu.Unlock()
+	}
+}
+
+// Notional returns the notional of amount on instrument in the account currency.
+// Base units are converted with the latest rates; when no rate is available the raw
+// base-unit notional is returned so the check errs on counting rather than ignoring exposure.
+func Notional(instrument string, amount float64, rates map[string]Tick) float64 {
+	units := amount * ContractSize(instrument)
+	if len(instrument) != 6 {
+		return units
+	}
+	if v, ok := ToAccountCurrency(units, instrument[:3], rates); ok {
+		return v
+	}
+	return units
+}
+
+// TotalNotional sums the notional of all open positions and working orders.
+func TotalNotional(info AccountInfo, rates map[string]Tick) float64 {
+	var total float64
+	for _, p := range info.Positions {
+		total += Notional(p.Instrument, p.Amount, rates)
+	}
+	for _, p := range info.PendingOrders {
+		total += Notional(p.Instrument, p.Amount, rates)
+	}
+	return total
+}
+
+// CheckNotionalLimit reports an error if adding amount on instrument would take the account's
+// total open notional above max (in the account currency). max <= 0 disables the check.
+func CheckNotionalLimit(info AccountInfo, rates map[string]Tick, instrument string, amount, max float64) error {
+	if max <= 0 {
+		return nil
+	}
+	current := TotalNotional(info, rates)
+	add := Notional(instrument, amount, rates)
+	if current+add > max {
+		return fmt.Errorf("notional limit: open %.0f + new %.0f > max %.0f %s", current, add, max, AccountCurrency())
+	}
+	return nil
+}
+
+// ErrInsufficientMargin is returned by CheckMargin when an order would exceed free margin.
+var ErrInsufficientMargin = errors.New("insufficient_margin")
+
+// EstimateMargin returns the margin an order is expected to use in the account currency: its notional
+// divided by the account leverage. Returns 0 when leverage is unknown.
+func EstimateMargin(instrument string, amount, leverage float64, rates map[string]Tick) float64 {
+	if leverage <= 0 {
+		return 0
+	}
+	return Notional(instrument, amount, rates) / leverage
+}
+
+// CheckMargin rejects an order whose estimated margin exceeds the account's free margin.
+// What: Pre-flight so orders the broker would reject are stopped client-side.
+// How: Free margin is Account.FreeMargin, falling back to MarginAvailable when the broker leaves it
+//      at zero. The check is skipped until account info (leverage and equity) has been received.
+// Returns: an error wrapping ErrInsufficientMargin, or nil.
+func CheckMargin(info AccountInfo, rates map[string]Tick, instrument string, amount float64) error {
+	acct := info.Account
+	if acct.Leverage <= 0 || (acct.Equity == 0 && acct.Balance == 0) {
+		return nil
+	}
+	free := acct.FreeMargin
+	if free <= 0 && acct.MarginAvailable > 0 {
+		free = acct.MarginAvailable
+	}
+	need := EstimateMargin(instrument, amount, acct.Leverage, rates)
+	if need > free {
+		return fmt.Errorf("%w: %s %.2f needs ~%.0f, free %.0f %s", ErrInsufficientMargin, instrument, amount, need, free, AccountCurrency())
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckMargin(t *testing.T) {
+	rates := map[string]Tick{"EURUSD": {Instrument: "EURUSD", Bid: 1.0999, Ask: 1.1001}}
+	info := AccountInfo{Account: Account{Balance: 10000, Equity: 10000, FreeMargin: 5000, Leverage: 30}}
+
+	// 0.1 lot EURUSD = 11,000 USD notional -> ~367 USD margin at 1:30
+	if got := EstimateMargin("EURUSD", 0.1, 30, rates); got < 366 || got > 367 {
+		t.Fatalf("EstimateMargin = %.2f, want ~366.67", got)
+	}
+	if err := CheckMargin(info, rates, "EURUSD", 0.1); err != nil {
+		t.Fatalf("small order rejected: %v", err)
+	}
+	err := CheckMargin(info, rates, "EURUSD", 2)
+	if !errors.Is(err, ErrInsufficientMargin) {
+		t.Fatalf("large order: err = %v, want insufficient_margin", err)
+	}
+
+	// MarginAvailable is used when FreeMargin is not reported
+	info.Account.FreeMargin, info.Account.MarginAvailable = 0, 100000
+	if err := CheckMargin(info, rates, "EURUSD", 2); err != nil {
+		t.Fatalf("MarginAvailable fallback: %v", err)
+	}
+	// No account info yet: skip
+	if err := CheckMargin(AccountInfo{}, rates, "EURUSD", 100); err != nil {
+		t.Fatalf("check should be skipped without account info: %v", err)
+	}
+}
+
+func TestContractSizeConcurrentUpdate(t *testing.T) {
+	defer func() { contractSizes = map[string]float64{} }()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 100; i++ {
+			SetContractSize("XAUUSD", float64(i))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		ContractSize("XAUUSD")
+	}
+	<-done
+	if got := ContractSize("XAUUSD"); got != 100 {
+		t.Fatalf("XAUUSD: got %v, want 100", got)
+	}
+	if got := ContractSize("EURUSD"); got != lotUnits {
+		t.Fatalf("EURUSD: got %v, want %v", got, lotUnits)
+	}
+}
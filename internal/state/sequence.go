@@ -0,0 +1,101 @@
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+// sequenceSettleWindow is how long a historical response must be quiet before gaps are reported.
+// It also separates responses: a bar arriving after a longer pause starts a new response.
+const sequenceSettleWindow = 10 * time.Second
+
+// SequenceStatus reports how complete the most recent historical response for an instrument/period is.
+// What: The JForex requester numbers a response N..1 from oldest to newest bar, so a partial send
+//       (e.g. 150 of 200) leaves holes in that range that timestamp dedup alone cannot see.
+// How: Expected is the highest sequence seen (the oldest bar, sent first); Missing lists the absent
+//      sequences below it. GapFromMs/GapToMs bound the missing bars by the bar-end timestamps of their
+//      received neighbours, with GapToMs left at 0 when the newest bars are missing.
+type SequenceStatus struct {
+	Instrument   string    `json:"instrument"`
+	Period       string    `json:"period"`
+	Received     int       `json:"received"`
+	Expected     int       `json:"expected"`
+	Missing      []int     `json:"missing,omitempty"`
+	Completeness float64   `json:"completeness"` // percent of Expected received
+	Settled      bool      `json:"settled"`
+	LastArrival  time.Time `json:"lastArrival"`
+	GapFromMs    int64     `json:"gapFromMs,omitempty"`
+	GapToMs      int64     `json:"gapToMs,omitempty"`
+}
+
+// sequenceTracker collects the sequences of one historical response.
+// It is not safe for concurrent use; StateManager guards it with its mutex.
+type sequenceTracker struct {
+	barEnds     map[int]int64 // sequence -> bar end timestamp
+	maxSeq      int
+	lastArrival time.Time
+}
+
+// add records a bar's sequence, starting a new response after a quiet period.
+func (t *sequenceTracker) add(seq int, barEnd int64, now time.Time) {
+	if t.barEnds == nil || now.Sub(t.lastArrival) > sequenceSettleWindow {
+		t.barEnds = make(map[int]int64)
+		t.maxSeq = 0
+	}
+	t.barEnds[seq] = barEnd
+	if seq > t.maxSeq {
+		t.maxSeq = seq
+	}
+	t.lastArrival = now
+}
+
+// status computes completeness and the gap range as of now.
+func (t *sequenceTracker) status(instrument, period string, now time.Time) SequenceStatus {
+	s := SequenceStatus{
+		Instrument:  instrument,
+		Period:      period,
+		Received:    len(t.barEnds),
+		Expected:    t.maxSeq,
+		Settled:     now.Sub(t.lastArrival) >= sequenceSettleWindow,
+		LastArrival: t.lastArrival,
+	}
+	if t.maxSeq == 0 {
+		return s
+	}
+	for seq := t.maxSeq - 1; seq >= 1; seq-- {
+		if _, ok := t.barEnds[seq]; !ok {
+			s.Missing = append(s.Missing, seq)
+		}
+	}
+	s.Completeness = float64(s.Received) / float64(s.Expected) * 100
+	if len(s.Missing) == 0 {
+		return s
+	}
+	// Missing is ordered oldest (highest sequence) first
+	seen := make([]int, 0, len(t.barEnds))
+	for seq := range t.barEnds {
+		seen = append(seen, seq)
+	}
+	sort.Ints(seen)
+	oldestGap, newestGap := s.Missing[0], s.Missing[len(s.Missing)-1]
+	// The received bar just older than the oldest gap always exists since maxSeq is received
+	if i := sort.SearchInts(seen, oldestGap); i < len(seen) {
+		s.GapFromMs = t.barEnds[seen[i]]
+	}
+	if i := sort.SearchInts(seen, newestGap); i > 0 {
+		s.GapToMs = t.barEnds[seen[i-1]]
+	}
+	return s
+}
+
+// HistoricalSequenceStatus returns the completeness of the latest historical response for
+// instrument/period. ok is false when no sequenced historical bar has been received.
+func (sm *StateManager) HistoricalSequenceStatus(instrument, period string, now time.Time) (SequenceStatus, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	t, ok := sm.histSeq[instrument+"|"+period]
+	if !ok {
+		return SequenceStatus{Instrument: instrument, Period: period}, false
+	}
+	return t.status(instrument, period, now), true
+}
@@ -0,0 +1,54 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSequenceTrackerDetectsGaps(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	var tr sequenceTracker
+	// Response of 10 bars sent oldest (10) to newest (1); 6, 5 and 1 never arrive
+	for seq := 10; seq >= 1; seq-- {
+		if seq == 6 || seq == 5 || seq == 1 {
+			continue
+		}
+		tr.add(seq, int64(1000*(11-seq)), start)
+	}
+
+	st := tr.status("EURUSD", "ONE_MIN", start.Add(time.Second))
+	if st.Settled {
+		t.Fatal("status should not be settled right after the last bar")
+	}
+	st = tr.status("EURUSD", "ONE_MIN", start.Add(sequenceSettleWindow))
+	if !st.Settled || st.Expected != 10 || st.Received != 7 || st.Completeness != 70 {
+		t.Fatalf("status = %+v", st)
+	}
+	if len(st.Missing) != 3 || st.Missing[0] != 6 || st.Missing[2] != 1 {
+		t.Fatalf("missing = %v, want [6 5 1]", st.Missing)
+	}
+	// Oldest gap borders sequence 7 (bar end 4000); the newest bar is missing so there is no upper bound
+	if st.GapFromMs != 4000 || st.GapToMs != 0 {
+		t.Fatalf("gap range = %d..%d, want 4000..0", st.GapFromMs, st.GapToMs)
+	}
+
+	// A later response starts afresh
+	tr.add(2, 99_000, start.Add(time.Minute))
+	tr.add(1, 100_000, start.Add(time.Minute))
+	st = tr.status("EURUSD", "ONE_MIN", start.Add(2*time.Minute))
+	if st.Expected != 2 || len(st.Missing) != 0 || st.Completeness != 100 {
+		t.Fatalf("second response status = %+v", st)
+	}
+}
+
+func TestSequenceGapRangeBetweenReceivedBars(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	var tr sequenceTracker
+	for _, seq := range []int{5, 4, 2, 1} {
+		tr.add(seq, int64(100*(6-seq)), now)
+	}
+	st := tr.status("GBPJPY", "FIVE_MINS", now.Add(sequenceSettleWindow))
+	if len(st.Missing) != 1 || st.Missing[0] != 3 || st.GapFromMs != 200 || st.GapToMs != 400 {
+		t.Fatalf("status = %+v", st)
+	}
+}
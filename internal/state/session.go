@@ -0,0 +1,91 @@
+package state
+
+import "time"
+
+// SessionStats is the open/high/low/last mid price of an instrument for the current trading session.
+type SessionStats struct {
+	Instrument   string  `json:"instrument"`
+	SessionStart int64   `json:"sessionStart"` // unix millis of the session boundary
+	Open         float64 `json:"open"`
+	High         float64 `json:"high"`
+	Low          float64 `json:"low"`
+	Last         float64 `json:"last"`
+	Ticks        int     `json:"ticks"`
+	UpdatedAt    int64   `json:"updatedAt"` // timestamp of the newest tick
+}
+
+// sessionAccumulator tracks session OHLC from ticks.
+// What: Gives the dashboard a per-instrument session summary without scanning tick history.
+// How: Each tick's timestamp is mapped to the start of its session (daily, at the configured offset
+//      from 00:00 UTC). A tick in a later session resets the stats with that tick as the open; late
+//      ticks from an earlier session are ignored so a reordered tick cannot reopen the old session.
+// It is not safe for concurrent use; StateManager guards it with its mutex.
+type sessionAccumulator struct {
+	stats SessionStats
+}
+
+// sessionStart returns the unix millis of the session boundary at or before ts.
+func sessionStart(ts int64, offset time.Duration) int64 {
+	day := (24 * time.Hour).Milliseconds()
+	off := offset.Milliseconds()
+	start := ts - off
+	start -= ((start % day) + day) % day
+	return start + off
+}
+
+// add records a mid price observed at ts.
+func (a *sessionAccumulator) add(instrument string, price float64, ts int64, offset time.Duration) {
+	start := sessionStart(ts, offset)
+	switch {
+	case start < a.stats.SessionStart:
+		return
+	case start > a.stats.SessionStart || a.stats.Ticks == 0:
+		a.stats = SessionStats{Instrument: instrument, SessionStart: start, Open: price, High: price, Low: price}
+	}
+	if price > a.stats.High {
+		a.stats.High = price
+	}
+	if price < a.stats.Low {
+		a.stats.Low = price
+	}
+	a.stats.Last = price
+	a.stats.Ticks++
+	if ts > a.stats.UpdatedAt {
+		a.stats.UpdatedAt = ts
+	}
+}
+
+// tickSessionPrice returns the tick's mid price and timestamp, or false for one-sided/unstamped ticks.
+func tickSessionPrice(t Tick) (float64, int64, bool) {
+	if t.Bid <= 0 || t.Ask <= 0 {
+		return 0, 0, false
+	}
+	ts := t.Timestamp
+	if ts <= 0 {
+		ts = t.ProducedAt
+	}
+	if ts <= 0 {
+		return 0, 0, false
+	}
+	return (t.Bid + t.Ask) / 2, ts, true
+}
+
+// SetSessionBoundary sets the daily session start as an offset from 00:00 UTC (e.g. 22h for the
+// 17:00 New York close). Sessions already in progress keep their start until the next boundary.
+func (sm *StateManager) SetSessionBoundary(offset time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.sessionOffset = offset % (24 * time.Hour)
+}
+
+// GetSessionStats returns the current session summary for an instrument.
+// ok is false when no valid tick has been seen for it yet.
+func (sm *StateManager) GetSessionStats(instrument string) (SessionStats, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	acc, ok := sm.sessions[instrument]
+	if !ok {
+		return SessionStats{Instrument: instrument}, false
+	}
+	return acc.stats, true
+}
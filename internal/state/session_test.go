@@ -0,0 +1,44 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionStatsResetAtBoundary(t *testing.T) {
+	sm := NewStateManager()
+	sm.SetSessionBoundary(22 * time.Hour)
+	boundary := time.Date(2024, 3, 4, 22, 0, 0, 0, time.UTC)
+	tick := func(at time.Time, bid float64) {
+		sm.UpdateTick(Tick{Instrument: "EURUSD", Timestamp: at.UnixMilli(), Bid: bid, Ask: bid + 0.0002})
+	}
+
+	tick(boundary.Add(-2*time.Hour), 1.1000)
+	tick(boundary.Add(-time.Hour), 1.1050)
+	tick(boundary.Add(-time.Minute), 1.0950)
+	st, ok := sm.GetSessionStats("EURUSD")
+	if !ok || st.Ticks != 3 || st.SessionStart != boundary.Add(-24*time.Hour).UnixMilli() {
+		t.Fatalf("first session = %+v", st)
+	}
+	if st.Open != 1.1001 || st.High != 1.1051 || st.Low != 1.0951 || st.Last != 1.0951 {
+		t.Fatalf("first session OHLC = %+v", st)
+	}
+
+	tick(boundary, 1.2000)
+	tick(boundary.Add(-time.Second), 1.0000) // late tick from the previous session is ignored
+	st, _ = sm.GetSessionStats("EURUSD")
+	if st.SessionStart != boundary.UnixMilli() || st.Ticks != 1 || st.Open != 1.2001 || st.Low != 1.2001 {
+		t.Fatalf("second session = %+v", st)
+	}
+	if snap := sm.Snapshot(); snap.Sessions["EURUSD"] != st {
+		t.Fatalf("snapshot session = %+v, want %+v", snap.Sessions["EURUSD"], st)
+	}
+}
+
+func TestSessionStartNegativeOffset(t *testing.T) {
+	ts := time.Date(2024, 3, 4, 1, 0, 0, 0, time.UTC).UnixMilli()
+	want := time.Date(2024, 3, 3, 22, 0, 0, 0, time.UTC).UnixMilli()
+	if got := sessionStart(ts, -2*time.Hour); got != want {
+		t.Fatalf("sessionStart = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,60 @@
+package state
+
+// StateSnapshot is a coherent point-in-time copy of the whole state.
+// What: Lets readers that look at several instruments/periods (broadcaster, ledger stats) see
+//       one consistent view instead of mixing data from before and after a concurrent update.
+// How: Produced by StateManager.Snapshot under a single read lock; every slice is a copy, so the
+//      snapshot can be used freely without locking.
+type StateSnapshot struct {
+	Ticks          map[string][]Tick                     // oldest to newest per instrument
+	Bars           map[string]map[string][]Bar           // instrument -> period -> bars
+	HistoricalBars map[string]map[string][]HistoricalBar // instrument -> period -> bars, newest-first
+	AccountInfo    AccountInfo
+	Sessions       map[string]SessionStats // current session OHLC per instrument
+}
+
+// LatestTicks returns the newest tick per instrument, usable as a rate table for conversions.
+func (s StateSnapshot) LatestTicks() map[string]Tick {
+	out := make(map[string]Tick, len(s.Ticks))
+	for instrument, ticks := range s.Ticks {
+		if len(ticks) > 0 {
+			out[instrument] = ticks[len(ticks)-1]
+		}
+	}
+	return out
+}
+
+// Snapshot copies all ticks, bars, historical bars, account info, and session stats under one read lock.
+func (sm *StateManager) Snapshot() StateSnapshot {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	snap := StateSnapshot{
+		Ticks:          make(map[string][]Tick, len(sm.ticks)),
+		Bars:           make(map[string]map[string][]Bar, len(sm.bars)),
+		HistoricalBars: make(map[string]map[string][]HistoricalBar, len(sm.historicalBars)),
+		AccountInfo:    sm.accountInfo,
+		Sessions:       make(map[string]SessionStats, len(sm.sessions)),
+	}
+	for instrument, acc := range sm.sessions {
+		snap.Sessions[instrument] = acc.stats
+	}
+	for instrument, ring := range sm.ticks {
+		snap.Ticks[instrument] = ring.snapshot()
+	}
+	for instrument, periods := range sm.bars {
+		m := make(map[string][]Bar, len(periods))
+		for period, bars := range periods {
+			m[period] = append([]Bar(nil), bars...)
+		}
+		snap.Bars[instrument] = m
+	}
+	for instrument, periods := range sm.historicalBars {
+		m := make(map[string][]HistoricalBar, len(periods))
+		for period, bars := range periods {
+			m[period] = append([]HistoricalBar(nil), bars...)
+		}
+		snap.HistoricalBars[instrument] = m
+	}
+	return snap
+}
@@ -0,0 +1,28 @@
+package state
+
+import "testing"
+
+func TestSnapshotIsDeepCopy(t *testing.T) {
+	sm := NewStateManager()
+	sm.UpdateTick(Tick{Instrument: "EURUSD", Bid: 1.1, Ask: 1.1002, Timestamp: 1})
+	sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 60, Bid: OHLCV{C: 1.1}})
+	sm.UpdateAccountInfo(AccountInfo{Positions: []Position{{Instrument: "EURUSD", State: "FILLED"}}})
+
+	snap := sm.Snapshot()
+	// Later updates, including in-place replacement of a bar, must not leak into the snapshot
+	sm.UpdateTick(Tick{Instrument: "EURUSD", Bid: 1.2, Ask: 1.2002, Timestamp: 2})
+	sm.UpdateHistoricalBar(HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 60, Bid: OHLCV{C: 9.9}})
+
+	if got := snap.Ticks["EURUSD"]; len(got) != 1 || got[0].Timestamp != 1 {
+		t.Fatalf("snapshot ticks = %+v", got)
+	}
+	if got := snap.HistoricalBars["EURUSD"]["ONE_MIN"]; len(got) != 1 || got[0].Bid.C != 1.1 {
+		t.Fatalf("snapshot historical bars = %+v", got)
+	}
+	if len(snap.AccountInfo.Positions) != 1 {
+		t.Fatalf("snapshot positions = %+v", snap.AccountInfo.Positions)
+	}
+	if lt := snap.LatestTicks(); lt["EURUSD"].Timestamp != 1 {
+		t.Fatalf("snapshot latest tick = %+v", lt["EURUSD"])
+	}
+}
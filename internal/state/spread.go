@@ -0,0 +1,79 @@
+package state
+
+// spreadWindowSize is the number of most recent ticks the rolling spread statistics cover.
+const spreadWindowSize = 1000
+
+// SpreadStats summarises the bid/ask spread of an instrument over the rolling window, in pips.
+type SpreadStats struct {
+	Instrument string  `json:"instrument"`
+	Last       float64 `json:"last"`
+	Min        float64 `json:"min"`
+	Max        float64 `json:"max"`
+	Avg        float64 `json:"avg"`
+	Samples    int     `json:"samples"`
+	UpdatedAt  int64   `json:"updatedAt,omitempty"` // timestamp of the newest tick
+}
+
+// spreadAccumulator keeps the last N spreads with a running sum.
+// What: O(1) update per tick; min/max are scanned on read since reads are rare.
+// It is not safe for concurrent use; StateManager guards it with its mutex.
+type spreadAccumulator struct {
+	buf       []float64
+	start     int
+	count     int
+	sum       float64
+	updatedAt int64
+}
+
+func newSpreadAccumulator(capacity int) *spreadAccumulator {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &spreadAccumulator{buf: make([]float64, capacity)}
+}
+
+// add records one spread sample, evicting the oldest once the window is full.
+func (a *spreadAccumulator) add(pips float64, ts int64) {
+	n := len(a.buf)
+	if a.count < n {
+		a.buf[(a.start+a.count)%n] = pips
+		a.count++
+	} else {
+		a.sum -= a.buf[a.start]
+		a.buf[a.start] = pips
+		a.start = (a.start + 1) % n
+	}
+	a.sum += pips
+	a.updatedAt = ts
+}
+
+// stats returns the window statistics for instrument.
+func (a *spreadAccumulator) stats(instrument string) SpreadStats {
+	s := SpreadStats{Instrument: instrument, Samples: a.count, UpdatedAt: a.updatedAt}
+	if a.count == 0 {
+		return s
+	}
+	n := len(a.buf)
+	s.Min = a.buf[a.start]
+	s.Max = s.Min
+	for i := 0; i < a.count; i++ {
+		v := a.buf[(a.start+i)%n]
+		if v < s.Min {
+			s.Min = v
+		}
+		if v > s.Max {
+			s.Max = v
+		}
+	}
+	s.Last = a.buf[(a.start+a.count-1)%n]
+	s.Avg = a.sum / float64(a.count)
+	return s
+}
+
+// tickSpreadPips returns the tick's ask-bid spread in pips, or false for an unusable quote.
+func tickSpreadPips(t Tick) (float64, bool) {
+	if t.Bid <= 0 || t.Ask <= 0 || t.Ask < t.Bid {
+		return 0, false
+	}
+	return (t.Ask - t.Bid) / PipSize(t.Instrument), true
+}
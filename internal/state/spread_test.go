@@ -0,0 +1,35 @@
+package state
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpreadStatsRollingWindow(t *testing.T) {
+	a := newSpreadAccumulator(3)
+	for i, pips := range []float64{5, 1, 2, 3} {
+		a.add(pips, int64(i+1))
+	}
+	// 5 was evicted; window is {1,2,3}
+	s := a.stats("EURUSD")
+	if s.Samples != 3 || s.Min != 1 || s.Max != 3 || s.Last != 3 || s.Avg != 2 || s.UpdatedAt != 4 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
+
+func TestUpdateTickTracksSpreadInPips(t *testing.T) {
+	sm := NewStateManager()
+	if _, ok := sm.GetSpreadStats("USDJPY"); ok {
+		t.Fatal("expected no stats before any tick")
+	}
+	sm.UpdateTick(Tick{Instrument: "USDJPY", Bid: 150.00, Ask: 150.02, Timestamp: 1})
+	sm.UpdateTick(Tick{Instrument: "USDJPY", Bid: 150.00, Ask: 150.04, Timestamp: 2})
+	sm.UpdateTick(Tick{Instrument: "USDJPY", Bid: 150.00, Ask: 0, Timestamp: 3}) // ignored
+	s, ok := sm.GetSpreadStats("USDJPY")
+	if !ok {
+		t.Fatal("expected stats")
+	}
+	if s.Samples != 2 || math.Abs(s.Min-2) > 1e-6 || math.Abs(s.Max-4) > 1e-6 || math.Abs(s.Avg-3) > 1e-6 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+}
@@ -0,0 +1,14 @@
+package state
+
+// AverageTickVolume returns the mean of (BidVol+AskVol) across the given ticks.
+// Returns 0 when no ticks are provided.
+func AverageTickVolume(ticks []Tick) float64 {
+	if len(ticks) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, t := range ticks {
+		sum += t.BidVol + t.AskVol
+	}
+	return sum / float64(len(ticks))
+}
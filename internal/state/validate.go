@@ -0,0 +1,32 @@
+package state
+
+import "fmt"
+
+// ValidateOHLC checks that a price bar is internally consistent.
+// Rejects non-positive prices, crossed bars (high < low), and open/close outside [low, high].
+func ValidateOHLC(o OHLCV) error {
+	if o.O <= 0 || o.H <= 0 || o.L <= 0 || o.C <= 0 {
+		return fmt.Errorf("non-positive price (o=%v h=%v l=%v c=%v)", o.O, o.H, o.L, o.C)
+	}
+	if o.H < o.L {
+		return fmt.Errorf("high %v below low %v", o.H, o.L)
+	}
+	if o.C < o.L || o.C > o.H {
+		return fmt.Errorf("close %v outside [%v, %v]", o.C, o.L, o.H)
+	}
+	if o.O < o.L || o.O > o.H {
+		return fmt.Errorf("open %v outside [%v, %v]", o.O, o.L, o.H)
+	}
+	return nil
+}
+
+// ValidateBarSides validates both the bid and ask OHLC of a bar.
+func ValidateBarSides(bid, ask OHLCV) error {
+	if err := ValidateOHLC(bid); err != nil {
+		return fmt.Errorf("bid: %w", err)
+	}
+	if err := ValidateOHLC(ask); err != nil {
+		return fmt.Errorf("ask: %w", err)
+	}
+	return nil
+}
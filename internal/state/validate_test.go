@@ -0,0 +1,44 @@
+package state
+
+import "testing"
+
+func TestValidateOHLC(t *testing.T) {
+	good := OHLCV{O: 1.1000, H: 1.1010, L: 1.0990, C: 1.1005, V: 10}
+	cases := []struct {
+		name    string
+		bar     OHLCV
+		wantErr bool
+	}{
+		{"valid", good, false},
+		{"valid flat bar", OHLCV{O: 1.1, H: 1.1, L: 1.1, C: 1.1}, false},
+		{"zero open", OHLCV{O: 0, H: 1.1010, L: 1.0990, C: 1.1005}, true},
+		{"zero high", OHLCV{O: 1.1, H: 0, L: 1.0990, C: 1.1005}, true},
+		{"zero low", OHLCV{O: 1.1, H: 1.1010, L: 0, C: 1.1005}, true},
+		{"zero close", OHLCV{O: 1.1, H: 1.1010, L: 1.0990, C: 0}, true},
+		{"negative price", OHLCV{O: -1.1, H: 1.1010, L: 1.0990, C: 1.1005}, true},
+		{"all zero", OHLCV{}, true},
+		{"crossed high below low", OHLCV{O: 1.1, H: 1.0990, L: 1.1010, C: 1.1}, true},
+		{"close above high", OHLCV{O: 1.1, H: 1.1010, L: 1.0990, C: 1.1020}, true},
+		{"close below low", OHLCV{O: 1.1, H: 1.1010, L: 1.0990, C: 1.0980}, true},
+		{"open outside range", OHLCV{O: 1.2, H: 1.1010, L: 1.0990, C: 1.1}, true},
+	}
+	for _, tc := range cases {
+		err := ValidateOHLC(tc.bar)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: ValidateOHLC() error = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestValidateBarSides(t *testing.T) {
+	good := OHLCV{O: 1.1, H: 1.2, L: 1.0, C: 1.15}
+	if err := ValidateBarSides(good, good); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ValidateBarSides(good, OHLCV{}); err == nil {
+		t.Fatal("expected error for invalid ask side")
+	}
+	if err := ValidateBarSides(OHLCV{O: 1, H: 0.5, L: 1, C: 1}, good); err == nil {
+		t.Fatal("expected error for invalid bid side")
+	}
+}
@@ -0,0 +1,67 @@
+package state
+
+// defaultTickVwapMinCoverage is the fraction of a bar's window the retained ticks must span
+// before a tick VWAP is computed for it.
+const defaultTickVwapMinCoverage = 0.5
+
+// tickVwap computes bid and ask tick VWAPs for the bar window [start, end] from ticks ordered
+// oldest to newest.
+// What: Live bars arrive without a tick VWAP, but the tick ring usually holds the ticks that formed them.
+// How: Each side is weighted by its own tick volume (bid by BidVol, ask by AskVol). The ring only keeps
+//      the newest N ticks, so when its oldest tick is later than the bar start the earlier part of the
+//      window is unknown; coverage is the fraction of the window from that tick to the bar end, and
+//      below minCoverage no VWAP is reported rather than one skewed towards the end of the bar.
+// Returns: nil for a side with no in-window volume, or for both sides when coverage is insufficient.
+func tickVwap(ticks []Tick, start, end int64, minCoverage float64) (bid, ask *float64) {
+	if len(ticks) == 0 || end <= start {
+		return nil, nil
+	}
+	if oldest := tickTime(ticks[0]); oldest > start {
+		if float64(end-oldest)/float64(end-start) < minCoverage {
+			return nil, nil
+		}
+	}
+	var bidPV, bidV, askPV, askV float64
+	for _, t := range ticks {
+		ts := tickTime(t)
+		if ts < start || ts > end {
+			continue
+		}
+		if t.Bid > 0 && t.BidVol > 0 {
+			bidPV += t.Bid * t.BidVol
+			bidV += t.BidVol
+		}
+		if t.Ask > 0 && t.AskVol > 0 {
+			askPV += t.Ask * t.AskVol
+			askV += t.AskVol
+		}
+	}
+	if bidV > 0 {
+		v := bidPV / bidV
+		bid = &v
+	}
+	if askV > 0 {
+		v := askPV / askV
+		ask = &v
+	}
+	return bid, ask
+}
+
+// tickTime returns the tick's market timestamp, falling back to when it was produced.
+func tickTime(t Tick) int64 {
+	if t.Timestamp > 0 {
+		return t.Timestamp
+	}
+	return t.ProducedAt
+}
+
+// SetTickVwapMinCoverage sets the fraction (0..1] of a live bar's window the retained ticks must
+// span before its tick VWAP is filled in. Raise the tick buffer size for long periods.
+func (sm *StateManager) SetTickVwapMinCoverage(f float64) {
+	if f <= 0 || f > 1 {
+		return
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.tickVwapMinCoverage = f
+}
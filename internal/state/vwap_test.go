@@ -0,0 +1,62 @@
+package state
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTickVwapWeightsEachSideByItsVolume(t *testing.T) {
+	ticks := []Tick{
+		{Timestamp: 900, Bid: 1.0, Ask: 1.1, BidVol: 5, AskVol: 5}, // before the bar
+		{Timestamp: 1000, Bid: 1.2, Ask: 1.3, BidVol: 1, AskVol: 3},
+		{Timestamp: 1500, Bid: 1.4, Ask: 1.5, BidVol: 3, AskVol: 1},
+		{Timestamp: 2100, Bid: 9.9, Ask: 9.9, BidVol: 5, AskVol: 5}, // after the bar
+	}
+	bid, ask := tickVwap(ticks, 1000, 1999, 0.5)
+	if bid == nil || ask == nil {
+		t.Fatal("expected both sides")
+	}
+	if want := (1.2*1 + 1.4*3) / 4; math.Abs(*bid-want) > 1e-12 {
+		t.Errorf("bid vwap %v, want %v", *bid, want)
+	}
+	if want := (1.3*3 + 1.5*1) / 4; math.Abs(*ask-want) > 1e-12 {
+		t.Errorf("ask vwap %v, want %v", *ask, want)
+	}
+}
+
+func TestTickVwapPartialCoverage(t *testing.T) {
+	// Oldest retained tick is 70% of the way through the bar: only 30% of the window is known
+	ticks := []Tick{{Timestamp: 1700, Bid: 1.2, Ask: 1.3, BidVol: 1, AskVol: 1}}
+	if bid, ask := tickVwap(ticks, 1000, 2000, 0.5); bid != nil || ask != nil {
+		t.Fatal("expected no vwap below minimum coverage")
+	}
+	if bid, _ := tickVwap(ticks, 1000, 2000, 0.25); bid == nil {
+		t.Fatal("expected vwap at or above minimum coverage")
+	}
+}
+
+func TestTickVwapNoVolume(t *testing.T) {
+	ticks := []Tick{{Timestamp: 1000, Bid: 1.2, Ask: 1.3, AskVol: 2}}
+	bid, ask := tickVwap(ticks, 1000, 2000, 0.5)
+	if bid != nil {
+		t.Error("bid vwap without bid volume")
+	}
+	if ask == nil || *ask != 1.3 {
+		t.Errorf("ask vwap %v", ask)
+	}
+}
+
+func TestUpdateLiveBarFillsTickVwap(t *testing.T) {
+	sm := NewStateManager()
+	sm.UpdateTick(Tick{Instrument: "EURUSD", Timestamp: 59_000, Bid: 1.1, Ask: 1.1002, BidVol: 1, AskVol: 1})
+	sm.UpdateTick(Tick{Instrument: "EURUSD", Timestamp: 60_500, Bid: 1.1010, Ask: 1.1012, BidVol: 2, AskVol: 2})
+	sm.UpdateLiveBar(Bar{Instrument: "EURUSD", Period: "ONE_MIN", BarStartTimestamp: 60_000, BarEndTimestamp: 119_999})
+
+	bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN")
+	if len(bars) != 1 {
+		t.Fatalf("got %d bars", len(bars))
+	}
+	if v := bars[0].BidVwap.TickVwap; v == nil || *v != 1.1010 {
+		t.Errorf("bid tick vwap %v, want 1.1010", v)
+	}
+}
@@ -0,0 +1,80 @@
+package strategy
+
+import "go-trader/internal/state"
+
+// What: Bollinger squeeze breakout strategy with optional param: squeezeBars.
+// How: A bar is "in squeeze" when its Bollinger bands sit inside its Keltner channel (volatility
+//      contraction). After at least squeezeBars consecutive squeeze bars, the first bar whose bands
+//      move back outside the channel fires in the direction of the breakout: BUY when the close is
+//      above the Keltner middle and rising, SELL when below and falling. The squeeze run is read from
+//      the bar history, so a restart or missed bar does not lose it. Bars with nil or zero channels
+//      (e.g. a raw bar sent before the indicators warmed up) break the run and never fire.
+// Params:
+//  - squeezeBars (int): consecutive squeeze bars required before a release counts. Default 3.
+// Returns: SignalBuy, SignalSell, or SignalNone.
+
+type SqueezeStrategy struct {
+	squeezeBars int
+}
+
+func init() {
+	DefaultRegistry.Register("BB_SQUEEZE", func() Strategy { return &SqueezeStrategy{} })
+	DefaultRegistry.Describe(Info{
+		Key: "BB_SQUEEZE", Name: "Bollinger Squeeze Breakout",
+		Description: "Breakout when Bollinger bands expand back out of the Keltner channel",
+		Params: []ParamSpec{
+			{Name: "squeezeBars", Type: "int", Default: 3, Min: bound(1), Description: "Consecutive squeeze bars required before a breakout"},
+		},
+	})
+}
+
+func (s *SqueezeStrategy) Key() string { return "BB_SQUEEZE" }
+
+// EventSignals reports that signals fire on the release bar only (see EventStrategy).
+func (s *SqueezeStrategy) EventSignals() bool { return true }
+
+// SetParams allows runtime configuration.
+func (s *SqueezeStrategy) SetParams(p Params) {
+	if p == nil { return }
+	if v, ok := p["squeezeBars"]; ok && int(v) >= 1 { s.squeezeBars = int(v) }
+}
+
+func (s *SqueezeStrategy) minSqueeze() int {
+	if s.squeezeBars >= 1 { return s.squeezeBars }
+	return 3
+}
+
+// MinBars is the squeeze run plus the release bar.
+func (s *SqueezeStrategy) MinBars() int { return s.minSqueeze() + 1 }
+
+// RequiredIndicators are the bands compared to detect the squeeze.
+func (s *SqueezeStrategy) RequiredIndicators() []string { return []string{"bid_bollinger", "bid_keltner"} }
+
+func (s *SqueezeStrategy) Evaluate(bars []state.HistoricalBar) Signal {
+	n := s.minSqueeze()
+	if len(bars) < n+1 { return SignalNone }
+	// The newest bar must be the release...
+	if on, ok := inSqueeze(bars[0]); !ok || on { return SignalNone }
+	// ...after n bars of squeeze
+	for i := 1; i <= n; i++ {
+		if on, ok := inSqueeze(bars[i]); !ok || !on { return SignalNone }
+	}
+	b0 := bars[0]
+	c0, c1 := b0.Bid.C, bars[1].Bid.C
+	mid := b0.BidKeltner.Middle
+	if mid == 0 && b0.BidBollinger.Middle != nil { mid = *b0.BidBollinger.Middle }
+	if mid == 0 { return SignalNone }
+	if c0 > mid && c0 > c1 { return SignalBuy }
+	if c0 < mid && c0 < c1 { return SignalSell }
+	return SignalNone
+}
+
+// inSqueeze reports whether the Bid Bollinger bands are inside the Bid Keltner channel.
+// ok is false when either channel is missing.
+func inSqueeze(b state.HistoricalBar) (on bool, ok bool) {
+	bb, kc := b.BidBollinger, b.BidKeltner
+	if bb.Upper == nil || bb.Lower == nil || *bb.Upper == 0 || *bb.Lower == 0 || kc.Upper == 0 || kc.Lower == 0 {
+		return false, false
+	}
+	return *bb.Upper < kc.Upper && *bb.Lower > kc.Lower, true
+}
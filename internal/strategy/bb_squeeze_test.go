@@ -0,0 +1,89 @@
+package strategy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go-trader/internal/state"
+)
+
+// squeezeBar builds a bar with Bollinger half-width bb and Keltner half-width kc around 1.1000.
+func squeezeBar(close, bb, kc float64) state.HistoricalBar {
+	up, mid, lo := 1.1+bb, 1.1, 1.1-bb
+	b := state.HistoricalBar{}
+	b.Bid.C = close
+	b.BidBollinger = state.Bollinger{Upper: &up, Middle: &mid, Lower: &lo}
+	b.BidKeltner = state.Keltner{Upper: 1.1 + kc, Middle: 1.1, Lower: 1.1 - kc}
+	return b
+}
+
+func TestSqueezeFiresOnRelease(t *testing.T) {
+	s := &SqueezeStrategy{}
+	in := squeezeBar(1.1, 0.001, 0.002)
+	// Newest first: release bar then three squeeze bars
+	up := []state.HistoricalBar{squeezeBar(1.1030, 0.003, 0.002), in, in, in}
+	if got := s.Evaluate(up); got != SignalBuy {
+		t.Fatalf("upside release: got %v, want BUY", got)
+	}
+	down := []state.HistoricalBar{squeezeBar(1.0970, 0.003, 0.002), in, in, in}
+	if got := s.Evaluate(down); got != SignalSell {
+		t.Fatalf("downside release: got %v, want SELL", got)
+	}
+	if got := s.Evaluate(up[1:]); got != SignalNone {
+		t.Fatalf("still in squeeze: got %v, want NONE", got)
+	}
+	short := []state.HistoricalBar{up[0], in, in, squeezeBar(1.1, 0.003, 0.002)}
+	if got := s.Evaluate(short); got != SignalNone {
+		t.Fatalf("squeeze too short: got %v, want NONE", got)
+	}
+}
+
+func TestSqueezeIgnoresMissingChannels(t *testing.T) {
+	s := &SqueezeStrategy{squeezeBars: 2}
+	in := squeezeBar(1.1, 0.001, 0.002)
+	release := squeezeBar(1.1030, 0.003, 0.002)
+	noKeltner := in
+	noKeltner.BidKeltner = state.Keltner{}
+	noBollinger := in
+	noBollinger.BidBollinger = state.Bollinger{}
+	for name, bars := range map[string][]state.HistoricalBar{
+		"zero keltner in run":      {release, noKeltner, in},
+		"nil bollinger in run":     {release, in, noBollinger},
+		"release without channels": {noKeltner, in, in},
+	} {
+		if got := s.Evaluate(bars); got != SignalNone {
+			t.Errorf("%s: got %v, want NONE", name, got)
+		}
+	}
+	if got := s.Evaluate([]state.HistoricalBar{release, in, in}); got != SignalBuy {
+		t.Fatalf("squeezeBars=2: got %v, want BUY", got)
+	}
+}
+
+func TestSqueezeFiresOnLiveBar(t *testing.T) {
+	sm := state.NewStateManager()
+	for i := int64(1); i <= 3; i++ {
+		b := squeezeBar(1.1, 0.001, 0.002)
+		b.Instrument, b.Period, b.BarEndTimestamp = "EURUSD", "ONE_MIN", i*60_000
+		sm.UpdateHistoricalBar(b)
+	}
+	// Release bar as published by the bar data feeder
+	var live state.Bar
+	if err := json.Unmarshal([]byte(`{"instrument":"EURUSD","period":"ONE_MIN","bar_end_timestamp":240000,
+		"bid":{"o":1.1,"h":1.104,"l":1.1,"c":1.103,"v":10},
+		"bid_atr":0.001,
+		"bid_bollinger":{"upper":1.103,"middle":1.1,"lower":1.097},
+		"bid_keltner":{"upper":1.102,"middle":1.1,"lower":1.098},
+		"bid_supertrend":{"upper":1.105,"lower":1.099}}`), &live); err != nil {
+		t.Fatal(err)
+	}
+	sm.UpdateLiveBar(live)
+
+	bars := sm.GetHistoricalBars("EURUSD", "ONE_MIN")
+	if bars[0].BidKeltner.Upper != 1.102 || bars[0].BidAtr != 0.001 || bars[0].BidSupertrend.Lower != 1.099 {
+		t.Fatalf("merged live bar lost its indicators: %+v", bars[0])
+	}
+	if got := (&SqueezeStrategy{}).Evaluate(bars); got != SignalBuy {
+		t.Fatalf("release on a live bar: got %v, want BUY", got)
+	}
+}
@@ -0,0 +1,33 @@
+package strategy
+
+import "go-trader/internal/state"
+
+// ConfidenceStrategy is optionally implemented by strategies that grade their signals.
+// What: Evaluate says which way to trade but not how strongly; a confidence lets the engine size
+//       high-conviction entries larger than marginal ones.
+// How: EvaluateWithConfidence returns the signal plus a confidence in [0, 1]. With the
+//      confidenceSizing run param set, the engine multiplies the order size by it; otherwise it is
+//      only logged. Strategies that only implement Evaluate report confidence 1 (see evaluate).
+type ConfidenceStrategy interface {
+	EvaluateWithConfidence(bars []state.HistoricalBar) (Signal, float64)
+}
+
+// evaluate runs s on bars, adapting plain strategies to a confidence of 1.
+// The returned confidence is clamped to [0, 1].
+func evaluate(s Strategy, bars []state.HistoricalBar) (Signal, float64) {
+	cs, ok := s.(ConfidenceStrategy)
+	if !ok {
+		return s.Evaluate(bars), 1
+	}
+	sig, conf := cs.EvaluateWithConfidence(bars)
+	return sig, min(max(conf, 0), 1)
+}
+
+// confidenceFactor is the order size multiplier for a signal's confidence: the confidence itself
+// when the confidenceSizing param is set, 1 otherwise.
+func (e *Engine) confidenceFactor(cfg *runConfig, confidence float64) float64 {
+	if v, _ := cfg.param("confidenceSizing"); v > 0 {
+		return confidence
+	}
+	return 1
+}
@@ -8,7 +8,8 @@ import "go-trader/internal/state"
 // Params:
 //   - len: number of bars for channel (int; default 20 if provided)
 //   - buf: multiplier of ATR as buffer distance (float; default 0.0)
-// Returns: SignalBuy, SignalSell, or SignalNone.
+// Returns: SignalBuy, SignalSell, or SignalNone. With evalOnTick, EvaluateTick applies the same
+//   channel to the forming bar's bid (see TickStrategy).
 
 type DonchianBreakoutStrategy struct {
 	len    int
@@ -16,6 +17,19 @@ type DonchianBreakoutStrategy struct {
 	atrLen int
 }
 
+func init() {
+	DefaultRegistry.Register("BREAKOUT_DC", func() Strategy { return &DonchianBreakoutStrategy{} })
+	DefaultRegistry.Describe(Info{
+		Key: "BREAKOUT_DC", Name: "Donchian Breakout",
+		Description: "Close beyond the Donchian channel, optionally by an ATR buffer",
+		Params: []ParamSpec{
+			{Name: "len", Type: "int", Default: 20, Min: bound(2), Description: "Channel lookback in bars"},
+			{Name: "buf", Type: "float", Default: 0, Min: bound(0), Description: "ATR multiple required beyond the band"},
+			{Name: "atrLen", Type: "int", Default: 14, Min: bound(2), Description: "ATR lookback when ATR must be computed"},
+		},
+	})
+}
+
 func (s *DonchianBreakoutStrategy) Key() string { return "BREAKOUT_DC" }
 
 // SetParams allows runtime configuration.
@@ -26,12 +40,35 @@ func (s *DonchianBreakoutStrategy) SetParams(p Params) {
 	if v, ok := p["atrLen"]; ok && int(v) > 1 { s.atrLen = int(v) }
 }
 
+// MinBars is the channel length when set, otherwise the two bars needed for precomputed bands.
+func (s *DonchianBreakoutStrategy) MinBars() int {
+	if s.len > 1 { return s.len }
+	return 2
+}
+
+// RequiredIndicators is the broker channel unless the channel is computed from len.
+func (s *DonchianBreakoutStrategy) RequiredIndicators() []string {
+	if s.len > 1 { return nil }
+	return []string{"bid_donchian"}
+}
+
 func (s *DonchianBreakoutStrategy) Evaluate(bars []state.HistoricalBar) Signal {
 	if len(bars) < 2 { return SignalNone }
+	upper, lower := s.channel(bars)
+	return breakout(bars[0].Bid.C, upper, lower)
+}
+
+// EvaluateTick signals as soon as the forming bar's bid trades beyond the channel of the completed
+// bars, instead of waiting for a bar to close there (evalOnTick).
+func (s *DonchianBreakoutStrategy) EvaluateTick(bars []state.HistoricalBar, forming state.HistoricalBar, ticks []state.Tick) Signal {
+	if len(bars) < 2 { return SignalNone }
+	upper, lower := s.channel(bars)
+	return breakout(forming.Bid.C, upper, lower)
+}
+
+// channel returns the bands of the newest bars widened by the ATR buffer; both are 0 when unknown.
+func (s *DonchianBreakoutStrategy) channel(bars []state.HistoricalBar) (upper, lower float64) {
 	b0 := bars[0]
-	c := b0.Bid.C
-	upper := 0.0
-	lower := 0.0
 	// Compute from params if provided
 	if s.len > 1 && len(bars) >= s.len {
 		high := bars[0].Bid.H
@@ -46,29 +83,24 @@ func (s *DonchianBreakoutStrategy) Evaluate(bars []state.HistoricalBar) Signal {
 		if b0.BidDonchian.Upper != nil { upper = *b0.BidDonchian.Upper }
 		if b0.BidDonchian.Lower != nil { lower = *b0.BidDonchian.Lower }
 	}
-	if upper == 0 && lower == 0 { return SignalNone }
+	if upper == 0 && lower == 0 { return 0, 0 }
 	// Apply ATR buffer if requested
 	if s.buf > 0 {
 		atr := b0.BidAtr
 		if atr <= 0 { atr = b0.AskAtr }
 		if atr <= 0 {
-			// fallback compute simple ATR as avg TR over atrLen or 14
+			// fallback: compute ATR locally over atrLen or 14
 			al := s.atrLen; if al <= 1 { al = 14 }
-			if len(bars) > al {
-				var sum float64
-				for i := 0; i < al; i++ {
-					h := bars[i].Bid.H; l := bars[i].Bid.L; pc := bars[i+1].Bid.C
-					tr := h - l
-					if v := abs(h-pc); v > tr { tr = v }
-					if v := abs(l-pc); v > tr { tr = v }
-					sum += tr
-				}
-				atr = sum / float64(al)
-			}
+			atr, _ = ATR(bars, al)
 		}
 		upper += s.buf * atr
 		lower -= s.buf * atr
 	}
+	return upper, lower
+}
+
+// breakout compares price c with the channel.
+func breakout(c, upper, lower float64) Signal {
 	if upper > 0 && c > upper { return SignalBuy }
 	if lower > 0 && c < lower { return SignalSell }
 	return SignalNone
@@ -0,0 +1,34 @@
+package strategy
+
+import (
+	"testing"
+
+	"go-trader/internal/state"
+)
+
+func TestDonchianEvaluateTickBreaksOutOnFormingBar(t *testing.T) {
+	s := &DonchianBreakoutStrategy{}
+	s.SetParams(Params{"len": 3})
+	bars := []state.HistoricalBar{
+		{Bid: state.OHLCV{H: 1.1010, L: 1.0995, C: 1.1005}},
+		{Bid: state.OHLCV{H: 1.1020, L: 1.0990, C: 1.1000}},
+		{Bid: state.OHLCV{H: 1.1015, L: 1.0985, C: 1.1010}},
+	}
+	for _, tc := range []struct {
+		close float64
+		want  Signal
+	}{
+		{1.1021, SignalBuy},
+		{1.1020, SignalNone}, // at the band is not beyond it
+		{1.1000, SignalNone},
+		{1.0984, SignalSell},
+	} {
+		forming := state.HistoricalBar{Bid: state.OHLCV{C: tc.close}}
+		if got := s.EvaluateTick(bars, forming, nil); got != tc.want {
+			t.Errorf("forming close %.4f: got %s, want %s", tc.close, got, tc.want)
+		}
+	}
+	if got := s.EvaluateTick(bars[:1], state.HistoricalBar{Bid: state.OHLCV{C: 2}}, nil); got != SignalNone {
+		t.Errorf("one completed bar: got %s, want NONE", got)
+	}
+}
@@ -3,12 +3,16 @@ package strategy
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"log"
+	mrand "math/rand/v2"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"go-trader/internal/amqp"
+	"go-trader/internal/clock"
 	"go-trader/internal/state"
 	"go-trader/internal/db"
 )
@@ -19,6 +23,10 @@ import (
 // Params:
 //  - StateManager provides bars/account
 //  - Publisher sends TradeCommand to JForex
+//  - Run params (alongside strategy params): minVol, riskPct, breakEvenPips, breakEvenBufferPips,
+//    maxConsecutiveLosses, warmupBars, slippage, evalOnTick (see TickStrategy), signalConfirmBars,
+//    slAtrMult, tpAtrMult, pyramidMaxAdds, pyramidQtyFactor (see positions.go), minBarsBetween,
+//    exitOnOpposite
 // Returns: Thread-safe Engine with Start/Stop controls per instrument.
 
 type Signal string
@@ -37,6 +45,19 @@ type Status struct {
 	Running      bool   `json:"running"`
 	LastSignal   string `json:"lastSignal"`
 	LastActionAt int64  `json:"lastActionAt"`
+	WarmingUp    bool   `json:"warmingUp,omitempty"`
+}
+
+// StatusChange is an out-of-band notification emitted when a run starts or stops.
+type StatusChange struct {
+	Type       string `json:"type"` // always "STRATEGY_STATUS_CHANGE"
+	Instrument string `json:"instrument"`
+	Period     string `json:"period"`
+	Key        string `json:"key"`
+	RunID      string `json:"runId"`
+	Status     string `json:"status"` // started | stopped | auto_stopped
+	Reason     string `json:"reason,omitempty"`
+	At         int64  `json:"at"`
 }
 
 // Params is a generic numeric parameter bag for strategies.
@@ -53,6 +74,27 @@ type Strategy interface {
 	Evaluate(bars []state.HistoricalBar) Signal
 }
 
+// WarmupAware is optionally implemented by strategies that need a minimum bar history;
+// the engine does not call Evaluate until that many bars are available.
+type WarmupAware interface {
+	MinBars() int
+}
+
+// Warmable is optionally implemented by strategies that keep state across evaluations (e.g. a
+// trend direction tracker), which starts cold. When a run starts the engine passes Warmup the
+// bars already held, newest-first and without the newest bar, which the loop evaluates live;
+// nothing Warmup does can place orders. Stateless strategies do not implement it.
+type Warmable interface {
+	Warmup(bars []state.HistoricalBar)
+}
+
+// EventStrategy is optionally implemented by strategies whose signals mark an event on one bar (a
+// cross, a squeeze release) rather than a condition that holds while it lasts. signalConfirmBars
+// confirms their signals by the direction persisting instead of repeating (see confirmSignal).
+type EventStrategy interface {
+	EventSignals() bool
+}
+
 // runConfig stores per-run settings.
 type runConfig struct {
 	instrument   string
@@ -62,24 +104,117 @@ type runConfig struct {
 	qty          float64
 	atrMult      float64
 	params       Params
+	// mu guards params and strategy state so SetParams can run concurrently with Evaluate
+	mu           sync.Mutex
 	stop         chan struct{}
 	running      bool
 	lastSignal   Signal
 	lastActionAt time.Time
+	// labels submitted by this run, used to recognise its positions
+	labels map[string]struct{}
+	// orderIDs whose stop has already been moved to break-even
+	breakEvenDone map[string]struct{}
+	// last seen snapshot of this run's open positions, keyed by orderID
+	openPositions map[string]state.Position
+	// when each open position was first seen, keyed by orderID (for hold time)
+	firstSeen map[string]time.Time
+	// ProducedAt of the first account snapshot each open position was missing from, keyed by orderID
+	missingSince map[string]int64
+	// consecutive losing closed trades (reset by a winner)
+	consecutiveLosses int
+	// true while evaluation is suppressed for lack of bar history
+	warmingUp bool
+	// required indicators missing from the newest bar when evaluation was last skipped (nil when none)
+	indicatorsMissing []string
+	// end timestamp of the bar on which each direction last placed an order (minBarsBetween)
+	lastActionBar map[Signal]int64
+	// orderIDs this run has requested closes for on an opposite signal (exitOnOpposite)
+	closing map[string]struct{}
+	// signal awaiting signalConfirmBars confirmation (empty when none) and the evaluations it has held
+	pendingSignal Signal
+	pendingEvals  int
+}
+
+// OrderSink receives the engine's trade commands; *amqp.Publisher is the production sink.
+type OrderSink interface {
+	PublishSubmitOrder(cmd amqp.TradeCommand) error
+	PublishModifyOrder(orderID string, sl, tp float64) error
+	PublishCloseOrder(orderID string) error
 }
 
 // Engine coordinates running strategies.
 type Engine struct {
 	sm        *state.StateManager
-	pub       *amqp.Publisher
+	pub       OrderSink
 	db        *db.Logger
 	mu        sync.Mutex
 	runs      map[string]*runConfig // key: instrument|period
+	clock     clock.Clock
+	// maxNotional caps total open notional across the account (account currency); 0 disables
+	maxNotional float64
+	// statusEvents carries StatusChange notifications for the broadcaster
+	statusEvents chan StatusChange
+	// evalJitter bounds the random phase offset of each new run's evaluation ticks
+	evalJitter time.Duration
 }
 
+// evalInterval is how often each run checks for new bars/ticks.
+const evalInterval = time.Second
+
 // NewEngine creates a new strategy engine.
-func NewEngine(sm *state.StateManager, pub *amqp.Publisher, dbl *db.Logger) *Engine {
-	return &Engine{sm: sm, pub: pub, db: dbl, runs: make(map[string]*runConfig)}
+func NewEngine(sm *state.StateManager, pub OrderSink, dbl *db.Logger) *Engine {
+	return &Engine{sm: sm, pub: pub, db: dbl, runs: make(map[string]*runConfig), clock: clock.Real(), statusEvents: make(chan StatusChange, 64),
+		evalJitter: evalInterval}
+}
+
+// StatusEvents returns the channel of run status changes (start/stop/auto-stop).
+func (e *Engine) StatusEvents() <-chan StatusChange { return e.statusEvents }
+
+// emitStatus publishes a status change without blocking the engine; events are dropped if nobody drains them.
+func (e *Engine) emitStatus(cfg *runConfig, status, reason string) {
+	ev := StatusChange{Type: "STRATEGY_STATUS_CHANGE", Instrument: cfg.instrument, Period: cfg.period, Key: cfg.strategy.Key(),
+		RunID: cfg.runID, Status: status, Reason: reason, At: e.clock.Now().UnixMilli()}
+	select {
+	case e.statusEvents <- ev:
+	default:
+		log.Printf("Strategy status event dropped (channel full): %s %s %s", ev.Instrument, ev.Period, ev.Status)
+	}
+}
+
+// SetMaxNotional sets the account-wide open notional cap applied before strategy orders.
+func (e *Engine) SetMaxNotional(max float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxNotional = max
+}
+
+// SetEvalJitter sets the spread of run evaluation phases for runs started afterwards.
+// What: Runs started together would all evaluate on the same boundary each second, bursting CPU and DB writes.
+// How: Each run waits a random offset in [0, d) before its first tick; d is capped at the one-second
+//      evaluation interval and 0 aligns all runs.
+func (e *Engine) SetEvalJitter(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.evalJitter = min(max(d, 0), evalInterval)
+}
+
+// evalPhase returns a random offset in [0, evalJitter) for a new run's evaluation ticks.
+func (e *Engine) evalPhase() time.Duration {
+	e.mu.Lock()
+	jitter := e.evalJitter
+	e.mu.Unlock()
+	if jitter <= 0 {
+		return 0
+	}
+	return mrand.N(jitter)
+}
+
+// SetClock injects the clock driving the evaluation loop (defaults to the real clock).
+// Call before starting strategies.
+func (e *Engine) SetClock(clk clock.Clock) {
+	if clk != nil {
+		e.clock = clk
+	}
 }
 
 // StartStrategy starts a strategy for instrument/period with basic params.
@@ -88,6 +223,7 @@ func (e *Engine) StartStrategy(instrument, period string, s Strategy, qty, atrMu
 }
 
 // StartStrategyWithParams starts a strategy and passes optional numeric params.
+// The run is refused when params fail CheckTickMode.
 func (e *Engine) StartStrategyWithParams(instrument, period string, s Strategy, qty, atrMult float64, params Params) {
 	key := e.key(instrument, period)
 	e.mu.Lock()
@@ -96,6 +232,10 @@ func (e *Engine) StartStrategyWithParams(instrument, period string, s Strategy,
 		log.Printf("Strategy already running for %s %s", instrument, period)
 		return
 	}
+	if err := CheckTickMode(s, params); err != nil {
+		log.Printf("Strategy not started on %s %s: %v", instrument, period, err)
+		return
+	}
 	// Guardrails
 	if qty <= 0 { qty = 0.10 }
 	if qty > 100 { qty = 100 }
@@ -107,18 +247,43 @@ func (e *Engine) StartStrategyWithParams(instrument, period string, s Strategy,
 	}
 	// Generate runID
 	runID := newRunID()
-	cfg := &runConfig{instrument: instrument, period: period, strategy: s, runID: runID, qty: qty, atrMult: atrMult, params: params, stop: make(chan struct{}), running: true}
+	cfg := &runConfig{instrument: instrument, period: period, strategy: s, runID: runID, qty: qty, atrMult: atrMult, params: params, stop: make(chan struct{}), running: true,
+		labels: make(map[string]struct{}), breakEvenDone: make(map[string]struct{}), openPositions: make(map[string]state.Position),
+		firstSeen: make(map[string]time.Time), missingSince: make(map[string]int64)}
 	e.runs[key] = cfg
+	e.warmup(cfg)
 	// Log run start
 	if e.db != nil {
 		e.db.LogStrategyRunStart(runID, instrument, period, s.Key(), qty, atrMult, params)
 	}
 	go e.loop(cfg)
+	e.emitStatus(cfg, "started", "")
 	log.Printf("▶️ Strategy %s started on %s @ %s (qty=%.2f, atrMult=%.2f)", s.Key(), instrument, period, qty, atrMult)
 }
 
+// warmup primes a Warmable strategy with the bars held before its run starts.
+func (e *Engine) warmup(cfg *runConfig) {
+	w, ok := cfg.strategy.(Warmable)
+	if !ok {
+		return
+	}
+	bars := e.sm.GetHistoricalBars(cfg.instrument, cfg.period)
+	if len(bars) < 2 {
+		return
+	}
+	cfg.mu.Lock()
+	w.Warmup(bars[1:])
+	cfg.mu.Unlock()
+	log.Printf("Strategy %s on %s @ %s warmed with %d historical bars", cfg.strategy.Key(), cfg.instrument, cfg.period, len(bars)-1)
+}
+
 // StopStrategy stops a running strategy for instrument/period.
 func (e *Engine) StopStrategy(instrument, period string) {
+	e.stopStrategyWithStatus(instrument, period, "stopped", "")
+}
+
+// stopStrategyWithStatus stops a run and records the given final status (e.g. "auto_stopped").
+func (e *Engine) stopStrategyWithStatus(instrument, period, status, reason string) {
 	key := e.key(instrument, period)
 	e.mu.Lock()
 	cfg, ok := e.runs[key]
@@ -129,77 +294,295 @@ func (e *Engine) StopStrategy(instrument, period string) {
 	if ok {
 		close(cfg.stop)
 		if e.db != nil {
-			e.db.LogStrategyRunStop(cfg.runID, "stopped")
+			e.db.LogStrategyRunStop(cfg.runID, status)
 		}
-		log.Printf("⏹️ Strategy stopped on %s @ %s", instrument, period)
+		e.emitStatus(cfg, status, reason)
+		log.Printf("⏹️ Strategy stopped on %s @ %s (%s)", instrument, period, status)
 	}
 }
 
 func (e *Engine) key(instrument, period string) string { return instrument + "|" + period }
 
-// loop polls for new bars and evaluates the strategy per bar close.
+// param returns a run param under the run lock.
+func (c *runConfig) param(name string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.params[name]
+	return v, ok
+}
+
+// GetParams returns a copy of the params of a running strategy.
+func (e *Engine) GetParams(instrument, period string) (Params, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cfg, ok := e.runs[e.key(instrument, period)]
+	if !ok {
+		return nil, false
+	}
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	return copyParams(cfg.params), true
+}
+
+// UpdateParams merges params into a running strategy and applies them via SetParams.
+// What: Tune a live run without restarting it (keeps runID and history).
+// How: Under the engine and run locks, merges the new values over the current params, calls
+//      SetParams on the strategy if supported, and logs a params_updated event with old/new values.
+// Params: instrument, period, params to set (existing keys not present are kept)
+// Returns: old and new params, or an error if no strategy runs on instrument/period or the merged
+//          params enable evalOnTick for a strategy without tick support (ErrTickModeUnsupported).
+func (e *Engine) UpdateParams(instrument, period string, params Params) (Params, Params, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cfg, ok := e.runs[e.key(instrument, period)]
+	if !ok {
+		return nil, nil, fmt.Errorf("no strategy running for %s %s", instrument, period)
+	}
+	cfg.mu.Lock()
+	old := copyParams(cfg.params)
+	merged := copyParams(cfg.params)
+	for k, v := range params {
+		merged[k] = v
+	}
+	if err := CheckTickMode(cfg.strategy, merged); err != nil {
+		cfg.mu.Unlock()
+		return nil, nil, err
+	}
+	if pz, ok := cfg.strategy.(Parametrizable); ok {
+		pz.SetParams(merged)
+	}
+	cfg.params = merged
+	cfg.mu.Unlock()
+
+	if e.db != nil {
+		e.db.LogStrategyEvent(cfg.runID, instrument, period, cfg.strategy.Key(), "params_updated", "", map[string]any{"old": old, "new": merged})
+	}
+	log.Printf("🔧 Params updated for %s on %s @ %s: %v -> %v", cfg.strategy.Key(), instrument, period, old, merged)
+	return old, copyParams(merged), nil
+}
+
+func copyParams(p Params) Params {
+	out := make(Params, len(p))
+	for k, v := range p {
+		out[k] = v
+	}
+	return out
+}
+
+// loop polls for new bars and evaluates the strategy per bar close, or per new tick in tick mode.
 func (e *Engine) loop(cfg *runConfig) {
-	var lastSeq int = -1
-	t := time.NewTicker(1 * time.Second)
+	// end timestamp of the last evaluated bar; a historical response replacing a live-merged bar keeps
+	// the end but renumbers it, so Sequence alone would evaluate the same bar twice
+	var lastBarEnd int64 = -1
+	var lastTickTs int64
+	// end timestamp of the completed bar whose forming successor already produced an order (tick mode)
+	var tickActedAfter int64 = -1
+	// Spread runs across the interval instead of all firing on the same boundary
+	if phase := e.evalPhase(); phase > 0 {
+		pt := e.clock.NewTicker(phase)
+		select {
+		case <-cfg.stop:
+			pt.Stop()
+			return
+		case <-pt.C():
+		}
+		pt.Stop()
+	}
+	t := e.clock.NewTicker(evalInterval)
 	defer t.Stop()
 	for {
 		select {
 		case <-cfg.stop:
 			return
-		case <-t.C:
+		case <-t.C():
+			if e.trackClosedPositions(cfg) {
+				return
+			}
+			e.manageBreakEven(cfg)
 			bars := e.sm.GetHistoricalBars(cfg.instrument, cfg.period)
 			if len(bars) == 0 {
 				continue
 			}
 			// Bars are newest-first by sequence (based on manager implementation)
 			latest := bars[0]
-			if latest.Sequence == lastSeq {
+			newBar := latest.BarEndTimestamp != lastBarEnd
+			lastBarEnd = latest.BarEndTimestamp
+			tickStrat, tickMode := cfg.tickMode()
+			var forming state.HistoricalBar
+			var formingTicks []state.Tick
+			if tickMode {
+				ticks := e.sm.GetTicks(cfg.instrument)
+				if len(ticks) == 0 || tickTime(ticks[len(ticks)-1]) == lastTickTs {
+					continue
+				}
+				lastTickTs = tickTime(ticks[len(ticks)-1])
+				var ok bool
+				if forming, formingTicks, ok = formingBar(latest, ticks); !ok {
+					continue
+				}
+			} else if !newBar {
+				continue
+			}
+			if need := e.warmupBars(cfg); len(bars) < need {
+				e.setWarmingUp(cfg, true, len(bars), need)
+				continue
+			}
+			e.setWarmingUp(cfg, false, len(bars), 0)
+			cfg.mu.Lock()
+			missing := missingIndicators(cfg.strategy, latest)
+			cfg.mu.Unlock()
+			if e.setIndicatorsMissing(cfg, missing, latest.Sequence) {
 				continue
 			}
-			lastSeq = latest.Sequence
-			sig := cfg.strategy.Evaluate(bars)
+			cfg.mu.Lock()
+			var sig Signal
+			confidence := 1.0
+			if tickMode {
+				sig = tickStrat.EvaluateTick(bars, forming, formingTicks)
+			} else {
+				sig, confidence = evaluate(cfg.strategy, bars)
+			}
+			cfg.mu.Unlock()
+			sig = e.confirmSignal(cfg, sig, latest.Sequence)
 			if sig == SignalNone {
 				continue
 			}
+			// In tick mode act at most once per forming bar, or every following tick would re-enter
+			if tickMode {
+				if tickActedAfter == latest.BarEndTimestamp {
+					continue
+				}
+				tickActedAfter = latest.BarEndTimestamp
+			}
+			// Close this run's positions against the signal when exitOnOpposite is set; exits are
+			// not subject to the entry filters below, and the signal may still open a new position
+			e.closeOnOpposite(cfg, sig, latest.Sequence)
+			// Suppress signals in thin markets when a minimum tick volume is configured
+			if minVol, _ := cfg.param("minVol"); minVol > 0 {
+				avgVol := state.AverageTickVolume(e.sm.GetTicks(cfg.instrument))
+				if avgVol < minVol {
+					log.Printf("Signal %s on %s @ %s filtered: avg tick volume %.2f < %.2f", sig, cfg.instrument, cfg.period, avgVol, minVol)
+					if e.db != nil {
+						e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "low_volume_filtered", string(sig), map[string]any{"avgVol": avgVol, "minVol": minVol, "seq": latest.Sequence})
+					}
+					continue
+				}
+			}
 			// Log signal event
 			if e.db != nil {
 				e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "signal", string(sig), map[string]any{"seq": latest.Sequence})
 			}
+			// Do not open against an existing position on the instrument unless allowOpposing is set
+			if opposing := e.opposingPositions(cfg, sig); len(opposing) > 0 {
+				ids := make([]string, 0, len(opposing))
+				for _, p := range opposing {
+					ids = append(ids, p.OrderID)
+				}
+				log.Printf("Signal %s on %s @ %s suppressed: %d opposing open positions %v", sig, cfg.instrument, cfg.period, len(ids), ids)
+				if e.db != nil {
+					e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "opposing_position_suppressed", string(sig), map[string]any{"orderIds": ids, "seq": latest.Sequence})
+				}
+				continue
+			}
+			// Do not re-enter a direction until minBarsBetween bars have closed since its last order
+			if since, minBars, tooSoon := e.tooSoonSameDirection(cfg, sig, bars); tooSoon {
+				log.Printf("Signal %s on %s @ %s suppressed: %d bars since the last %s order (min %d)", sig, cfg.instrument, cfg.period, since, sig, minBars)
+				if e.db != nil {
+					e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "too_soon_same_direction", string(sig), map[string]any{"barsSince": since, "minBarsBetween": minBars, "seq": latest.Sequence})
+				}
+				continue
+			}
+			// Pyramiding: a signal in the direction of open positions adds to them or is skipped
+			isAdd, qtyFactor, addTo, skip := e.pyramidEntry(cfg, sig)
+			if skip != "" {
+				log.Printf("Signal %s on %s @ %s not added to position: %s", sig, cfg.instrument, cfg.period, skip)
+				if e.db != nil {
+					e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "pyramid_skipped", string(sig), map[string]any{"reason": skip, "seq": latest.Sequence})
+				}
+				continue
+			}
+			// Refuse to trade on a frozen price after a feed outage
+			if stale, ageMs, maxAgeMs := e.staleTick(cfg); stale {
+				log.Printf("Signal %s on %s @ %s not traded: newest tick is %d ms old (max %d)", sig, cfg.instrument, cfg.period, ageMs, maxAgeMs)
+				if e.db != nil {
+					e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "stale_price_no_trade", string(sig), map[string]any{"tickAgeMs": ageMs, "maxTickAgeMs": maxAgeMs, "seq": latest.Sequence})
+				}
+				continue
+			}
 			// Prepare order with ATR-based SL/TP if available
 			pip := getPipSize(cfg.instrument)
 			atr := latest.BidAtr
 			if atr <= 0 {
 				atr = latest.AskAtr
 			}
-			slPips := 10.0
+			slMult, tpMult := e.stopMults(cfg)
+			slPips, tpPips := 10.0, 10.0*tpMult/slMult
 			if atr > 0 {
-				slPips = cfg.atrMult * (atr / pip)
+				slPips = slMult * (atr / pip)
 				if slPips < 1 { slPips = 1 }
+				tpPips = tpMult * (atr / pip)
+				if tpPips < 1 { tpPips = 1 }
 			}
+			slPips, tpPips = e.applyMinStop(cfg, sig, slPips, tpPips)
 			// Use latest mid as reference; market order
 			price := (latest.Bid.C + latest.Ask.C) / 2.0
 			var sl, tp float64
 			if sig == SignalBuy {
 				sl = price - slPips*pip
-				tp = price + slPips*pip
+				tp = price + tpPips*pip
 			} else {
 				sl = price + slPips*pip
-				tp = price - slPips*pip
+				tp = price - tpPips*pip
 			}
-			label := cfg.instrument + "_strat_" + strings.ToLower(string(sig)) + "_" + time.Now().Format("150405")
+			sl, tp = state.RoundPrice(cfg.instrument, sl), state.RoundPrice(cfg.instrument, tp)
+			// Scale by conviction when confidenceSizing is set (the 0.001 minimum lot still applies)
+			sizeFactor := e.confidenceFactor(cfg, confidence)
+			label := cfg.instrument + "_strat_" + strings.ToLower(string(sig)) + "_" + e.clock.Now().Format("150405")
 			cmd := amqp.TradeCommand{
 				Label:           label,
 				Instrument:      cfg.instrument,
 				OrderCmd:        string(sig), // BUY or SELL
-				Amount:          cfg.qty,
+				Amount:          max(e.riskSizedQty(cfg, slPips)*qtyFactor*sizeFactor, 0.001),
 				Price:           0,
-				Slippage:        5,
+				Slippage:        e.slippage(cfg),
 				StopLossPrice:   sl,
 				TakeProfitPrice: tp,
 			}
-			// Record that we acted on a signal
+			// Account-wide notional cap
+			e.mu.Lock()
+			maxNotional := e.maxNotional
+			e.mu.Unlock()
+			if err := state.CheckNotionalLimit(e.sm.GetAccountInfo(), e.sm.LatestTicks(), cfg.instrument, cmd.Amount, maxNotional); err != nil {
+				log.Printf("Strategy order rejected on %s: %v", cfg.instrument, err)
+				if e.db != nil {
+					e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "notional_limit", string(sig), map[string]any{"label": label, "qty": cmd.Amount, "reason": err.Error()})
+				}
+				continue
+			}
+			// Free margin pre-flight
+			if err := state.CheckMargin(e.sm.GetAccountInfo(), e.sm.LatestTicks(), cfg.instrument, cmd.Amount); err != nil {
+				log.Printf("Strategy order rejected on %s: %v", cfg.instrument, err)
+				if e.db != nil {
+					e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "insufficient_margin", string(sig), map[string]any{"label": label, "qty": cmd.Amount, "reason": err.Error()})
+				}
+				continue
+			}
+			if err := e.pub.PublishSubmitOrder(cmd); err != nil {
+				log.Printf("Strategy publish failed: %v", err)
+				if e.db != nil {
+					e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "publish_failed", string(sig), map[string]any{"label": label, "reason": err.Error()})
+				}
+				continue
+			}
+			// Record that we acted on a signal once the order is on its way, so a failed publish
+			// neither claims the label nor starts the minBarsBetween gap
+			cfg.labels[label] = struct{}{}
 			cfg.lastSignal = sig
-			cfg.lastActionAt = time.Now()
+			cfg.lastActionAt = e.clock.Now()
+			if cfg.lastActionBar == nil {
+				cfg.lastActionBar = make(map[Signal]int64)
+			}
+			cfg.lastActionBar[sig] = latest.BarEndTimestamp
 			// DB logs for strategy-sourced order
 			if e.db != nil {
 				e.db.LogStrategyEvent(
@@ -211,30 +594,244 @@ func (e *Engine) loop(cfg *runConfig) {
 						"entryMidPrice":  price,
 						"pipSize":        pip,
 						"plannedSlPips":  slPips,
-						"plannedTpPips":  slPips,
+						"plannedTpPips":  tpPips,
+						"slAtrMult":      slMult,
+						"tpAtrMult":      tpMult,
+						"confidence":     confidence,
+						"sizeFactor":     sizeFactor,
 						"sl":             sl,
 						"tp":             tp,
 						"seq":            latest.Sequence,
 					},
 				)
+				if isAdd {
+					ids := make([]string, 0, len(addTo))
+					for _, p := range addTo {
+						ids = append(ids, p.OrderID)
+					}
+					e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "added_to_position", string(sig),
+						map[string]any{"label": label, "qty": cmd.Amount, "qtyFactor": qtyFactor, "addNumber": len(addTo), "addsTo": ids, "sl": sl, "tp": tp})
+				}
 				e.db.LogTradeSubmitted(
 					label, cfg.instrument, string(sig), cmd.OrderCmd,
 					cmd.Amount, cmd.Price, cmd.StopLossPrice, cmd.TakeProfitPrice,
-					map[string]any{"orderType":"MARKET","source":"strategy","strategyKey":cfg.strategy.Key(),"runId":cfg.runID, "pipSize": pip, "plannedSlPips": slPips},
+					map[string]any{"orderType":"MARKET","source":"strategy","strategyKey":cfg.strategy.Key(),"runId":cfg.runID, "pipSize": pip, "plannedSlPips": slPips, "plannedTpPips": tpPips},
 				)
 			}
-			if err := e.pub.PublishSubmitOrder(cmd); err != nil {
-				log.Printf("Strategy publish failed: %v", err)
+		}
+	}
+}
+
+// confirmSignal applies the signalConfirmBars debounce and returns the signal to act on.
+// What: Near a crossover a strategy can flip BUY/SELL on consecutive bars and churn commissions.
+// How: A signal becomes pending and is confirmed once the strategy has produced it on N consecutive
+//      evaluations (counting the one that produced it). SignalNone drops the pending signal; an
+//      opposite signal is a flap, which is logged and replaces it. An EventStrategy signals on the
+//      event bar only, so for it SignalNone keeps the pending direction and counts as held: the
+//      signal is confirmed when no opposite signal followed within N evaluations. Unlike a cooldown
+//      this counts evaluations, not time.
+// Returns: sig unchanged when signalConfirmBars is below 2, otherwise the confirmed signal or SignalNone.
+func (e *Engine) confirmSignal(cfg *runConfig, sig Signal, seq int) Signal {
+	n, _ := cfg.param("signalConfirmBars")
+	if n < 2 {
+		return sig
+	}
+	es, event := cfg.strategy.(EventStrategy)
+	event = event && es.EventSignals()
+	switch {
+	case sig == SignalNone && (!event || cfg.pendingSignal == ""):
+		cfg.pendingSignal, cfg.pendingEvals = "", 0
+		return SignalNone
+	case sig == SignalNone:
+		// The event's direction persisted through this bar
+		cfg.pendingEvals++
+	case sig != cfg.pendingSignal:
+		if cfg.pendingSignal != "" {
+			log.Printf("Signal flap on %s @ %s: %s after %s held %d/%d, restarting confirmation", cfg.instrument, cfg.period, sig, cfg.pendingSignal, cfg.pendingEvals, int(n))
+			if e.db != nil {
+				e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "signal_flap_suppressed", string(cfg.pendingSignal), map[string]any{"next": string(sig), "held": cfg.pendingEvals, "confirmBars": int(n), "seq": seq})
 			}
 		}
+		cfg.pendingSignal, cfg.pendingEvals = sig, 1
+	default:
+		cfg.pendingEvals++
+	}
+	if cfg.pendingEvals < int(n) {
+		return SignalNone
+	}
+	confirmed := cfg.pendingSignal
+	cfg.pendingSignal, cfg.pendingEvals = "", 0
+	return confirmed
+}
+
+// periodDurations are the bar lengths of the broker's periods.
+var periodDurations = map[string]time.Duration{
+	"TEN_SECS":     10 * time.Second,
+	"ONE_MIN":      time.Minute,
+	"FIVE_MINS":    5 * time.Minute,
+	"FIFTEEN_MINS": 15 * time.Minute,
+	"ONE_HOUR":     time.Hour,
+	"FOUR_HOURS":   4 * time.Hour,
+	"DAILY":        24 * time.Hour,
+}
+
+// tooSoonSameDirection applies the minBarsBetween guard to sig.
+// What: Keeps a run from re-entering the same direction shortly after its last order in that direction.
+// How: Divides the time from the bar the last sig order was placed on to the newest bar by the period
+//      length, so the gap scales with the timeframe and still passes once that bar has left the buffer;
+//      unknown periods count the buffered bars instead. The opposite direction is not affected.
+// Returns: bars since the last sig order, the minimum, and whether sig must be suppressed.
+func (e *Engine) tooSoonSameDirection(cfg *runConfig, sig Signal, bars []state.HistoricalBar) (since, minBars int, tooSoon bool) {
+	n, _ := cfg.param("minBarsBetween")
+	last, ok := cfg.lastActionBar[sig]
+	if n < 1 || !ok {
+		return 0, int(n), false
+	}
+	if d := periodDurations[cfg.period]; d > 0 && len(bars) > 0 {
+		since = int((bars[0].BarEndTimestamp - last) / d.Milliseconds())
+		return since, int(n), since < int(n)
+	}
+	for _, b := range bars {
+		if b.BarEndTimestamp > last {
+			since++
+		}
+	}
+	return since, int(n), since < int(n)
+}
+
+// stopMults returns the ATR multiples for the stop-loss and take-profit distances: the slAtrMult and
+// tpAtrMult run params when set (capped like atrMult), otherwise the run's atrMult for both.
+func (e *Engine) stopMults(cfg *runConfig) (sl, tp float64) {
+	sl, tp = cfg.atrMult, cfg.atrMult
+	if v, ok := cfg.param("slAtrMult"); ok && v > 0 {
+		sl = min(v, 20)
+	}
+	if v, ok := cfg.param("tpAtrMult"); ok && v > 0 {
+		tp = min(v, 20)
+	}
+	return sl, tp
+}
+
+// applyMinStop widens SL/TP distances (pips) to the instrument's broker minimum so the order is
+// not rejected, logging a stop_widened event when either distance changed.
+func (e *Engine) applyMinStop(cfg *runConfig, sig Signal, slPips, tpPips float64) (float64, float64) {
+	sl, slWidened := state.ClampStopPips(cfg.instrument, slPips)
+	tp, tpWidened := state.ClampStopPips(cfg.instrument, tpPips)
+	if slWidened || tpWidened {
+		log.Printf("Strategy stop on %s widened to broker minimum %.1f pips: sl %.1f -> %.1f, tp %.1f -> %.1f",
+			cfg.instrument, state.MinStopPips(cfg.instrument), slPips, sl, tpPips, tp)
+		if e.db != nil {
+			e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "stop_widened", string(sig),
+				map[string]any{"minStopPips": state.MinStopPips(cfg.instrument), "slPips": slPips, "tpPips": tpPips, "newSlPips": sl, "newTpPips": tp})
+		}
+	}
+	return sl, tp
+}
+
+// defaultMaxTickAgeMs is the reference tick age above which orders are refused when the
+// maxTickAgeMs param is unset.
+const defaultMaxTickAgeMs = 60_000
+
+// staleTick reports whether the instrument's newest tick is too old to trade on: older than the
+// maxTickAgeMs run param (default defaultMaxTickAgeMs; 0 disables the check), or missing entirely.
+// ageMs is -1 when no tick has arrived.
+func (e *Engine) staleTick(cfg *runConfig) (stale bool, ageMs, maxAgeMs int64) {
+	maxAgeMs = defaultMaxTickAgeMs
+	if v, ok := cfg.param("maxTickAgeMs"); ok {
+		maxAgeMs = int64(v)
+	}
+	if maxAgeMs <= 0 {
+		return false, 0, 0
+	}
+	ticks := e.sm.GetTicks(cfg.instrument)
+	if len(ticks) == 0 {
+		return true, -1, maxAgeMs
+	}
+	ageMs = e.clock.Now().UnixMilli() - tickTime(ticks[len(ticks)-1])
+	return ageMs > maxAgeMs, ageMs, maxAgeMs
+}
+
+// slippage returns the market-order slippage in pips: the slippage run param when set,
+// otherwise the instrument default.
+func (e *Engine) slippage(cfg *runConfig) float64 {
+	if v, ok := cfg.param("slippage"); ok && v > 0 {
+		return v
+	}
+	return state.DefaultSlippage(cfg.instrument)
+}
+
+// warmupBars returns the bar count required before evaluating: the larger of the
+// strategy's MinBars and the warmupBars run param.
+func (e *Engine) warmupBars(cfg *runConfig) int {
+	need := 0
+	cfg.mu.Lock()
+	if w, ok := cfg.strategy.(WarmupAware); ok {
+		need = w.MinBars()
+	}
+	cfg.mu.Unlock()
+	if v, ok := cfg.param("warmupBars"); ok && int(v) > need {
+		need = int(v)
+	}
+	return need
+}
+
+// setWarmingUp records warm-up transitions, logging a warming_up event when evaluation is first suppressed.
+func (e *Engine) setWarmingUp(cfg *runConfig, warming bool, have, need int) {
+	e.mu.Lock()
+	changed := cfg.warmingUp != warming
+	cfg.warmingUp = warming
+	e.mu.Unlock()
+	if !changed {
+		return
+	}
+	if !warming {
+		log.Printf("Strategy %s on %s @ %s warmed up (%d bars)", cfg.strategy.Key(), cfg.instrument, cfg.period, have)
+		return
+	}
+	log.Printf("Strategy %s on %s @ %s warming up: %d/%d bars", cfg.strategy.Key(), cfg.instrument, cfg.period, have, need)
+	if e.db != nil {
+		e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "warming_up", "", map[string]any{"bars": have, "needed": need})
 	}
 }
 
+// setIndicatorsMissing records which required indicators the newest bar lacks and reports whether
+// evaluation must be skipped. An indicators_missing event is logged when the missing set changes.
+func (e *Engine) setIndicatorsMissing(cfg *runConfig, missing []string, seq int) bool {
+	if slices.Equal(cfg.indicatorsMissing, missing) {
+		return len(missing) > 0
+	}
+	cfg.indicatorsMissing = missing
+	if len(missing) == 0 {
+		log.Printf("Strategy %s on %s @ %s: required indicators present again", cfg.strategy.Key(), cfg.instrument, cfg.period)
+		return false
+	}
+	log.Printf("Strategy %s on %s @ %s skipping evaluation: indicators missing %v", cfg.strategy.Key(), cfg.instrument, cfg.period, missing)
+	if e.db != nil {
+		e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "indicators_missing", "", map[string]any{"missing": missing, "seq": seq})
+	}
+	return true
+}
+
 func getPipSize(instrument string) float64 {
-	if strings.Contains(instrument, "JPY") {
-		return 0.01
+	return state.PipSize(instrument)
+}
+
+// riskSizedQty sizes an order so that hitting the stop loses riskPct% of equity in account currency.
+// Falls back to the run's fixed qty when equity or pip value is unavailable.
+func (e *Engine) riskSizedQty(cfg *runConfig, slPips float64) float64 {
+	riskPct, _ := cfg.param("riskPct")
+	if riskPct <= 0 || slPips <= 0 {
+		return cfg.qty
 	}
-	return 0.0001
+	equity := e.sm.GetAccountInfo().Account.Equity
+	pipValuePerLot := state.PipValue(cfg.instrument, 1, e.sm.LatestTicks())
+	if equity <= 0 || pipValuePerLot <= 0 {
+		return cfg.qty
+	}
+	qty := (equity * riskPct / 100) / (slPips * pipValuePerLot)
+	if qty < 0.001 { qty = 0.001 }
+	if qty > 100 { qty = 100 }
+	return qty
 }
 
 // Statuses returns a snapshot of running strategy instances.
@@ -250,6 +847,7 @@ func (e *Engine) Statuses() []Status {
 			Running:      cfg.running,
 			LastSignal:   string(cfg.lastSignal),
 			LastActionAt: func() int64 { if cfg.lastActionAt.IsZero() { return 0 } ; return cfg.lastActionAt.UnixMilli() }(),
+			WarmingUp:    cfg.warmingUp,
 		})
 	}
 	return out
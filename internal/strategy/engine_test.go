@@ -0,0 +1,704 @@
+package strategy
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go-trader/internal/amqp"
+	"go-trader/internal/clock"
+	"go-trader/internal/state"
+)
+
+func TestWarmupBarsUsesLargerOfStrategyAndParam(t *testing.T) {
+	e := &Engine{}
+	st := &SupertrendStrategy{atrLen: 10, mult: 3}
+	cfg := &runConfig{strategy: st}
+	if got := e.warmupBars(cfg); got != 11 {
+		t.Fatalf("strategy minimum: got %d, want 11", got)
+	}
+	cfg.params = Params{"warmupBars": 50}
+	if got := e.warmupBars(cfg); got != 50 {
+		t.Fatalf("param above minimum: got %d, want 50", got)
+	}
+	cfg.params = Params{"warmupBars": 5}
+	if got := e.warmupBars(cfg); got != 11 {
+		t.Fatalf("param below minimum: got %d, want 11", got)
+	}
+	if got := (&DemaRsiStrategy{minSlope: 0.5}).MinBars(); got != 6 {
+		t.Fatalf("DEMA_RSI with default slopeBars: got %d, want 6", got)
+	}
+}
+
+func TestSlippageParamOverridesInstrumentDefault(t *testing.T) {
+	e := &Engine{}
+	cfg := &runConfig{instrument: "GBPJPY"}
+	if got := e.slippage(cfg); got != 10 {
+		t.Fatalf("instrument default: got %v, want 10", got)
+	}
+	cfg.params = Params{"slippage": 3}
+	if got := e.slippage(cfg); got != 3 {
+		t.Fatalf("run param: got %v, want 3", got)
+	}
+}
+
+func TestStopMultsFallBackToAtrMult(t *testing.T) {
+	e := &Engine{}
+	cfg := &runConfig{atrMult: 1.5}
+	if sl, tp := e.stopMults(cfg); sl != 1.5 || tp != 1.5 {
+		t.Fatalf("fallback: got %v/%v, want 1.5/1.5", sl, tp)
+	}
+	cfg.params = Params{"slAtrMult": 1, "tpAtrMult": 2}
+	if sl, tp := e.stopMults(cfg); sl != 1 || tp != 2 {
+		t.Fatalf("params: got %v/%v, want 1/2", sl, tp)
+	}
+	cfg.params = Params{"tpAtrMult": 50}
+	if sl, tp := e.stopMults(cfg); sl != 1.5 || tp != 20 {
+		t.Fatalf("partial and capped: got %v/%v, want 1.5/20", sl, tp)
+	}
+}
+
+func TestPyramidEntry(t *testing.T) {
+	sm := state.NewStateManager()
+	e := &Engine{sm: sm}
+	cfg := &runConfig{labels: map[string]struct{}{"a": {}, "b": {}}, params: Params{"pyramidMaxAdds": 1}}
+	setPositions := func(ps ...state.Position) { sm.UpdateAccountInfo(state.AccountInfo{Positions: ps}) }
+
+	if add, f, _, skip := e.pyramidEntry(cfg, SignalBuy); add || f != 1 || skip != "" {
+		t.Fatalf("no open position: got add=%v factor=%v skip=%q, want a normal entry", add, f, skip)
+	}
+	setPositions(state.Position{OrderID: "1", Label: "a", OrderCommand: "BUY", PnL: -5})
+	if _, _, _, skip := e.pyramidEntry(cfg, SignalBuy); skip == "" {
+		t.Fatal("losing position should not be added to")
+	}
+	if add, _, _, skip := e.pyramidEntry(cfg, SignalSell); add || skip != "" {
+		t.Fatal("opposite signal should be a normal entry")
+	}
+	setPositions(state.Position{OrderID: "1", Label: "a", OrderCommand: "BUY", PnL: 5})
+	if add, f, same, skip := e.pyramidEntry(cfg, SignalBuy); !add || f != 0.5 || len(same) != 1 || skip != "" {
+		t.Fatalf("winning position: got add=%v factor=%v same=%d skip=%q, want an add at 0.5", add, f, len(same), skip)
+	}
+	setPositions(state.Position{OrderID: "1", Label: "a", OrderCommand: "BUY", PnL: 5}, state.Position{OrderID: "2", Label: "b", OrderCommand: "BUY", PnL: 1})
+	if _, _, _, skip := e.pyramidEntry(cfg, SignalBuy); skip == "" {
+		t.Fatal("max adds reached should skip")
+	}
+	cfg.params = Params{}
+	if add, f, _, skip := e.pyramidEntry(cfg, SignalBuy); add || f != 1 || skip != "" {
+		t.Fatal("disabled pyramiding should leave entries unchanged")
+	}
+}
+
+func TestOpposingPositionsSuppressUnlessAllowed(t *testing.T) {
+	sm := state.NewStateManager()
+	e := &Engine{sm: sm}
+	cfg := &runConfig{instrument: "EURUSD"}
+	sm.UpdateAccountInfo(state.AccountInfo{Positions: []state.Position{
+		{OrderID: "1", Instrument: "EURUSD", OrderCommand: "SELL", Label: "manual"},
+		{OrderID: "2", Instrument: "GBPUSD", OrderCommand: "BUY"},
+	}})
+	if got := e.opposingPositions(cfg, SignalBuy); len(got) != 1 || got[0].OrderID != "1" {
+		t.Fatalf("buy against a manual sell: got %+v, want order 1", got)
+	}
+	if got := e.opposingPositions(cfg, SignalSell); len(got) != 0 {
+		t.Fatalf("sell with only a sell open on the instrument: got %+v, want none", got)
+	}
+	cfg.params = Params{"allowOpposing": 1}
+	if got := e.opposingPositions(cfg, SignalBuy); got != nil {
+		t.Fatalf("allowOpposing: got %+v, want nil", got)
+	}
+}
+
+func TestCloseOnOpposite(t *testing.T) {
+	sm := state.NewStateManager()
+	sink := &recordingSink{}
+	e := &Engine{sm: sm, pub: sink}
+	cfg := &runConfig{instrument: "EURUSD", labels: map[string]struct{}{"a": {}, "b": {}}}
+	sm.UpdateAccountInfo(state.AccountInfo{Positions: []state.Position{
+		{OrderID: "1", Instrument: "EURUSD", OrderCommand: "BUY", Label: "a"},
+		{OrderID: "2", Instrument: "EURUSD", OrderCommand: "SELL", Label: "b"},
+		{OrderID: "3", Instrument: "EURUSD", OrderCommand: "BUY", Label: "manual"},
+	}})
+	if got := e.closeOnOpposite(cfg, SignalSell, 1); got != nil {
+		t.Fatalf("disabled: closed %v, want none", got)
+	}
+	cfg.params = Params{"exitOnOpposite": 1}
+	if got := e.closeOnOpposite(cfg, SignalSell, 1); len(got) != 1 || got[0] != "1" {
+		t.Fatalf("sell signal: closed %v, want only the run's long 1", got)
+	}
+	if got := e.closeOnOpposite(cfg, SignalSell, 2); len(got) != 0 || len(sink.closes) != 1 {
+		t.Fatalf("repeat signal: closed %v (sink %v), want the close sent once", got, sink.closes)
+	}
+	if got := e.opposingPositions(cfg, SignalSell); len(got) != 1 || got[0].OrderID != "3" {
+		t.Fatalf("opposing after close: got %+v, want only the manual long", got)
+	}
+}
+
+func TestClosedPositionNeedsTwoSnapshotsWithoutIt(t *testing.T) {
+	sm := state.NewStateManager()
+	e := NewEngine(sm, nil, nil)
+	cfg := &runConfig{strategy: &countingStrategy{}, labels: map[string]struct{}{"a": {}}, openPositions: make(map[string]state.Position),
+		firstSeen: make(map[string]time.Time), missingSince: make(map[string]int64)}
+	pos := state.Position{OrderID: "1", Label: "a", OrderCommand: "BUY", PnL: -5}
+	snapshot := func(producedAt int64, ps ...state.Position) {
+		sm.UpdateAccountInfo(state.AccountInfo{ProducedAt: producedAt, Positions: ps})
+		e.trackClosedPositions(cfg)
+	}
+
+	snapshot(1, pos)
+	snapshot(2) // partial report
+	snapshot(2) // same snapshot checked again
+	snapshot(3, pos)
+	if cfg.consecutiveLosses != 0 || len(cfg.openPositions) != 1 {
+		t.Fatalf("losses %d, open %v: a position missing from one snapshot should stay open", cfg.consecutiveLosses, cfg.openPositions)
+	}
+	snapshot(4)
+	snapshot(5)
+	if cfg.consecutiveLosses != 1 || len(cfg.openPositions) != 0 {
+		t.Fatalf("losses %d, open %v: want the close recorded after two snapshots without it", cfg.consecutiveLosses, cfg.openPositions)
+	}
+}
+
+// countingStrategy reports each evaluation on calls and never signals.
+type countingStrategy struct{ calls chan int }
+
+func (s *countingStrategy) Key() string { return "COUNTING" }
+
+func (s *countingStrategy) Evaluate(bars []state.HistoricalBar) Signal {
+	s.calls <- len(bars)
+	return SignalNone
+}
+
+// awaitEvaluation advances the fake clock until the engine evaluates or the deadline passes.
+func awaitEvaluation(fc *clock.FakeClock, calls chan int) bool {
+	deadline := time.After(time.Second)
+	for {
+		fc.Advance(time.Second)
+		select {
+		case <-calls:
+			return true
+		case <-deadline:
+			return false
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestEngineEvaluatesEachLiveBar(t *testing.T) {
+	sm := state.NewStateManager()
+	fc := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	e := NewEngine(sm, nil, nil)
+	e.SetClock(fc)
+	st := &countingStrategy{calls: make(chan int, 16)}
+
+	sm.UpdateLiveBar(state.Bar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 60_000})
+	e.StartStrategy("EURUSD", "ONE_MIN", st, 1, 1)
+	defer e.StopStrategy("EURUSD", "ONE_MIN")
+	if !awaitEvaluation(fc, st.calls) {
+		t.Fatal("first live bar not evaluated")
+	}
+
+	// Previously every merged live bar had Sequence 1, so this bar looked unchanged and was skipped
+	sm.UpdateLiveBar(state.Bar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 120_000})
+	if !awaitEvaluation(fc, st.calls) {
+		t.Fatal("second live bar not evaluated")
+	}
+}
+
+func TestEngineEvaluatesBarOnceWhenHistoryReplacesLiveBar(t *testing.T) {
+	sm := state.NewStateManager()
+	fc := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	e := NewEngine(sm, nil, nil)
+	e.SetClock(fc)
+	st := &countingStrategy{calls: make(chan int, 16)}
+
+	sm.UpdateLiveBar(state.Bar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 60_000})
+	e.StartStrategy("EURUSD", "ONE_MIN", st, 1, 1)
+	defer e.StopStrategy("EURUSD", "ONE_MIN")
+	if !awaitEvaluation(fc, st.calls) {
+		t.Fatal("live bar not evaluated")
+	}
+
+	// The historical response for the same bar renumbers it but is not a new bar
+	sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 60_000, Sequence: 7})
+	if awaitEvaluation(fc, st.calls) {
+		t.Fatal("historical bar with the live bar's end was evaluated again")
+	}
+}
+
+// warmableStrategy records the bars it was warmed with and reports each evaluation on calls.
+type warmableStrategy struct {
+	warmed []state.HistoricalBar
+	calls  chan int
+}
+
+func (s *warmableStrategy) Key() string                       { return "WARMABLE" }
+func (s *warmableStrategy) Warmup(bars []state.HistoricalBar) { s.warmed = append(s.warmed, bars...) }
+func (s *warmableStrategy) Evaluate(bars []state.HistoricalBar) Signal {
+	s.calls <- len(bars)
+	return SignalNone
+}
+
+func TestStartWarmsStatefulStrategyWithHistory(t *testing.T) {
+	sm := state.NewStateManager()
+	fc := clock.NewFake(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	sink := &recordingSink{orders: make(chan amqp.TradeCommand, 1)}
+	e := NewEngine(sm, sink, nil)
+	e.SetClock(fc)
+	for i := 1; i <= 5; i++ {
+		sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: int64(i) * 60_000, Sequence: 6 - i})
+	}
+	st := &warmableStrategy{calls: make(chan int, 16)}
+	e.StartStrategy("EURUSD", "ONE_MIN", st, 1, 1)
+	defer e.StopStrategy("EURUSD", "ONE_MIN")
+
+	if len(st.warmed) != 4 || st.warmed[0].BarEndTimestamp != 240_000 || st.warmed[3].BarEndTimestamp != 60_000 {
+		t.Fatalf("warmed with %d bars (%+v), want the 4 bars before the newest, newest-first", len(st.warmed), st.warmed)
+	}
+	if !awaitEvaluation(fc, st.calls) {
+		t.Fatal("newest bar not evaluated live after warmup")
+	}
+	if len(sink.orders) != 0 {
+		t.Fatal("warmup must not place orders")
+	}
+
+	// Strategies without Warmup start as before
+	e.StartStrategy("EURUSD", "FIVE_MINS", &countingStrategy{calls: make(chan int, 16)}, 1, 1)
+	e.StopStrategy("EURUSD", "FIVE_MINS")
+}
+
+func TestFormingBarAggregatesTicksAfterCompletedBar(t *testing.T) {
+	completed := state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: 1000}
+	ticks := []state.Tick{
+		{Timestamp: 900, Bid: 1.0, Ask: 1.1},
+		{Timestamp: 1100, Bid: 1.2, Ask: 1.3, BidVol: 1, AskVol: 2},
+		{Timestamp: 1200, Bid: 1.5, Ask: 1.6, BidVol: 1, AskVol: 2},
+		{Timestamp: 1300, Bid: 1.1, Ask: 1.2, BidVol: 1, AskVol: 2},
+	}
+	b, in, ok := formingBar(completed, ticks)
+	if !ok || len(in) != 3 {
+		t.Fatalf("got ok=%v with %d ticks, want 3", ok, len(in))
+	}
+	if b.Bid.O != 1.2 || b.Bid.H != 1.5 || b.Bid.L != 1.1 || b.Bid.C != 1.1 || b.Bid.V != 3 {
+		t.Fatalf("bid OHLCV: got %+v", b.Bid)
+	}
+	if b.BarStartTimestamp != 1000 || b.BarEndTimestamp != 1300 {
+		t.Fatalf("span: got %d-%d, want 1000-1300", b.BarStartTimestamp, b.BarEndTimestamp)
+	}
+	if _, _, ok := formingBar(completed, ticks[:1]); ok {
+		t.Fatal("no ticks after the completed bar should not form a bar")
+	}
+}
+
+// tickProbe buys on every forming bar and reports the number of forming-bar ticks of each evaluation.
+type tickProbe struct{ calls chan int }
+
+func (s *tickProbe) Key() string                                { return "TICK_PROBE" }
+func (s *tickProbe) Evaluate(bars []state.HistoricalBar) Signal { return SignalNone }
+func (s *tickProbe) EvaluateTick(bars []state.HistoricalBar, forming state.HistoricalBar, ticks []state.Tick) Signal {
+	s.calls <- len(ticks)
+	return SignalBuy
+}
+
+func TestTickModeActsOncePerFormingBar(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	ms := start.UnixMilli()
+	fc := clock.NewFake(start)
+	sm := state.NewStateManager()
+	sink := &recordingSink{orders: make(chan amqp.TradeCommand, 4)}
+	e := NewEngine(sm, sink, nil)
+	e.SetClock(fc)
+	e.SetEvalJitter(0)
+	bar := func(seq int, end int64) {
+		sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: end, Sequence: seq,
+			Bid: state.OHLCV{C: 1.1000}, Ask: state.OHLCV{C: 1.1002}})
+	}
+	tick := func(ts int64) {
+		sm.UpdateTick(state.Tick{Instrument: "EURUSD", Timestamp: ts, Bid: 1.1000, Ask: 1.1002})
+	}
+	st := &tickProbe{calls: make(chan int, 16)}
+	bar(1, ms)
+	tick(ms + 1000)
+	e.StartStrategyWithParams("EURUSD", "ONE_MIN", st, 0.1, 1, Params{"evalOnTick": 1, "maxTickAgeMs": 0})
+	defer e.StopStrategy("EURUSD", "ONE_MIN")
+
+	steps := []struct {
+		name   string
+		feed   func()
+		ticks  int // forming-bar ticks passed to EvaluateTick
+		orders int // orders placed so far
+	}{
+		{"first tick of the forming bar", func() {}, 1, 1},
+		{"next tick of the same forming bar", func() { tick(ms + 2000) }, 2, 1},
+		{"tick after the next bar completed", func() { bar(2, ms+60_000); tick(ms + 61_000) }, 1, 2},
+	}
+	placed := 0
+	for _, s := range steps {
+		s.feed()
+		deadline := time.After(time.Second)
+	eval:
+		for {
+			fc.Advance(time.Second)
+			select {
+			case n := <-st.calls:
+				if n != s.ticks {
+					t.Fatalf("%s: evaluated with %d forming-bar ticks, want %d", s.name, n, s.ticks)
+				}
+				break eval
+			case <-deadline:
+				t.Fatalf("%s: not evaluated", s.name)
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+		// The loop places an order before its next evaluation, so wait for the one expected here
+		for placed < s.orders {
+			select {
+			case <-sink.orders:
+				placed++
+			case <-time.After(time.Second):
+				t.Fatalf("%s: %d orders placed, want %d", s.name, placed, s.orders)
+			}
+		}
+	}
+	// Without a new tick there is nothing to evaluate
+	fc.Advance(time.Second)
+	fc.Advance(time.Second)
+	select {
+	case <-st.calls:
+		t.Fatal("evaluated again without a new tick")
+	case cmd := <-sink.orders:
+		t.Fatalf("extra order %+v, want one per forming bar", cmd)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestTickModeRefusedWithoutTickSupport(t *testing.T) {
+	e := NewEngine(state.NewStateManager(), nil, nil)
+	if err := CheckTickMode(fixedStrategy{SignalBuy}, Params{"evalOnTick": 1}); !errors.Is(err, ErrTickModeUnsupported) {
+		t.Fatalf("bar-close strategy: got %v, want ErrTickModeUnsupported", err)
+	}
+	if err := CheckTickMode(&DonchianBreakoutStrategy{}, Params{"evalOnTick": 1}); err != nil {
+		t.Fatalf("BREAKOUT_DC supports tick mode: got %v", err)
+	}
+
+	e.StartStrategyWithParams("EURUSD", "ONE_MIN", fixedStrategy{SignalBuy}, 0.1, 1, Params{"evalOnTick": 1})
+	if _, running := e.GetParams("EURUSD", "ONE_MIN"); running {
+		e.StopStrategy("EURUSD", "ONE_MIN")
+		t.Fatal("run started with evalOnTick on a strategy without EvaluateTick")
+	}
+
+	e.StartStrategyWithParams("EURUSD", "ONE_MIN", fixedStrategy{SignalBuy}, 0.1, 1, nil)
+	defer e.StopStrategy("EURUSD", "ONE_MIN")
+	if _, _, err := e.UpdateParams("EURUSD", "ONE_MIN", Params{"evalOnTick": 1}); !errors.Is(err, ErrTickModeUnsupported) {
+		t.Fatalf("UpdateParams: got %v, want ErrTickModeUnsupported", err)
+	}
+	if p, _ := e.GetParams("EURUSD", "ONE_MIN"); p["evalOnTick"] != 0 {
+		t.Fatalf("params %v after a refused update, want evalOnTick unset", p)
+	}
+}
+
+func TestConfirmSignalRequiresConsecutiveSameSignals(t *testing.T) {
+	e := &Engine{}
+	cfg := &runConfig{strategy: &countingStrategy{}, params: Params{"signalConfirmBars": 3}}
+	steps := []struct {
+		in, want Signal
+	}{
+		{SignalBuy, SignalNone},
+		{SignalNone, SignalNone}, // NONE drops the pending BUY
+		{SignalNone, SignalNone},
+		{SignalBuy, SignalNone},
+		{SignalSell, SignalNone}, // flap restarts confirmation
+		{SignalSell, SignalNone},
+		{SignalSell, SignalSell},
+		{SignalNone, SignalNone}, // nothing pending after acting
+		{SignalBuy, SignalNone},
+		{SignalBuy, SignalNone},
+		{SignalBuy, SignalBuy},
+	}
+	for i, s := range steps {
+		if got := e.confirmSignal(cfg, s.in, i); got != s.want {
+			t.Fatalf("step %d: got %s, want %s", i, got, s.want)
+		}
+	}
+	cfg.params = Params{}
+	if got := e.confirmSignal(cfg, SignalBuy, 0); got != SignalBuy {
+		t.Fatalf("disabled: got %s, want BUY", got)
+	}
+}
+
+func TestConfirmSignalLetsEventStrategiesTrade(t *testing.T) {
+	e := &Engine{}
+	cfg := &runConfig{strategy: &RsiCrossStrategy{}, params: Params{"signalConfirmBars": 3}}
+	// Fast RSI per bar, oldest first, against a slow RSI of 50: a cross fires on one bar only
+	fast := []float64{45, 55, 58, 60, 62, 40, 42, 60, 61, 63}
+	want := []Signal{SignalNone, // cross up, BUY pending
+		SignalNone, SignalBuy, // no opposite signal within 3 bars
+		SignalNone,             // nothing pending after acting
+		SignalNone, SignalNone, // cross down, SELL pending
+		SignalNone, // cross up within 3 bars: flap, BUY pending
+		SignalNone, SignalBuy,
+	}
+	var bars []state.HistoricalBar // newest first
+	for i, f := range fast {
+		bars = append([]state.HistoricalBar{{BarEndTimestamp: int64(i + 1), BidRsi: state.Rsi{Fast: f, Slow: 50}}}, bars...)
+		if i == 0 {
+			continue
+		}
+		sig := cfg.strategy.Evaluate(bars)
+		if got := e.confirmSignal(cfg, sig, i); got != want[i-1] {
+			t.Fatalf("bar %d (%s): got %s, want %s", i, sig, got, want[i-1])
+		}
+	}
+}
+
+func TestMissingIndicators(t *testing.T) {
+	upper, lower := 1.2, 1.1
+	bar := state.HistoricalBar{BidBollinger: state.Bollinger{Upper: &upper, Lower: &lower}}
+	sq := &SqueezeStrategy{}
+	if got := missingIndicators(sq, bar); len(got) != 1 || got[0] != "bid_keltner" {
+		t.Fatalf("squeeze: got %v, want [bid_keltner]", got)
+	}
+	bar.BidKeltner = state.Keltner{Upper: 1.3, Lower: 1.0}
+	if got := missingIndicators(sq, bar); len(got) != 0 {
+		t.Fatalf("squeeze with bands: got %v, want none", got)
+	}
+	if got := missingIndicators(&DonchianBreakoutStrategy{}, state.HistoricalBar{}); len(got) != 1 {
+		t.Fatalf("donchian without len: got %v, want [bid_donchian]", got)
+	}
+	if got := missingIndicators(&DonchianBreakoutStrategy{len: 20}, state.HistoricalBar{}); len(got) != 0 {
+		t.Fatalf("donchian with len: got %v, want none", got)
+	}
+	if got := missingIndicators(&RsiCrossStrategy{}, state.HistoricalBar{}); len(got) != 0 {
+		t.Fatalf("rsi cross computes locally: got %v, want none", got)
+	}
+}
+
+func TestConfidenceScalesSizeOnlyWhenEnabled(t *testing.T) {
+	bar := func(fast, slow float64) state.HistoricalBar {
+		return state.HistoricalBar{BidRsi: state.Rsi{Fast: fast, Slow: slow}}
+	}
+	// Newest first: fast RSI crosses above slow by 4 points
+	sig, conf := evaluate(&RsiCrossStrategy{}, []state.HistoricalBar{bar(54, 50), bar(48, 50)})
+	if sig != SignalBuy || conf != 0.4 {
+		t.Fatalf("rsi cross: got %s/%v, want BUY/0.4", sig, conf)
+	}
+	if sig, conf := evaluate(&SupertrendStrategy{atrLen: 10, mult: 3}, nil); sig != SignalNone || conf != 1 {
+		t.Fatalf("plain strategy adapter: got %s/%v, want NONE/1", sig, conf)
+	}
+
+	e := &Engine{}
+	cfg := &runConfig{}
+	if got := e.confidenceFactor(cfg, 0.4); got != 1 {
+		t.Fatalf("sizing off: got %v, want 1", got)
+	}
+	cfg.params = Params{"confidenceSizing": 1}
+	if got := e.confidenceFactor(cfg, 0.4); got != 0.4 {
+		t.Fatalf("sizing on: got %v, want 0.4", got)
+	}
+}
+
+func TestStaleTickBlocksTrading(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	sm := state.NewStateManager()
+	fc := clock.NewFake(start)
+	e := NewEngine(sm, nil, nil)
+	e.SetClock(fc)
+	cfg := &runConfig{instrument: "EURUSD"}
+
+	if stale, age, _ := e.staleTick(cfg); !stale || age != -1 {
+		t.Fatalf("no ticks: got stale=%v age=%d, want stale with age -1", stale, age)
+	}
+	sm.UpdateTick(state.Tick{Instrument: "EURUSD", Timestamp: start.UnixMilli(), Bid: 1.1, Ask: 1.1002})
+	fc.Advance(defaultMaxTickAgeMs * time.Millisecond)
+	if stale, _, _ := e.staleTick(cfg); stale {
+		t.Fatal("tick exactly at the default max age should be tradable")
+	}
+	fc.Advance(time.Millisecond)
+	if stale, age, max := e.staleTick(cfg); !stale || age != defaultMaxTickAgeMs+1 || max != defaultMaxTickAgeMs {
+		t.Fatalf("got stale=%v age=%d max=%d", stale, age, max)
+	}
+	cfg.params = Params{"maxTickAgeMs": 0}
+	if stale, _, _ := e.staleTick(cfg); stale {
+		t.Fatal("maxTickAgeMs 0 should disable the check")
+	}
+}
+
+// fixedStrategy always returns sig.
+type fixedStrategy struct{ sig Signal }
+
+func (s fixedStrategy) Key() string                                { return "FIXED" }
+func (s fixedStrategy) Evaluate(bars []state.HistoricalBar) Signal { return s.sig }
+
+// recordingSink collects submitted orders and close requests.
+type recordingSink struct {
+	orders chan amqp.TradeCommand
+	closes []string
+	fail   error // returned by PublishSubmitOrder after recording the order
+}
+
+func (s *recordingSink) PublishSubmitOrder(cmd amqp.TradeCommand) error {
+	s.orders <- cmd
+	return s.fail
+}
+
+func (s *recordingSink) PublishModifyOrder(orderID string, sl, tp float64) error { return nil }
+
+func (s *recordingSink) PublishCloseOrder(orderID string) error {
+	s.closes = append(s.closes, orderID)
+	return nil
+}
+
+func TestEngineOrderConstruction(t *testing.T) {
+	cases := []struct {
+		name       string
+		instrument string
+		sig        Signal
+		bid, ask   float64
+		atr        float64
+		params     Params
+		wantSL     float64
+		wantTP     float64
+		wantSlip   float64
+	}{
+		// ATR 10 pips at atrMult 1.5: 15-pip stops around mid 1.1001
+		{"buy", "EURUSD", SignalBuy, 1.1000, 1.1002, 0.0010, nil, 1.0986, 1.1016, state.DefaultSlippagePips},
+		{"sell", "EURUSD", SignalSell, 1.1000, 1.1002, 0.0010, nil, 1.1016, 1.0986, state.DefaultSlippagePips},
+		// JPY pip is 0.01: ATR 15 pips, SL 1x and TP 2x ATR around mid 150.005
+		{"sell jpy", "USDJPY", SignalSell, 150.00, 150.01, 0.15, Params{"slAtrMult": 1, "tpAtrMult": 2}, 150.155, 149.705, state.DefaultSlippage("USDJPY")},
+		{"buy jpy", "USDJPY", SignalBuy, 150.00, 150.01, 0.15, Params{"slAtrMult": 1, "tpAtrMult": 2}, 149.855, 150.305, state.DefaultSlippage("USDJPY")},
+		// Without ATR the stop falls back to 10 pips and TP keeps the tp/sl ratio
+		{"no atr", "EURUSD", SignalBuy, 1.1000, 1.1002, 0, Params{"slAtrMult": 1, "tpAtrMult": 3}, 1.0991, 1.1031, state.DefaultSlippagePips},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+			fc := clock.NewFake(start)
+			sm := state.NewStateManager()
+			sink := &recordingSink{orders: make(chan amqp.TradeCommand, 1)}
+			e := NewEngine(sm, sink, nil)
+			e.SetClock(fc)
+			sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: tc.instrument, Period: "ONE_MIN", BarEndTimestamp: start.UnixMilli(), Sequence: 1,
+				Bid: state.OHLCV{C: tc.bid}, Ask: state.OHLCV{C: tc.ask}, BidAtr: tc.atr})
+			sm.UpdateTick(state.Tick{Instrument: tc.instrument, Timestamp: start.UnixMilli(), Bid: tc.bid, Ask: tc.ask})
+
+			e.StartStrategyWithParams(tc.instrument, "ONE_MIN", fixedStrategy{tc.sig}, 0.25, 1.5, tc.params)
+			defer e.StopStrategy(tc.instrument, "ONE_MIN")
+			var cmd amqp.TradeCommand
+			deadline := time.After(time.Second)
+		wait:
+			for {
+				fc.Advance(time.Second)
+				select {
+				case cmd = <-sink.orders:
+					break wait
+				case <-deadline:
+					t.Fatal("no order submitted")
+				case <-time.After(5 * time.Millisecond):
+				}
+			}
+
+			if cmd.Instrument != tc.instrument || cmd.OrderCmd != string(tc.sig) || cmd.Amount != 0.25 || cmd.Price != 0 || cmd.Slippage != tc.wantSlip {
+				t.Fatalf("got %+v, want a %s market order for 0.25 %s", cmd, tc.sig, tc.instrument)
+			}
+			if math.Abs(cmd.StopLossPrice-tc.wantSL) > 1e-9 || math.Abs(cmd.TakeProfitPrice-tc.wantTP) > 1e-9 {
+				t.Fatalf("sl/tp = %.5f/%.5f, want %.5f/%.5f", cmd.StopLossPrice, cmd.TakeProfitPrice, tc.wantSL, tc.wantTP)
+			}
+		})
+	}
+}
+
+func TestFailedPublishRecordsNoAction(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	fc := clock.NewFake(start)
+	sm := state.NewStateManager()
+	sink := &recordingSink{orders: make(chan amqp.TradeCommand, 1), fail: errors.New("channel closed")}
+	e := NewEngine(sm, sink, nil)
+	e.SetClock(fc)
+	sm.UpdateHistoricalBar(state.HistoricalBar{Instrument: "EURUSD", Period: "ONE_MIN", BarEndTimestamp: start.UnixMilli(), Sequence: 1,
+		Bid: state.OHLCV{C: 1.1000}, Ask: state.OHLCV{C: 1.1002}, BidAtr: 0.0010})
+	sm.UpdateTick(state.Tick{Instrument: "EURUSD", Timestamp: start.UnixMilli(), Bid: 1.1000, Ask: 1.1002})
+
+	e.StartStrategy("EURUSD", "ONE_MIN", fixedStrategy{SignalBuy}, 0.25, 1.5)
+	deadline := time.After(time.Second)
+wait:
+	for {
+		fc.Advance(time.Second)
+		select {
+		case <-sink.orders:
+			break wait
+		case <-deadline:
+			t.Fatal("no order submitted")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	e.mu.Lock()
+	cfg := e.runs[e.key("EURUSD", "ONE_MIN")]
+	e.mu.Unlock()
+	e.StopStrategy("EURUSD", "ONE_MIN")
+	if len(cfg.labels) != 0 || cfg.lastSignal != "" || len(cfg.lastActionBar) != 0 {
+		t.Fatalf("labels %v, last signal %q, last action bars %v; want nothing recorded for an unpublished order", cfg.labels, cfg.lastSignal, cfg.lastActionBar)
+	}
+}
+
+func TestEvalPhaseSpreadsRuns(t *testing.T) {
+	e := NewEngine(state.NewStateManager(), nil, nil)
+	// Peak number of runs whose evaluation falls in the same 10 ms slot of the second
+	peak := func(runs int) int {
+		slots := make(map[time.Duration]int)
+		n := 0
+		for i := 0; i < runs; i++ {
+			slot := e.evalPhase() / (10 * time.Millisecond)
+			slots[slot]++
+			n = max(n, slots[slot])
+		}
+		return n
+	}
+
+	e.SetEvalJitter(0)
+	if got := peak(100); got != 100 {
+		t.Fatalf("no jitter: peak %d, want all 100 runs aligned", got)
+	}
+	e.SetEvalJitter(time.Hour)
+	if e.evalJitter != evalInterval {
+		t.Fatalf("jitter %v, want capped at %v", e.evalJitter, evalInterval)
+	}
+	if got := peak(100); got > 10 {
+		t.Fatalf("full jitter: peak %d of 100 runs in one slot, want them spread", got)
+	}
+}
+
+func TestTooSoonSameDirection(t *testing.T) {
+	e := &Engine{}
+	cfg := &runConfig{params: Params{"minBarsBetween": 3}, lastActionBar: map[Signal]int64{SignalBuy: 120_000}}
+	bars := func(newestEnd int64) []state.HistoricalBar {
+		var out []state.HistoricalBar
+		for end := newestEnd; end > 0; end -= 60_000 {
+			out = append(out, state.HistoricalBar{BarEndTimestamp: end})
+		}
+		return out
+	}
+
+	if since, _, tooSoon := e.tooSoonSameDirection(cfg, SignalBuy, bars(240_000)); !tooSoon || since != 2 {
+		t.Fatalf("2 bars after the buy: got since=%d tooSoon=%v, want suppressed", since, tooSoon)
+	}
+	if _, _, tooSoon := e.tooSoonSameDirection(cfg, SignalBuy, bars(300_000)); tooSoon {
+		t.Fatal("3 bars after the buy should allow another buy")
+	}
+	if _, _, tooSoon := e.tooSoonSameDirection(cfg, SignalSell, bars(180_000)); tooSoon {
+		t.Fatal("the opposite direction should not be affected")
+	}
+	cfg.params = nil
+	if _, _, tooSoon := e.tooSoonSameDirection(cfg, SignalBuy, bars(180_000)); tooSoon {
+		t.Fatal("minBarsBetween unset should disable the guard")
+	}
+
+	// With a known period, bars that already left the buffer still count
+	cfg.period, cfg.params = "ONE_MIN", Params{"minBarsBetween": 5}
+	short := bars(480_000)[:2]
+	if since, _, tooSoon := e.tooSoonSameDirection(cfg, SignalBuy, short); tooSoon || since != 6 {
+		t.Fatalf("6 minutes after the buy on a 2-bar buffer: got since=%d tooSoon=%v, want allowed", since, tooSoon)
+	}
+	if since, _, tooSoon := e.tooSoonSameDirection(cfg, SignalBuy, bars(360_000)[:2]); !tooSoon || since != 4 {
+		t.Fatalf("4 minutes after the buy: got since=%d tooSoon=%v, want suppressed", since, tooSoon)
+	}
+}
@@ -2,35 +2,119 @@ package strategy
 
 import "go-trader/internal/state"
 
-// What: DEMA+RSI starter strategy.
+// What: DEMA+RSI starter strategy with an optional DEMA50 slope filter.
 // How: Generates BUY when DEMA25 crosses above DEMA50 and RSI Fast > 50; SELL on opposite cross and RSI Fast < 50.
-// Params: Uses the bars provided; no external params.
+//      When minSlope is set, the DEMA50 slope over the last slopeBars bars must be at least minSlope
+//      pips/bar in the signal direction, avoiding entries into a flattening trend.
+// Params:
+//  - minSlope (float): minimum DEMA50 slope in pips per bar. Disabled when 0.
+//  - slopeBars (int): lookback for the slope. Default 5.
 // Returns: SignalBuy, SignalSell, or SignalNone.
 
-type DemaRsiStrategy struct{}
+type DemaRsiStrategy struct {
+	minSlope  float64
+	slopeBars int
+}
+
+func init() {
+	DefaultRegistry.Register("DEMA_RSI", func() Strategy { return &DemaRsiStrategy{} })
+	DefaultRegistry.Describe(Info{
+		Key: "DEMA_RSI", Name: "DEMA + RSI", Aliases: []string{"DEMA+RSI", "DEMA"},
+		Description: "DEMA25/DEMA50 cross confirmed by fast RSI, with optional DEMA50 slope filter",
+		Params: []ParamSpec{
+			{Name: "minSlope", Type: "float", Default: 0, Min: bound(0), Description: "Minimum DEMA50 slope (pips/bar) in signal direction; 0 disables"},
+			{Name: "slopeBars", Type: "int", Default: 5, Min: bound(1), Description: "Bars used to measure DEMA50 slope"},
+		},
+	})
+}
+
+func (s *DemaRsiStrategy) Key() string { return "DEMA_RSI" }
+
+// EventSignals reports that signals fire on the crossing bar only (see EventStrategy).
+func (s *DemaRsiStrategy) EventSignals() bool { return true }
+
+// SetParams allows runtime configuration.
+func (s *DemaRsiStrategy) SetParams(p Params) {
+	if p == nil { return }
+	if v, ok := p["minSlope"]; ok && v >= 0 { s.minSlope = v }
+	if v, ok := p["slopeBars"]; ok && int(v) >= 1 { s.slopeBars = int(v) }
+}
+
+// MinBars is 3, plus the slope lookback when the slope filter is on.
+func (s *DemaRsiStrategy) MinBars() int {
+	if s.minSlope <= 0 {
+		return 3
+	}
+	k := s.slopeBars
+	if k < 1 { k = 5 }
+	if k+1 > 3 {
+		return k + 1
+	}
+	return 3
+}
 
-func (s DemaRsiStrategy) Key() string { return "DEMA_RSI" }
+// RequiredIndicators is empty: missing DEMA and RSI values are computed locally.
+func (s *DemaRsiStrategy) RequiredIndicators() []string { return nil }
 
-func (s DemaRsiStrategy) Evaluate(bars []state.HistoricalBar) Signal {
+func (s *DemaRsiStrategy) Evaluate(bars []state.HistoricalBar) Signal {
 	if len(bars) < 3 {
 		return SignalNone
 	}
 	// bars[0] is newest per StateManager; use two most recent closes for cross
 	b0 := bars[0]
 	b1 := bars[1]
-	// Use Bid side indicators when available
-	d25_0 := b0.BidDemas.Dema25
-	d50_0 := b0.BidDemas.Dema50
-	d25_1 := b1.BidDemas.Dema25
-	d50_1 := b1.BidDemas.Dema50
-	rsi0 := b0.BidRsi.Fast
+	// Use Bid side indicators when available, computing them locally during warm-up
+	d25_0 := demaOrLocal(b0.BidDemas.Dema25, bars, 25)
+	d50_0 := demaOrLocal(b0.BidDemas.Dema50, bars, 50)
+	d25_1 := demaOrLocal(b1.BidDemas.Dema25, bars[1:], 25)
+	d50_1 := demaOrLocal(b1.BidDemas.Dema50, bars[1:], 50)
+	rsi0 := rsiOrLocal(b0.BidRsi.Fast, bars, brokerRsiFastPeriod)
+	if d25_0 == 0 || d50_0 == 0 || d25_1 == 0 || d50_1 == 0 || rsi0 == 0 {
+		return SignalNone
+	}
 	// Cross up: d25 crosses above d50 and RSI confirms
 	if d25_1 <= d50_1 && d25_0 > d50_0 && rsi0 > 50 {
+		if !s.slopeOK(bars, 1) {
+			return SignalNone
+		}
 		return SignalBuy
 	}
 	// Cross down: d25 crosses below d50 and RSI confirms
 	if d25_1 >= d50_1 && d25_0 < d50_0 && rsi0 < 50 {
+		if !s.slopeOK(bars, -1) {
+			return SignalNone
+		}
 		return SignalSell
 	}
 	return SignalNone
 }
+
+// slopeOK applies the minSlope filter in the given direction (+1 up, -1 down).
+// The filter is skipped when disabled or when there is not enough DEMA history.
+func (s *DemaRsiStrategy) slopeOK(bars []state.HistoricalBar, dir float64) bool {
+	if s.minSlope <= 0 {
+		return true
+	}
+	k := s.slopeBars
+	if k < 1 { k = 5 }
+	slope, ok := dema50Slope(bars, k)
+	if !ok {
+		return true
+	}
+	return slope*dir >= s.minSlope
+}
+
+// dema50Slope returns the average per-bar change of Bid DEMA50 over the last k bars, in pips.
+// bars[0] is newest. Returns false if there are fewer than k+1 bars or any DEMA50 is missing.
+func dema50Slope(bars []state.HistoricalBar, k int) (float64, bool) {
+	if k < 1 || len(bars) <= k {
+		return 0, false
+	}
+	for i := 0; i <= k; i++ {
+		if bars[i].BidDemas.Dema50 == 0 {
+			return 0, false
+		}
+	}
+	pip := getPipSize(bars[0].Instrument)
+	return (bars[0].BidDemas.Dema50 - bars[k].BidDemas.Dema50) / pip / float64(k), true
+}
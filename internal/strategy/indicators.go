@@ -0,0 +1,168 @@
+package strategy
+
+import "go-trader/internal/state"
+
+// Locally computed indicators used when broker-provided values are missing.
+// What: JForex leaves indicator fields at zero until it has warmed up, and live-merged bars always
+//       carry zeroed indicators. Strategies fall back to these so they keep working in that window.
+// How: All functions read Bid prices from bars ordered newest-first (as StateManager stores them) and
+//      return the value for bars[0]. The periods match the JForex requester so the fallback lines up
+//      with the broker values once they arrive.
+// Returns: (value, true), or (0, false) when there are not enough bars.
+
+const (
+	// Periods used by the JForex HistoricalBarRequester
+	brokerRsiFastPeriod        = 7
+	brokerRsiSlowPeriod        = 21
+	brokerSupertrendPeriod     = 12
+	brokerSupertrendMultiplier = 3.0
+)
+
+// closesOldestFirst returns up to the last n Bid closes in chronological order.
+func closesOldestFirst(bars []state.HistoricalBar, n int) []float64 {
+	if n > len(bars) {
+		n = len(bars)
+	}
+	out := make([]float64, n)
+	for i := 0; i < n; i++ {
+		out[n-1-i] = bars[i].Bid.C
+	}
+	return out
+}
+
+// SMA returns the simple moving average of the last n Bid closes.
+func SMA(bars []state.HistoricalBar, n int) (float64, bool) {
+	if n < 1 || len(bars) < n {
+		return 0, false
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		sum += bars[i].Bid.C
+	}
+	return sum / float64(n), true
+}
+
+// emaSeries returns the EMA of values (oldest first), seeded with the SMA of the first n values.
+// The result has len(values)-n+1 entries, the last being the newest.
+func emaSeries(values []float64, n int) []float64 {
+	if n < 1 || len(values) < n {
+		return nil
+	}
+	var seed float64
+	for _, v := range values[:n] {
+		seed += v
+	}
+	k := 2.0 / float64(n+1)
+	out := make([]float64, 0, len(values)-n+1)
+	ema := seed / float64(n)
+	out = append(out, ema)
+	for _, v := range values[n:] {
+		ema += (v - ema) * k
+		out = append(out, ema)
+	}
+	return out
+}
+
+// EMA returns the exponential moving average of Bid closes over all available bars.
+// Needs at least n bars; more history makes it converge towards the broker value.
+func EMA(bars []state.HistoricalBar, n int) (float64, bool) {
+	s := emaSeries(closesOldestFirst(bars, len(bars)), n)
+	if len(s) == 0 {
+		return 0, false
+	}
+	return s[len(s)-1], true
+}
+
+// DEMA returns the double EMA (2*EMA - EMA(EMA)) of Bid closes. Needs at least 2n-1 bars.
+func DEMA(bars []state.HistoricalBar, n int) (float64, bool) {
+	e1 := emaSeries(closesOldestFirst(bars, len(bars)), n)
+	e2 := emaSeries(e1, n)
+	if len(e2) == 0 {
+		return 0, false
+	}
+	return 2*e1[len(e1)-1] - e2[len(e2)-1], true
+}
+
+// RSI returns Wilder's relative strength index of Bid closes. Needs at least n+1 bars.
+func RSI(bars []state.HistoricalBar, n int) (float64, bool) {
+	if n < 1 || len(bars) < n+1 {
+		return 0, false
+	}
+	c := closesOldestFirst(bars, len(bars))
+	var gain, loss float64
+	for i := 1; i <= n; i++ {
+		if d := c[i] - c[i-1]; d > 0 {
+			gain += d
+		} else {
+			loss -= d
+		}
+	}
+	gain /= float64(n)
+	loss /= float64(n)
+	for i := n + 1; i < len(c); i++ {
+		d := c[i] - c[i-1]
+		g, l := 0.0, 0.0
+		if d > 0 {
+			g = d
+		} else {
+			l = -d
+		}
+		gain = (gain*float64(n-1) + g) / float64(n)
+		loss = (loss*float64(n-1) + l) / float64(n)
+	}
+	if loss == 0 {
+		if gain == 0 {
+			return 50, true
+		}
+		return 100, true
+	}
+	return 100 - 100/(1+gain/loss), true
+}
+
+// ATR returns Wilder's average true range of Bid prices. Needs at least n+1 bars.
+func ATR(bars []state.HistoricalBar, n int) (float64, bool) {
+	if n < 1 || len(bars) < n+1 {
+		return 0, false
+	}
+	// True ranges oldest first; the oldest bar only serves as previous close
+	m := len(bars) - 1
+	tr := make([]float64, m)
+	for i := 0; i < m; i++ {
+		b, pc := bars[i].Bid, bars[i+1].Bid.C
+		r := b.H - b.L
+		if v := abs(b.H - pc); v > r {
+			r = v
+		}
+		if v := abs(b.L - pc); v > r {
+			r = v
+		}
+		tr[m-1-i] = r
+	}
+	var atr float64
+	for _, v := range tr[:n] {
+		atr += v
+	}
+	atr /= float64(n)
+	for _, v := range tr[n:] {
+		atr = (atr*float64(n-1) + v) / float64(n)
+	}
+	return atr, true
+}
+
+// rsiOrLocal returns the broker RSI when set, otherwise the locally computed one.
+func rsiOrLocal(broker float64, bars []state.HistoricalBar, n int) float64 {
+	if broker != 0 {
+		return broker
+	}
+	v, _ := RSI(bars, n)
+	return v
+}
+
+// demaOrLocal returns the broker DEMA when set, otherwise the locally computed one.
+func demaOrLocal(broker float64, bars []state.HistoricalBar, n int) float64 {
+	if broker != 0 {
+		return broker
+	}
+	v, _ := DEMA(bars, n)
+	return v
+}
@@ -0,0 +1,111 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+
+	"go-trader/internal/state"
+)
+
+// barsFromCloses builds newest-first bars from chronological closes.
+func barsFromCloses(closes []float64) []state.HistoricalBar {
+	bars := make([]state.HistoricalBar, len(closes))
+	for i, c := range closes {
+		bars[len(closes)-1-i] = state.HistoricalBar{Instrument: "EURUSD", Bid: state.OHLCV{O: c, H: c, L: c, C: c}}
+	}
+	return bars
+}
+
+func approx(t *testing.T, name string, got, want, tol float64) {
+	t.Helper()
+	if math.Abs(got-want) > tol {
+		t.Errorf("%s = %.4f, want %.4f", name, got, want)
+	}
+}
+
+func TestSMAAndEMA(t *testing.T) {
+	bars := barsFromCloses([]float64{1, 2, 3, 4, 5})
+	if v, ok := SMA(bars, 3); !ok || v != 4 {
+		t.Errorf("SMA(3) = %v, %v; want 4", v, ok)
+	}
+	// seed SMA(1,2,3)=2, k=0.5: 4 -> 3, 5 -> 4
+	if v, ok := EMA(bars, 3); !ok || v != 4 {
+		t.Errorf("EMA(3) = %v, %v; want 4", v, ok)
+	}
+	// DEMA tracks a linear series exactly
+	if v, ok := DEMA(bars, 2); !ok {
+		t.Error("DEMA(2) not ok")
+	} else {
+		approx(t, "DEMA(2)", v, 5, 1e-9)
+	}
+	if _, ok := EMA(bars, 6); ok {
+		t.Error("EMA with too few bars should not be ok")
+	}
+	if _, ok := DEMA(bars, 4); ok {
+		t.Error("DEMA needs 2n-1 bars")
+	}
+}
+
+func TestRSIWilderFixture(t *testing.T) {
+	// Classic Wilder RSI(14) worked example (70.53/66.32 in the usual table, which rounds the averages)
+	closes := []float64{44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+		45.89, 46.03, 45.61, 46.28, 46.28}
+	v, ok := RSI(barsFromCloses(closes), 14)
+	if !ok {
+		t.Fatal("RSI not ok")
+	}
+	approx(t, "RSI first", v, 70.46, 0.01)
+
+	v, _ = RSI(barsFromCloses(append(closes, 46.00)), 14)
+	approx(t, "RSI second", v, 66.25, 0.01)
+
+	if _, ok := RSI(barsFromCloses(closes[:14]), 14); ok {
+		t.Error("RSI(14) needs 15 closes")
+	}
+}
+
+func TestATRWilder(t *testing.T) {
+	// Chronological bars: constant 10-pip ranges, then one 40-pip bar
+	var bars []state.HistoricalBar
+	for i := 0; i < 5; i++ {
+		bars = append([]state.HistoricalBar{{Bid: state.OHLCV{H: 1.0010, L: 1.0000, C: 1.0005}}}, bars...)
+	}
+	v, ok := ATR(bars, 3)
+	if !ok {
+		t.Fatal("ATR not ok")
+	}
+	approx(t, "ATR flat", v, 0.0010, 1e-12)
+
+	bars = append([]state.HistoricalBar{{Bid: state.OHLCV{H: 1.0040, L: 1.0000, C: 1.0020}}}, bars...)
+	v, _ = ATR(bars, 3)
+	approx(t, "ATR after spike", v, (0.0010*2+0.0040)/3, 1e-12)
+}
+
+func TestRsiCrossFallsBackToLocalRSI(t *testing.T) {
+	// Oscillating closes without broker indicators must give the same signals as bars whose
+	// indicator fields were filled with the same values.
+	var closes []float64
+	for i := 0; i < 80; i++ {
+		closes = append(closes, 1.2+0.002*math.Sin(float64(i)/4)+0.0007*math.Sin(float64(i)*1.3))
+	}
+	s := &RsiCrossStrategy{}
+	signals := 0
+	for n := 25; n <= len(closes); n++ {
+		bare := barsFromCloses(closes[:n])
+		filled := barsFromCloses(closes[:n])
+		for i := 0; i < 2; i++ {
+			filled[i].BidRsi.Fast, _ = RSI(filled[i:], brokerRsiFastPeriod)
+			filled[i].BidRsi.Slow, _ = RSI(filled[i:], brokerRsiSlowPeriod)
+		}
+		got, want := s.Evaluate(bare), s.Evaluate(filled)
+		if got != want {
+			t.Fatalf("n=%d: fallback signal %v, broker signal %v", n, got, want)
+		}
+		if got != SignalNone {
+			signals++
+		}
+	}
+	if signals == 0 {
+		t.Fatal("expected the fallback to produce signals")
+	}
+}
@@ -0,0 +1,252 @@
+package strategy
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"go-trader/internal/state"
+)
+
+// What: Trade-management helpers that act on the open positions belonging to a strategy run.
+// How: Positions are matched to a run by the labels it submitted. Called from the engine loop each tick.
+// Params (run params):
+//  - breakEvenPips: favorable excursion in pips after which the stop moves to entry. Disabled when 0.
+//  - breakEvenBufferPips: pips beyond entry to place the break-even stop. Default 1.
+//  - maxConsecutiveLosses: auto-stop the run after this many losing closes in a row. Disabled when 0.
+//  - pyramidMaxAdds: follow-on entries allowed per direction while the run's positions are in profit. Disabled when 0.
+//  - pyramidQtyFactor: size of each follow-on entry relative to a normal entry. Default 0.5.
+//  - allowOpposing: 1 lets a signal open against an existing opposite position on the instrument
+//    (from any source: manual trades, other runs, or this run). Default 0 suppresses it.
+//  - exitOnOpposite: 1 closes the run's open positions when the strategy signals the opposite
+//    direction, instead of waiting for SL/TP. Positions from other sources are left alone.
+// Returns: n/a (publishes MODIFY_ORDER/CLOSE_ORDER and logs events).
+
+// runPositions returns the open positions opened by this run.
+func (e *Engine) runPositions(cfg *runConfig) []state.Position {
+	acct := e.sm.GetAccountInfo()
+	var out []state.Position
+	for _, p := range acct.Positions {
+		if _, ok := cfg.labels[p.Label]; ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// opposingPositions returns the open positions on the run's instrument in the opposite direction
+// to sig, from any source, except those this run is already closing. It returns nil when the
+// allowOpposing param is set.
+func (e *Engine) opposingPositions(cfg *runConfig, sig Signal) []state.Position {
+	if v, _ := cfg.param("allowOpposing"); v > 0 {
+		return nil
+	}
+	opposite := SignalSell
+	if sig == SignalSell {
+		opposite = SignalBuy
+	}
+	var out []state.Position
+	for _, p := range e.sm.GetAccountInfo().Positions {
+		if _, closing := cfg.closing[p.OrderID]; closing {
+			continue
+		}
+		if p.Instrument == cfg.instrument && strings.HasPrefix(strings.ToUpper(p.OrderCommand), string(opposite)) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// closeOnOpposite requests a close of each of the run's open positions in the opposite direction
+// to sig when the exitOnOpposite param is set. A position is closed once; the request is forgotten
+// when the position disappears from the account snapshot.
+// Returns the orderIDs a close was requested for.
+func (e *Engine) closeOnOpposite(cfg *runConfig, sig Signal, seq int) []string {
+	if v, _ := cfg.param("exitOnOpposite"); v <= 0 {
+		return nil
+	}
+	var closed []string
+	for _, p := range e.runPositions(cfg) {
+		if p.OrderID == "" || strings.HasPrefix(strings.ToUpper(p.OrderCommand), string(sig)) {
+			continue
+		}
+		if _, done := cfg.closing[p.OrderID]; done {
+			continue
+		}
+		if err := e.pub.PublishCloseOrder(p.OrderID); err != nil {
+			log.Printf("Close on opposite signal failed for %s: %v", p.OrderID, err)
+			continue
+		}
+		if cfg.closing == nil {
+			cfg.closing = make(map[string]struct{})
+		}
+		cfg.closing[p.OrderID] = struct{}{}
+		closed = append(closed, p.OrderID)
+		log.Printf("Closing %s %s on %s @ %s on opposite signal %s (pnl %.2f)", p.OrderID, p.OrderCommand, cfg.instrument, cfg.period, sig, p.PnL)
+		if e.db != nil {
+			e.db.LogTradeCloseRequested(p.OrderID, p.Instrument, p.OrderCommand)
+			e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "closed_on_opposite", string(sig),
+				map[string]any{"orderId": p.OrderID, "label": p.Label, "side": p.OrderCommand, "pnl": p.PnL, "seq": seq})
+		}
+	}
+	return closed
+}
+
+// pyramidEntry applies the opt-in pyramiding rules to a signal.
+// With pyramidMaxAdds set, a signal in the direction of the run's open positions is an add: it is
+// taken only while every one of those positions is in profit and fewer than pyramidMaxAdds adds are
+// open, sized by pyramidQtyFactor, with its own stop. Signals with no open position in their
+// direction are normal entries.
+// Returns whether the order is an add, the qty factor (1 for a normal entry), the open positions it
+// adds to, and a non-empty skip reason when the signal must not be acted on.
+func (e *Engine) pyramidEntry(cfg *runConfig, sig Signal) (add bool, factor float64, same []state.Position, skip string) {
+	maxAdds, _ := cfg.param("pyramidMaxAdds")
+	if maxAdds <= 0 {
+		return false, 1, nil, ""
+	}
+	for _, p := range e.runPositions(cfg) {
+		if strings.HasPrefix(strings.ToUpper(p.OrderCommand), string(sig)) {
+			same = append(same, p)
+		}
+	}
+	if len(same) == 0 {
+		return false, 1, nil, ""
+	}
+	if adds := len(same) - 1; adds >= int(maxAdds) {
+		return false, 0, same, fmt.Sprintf("%d adds already open (max %d)", adds, int(maxAdds))
+	}
+	for _, p := range same {
+		if p.PnL <= 0 {
+			return false, 0, same, fmt.Sprintf("position %s not in profit (pnl %.2f)", p.OrderID, p.PnL)
+		}
+	}
+	factor = 0.5
+	if v, ok := cfg.param("pyramidQtyFactor"); ok && v > 0 {
+		factor = min(v, 1)
+	}
+	return true, factor, same, ""
+}
+
+// manageBreakEven moves the stop to entry (+buffer) once a position is up by breakEvenPips.
+// Fires at most once per position.
+func (e *Engine) manageBreakEven(cfg *runConfig) {
+	bePips, _ := cfg.param("breakEvenPips")
+	if bePips <= 0 || len(cfg.labels) == 0 {
+		return
+	}
+	bufPips := 1.0
+	if v, ok := cfg.param("breakEvenBufferPips"); ok && v >= 0 {
+		bufPips = v
+	}
+	ticks := e.sm.GetTicks(cfg.instrument)
+	if len(ticks) == 0 {
+		return
+	}
+	last := ticks[len(ticks)-1]
+	pip := getPipSize(cfg.instrument)
+	for _, p := range e.runPositions(cfg) {
+		if _, done := cfg.breakEvenDone[p.OrderID]; done || p.OrderID == "" {
+			continue
+		}
+		isBuy := strings.HasPrefix(strings.ToUpper(p.OrderCommand), "BUY")
+		var excursionPips, newSL float64
+		if isBuy {
+			excursionPips = (last.Bid - p.OpenPrice) / pip
+			newSL = p.OpenPrice + bufPips*pip
+			if p.StopLoss >= newSL {
+				cfg.breakEvenDone[p.OrderID] = struct{}{}
+				continue
+			}
+		} else {
+			excursionPips = (p.OpenPrice - last.Ask) / pip
+			newSL = p.OpenPrice - bufPips*pip
+			if p.StopLoss > 0 && p.StopLoss <= newSL {
+				cfg.breakEvenDone[p.OrderID] = struct{}{}
+				continue
+			}
+		}
+		if excursionPips < bePips {
+			continue
+		}
+		newSL = state.RoundPrice(cfg.instrument, newSL)
+		cfg.breakEvenDone[p.OrderID] = struct{}{}
+		if err := e.pub.PublishModifyOrder(p.OrderID, newSL, p.TakeProfit); err != nil {
+			log.Printf("Break-even modify failed for %s: %v", p.OrderID, err)
+			delete(cfg.breakEvenDone, p.OrderID)
+			continue
+		}
+		log.Printf("🔒 Moved stop to break-even for %s on %s (sl=%.5f)", p.OrderID, cfg.instrument, newSL)
+		if e.db != nil {
+			e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "moved_to_breakeven", "",
+				map[string]any{"orderId": p.OrderID, "label": p.Label, "openPrice": p.OpenPrice, "sl": newSL, "excursionPips": excursionPips})
+		}
+	}
+}
+
+// trackClosedPositions diffs the run's open positions against the previous snapshot.
+// A position missing from two consecutive account snapshots is treated as closed with its last seen
+// PnL, logged as trade_closed, and counted toward the consecutive-loss kill switch; one snapshot
+// without it may be a partial broker report. Snapshots without ProducedAt confirm on the next check.
+// Returns true if the run was auto-stopped.
+func (e *Engine) trackClosedPositions(cfg *runConfig) bool {
+	if len(cfg.labels) == 0 {
+		return false
+	}
+	now := e.clock.Now()
+	acct := e.sm.GetAccountInfo()
+	current := make(map[string]state.Position)
+	for _, p := range acct.Positions {
+		if _, ok := cfg.labels[p.Label]; ok && p.OrderID != "" {
+			current[p.OrderID] = p
+			if _, ok := cfg.firstSeen[p.OrderID]; !ok {
+				cfg.firstSeen[p.OrderID] = now
+			}
+		}
+	}
+	for id, prev := range cfg.openPositions {
+		if _, still := current[id]; still {
+			delete(cfg.missingSince, id)
+			continue
+		}
+		if since, missing := cfg.missingSince[id]; !missing || (acct.ProducedAt != 0 && acct.ProducedAt <= since) {
+			if !missing {
+				cfg.missingSince[id] = acct.ProducedAt
+			}
+			current[id] = prev
+			continue
+		}
+		delete(cfg.missingSince, id)
+		delete(cfg.breakEvenDone, id)
+		delete(cfg.closing, id)
+		holdMins := now.Sub(cfg.firstSeen[id]).Minutes()
+		delete(cfg.firstSeen, id)
+		pnlPips := 0.0
+		if pv := state.PipValue(prev.Instrument, prev.Amount, e.sm.LatestTicks()); pv > 0 {
+			pnlPips = prev.PnL / pv
+		}
+		if prev.PnL < 0 {
+			cfg.consecutiveLosses++
+		} else {
+			cfg.consecutiveLosses = 0
+		}
+		if e.db != nil {
+			e.db.LogStrategyTradeClosed(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(),
+				map[string]any{"label": prev.Label, "orderId": id, "side": prev.OrderCommand, "entryPrice": prev.OpenPrice,
+					"qty": prev.Amount, "pnl": prev.PnL, "pnlPips": pnlPips, "holdMins": holdMins, "consecutiveLosses": cfg.consecutiveLosses})
+		}
+	}
+	cfg.openPositions = current
+
+	maxLossesParam, _ := cfg.param("maxConsecutiveLosses")
+	maxLosses := int(maxLossesParam)
+	if maxLosses > 0 && cfg.consecutiveLosses >= maxLosses {
+		reason := fmt.Sprintf("%d consecutive losing trades (max %d)", cfg.consecutiveLosses, maxLosses)
+		log.Printf("🛑 Auto-stopping %s on %s @ %s: %s", cfg.strategy.Key(), cfg.instrument, cfg.period, reason)
+		if e.db != nil {
+			e.db.LogStrategyEvent(cfg.runID, cfg.instrument, cfg.period, cfg.strategy.Key(), "auto_stopped", "", map[string]any{"reason": reason})
+		}
+		e.stopStrategyWithStatus(cfg.instrument, cfg.period, "auto_stopped", reason)
+		return true
+	}
+	return false
+}
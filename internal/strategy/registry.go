@@ -0,0 +1,155 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ParamSpec declares a tunable numeric parameter accepted via SetParams.
+type ParamSpec struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"` // "int" | "float"
+	Default     float64  `json:"default"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Info describes a registered strategy for the frontend catalog.
+type Info struct {
+	Key         string      `json:"key"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Aliases     []string    `json:"aliases,omitempty"`
+	Params      []ParamSpec `json:"params"`
+}
+
+// Registry maps strategy keys to factories and catalog metadata.
+// What: Single place to instantiate strategies by key for WS and HTTP start paths.
+// How: Each strategy file registers itself from init() via DefaultRegistry; keys are case-insensitive.
+// Returns: fresh Strategy instances from New so runs never share parameter state.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]func() Strategy
+	infos     map[string]Info
+	aliases   map[string]string // alias -> key
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]func() Strategy),
+		infos:     make(map[string]Info),
+		aliases:   make(map[string]string),
+	}
+}
+
+// DefaultRegistry holds all built-in strategies.
+var DefaultRegistry = NewRegistry()
+
+// DefaultKey is started when no strategy key is given, or in place of an unknown key when the
+// caller explicitly allows the fallback.
+const DefaultKey = "DEMA_RSI"
+
+// Register adds a strategy factory under key. Registering the same key twice panics.
+func (r *Registry) Register(key string, factory func() Strategy) {
+	key = normalizeKey(key)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.factories[key]; dup {
+		panic("strategy: duplicate registration for " + key)
+	}
+	r.factories[key] = factory
+}
+
+// Describe attaches catalog metadata (params, aliases) to a registered key.
+func (r *Registry) Describe(info Info) {
+	key := normalizeKey(info.Key)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info.Key = key
+	r.infos[key] = info
+	for _, a := range info.Aliases {
+		r.aliases[normalizeKey(a)] = key
+	}
+}
+
+// New returns a new instance of the strategy registered under key (or an alias).
+func (r *Registry) New(key string) (Strategy, error) {
+	key = normalizeKey(key)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if k, ok := r.aliases[key]; ok {
+		key = k
+	}
+	f, ok := r.factories[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy key %q", key)
+	}
+	return f(), nil
+}
+
+// Keys returns all registered keys (without aliases), sorted.
+func (r *Registry) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.factories))
+	for k := range r.factories {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Catalog returns metadata for all registered strategies, sorted by key.
+// Strategies registered without Describe are listed with their key only.
+func (r *Registry) Catalog() []Info {
+	keys := r.Keys()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Info, 0, len(keys))
+	for _, k := range keys {
+		info, ok := r.infos[k]
+		if !ok {
+			info = Info{Key: k, Name: k}
+		}
+		if info.Params == nil {
+			info.Params = []ParamSpec{}
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// Catalog returns metadata for all strategies in DefaultRegistry.
+func Catalog() []Info { return DefaultRegistry.Catalog() }
+
+func normalizeKey(k string) string { return strings.ToUpper(strings.TrimSpace(k)) }
+
+func bound(v float64) *float64 { return &v }
+
+// EngineParams declares run-level params handled by the Engine for every strategy.
+func EngineParams() []ParamSpec {
+	return []ParamSpec{
+		{Name: "minVol", Type: "float", Default: 0, Min: bound(0), Description: "Minimum average tick volume; signals are filtered below it"},
+		{Name: "riskPct", Type: "float", Default: 0, Min: bound(0), Max: bound(100), Description: "Percent of equity risked per trade; 0 uses fixed qty"},
+		{Name: "breakEvenPips", Type: "float", Default: 0, Min: bound(0), Description: "Move stop to entry after this favorable excursion; 0 disables"},
+		{Name: "breakEvenBufferPips", Type: "float", Default: 1, Min: bound(0), Description: "Pips beyond entry for the break-even stop"},
+		{Name: "maxConsecutiveLosses", Type: "int", Default: 0, Min: bound(0), Description: "Auto-stop after this many losing closes in a row; 0 disables"},
+		{Name: "slippage", Type: "float", Default: 0, Min: bound(0), Description: "Market-order slippage in pips; 0 uses the instrument default"},
+		{Name: "warmupBars", Type: "int", Default: 0, Min: bound(0), Description: "Bars required before trading; the strategy's own minimum applies when larger"},
+		{Name: "evalOnTick", Type: "int", Default: 0, Min: bound(0), Max: bound(1), Description: "1 re-evaluates on each new tick using the forming bar; only strategies that support it (BREAKOUT_DC) accept 1. 0 evaluates on bar close"},
+		{Name: "signalConfirmBars", Type: "int", Default: 0, Min: bound(0), Description: "Act only once the strategy has produced the same signal on this many consecutive evaluations (for cross-style strategies: once no opposite signal followed within this many); 0 or 1 acts immediately"},
+		{Name: "slAtrMult", Type: "float", Default: 0, Min: bound(0), Max: bound(20), Description: "Stop-loss distance in ATR multiples; 0 uses atrMult"},
+		{Name: "tpAtrMult", Type: "float", Default: 0, Min: bound(0), Max: bound(20), Description: "Take-profit distance in ATR multiples; 0 uses atrMult"},
+		{Name: "pyramidMaxAdds", Type: "int", Default: 0, Min: bound(0), Description: "Follow-on entries allowed while same-direction positions are in profit; 0 disables pyramiding"},
+		{Name: "pyramidQtyFactor", Type: "float", Default: 0.5, Min: bound(0), Max: bound(1), Description: "Size of each follow-on entry relative to a normal entry"},
+		{Name: "confidenceSizing", Type: "int", Default: 0, Min: bound(0), Max: bound(1), Description: "1 scales order size by the strategy's signal confidence (0-1); 0 ignores it"},
+		{Name: "maxTickAgeMs", Type: "int", Default: defaultMaxTickAgeMs, Min: bound(0), Description: "Refuse orders when the newest tick is older than this (ms); 0 disables"},
+		{Name: "minBarsBetween", Type: "int", Default: 0, Min: bound(0), Description: "Bar periods that must elapse after an order before another order in the same direction; 0 disables"},
+		{Name: "exitOnOpposite", Type: "int", Default: 0, Min: bound(0), Max: bound(1), Description: "1 closes the run's open positions when the strategy signals the opposite direction; SL/TP still apply"},
+		{Name: "allowOpposing", Type: "int", Default: 0, Min: bound(0), Max: bound(1), Description: "1 allows signals against an open opposite position on the instrument; 0 suppresses them"},
+	}
+}
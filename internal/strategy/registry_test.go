@@ -0,0 +1,53 @@
+package strategy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaultRegistryKeys(t *testing.T) {
+	want := []string{"BB_SQUEEZE", "BREAKOUT_DC", "DEMA_RSI", "RSI_CROSS", "SUPERTREND_TREND"}
+	if got := DefaultRegistry.Keys(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("registered keys = %v, want %v", got, want)
+	}
+	for _, k := range DefaultRegistry.Keys() {
+		s, err := DefaultRegistry.New(k)
+		if err != nil {
+			t.Fatalf("New(%q) error: %v", k, err)
+		}
+		if s.Key() != k {
+			t.Errorf("New(%q).Key() = %q", k, s.Key())
+		}
+	}
+}
+
+func TestRegistryAliasesAndUnknown(t *testing.T) {
+	for _, alias := range []string{"dema", "DEMA+RSI", " dema_rsi "} {
+		s, err := DefaultRegistry.New(alias)
+		if err != nil || s.Key() != "DEMA_RSI" {
+			t.Errorf("New(%q) = %v, %v; want DEMA_RSI", alias, s, err)
+		}
+	}
+	if _, err := DefaultRegistry.New("NOPE"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
+
+func TestRegistryNewReturnsFreshInstances(t *testing.T) {
+	a, _ := DefaultRegistry.New("BREAKOUT_DC")
+	b, _ := DefaultRegistry.New("BREAKOUT_DC")
+	if a == b {
+		t.Fatal("New must return a fresh instance per call")
+	}
+}
+
+func TestRegistryDuplicatePanics(t *testing.T) {
+	r := NewRegistry()
+	r.Register("X", func() Strategy { return &RsiCrossStrategy{} })
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate registration")
+		}
+	}()
+	r.Register("x", func() Strategy { return &RsiCrossStrategy{} })
+}
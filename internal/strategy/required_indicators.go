@@ -0,0 +1,49 @@
+package strategy
+
+import (
+	"sort"
+
+	"go-trader/internal/state"
+)
+
+// IndicatorAware is optionally implemented by strategies to declare the broker indicators they
+// read on the newest bar without a local fallback. The engine skips evaluation, logging an
+// indicators_missing event, while any of them is zero or nil (JForex sent a raw bar), so a
+// zero-valued indicator cannot produce a spurious signal. Names are keys of indicatorPresent.
+// Strategies that compute missing indicators locally (see indicators.go) require none.
+type IndicatorAware interface {
+	RequiredIndicators() []string
+}
+
+// indicatorPresent reports, per indicator name (the bar's JSON field), whether a bar carries it.
+var indicatorPresent = map[string]func(b state.HistoricalBar) bool{
+	"bid_atr":   func(b state.HistoricalBar) bool { return b.BidAtr != 0 },
+	"bid_demas": func(b state.HistoricalBar) bool { return b.BidDemas.Dema25 != 0 && b.BidDemas.Dema50 != 0 },
+	"bid_rsi":   func(b state.HistoricalBar) bool { return b.BidRsi.Fast != 0 && b.BidRsi.Slow != 0 },
+	"bid_macd":  func(b state.HistoricalBar) bool { return b.BidMacd.Line != 0 || b.BidMacd.Signal != 0 },
+	"bid_bollinger": func(b state.HistoricalBar) bool {
+		return nonZero(b.BidBollinger.Upper) && nonZero(b.BidBollinger.Lower)
+	},
+	"bid_keltner":    func(b state.HistoricalBar) bool { return b.BidKeltner.Upper != 0 && b.BidKeltner.Lower != 0 },
+	"bid_donchian":   func(b state.HistoricalBar) bool { return nonZero(b.BidDonchian.Upper) && nonZero(b.BidDonchian.Lower) },
+	"bid_supertrend": func(b state.HistoricalBar) bool { return b.BidSupertrend.Upper != 0 && b.BidSupertrend.Lower != 0 },
+}
+
+func nonZero(v *float64) bool { return v != nil && *v != 0 }
+
+// missingIndicators returns the strategy's required indicators absent from bar, sorted.
+// Unknown names are reported as missing so a typo cannot silently disable the check.
+func missingIndicators(s Strategy, bar state.HistoricalBar) []string {
+	ia, ok := s.(IndicatorAware)
+	if !ok {
+		return nil
+	}
+	var missing []string
+	for _, name := range ia.RequiredIndicators() {
+		if present, ok := indicatorPresent[name]; !ok || !present(bar) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,80 @@
+package strategy
+
+import (
+	"math"
+
+	"go-trader/internal/state"
+)
+
+// What: RSI Cross momentum strategy using fast and slow RSI.
+// How: Emits BUY when fast RSI crosses above slow RSI; SELL when it crosses below.
+//      Optional confirmation levels require the cross to happen from an oversold/overbought zone.
+// Params:
+//  - ob (float): overbought level; SELL requires previous fast RSI >= ob. Disabled when 0.
+//  - os (float): oversold level; BUY requires previous fast RSI <= os. Disabled when 0.
+// Returns: SignalBuy, SignalSell, or SignalNone; EvaluateWithConfidence adds the post-cross RSI spread as confidence.
+
+type RsiCrossStrategy struct {
+	ob float64
+	os float64
+}
+
+func init() {
+	DefaultRegistry.Register("RSI_CROSS", func() Strategy { return &RsiCrossStrategy{} })
+	DefaultRegistry.Describe(Info{
+		Key: "RSI_CROSS", Name: "RSI Cross",
+		Description: "Fast RSI crossing slow RSI, optionally from overbought/oversold zones",
+		Params: []ParamSpec{
+			{Name: "ob", Type: "float", Default: 0, Min: bound(0), Max: bound(100), Description: "Overbought level confirming sells; 0 disables"},
+			{Name: "os", Type: "float", Default: 0, Min: bound(0), Max: bound(100), Description: "Oversold level confirming buys; 0 disables"},
+		},
+	})
+}
+
+func (s *RsiCrossStrategy) Key() string { return "RSI_CROSS" }
+
+// EventSignals reports that signals fire on the crossing bar only (see EventStrategy).
+func (s *RsiCrossStrategy) EventSignals() bool { return true }
+
+// SetParams allows runtime configuration.
+func (s *RsiCrossStrategy) SetParams(p Params) {
+	if p == nil { return }
+	if v, ok := p["ob"]; ok && v > 0 && v < 100 { s.ob = v }
+	if v, ok := p["os"]; ok && v > 0 && v < 100 { s.os = v }
+}
+
+// MinBars is the two bars needed to detect a cross.
+func (s *RsiCrossStrategy) MinBars() int { return 2 }
+
+// RequiredIndicators is empty: missing RSI values are computed locally.
+func (s *RsiCrossStrategy) RequiredIndicators() []string { return nil }
+
+func (s *RsiCrossStrategy) Evaluate(bars []state.HistoricalBar) Signal {
+	sig, _ := s.EvaluateWithConfidence(bars)
+	return sig
+}
+
+// rsiCrossFullSpread is the fast/slow RSI separation after a cross that counts as full confidence.
+const rsiCrossFullSpread = 10.0
+
+// EvaluateWithConfidence grades a cross by how far fast RSI has moved past slow RSI, reaching 1 at
+// rsiCrossFullSpread points; a barely-touching cross is close to 0.
+func (s *RsiCrossStrategy) EvaluateWithConfidence(bars []state.HistoricalBar) (Signal, float64) {
+	if len(bars) < 2 { return SignalNone, 0 }
+	f0 := rsiOrLocal(bars[0].BidRsi.Fast, bars, brokerRsiFastPeriod)
+	s0 := rsiOrLocal(bars[0].BidRsi.Slow, bars, brokerRsiSlowPeriod)
+	f1 := rsiOrLocal(bars[1].BidRsi.Fast, bars[1:], brokerRsiFastPeriod)
+	s1 := rsiOrLocal(bars[1].BidRsi.Slow, bars[1:], brokerRsiSlowPeriod)
+	// Live-merged and warm-up bars carry zeroed indicators; skip if not enough history to compute them
+	if f0 == 0 || s0 == 0 || f1 == 0 || s1 == 0 { return SignalNone, 0 }
+	conf := min(math.Abs(f0-s0)/rsiCrossFullSpread, 1)
+	if f1 <= s1 && f0 > s0 {
+		if s.os > 0 && f1 > s.os { return SignalNone, 0 }
+		return SignalBuy, conf
+	}
+	if f1 >= s1 && f0 < s0 {
+		if s.ob > 0 && f1 < s.ob { return SignalNone, 0 }
+		return SignalSell, conf
+	}
+	return SignalNone, 0
+}
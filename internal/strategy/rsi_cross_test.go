@@ -0,0 +1,53 @@
+package strategy
+
+import (
+	"testing"
+
+	"go-trader/internal/state"
+)
+
+func TestRsiCrossEvaluate(t *testing.T) {
+	// rsiBars returns the newest bar with fast/slow RSI f0/s0 and the previous one with f1/s1
+	rsiBars := func(f0, s0, f1, s1 float64) []state.HistoricalBar {
+		return []state.HistoricalBar{
+			{BidRsi: state.Rsi{Fast: f0, Slow: s0}},
+			{BidRsi: state.Rsi{Fast: f1, Slow: s1}},
+		}
+	}
+	for _, tc := range []struct {
+		name   string
+		params Params
+		bars   []state.HistoricalBar
+		want   Signal
+	}{
+		{"crossover up", nil, rsiBars(55, 50, 45, 50), SignalBuy},
+		{"crossover down", nil, rsiBars(45, 50, 55, 50), SignalSell},
+		{"fast stays above", nil, rsiBars(60, 50, 55, 50), SignalNone},
+		{"fast stays below", nil, rsiBars(40, 50, 45, 50), SignalNone},
+		{"touch without crossing", nil, rsiBars(50, 50, 45, 50), SignalNone},
+		{"crossover up outside oversold", Params{"os": 30}, rsiBars(55, 50, 45, 50), SignalNone},
+		{"crossover up from oversold", Params{"os": 30}, rsiBars(35, 30, 25, 30), SignalBuy},
+		{"crossover down from overbought", Params{"ob": 70}, rsiBars(65, 70, 75, 70), SignalSell},
+	} {
+		s := &RsiCrossStrategy{}
+		s.SetParams(tc.params)
+		if got := s.Evaluate(tc.bars); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+	if got := (&RsiCrossStrategy{}).Evaluate(rsiBars(55, 50, 45, 50)[:1]); got != SignalNone {
+		t.Errorf("one bar: got %s, want NONE", got)
+	}
+}
+
+func TestRsiCrossConfidenceGrowsWithSpread(t *testing.T) {
+	s := &RsiCrossStrategy{}
+	bars := func(f0 float64) []state.HistoricalBar {
+		return []state.HistoricalBar{{BidRsi: state.Rsi{Fast: f0, Slow: 50}}, {BidRsi: state.Rsi{Fast: 45, Slow: 50}}}
+	}
+	_, narrow := s.EvaluateWithConfidence(bars(51))
+	_, wide := s.EvaluateWithConfidence(bars(50 + 2*rsiCrossFullSpread))
+	if narrow <= 0 || narrow >= 0.5 || wide != 1 {
+		t.Fatalf("confidence: narrow cross %v, wide cross %v; want small and 1", narrow, wide)
+	}
+}
@@ -3,7 +3,8 @@ package strategy
 import "go-trader/internal/state"
 
 // What: Supertrend Trend-Follow strategy with optional params: atrLen and mult.
-// How: If params provided, computes simple Supertrend bands from bars; otherwise uses precomputed bands.
+// How: If params provided, computes simple Supertrend bands from bars; otherwise uses precomputed bands,
+//      computing them locally with the broker's settings (12, 3.0) while those are still zero.
 //      Emits BUY when price crosses above lower band; SELL when crosses below upper band.
 // Params:
 //  - atrLen (int): ATR lookback. Default 10 if provided.
@@ -15,8 +16,23 @@ type SupertrendStrategy struct {
 	mult   float64
 }
 
+func init() {
+	DefaultRegistry.Register("SUPERTREND_TREND", func() Strategy { return &SupertrendStrategy{} })
+	DefaultRegistry.Describe(Info{
+		Key: "SUPERTREND_TREND", Name: "Supertrend Trend-Follow",
+		Description: "Price crossing Supertrend bands",
+		Params: []ParamSpec{
+			{Name: "atrLen", Type: "int", Default: 10, Min: bound(2), Description: "ATR lookback"},
+			{Name: "mult", Type: "float", Default: 3.0, Min: bound(0), Description: "ATR multiplier for bands"},
+		},
+	})
+}
+
 func (s *SupertrendStrategy) Key() string { return "SUPERTREND_TREND" }
 
+// EventSignals reports that signals fire on the crossing bar only (see EventStrategy).
+func (s *SupertrendStrategy) EventSignals() bool { return true }
+
 // SetParams allows runtime configuration.
 func (s *SupertrendStrategy) SetParams(p Params) {
 	if p == nil { return }
@@ -24,27 +40,43 @@ func (s *SupertrendStrategy) SetParams(p Params) {
 	if v, ok := p["mult"]; ok && v > 0 { s.mult = v }
 }
 
+// MinBars is atrLen+1 when bands are computed locally, otherwise 2.
+func (s *SupertrendStrategy) MinBars() int {
+	if s.atrLen > 1 && s.mult > 0 { return s.atrLen + 1 }
+	return 2
+}
+
+// RequiredIndicators is empty: bands are computed locally when the broker's are missing.
+func (s *SupertrendStrategy) RequiredIndicators() []string { return nil }
+
 func (s *SupertrendStrategy) Evaluate(bars []state.HistoricalBar) Signal {
 	if len(bars) < 2 { return SignalNone }
 	b0 := bars[0]; b1 := bars[1]
 	c0 := b0.Bid.C; c1 := b1.Bid.C
 	var upper0, lower0, upper1, lower1 float64
-	if s.atrLen > 1 && s.mult > 0 {
-		al := s.atrLen
+	al, mult := s.atrLen, s.mult
+	if al <= 1 || mult <= 0 {
+		upper0 = b0.BidSupertrend.Upper
+		lower0 = b0.BidSupertrend.Lower
+		upper1 = b1.BidSupertrend.Upper
+		lower1 = b1.BidSupertrend.Lower
+		if upper0 != 0 && lower0 != 0 && upper1 != 0 && lower1 != 0 {
+			al = 0 // broker bands available
+		} else {
+			// Warm-up: compute bands locally with the broker's settings
+			al, mult = brokerSupertrendPeriod, brokerSupertrendMultiplier
+		}
+	}
+	if al > 1 {
 		if len(bars) <= al { return SignalNone }
 		atr0 := simpleATR(bars, al)    // ATR at current
 		atr1 := simpleATR(bars[1:], al) // ATR at previous (shifted)
 		m0 := (b0.Bid.H + b0.Bid.L) / 2.0
 		m1 := (b1.Bid.H + b1.Bid.L) / 2.0
-		upper0 = m0 + s.mult*atr0
-		lower0 = m0 - s.mult*atr0
-		upper1 = m1 + s.mult*atr1
-		lower1 = m1 - s.mult*atr1
-	} else {
-		upper0 = b0.BidSupertrend.Upper
-		lower0 = b0.BidSupertrend.Lower
-		upper1 = b1.BidSupertrend.Upper
-		lower1 = b1.BidSupertrend.Lower
+		upper0 = m0 + mult*atr0
+		lower0 = m0 - mult*atr0
+		upper1 = m1 + mult*atr1
+		lower1 = m1 - mult*atr1
 	}
 	if lower1 > 0 && c1 <= lower1 && lower0 > 0 && c0 > lower0 { return SignalBuy }
 	if upper1 > 0 && c1 >= upper1 && upper0 > 0 && c0 < upper0 { return SignalSell }
@@ -0,0 +1,86 @@
+package strategy
+
+import (
+	"errors"
+	"fmt"
+
+	"go-trader/internal/state"
+)
+
+// TickStrategy is optionally implemented by strategies that can react within a bar.
+// What: Scalping-style strategies want to act on the tick that crosses a level, not on bar close.
+// How: With the evalOnTick run param set, the engine calls EvaluateTick whenever a new tick has
+//      arrived, passing the completed bars (newest-first), a forming bar built from the ticks since
+//      the newest completed bar closed, and those ticks (oldest first).
+// Tradeoffs: evaluation still happens at most once per engine poll (1s), so bursts of ticks collapse
+//      into one evaluation; the forming bar only covers the retained ticks (the tick buffer size) and
+//      carries no indicators; at most one order is placed per forming bar to avoid re-entering on
+//      every tick; and signals on partial bars are noisier than on closed bars.
+// Runs of strategies that do not implement it are refused when evalOnTick is set (see CheckTickMode).
+type TickStrategy interface {
+	EvaluateTick(bars []state.HistoricalBar, forming state.HistoricalBar, ticks []state.Tick) Signal
+}
+
+// ErrTickModeUnsupported is returned for evalOnTick on a strategy that does not implement TickStrategy.
+var ErrTickModeUnsupported = errors.New("evalOnTick is not supported")
+
+// CheckTickMode returns an error when params set evalOnTick but s does not implement TickStrategy,
+// so the run is refused instead of silently evaluating on bar close.
+func CheckTickMode(s Strategy, params Params) error {
+	if params["evalOnTick"] < 1 {
+		return nil
+	}
+	if _, ok := s.(TickStrategy); ok {
+		return nil
+	}
+	return fmt.Errorf("%w by %s", ErrTickModeUnsupported, s.Key())
+}
+
+// formingBar aggregates the ticks after the newest completed bar into a partial bar.
+// ticks are ordered oldest to newest. ok is false when no tick is newer than the completed bar.
+func formingBar(completed state.HistoricalBar, ticks []state.Tick) (state.HistoricalBar, []state.Tick, bool) {
+	var in []state.Tick
+	for _, t := range ticks {
+		if tickTime(t) > completed.BarEndTimestamp && t.Bid > 0 && t.Ask > 0 {
+			in = append(in, t)
+		}
+	}
+	if len(in) == 0 {
+		return state.HistoricalBar{}, nil, false
+	}
+	first := in[0]
+	b := state.HistoricalBar{
+		ProducedAt:        first.ProducedAt,
+		BarStartTimestamp: completed.BarEndTimestamp,
+		Instrument:        completed.Instrument,
+		Period:            completed.Period,
+		Bid:               state.OHLCV{O: first.Bid, H: first.Bid, L: first.Bid},
+		Ask:               state.OHLCV{O: first.Ask, H: first.Ask, L: first.Ask},
+	}
+	for _, t := range in {
+		b.Bid.H, b.Bid.L = max(b.Bid.H, t.Bid), min(b.Bid.L, t.Bid)
+		b.Ask.H, b.Ask.L = max(b.Ask.H, t.Ask), min(b.Ask.L, t.Ask)
+		b.Bid.C, b.Ask.C = t.Bid, t.Ask
+		b.Bid.V += t.BidVol
+		b.Ask.V += t.AskVol
+		b.BarEndTimestamp = tickTime(t)
+	}
+	return b, in, true
+}
+
+// tickTime returns the tick's market timestamp, falling back to when it was produced.
+func tickTime(t state.Tick) int64 {
+	if t.Timestamp > 0 {
+		return t.Timestamp
+	}
+	return t.ProducedAt
+}
+
+// tickMode reports whether the run evaluates on ticks: evalOnTick is set and the strategy supports it.
+func (c *runConfig) tickMode() (TickStrategy, bool) {
+	if v, _ := c.param("evalOnTick"); v < 1 {
+		return nil, false
+	}
+	ts, ok := c.strategy.(TickStrategy)
+	return ts, ok
+}
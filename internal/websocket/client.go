@@ -2,7 +2,10 @@ package websocket
 
 import (
 	"bytes"
+	"encoding/json"
 	"log"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -43,6 +46,80 @@ type Client struct {
 
 	// Buffered channel of outbound messages.
 	send chan []byte
+
+	// remoteAddr and connectedAt identify the client in logs and stats.
+	remoteAddr  string
+	connectedAt time.Time
+
+	// sent and dropped count messages queued for and skipped for this client.
+	sent    atomic.Int64
+	dropped atomic.Int64
+
+	// verbosity is the level chosen with SET_VERBOSITY; unset means VerbosityFull.
+	verbosity atomic.Value
+
+	// encoding is the broadcast encoding negotiated at the handshake; empty means EncodingJSON.
+	encoding string
+}
+
+// setVerbosityCommand selects which state categories a client is sent.
+type setVerbosityCommand struct {
+	Type  string `json:"type"`  // SET_VERBOSITY
+	Level string `json:"level"` // full | bars | ticks | account
+}
+
+// Verbosity returns the client's broadcast verbosity level.
+func (c *Client) Verbosity() string {
+	if level, ok := c.verbosity.Load().(string); ok {
+		return level
+	}
+	return VerbosityFull
+}
+
+// Encoding returns the client's broadcast encoding.
+func (c *Client) Encoding() string {
+	if c.encoding == "" {
+		return EncodingJSON
+	}
+	return c.encoding
+}
+
+// frameType returns the WebSocket frame type for message: JSON payloads are text frames and
+// MessagePack payloads (always maps, whose first byte is never '{' or '[') binary ones.
+func frameType(message []byte) int {
+	if len(message) > 0 && (message[0] == '{' || message[0] == '[') {
+		return websocket.TextMessage
+	}
+	return websocket.BinaryMessage
+}
+
+// handleVerbosity applies a SET_VERBOSITY command and reports whether message was one.
+// The setting only concerns this connection, so the command is not forwarded to the hub.
+func (c *Client) handleVerbosity(message []byte) bool {
+	var cmd setVerbosityCommand
+	if json.Unmarshal(message, &cmd) != nil || cmd.Type != "SET_VERBOSITY" {
+		return false
+	}
+	level := strings.ToLower(strings.TrimSpace(cmd.Level))
+	if !validVerbosity(level) {
+		log.Printf("Ignoring SET_VERBOSITY from %s: unknown level %q", c.remoteAddr, cmd.Level)
+		return true
+	}
+	c.verbosity.Store(level)
+	log.Printf("WebSocket client %s verbosity set to %s", c.remoteAddr, level)
+	return true
+}
+
+// trySend queues message without blocking and reports whether it fit in the send buffer.
+func (c *Client) trySend(message []byte) bool {
+	select {
+	case c.send <- message:
+		c.sent.Add(1)
+		return true
+	default:
+		c.dropped.Add(1)
+		return false
+	}
 }
 
 // readPump pumps messages from the WebSocket connection to the hub.
@@ -67,6 +144,9 @@ func (c *Client) readPump() {
 			break
 		}
 		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
+		if c.handleVerbosity(message) {
+			continue
+		}
 		// Send command to hub for processing by external handlers
 		c.hub.SendCommand(message)
 		log.Printf("Received command from client: %s", message)
@@ -93,6 +173,13 @@ func (c *Client) writePump() {
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
+			if c.encoding == EncodingMsgpack {
+				// Binary frames cannot be joined with newlines, so each message is its own frame
+				if err := c.conn.WriteMessage(frameType(message), message); err != nil {
+					return
+				}
+				continue
+			}
 
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
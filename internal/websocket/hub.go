@@ -6,28 +6,114 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-trader/internal/broadcast"
 
 	"github.com/gorilla/websocket"
 )
 
+// Broadcast verbosity levels a client selects with {"type":"SET_VERBOSITY","level":...}.
+const (
+	VerbosityFull    = "full"    // complete state snapshots and all events (default)
+	VerbosityBars    = "bars"    // live and historical bars
+	VerbosityTicks   = "ticks"   // prices only
+	VerbosityAccount = "account" // account, positions, exposure and strategy statuses
+)
+
+// validVerbosity reports whether level is one of the Verbosity* levels.
+func validVerbosity(level string) bool {
+	switch level {
+	case VerbosityFull, VerbosityBars, VerbosityTicks, VerbosityAccount:
+		return true
+	}
+	return false
+}
+
+// Broadcast encodings a client negotiates with the Sec-WebSocket-Protocol handshake header.
+// Clients that offer the "msgpack" subprotocol receive snapshots as binary MessagePack frames;
+// producers add those payloads under MsgpackView(level). Such a client falls back to the JSON
+// payload when a level has no MessagePack entry, so events only built as JSON (alerts, trade
+// results, historical bars) still reach it as text frames. JSON stays the default.
+const (
+	EncodingJSON    = "json"
+	EncodingMsgpack = "msgpack"
+)
+
+// MsgpackView returns the Views key of the MessagePack payload for level.
+func MsgpackView(level string) string {
+	return level + "+" + EncodingMsgpack
+}
+
+// Views holds one payload per verbosity level. Levels without an entry receive the
+// VerbosityFull payload; an entry set to nil means clients at that level get nothing.
+// MessagePack payloads are keyed by MsgpackView(level).
+type Views map[string][]byte
+
+// For returns the payload for clients at level.
+func (v Views) For(level string) []byte {
+	if msg, ok := v[level]; ok {
+		return msg
+	}
+	return v[VerbosityFull]
+}
+
+// ForEncoding returns the payload for clients at level using encoding, falling back to JSON
+// when the views have no MessagePack entry for the level.
+func (v Views) ForEncoding(level, encoding string) []byte {
+	if encoding == EncodingMsgpack {
+		if msg, ok := v[MsgpackView(level)]; ok {
+			return msg
+		}
+	}
+	return v.For(level)
+}
+
 // Hub manages all WebSocket clients and broadcasts messages to them.
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan Views
 	register   chan *Client
 	unregister chan *Client
 	Commands   chan []byte
 	mu         sync.RWMutex
+
+	// lastBroadcast retains the most recent payloads so new clients get an immediate snapshot.
+	lastBroadcast Views
+	// retained holds the latest keyed message (e.g. per-period historical bars) replayed to new clients.
+	retained map[string]Views
+	// evictions counts clients dropped because their send buffer was full.
+	evictions atomic.Int64
+}
+
+// ClientStats describes one connected WebSocket client.
+type ClientStats struct {
+	RemoteAddr  string    `json:"remoteAddr"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	Sent        int64     `json:"sent"`    // messages queued for the client
+	Dropped     int64     `json:"dropped"` // messages skipped because the send buffer was full
+	Buffered    int       `json:"buffered"`
+	Verbosity   string    `json:"verbosity"`
+	Encoding    string    `json:"encoding"` // json | msgpack
+}
+
+// HubStats summarises connected clients and slow-client evictions for monitoring.
+type HubStats struct {
+	Connected int           `json:"connected"`
+	Evictions int64         `json:"evictions"`
+	Clients   []ClientStats `json:"clients"`
 }
 
 // NewHub creates a new Hub.
 func NewHub() *Hub {
 	return &Hub{
-		broadcast:  make(chan []byte),
+		broadcast:  make(chan Views),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		Commands:   make(chan []byte),
 		clients:    make(map[*Client]bool),
+		retained:   make(map[string]Views),
 	}
 }
 
@@ -37,9 +123,20 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			// Send the retained snapshot before the client joins the broadcast stream
+			level := client.Verbosity()
+			if msg := h.lastBroadcast.ForEncoding(level, client.encoding); msg != nil {
+				client.trySend(msg)
+			}
+			for _, views := range h.retained {
+				if msg := views.ForEncoding(level, client.encoding); msg != nil {
+					client.trySend(msg)
+				}
+			}
 			h.clients[client] = true
+			n := len(h.clients)
 			h.mu.Unlock()
-			log.Println("WebSocket client registered")
+			log.Printf("WebSocket client registered: %s (%d connected)", client.remoteAddr, n)
 
 		case client := <-h.unregister:
 			h.mu.Lock()
@@ -47,21 +144,12 @@ func (h *Hub) Run() {
 				delete(h.clients, client)
 				close(client.send)
 			}
+			n := len(h.clients)
 			h.mu.Unlock()
-			log.Println("WebSocket client unregistered")
-
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					// If the client's send buffer is full, unregister and close.
-					close(client.send)
-					delete(h.clients, client)
-				}
-			}
-			h.mu.RUnlock()
+			log.Printf("WebSocket client unregistered: %s (%d connected)", client.remoteAddr, n)
+
+		case views := <-h.broadcast:
+			h.deliverViews(views)
 
 		case command := <-h.Commands:
 			// Commands are handled by external processors (like FrontendCommunicator)
@@ -70,9 +158,157 @@ func (h *Hub) Run() {
 	}
 }
 
+// deliver queues message for every client and evicts those whose send buffer is full.
+func (h *Hub) deliver(message []byte) {
+	h.deliverViews(Views{VerbosityFull: message})
+}
+
+// Fan-out worker pool bounds for deliverViews.
+const (
+	fanoutWorkers = 8   // maximum concurrent senders per broadcast
+	fanoutChunk   = 128 // clients per sender; below this goroutine overhead outweighs the parallelism
+)
+
+// deliverViews queues each client's payload for its verbosity level and evicts clients
+// whose send buffer is full. The client list is snapshotted under the lock and the
+// non-blocking sends run in up to fanoutWorkers goroutines without holding it. Send channels
+// are only closed by Run's goroutine (unregister and eviction), which is the one running
+// deliverViews, so no channel can be closed mid fan-out.
+func (h *Hub) deliverViews(views Views) {
+	h.mu.RLock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.RUnlock()
+
+	workers := min(fanoutWorkers, (len(clients)+fanoutChunk-1)/fanoutChunk)
+	full := make([][]*Client, max(workers, 1))
+	send := func(w, stride int) {
+		for i := w; i < len(clients); i += stride {
+			message := views.ForEncoding(clients[i].Verbosity(), clients[i].encoding)
+			if message != nil && !clients[i].trySend(message) {
+				full[w] = append(full[w], clients[i])
+			}
+		}
+	}
+	if workers <= 1 {
+		send(0, 1)
+	} else {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				send(w, workers)
+			}(w)
+		}
+		wg.Wait()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, slow := range full {
+		for _, client := range slow {
+			if _, ok := h.clients[client]; ok {
+				h.evict(client)
+			}
+		}
+	}
+}
+
+// evict disconnects a client whose send buffer is full; the caller holds h.mu.
+func (h *Hub) evict(client *Client) {
+	// The client is not keeping up; disconnect it rather than block everyone else
+	close(client.send)
+	delete(h.clients, client)
+	total := h.evictions.Add(1)
+	log.Printf("WARNING: Evicting slow WebSocket client %s (send buffer full; sent=%d dropped=%d, connected %s, evictions total=%d)",
+		client.remoteAddr, client.sent.Load(), client.dropped.Load(),
+		time.Since(client.connectedAt).Truncate(time.Second), total)
+}
+
+// Stats returns the connected clients and the eviction count.
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := HubStats{Connected: len(h.clients), Evictions: h.evictions.Load(), Clients: make([]ClientStats, 0, len(h.clients))}
+	for client := range h.clients {
+		out.Clients = append(out.Clients, ClientStats{
+			RemoteAddr:  client.remoteAddr,
+			ConnectedAt: client.connectedAt,
+			Sent:        client.sent.Load(),
+			Dropped:     client.dropped.Load(),
+			Buffered:    len(client.send),
+			Verbosity:   client.Verbosity(),
+			Encoding:    client.Encoding(),
+		})
+	}
+	return out
+}
+
+// Verbosities returns the verbosity levels of the connected clients, so callers only build
+// the views someone will receive.
+func (h *Hub) Verbosities() map[string]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make(map[string]bool)
+	for client := range h.clients {
+		out[client.Verbosity()] = true
+	}
+	return out
+}
+
+// ViewKeys returns the Views keys the connected clients read: the verbosity level of JSON
+// clients and MsgpackView(level) of MessagePack clients.
+func (h *Hub) ViewKeys() map[string]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make(map[string]bool)
+	for client := range h.clients {
+		if client.encoding == EncodingMsgpack {
+			out[MsgpackView(client.Verbosity())] = true
+		} else {
+			out[client.Verbosity()] = true
+		}
+	}
+	return out
+}
+
+var _ broadcast.Broadcaster = (*Hub)(nil)
+
 // Broadcast sends a message to all connected clients.
 func (h *Hub) Broadcast(message []byte) {
-	h.broadcast <- message
+	h.BroadcastViews(Views{VerbosityFull: message})
+}
+
+// BroadcastViews sends each connected client the payload for its verbosity level and
+// retains the views as the snapshot new clients receive on connect.
+func (h *Hub) BroadcastViews(views Views) {
+	h.mu.Lock()
+	h.lastBroadcast = views
+	h.mu.Unlock()
+	h.broadcast <- views
+}
+
+// Notify sends an out-of-band event to all connected clients without replacing the
+// retained snapshot that new clients receive on connect.
+func (h *Hub) Notify(message []byte) {
+	h.broadcast <- Views{VerbosityFull: message}
+}
+
+// BroadcastRetained sends a message to all connected clients and keeps it under key, replacing
+// any earlier message with the same key, so clients that connect later receive it too.
+func (h *Hub) BroadcastRetained(key string, message []byte) {
+	h.BroadcastRetainedViews(key, Views{VerbosityFull: message})
+}
+
+// BroadcastRetainedViews is BroadcastRetained with a payload per verbosity level.
+func (h *Hub) BroadcastRetainedViews(key string, views Views) {
+	h.mu.Lock()
+	h.retained[key] = views
+	h.mu.Unlock()
+	h.broadcast <- views
 }
 
 // SendCommand sends a command to be processed by external handlers.
@@ -84,25 +320,29 @@ func (h *Hub) SendCommand(command []byte) {
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
+	// Negotiate permessage-deflate; the JSON snapshots and bar series compress very well
+	EnableCompression: true,
+	// Clients offering "msgpack" get binary snapshots; no subprotocol means JSON
+	Subprotocols: []string{EncodingMsgpack},
 	// Allow localhost and 10.10.10.0/24 network
 	CheckOrigin: func(r *http.Request) bool {
 		origin := r.Header.Get("Origin")
 		if origin == "" {
 			return true // Allow requests without Origin header
 		}
-		
+
 		// Allow localhost (development)
 		if origin == "http://localhost:5173" || origin == "https://localhost:5173" {
 			return true
 		}
-		
+
 		// Allow 10.10.10.0/24 network
 		if host, _, err := net.SplitHostPort(r.Host); err == nil {
 			if strings.HasPrefix(host, "10.10.10.") {
 				return true
 			}
 		}
-		
+
 		return false
 	},
 }
@@ -114,7 +354,11 @@ func (h *Hub) ServeWs(w http.ResponseWriter, r *http.Request) {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: h, conn: conn, send: make(chan []byte, 256)}
+	client := &Client{hub: h, conn: conn, send: make(chan []byte, 256), remoteAddr: r.RemoteAddr, connectedAt: time.Now(),
+		encoding: EncodingJSON}
+	if conn.Subprotocol() == EncodingMsgpack {
+		client.encoding = EncodingMsgpack
+	}
 	h.register <- client
 
 	// Allow collection of memory referenced by the caller by doing all work in new goroutines.
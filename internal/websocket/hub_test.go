@@ -0,0 +1,144 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestDeliverEvictsSlowClient(t *testing.T) {
+	h := NewHub()
+	fast := &Client{hub: h, send: make(chan []byte, 4), remoteAddr: "10.10.10.1:1000"}
+	slow := &Client{hub: h, send: make(chan []byte, 1), remoteAddr: "10.10.10.2:2000"}
+	h.clients[fast] = true
+	h.clients[slow] = true
+
+	h.deliver([]byte("a"))
+	h.deliver([]byte("b")) // slow client's buffer is full
+
+	stats := h.Stats()
+	if stats.Connected != 1 || stats.Evictions != 1 {
+		t.Fatalf("connected=%d evictions=%d, want 1 and 1", stats.Connected, stats.Evictions)
+	}
+	if c := stats.Clients[0]; c.RemoteAddr != fast.remoteAddr || c.Sent != 2 || c.Dropped != 0 || c.Buffered != 2 {
+		t.Fatalf("fast client stats = %+v", c)
+	}
+	if slow.sent.Load() != 1 || slow.dropped.Load() != 1 {
+		t.Fatalf("slow client sent=%d dropped=%d, want 1 and 1", slow.sent.Load(), slow.dropped.Load())
+	}
+	if _, ok := <-slow.send; !ok {
+		t.Fatal("expected buffered message before close")
+	}
+	if _, ok := <-slow.send; ok {
+		t.Fatal("slow client send channel should be closed")
+	}
+}
+
+func TestDeliverViewsFollowsClientVerbosity(t *testing.T) {
+	h := NewHub()
+	full := &Client{hub: h, send: make(chan []byte, 4)}
+	ticks := &Client{hub: h, send: make(chan []byte, 4)}
+	account := &Client{hub: h, send: make(chan []byte, 4)}
+	h.clients[full] = true
+	h.clients[ticks] = true
+	h.clients[account] = true
+
+	if !ticks.handleVerbosity([]byte(`{"type":"SET_VERBOSITY","level":"ticks"}`)) {
+		t.Fatal("SET_VERBOSITY should be handled by the client")
+	}
+	account.handleVerbosity([]byte(`{"type":"SET_VERBOSITY","level":"account"}`))
+	ticks.handleVerbosity([]byte(`{"type":"SET_VERBOSITY","level":"everything"}`)) // ignored
+	if full.handleVerbosity([]byte(`{"type":"STRATEGY_STOP"}`)) {
+		t.Fatal("other commands must be forwarded")
+	}
+
+	h.deliverViews(Views{VerbosityFull: []byte("full"), VerbosityTicks: []byte("ticks"), VerbosityAccount: nil})
+	h.deliver([]byte("event"))
+
+	for c, want := range map[*Client][]string{full: {"full", "event"}, ticks: {"ticks", "event"}, account: {"event"}} {
+		if len(c.send) != len(want) {
+			t.Fatalf("%s client got %d messages, want %v", c.Verbosity(), len(c.send), want)
+		}
+		for _, w := range want {
+			if got := string(<-c.send); got != w {
+				t.Fatalf("%s client got %q, want %q", c.Verbosity(), got, w)
+			}
+		}
+	}
+	if got := h.Verbosities(); len(got) != 3 || !got[VerbosityTicks] {
+		t.Fatalf("verbosities = %v", got)
+	}
+}
+
+func TestDeliverViewsFollowsClientEncoding(t *testing.T) {
+	h := NewHub()
+	jsonClient := &Client{hub: h, send: make(chan []byte, 4)}
+	packed := &Client{hub: h, send: make(chan []byte, 4), encoding: EncodingMsgpack}
+	packedTicks := &Client{hub: h, send: make(chan []byte, 4), encoding: EncodingMsgpack}
+	packedTicks.handleVerbosity([]byte(`{"type":"SET_VERBOSITY","level":"ticks"}`))
+	h.clients[jsonClient] = true
+	h.clients[packed] = true
+	h.clients[packedTicks] = true
+
+	keys := h.ViewKeys()
+	if len(keys) != 3 || !keys[VerbosityFull] || !keys[MsgpackView(VerbosityFull)] || !keys[MsgpackView(VerbosityTicks)] {
+		t.Fatalf("view keys = %v", keys)
+	}
+	h.deliverViews(Views{VerbosityFull: []byte("{full}"), MsgpackView(VerbosityFull): {0x81}, VerbosityTicks: []byte("{ticks}")})
+	h.deliver([]byte("{event}"))
+
+	for c, want := range map[*Client][]string{jsonClient: {"{full}", "{event}"}, packed: {"\x81", "{event}"}, packedTicks: {"{ticks}", "{event}"}} {
+		for _, w := range want {
+			if got := string(<-c.send); got != w {
+				t.Fatalf("%s/%s client got %q, want %q", c.Encoding(), c.Verbosity(), got, w)
+			}
+		}
+	}
+	if frameType([]byte{0x81}) != websocket.BinaryMessage || frameType([]byte("{}")) != websocket.TextMessage {
+		t.Fatal("MessagePack payloads should go out as binary frames and JSON as text")
+	}
+}
+
+// BenchmarkDeliverViews measures fan-out latency of one 64 KiB snapshot to 100 and 1000 clients.
+func BenchmarkDeliverViews(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("clients=%d", n), func(b *testing.B) {
+			h := NewHub()
+			clients := make([]*Client, n)
+			for i := range clients {
+				clients[i] = &Client{hub: h, send: make(chan []byte, 1)}
+				h.clients[clients[i]] = true
+			}
+			views := Views{VerbosityFull: make([]byte, 64<<10)}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.deliverViews(views)
+				b.StopTimer()
+				for _, c := range clients {
+					<-c.send
+				}
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func TestDeliverViewsFansOutAcrossWorkers(t *testing.T) {
+	h := NewHub()
+	n := fanoutChunk*fanoutWorkers + 10
+	clients := make([]*Client, n)
+	for i := range clients {
+		clients[i] = &Client{hub: h, send: make(chan []byte, 1)}
+		h.clients[clients[i]] = true
+	}
+	h.deliver([]byte("a"))
+	<-clients[0].send // only this client keeps up
+	h.deliver([]byte("b"))
+	if stats := h.Stats(); stats.Connected != 1 || stats.Evictions != int64(n-1) {
+		t.Fatalf("connected=%d evictions=%d, want 1 and %d", stats.Connected, stats.Evictions, n-1)
+	}
+	if got := string(<-clients[0].send); got != "b" {
+		t.Fatalf("kept client got %q, want b", got)
+	}
+}
@@ -13,6 +13,36 @@ set -euo pipefail
 #
 # Parameters (environment variables):
 #   - GOTRADER_ADDR: address:port to bind the backend (default ":8080"). Example: "0.0.0.0:8080".
+#     If the port is taken, the next ports are tried in turn.
+#   - GOTRADER_TLS_CERT / GOTRADER_TLS_KEY: PEM cert and key paths; when both are set the backend
+#     serves HTTPS/wss:// instead of plain HTTP.
+#   - KILL_PORT_CONFLICTS: "true" to kill the process holding the port instead of moving on (default off).
+#   - GOTRADER_ADMIN_TOKEN: enables admin endpoints (e.g. POST /api/state/clear); send it as
+#     "Authorization: Bearer <token>". Admin endpoints are disabled when unset.
+#   - GOTRADER_DB_RETENTION: per-table row retention, e.g. "logs:30d,strategy_events:90d" (tables:
+#     trades, logs, strategy_events). Purged hourly; POST /api/admin/purge runs it on demand.
+#   - GOTRADER_TICK_DEDUP: "timestamp" or "exact" to drop ticks resent with the newest tick's timestamp
+#     ("exact" also requires identical bid/ask; default "off"). Dropped ticks are counted in /healthz.
+#   - GOTRADER_DERISK_MARGIN_RATIO: e.g. "0.25" closes the largest losing positions whenever
+#     MarginAvailable/Equity falls below 25% until it recovers (default 0, off). POST
+#     /api/derisk?targetMargin=0.3 runs the same liquidation on demand.
+#   - GOTRADER_ACCOUNT_CURRENCY: currency of the broker account, e.g. "EUR" (default "USD"). Locally
+#     computed PnL, pip values and notional limits are converted into it using the tick stream.
+#   - GOTRADER_TICK_BUFFER_SIZES: ticks retained per instrument, overriding the default 20, e.g.
+#     "EURUSD:2000" for scalping on TEN_SECS.
+#   - GOTRADER_BAR_BUFFER_SIZES: bars retained per period, overriding the default 200, e.g.
+#     "TEN_SECS:1000,DAILY:50".
+#   - GOTRADER_BROADCAST_BAR_CAPS: send only the newest N bars of a period to WebSocket clients,
+#     e.g. "TEN_SECS:60,ONE_MIN:60" (default: all). GET /api/bars?instrument=&period= returns every bar.
+#   - GOTRADER_CHAOS: "1" injects faults for resilience testing (never in production). Knobs:
+#     GOTRADER_CHAOS_DROP_RATE (fraction of data messages lost, default 0.01), GOTRADER_CHAOS_LATENCY
+#     (random delay per message, default "0s-100ms"), GOTRADER_CHAOS_DISCONNECT_INTERVAL (fail a
+#     consumer channel, default 5m), GOTRADER_CHAOS_CONNECTION_DROP_INTERVAL (drop and re-dial the
+#     consumer connection, default 30m), GOTRADER_CHAOS_DB_STALL_RATE / GOTRADER_CHAOS_DB_STALL
+#     (fraction of DB writes stalled and for how long, default 0.01 / 5s).
+#   - GOTRADER_CONFIG: optional KEY=VALUE file using the same GOTRADER_* keys (file values win).
+#     Send SIGHUP (kill -HUP <pid>) to reload risk limits, slippage, min stops, price digits,
+#     session boundary, broadcast interval, bar caps and warn throttle without a restart.
 #
 # Returns:
 #   This script replaces itself with the running server (exec). Exit code is the server's exit code.